@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"first_aid_companion/services"
+	"fmt"
+	"log"
+)
+
+// blobsUsage describes the blobs subcommand.
+const blobsUsage = `usage: main blobs <command>
+
+commands:
+  move     move document files from the database to the blob store
+  restore  copy document files from the blob store back into the database`
+
+// runBlobs executes the "blobs" subcommand with the given arguments.
+func runBlobs(dbService *services.DBService, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("invalid arguments\n%s", blobsUsage)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "move":
+		moved, err := services.MoveFilesToBlobs(ctx, dbService.DocsDB, dbService.Blobs, dbService.Keyring)
+		log.Printf("Moved %d document files to the blob store", moved)
+		return err
+	case "restore":
+		restored, err := services.MoveFilesToDatabase(ctx, dbService.DocsDB, dbService.Blobs, dbService.Keyring)
+		log.Printf("Restored %d document files to the database", restored)
+		return err
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], blobsUsage)
+}
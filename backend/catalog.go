@@ -0,0 +1,28 @@
+package main
+
+import (
+	"first_aid_companion/services"
+	"fmt"
+	"log"
+)
+
+// catalogUsage describes the catalog subcommand.
+const catalogUsage = `usage: main catalog import <file>
+
+Imports a drug registry file (.csv with a header row or .json array) into the catalog.
+Entries with a barcode replace existing entries with the same barcode, entries without
+one replace existing entries with the same name, manufacturer, dose and amount.`
+
+// runCatalog executes the "catalog" subcommand with the given arguments.
+func runCatalog(dbService *services.DBService, args []string) error {
+	if len(args) != 2 || args[0] != "import" {
+		return fmt.Errorf("invalid arguments\n%s", catalogUsage)
+	}
+
+	imported, skipped, err := services.ImportCatalog(dbService.CatalogDB, args[1])
+	if err != nil {
+		return err
+	}
+	log.Printf("Imported %d catalog entries, skipped %d", imported, skipped)
+	return nil
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"first_aid_companion/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Limits of catalog search results
+const (
+	defaultCatalogResults = 20
+	maxCatalogResults     = 50
+)
+
+// CatalogService provides search and barcode lookup in the offline drug catalog.
+type CatalogService struct {
+	DB *models.CatalogGorm // Database access object for the catalog
+}
+
+// prefilledDrug converts a catalog entry into a drug creation request.
+// The expiry date is estimated from the shelf life and should be checked against the pack.
+func prefilledDrug(entry *models.CatalogDrug) *DrugCreationRequest {
+	drug := &DrugCreationRequest{
+		Name:         entry.Name,
+		Type:         entry.Type,
+		Description:  entry.Description,
+		Manufacturer: entry.Manufacturer,
+		Dose:         entry.Dose,
+		Amount:       entry.Amount,
+		Quantity:     entry.Quantity,
+		Unit:         entry.Unit,
+	}
+	if entry.ShelfLifeMonths > 0 {
+		y, m, d := time.Now().UTC().Date()
+		drug.Expiry = time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, entry.ShelfLifeMonths, 0)
+	}
+	return drug
+}
+
+// @Summary Search drug catalog
+// @Description Finds catalog entries by name, tolerating typos. Best matches come first.
+// @Tags catalog
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Name or part of it"
+// @Param limit query int false "Maximum number of results, 20 by default, at most 50"
+// @Success 200 {object} APIResponse{data=[]models.CatalogDrug}
+// @Failure 422 {object} APIResponse "Empty query"
+// @Router /auth/catalog/search [get]
+func (cs *CatalogService) Search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		WriteRequestError(w, NewValidationError("q", "search query must not be empty"))
+		return
+	}
+
+	limit := defaultCatalogResults
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxCatalogResults {
+			WriteRequestError(w, NewValidationError("limit", "limit must be between 1 and 50"))
+			return
+		}
+		limit = n
+	}
+
+	drugs, err := cs.DB.SearchCatalog(query, limit)
+	if err != nil {
+		log.Printf("Error searching catalog: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: drugs})
+}
+
+// @Summary Look up drug by barcode
+// @Description Returns a prefilled drug for a GTIN/EAN barcode, ready to be sent to /auth/drugs/add.
+// @Description The expiry date is estimated from the shelf life if the catalog knows it.
+// @Tags catalog
+// @Produce json
+// @Security BearerAuth
+// @Param gtin path string true "GTIN-8, UPC, EAN-13 or GTIN-14 barcode"
+// @Success 200 {object} APIResponse{data=DrugCreationRequest}
+// @Failure 404 {object} APIResponse "Barcode not in catalog"
+// @Failure 422 {object} APIResponse "Invalid barcode"
+// @Router /auth/catalog/barcode/{gtin} [get]
+func (cs *CatalogService) Barcode(w http.ResponseWriter, r *http.Request) {
+	gtin, err := models.NormalizeGTIN(mux.Vars(r)["gtin"])
+	if err != nil {
+		WriteRequestError(w, NewValidationError("gtin", err.Error()))
+		return
+	}
+
+	entry, err := cs.DB.GetCatalogDrugByGTIN(gtin)
+	if err != nil {
+		WriteLookupError(w, err, "drug")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: prefilledDrug(entry)})
+}
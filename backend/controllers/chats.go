@@ -98,6 +98,7 @@ func (cs *ChatService) GetUsersChats(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path int true "Chat ID"
 // @Success 200 {object} APIResponse "[ {id: message_id, sender: 0/1, text: message_text}, ...]"
+// @Failure 404 {object} APIResponse "Chat not found"
 // @Failure 500 {object} APIResponse "Failed to fetch chat or messages"
 // @Router /auth/chats/{id} [get]
 // @Security BearerAuth
@@ -111,11 +112,18 @@ func (cs *ChatService) GetChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Retrieve the chat and its associated messages from the database by chat ID
-	chat, err := cs.DB.GetChatByID(uint(id))
+	// Get authenticated user ID from context
+	userID, _, err := GetUserFromContext(r.Context(), cs.DB.DB)
 	if err != nil {
-		log.Printf("Error getting chat in GetChat: %v", err)
-		WriteError(w, 500, err.Error())
+		log.Printf("Error getting user in GetChat: %v", err)
+		WriteError(w, 401, err.Error())
+		return
+	}
+
+	// Retrieve the chat and its associated messages, only if it belongs to the user
+	chat, err := cs.DB.GetUserChat(uint(userID), uint(id))
+	if err != nil {
+		WriteLookupError(w, err, "chat")
 		return
 	}
 
@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"first_aid_companion/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// OnBehalfHeader selects the dependent profile a request acts for.
+const OnBehalfHeader = "X-On-Behalf-Of"
+
+// Limits of the audit trail
+const (
+	defaultAuditEntries = 50
+	maxAuditEntries     = 500
+)
+
+// accountPaths are endpoints about the caller's own account, they can't be used on behalf of a profile.
+var accountPaths = []string{"/auth/logout", "/auth/sessions", "/auth/dependents", "/auth/groups", "/auth/invitations"}
+
+// DependentRequest represents a request to create or change a dependent profile.
+type DependentRequest struct {
+	Name      string     `json:"name" example:"Grandma"`                    // Name of the profile
+	BirthDate *time.Time `json:"birth_date" example:"1950-03-01T00:00:00Z"` // Optional date of birth
+	GroupID   *uint      `json:"group_id"`                                  // Household whose owner and caregivers also manage the profile
+}
+
+// DependentService manages dependent profiles and requests made on their behalf.
+type DependentService struct {
+	DB      *models.DependentGorm // Database access object for dependents and the audit trail
+	UserDB  *models.UserGorm      // Users, profiles are removed with all their data
+	GroupDB *models.GroupGorm     // Memberships, group caregivers manage the group's profiles
+
+	Documents *DocumentService // Deletes the files of removed profiles
+}
+
+// Validate checks the profile name.
+func (req *DependentRequest) Validate() *RequestError {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return NewValidationError("name", "name must not be empty")
+	}
+	return nil
+}
+
+// ActorFromContext returns the caregiver making a request on behalf of a profile.
+// Returns false for requests made by users for themselves.
+func ActorFromContext(ctx context.Context) (uint, bool) {
+	actor, ok := ctx.Value("actor").(uint)
+	return actor, ok
+}
+
+// ActOnBehalf checks that the caller may act for the profile in the X-On-Behalf-Of header and
+// returns the request with the profile as the current user. The caller is kept as the actor.
+func (ds *DependentService) ActOnBehalf(r *http.Request) (*http.Request, *RequestError) {
+	for _, path := range accountPaths {
+		if r.URL.Path == path || strings.HasPrefix(r.URL.Path, path+"/") {
+			return nil, NewValidationError(OnBehalfHeader, "this endpoint can't be used on behalf of a profile")
+		}
+	}
+	if r.URL.Path == "/auth/me" && r.Method == http.MethodDelete {
+		return nil, NewValidationError(OnBehalfHeader, "use /auth/dependents/remove to remove a profile")
+	}
+
+	id, err := strconv.Atoi(r.Header.Get(OnBehalfHeader))
+	if err != nil {
+		return nil, NewNotFoundError("profile not found")
+	}
+	claims, ok := r.Context().Value("user").(*Claims)
+	if !ok || claims == nil {
+		return nil, &RequestError{Status: http.StatusUnauthorized, Message: "no user in context"}
+	}
+	actor, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return nil, &RequestError{Status: http.StatusUnauthorized, Message: "invalid token"}
+	}
+
+	if _, err := ds.DB.GetManagedDependent(uint(actor), uint(id)); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Error checking dependent access: %v", err)
+		}
+		return nil, NewNotFoundError("profile not found")
+	}
+
+	// The profile has no email, tokens of its own or sessions
+	profile := *claims
+	profile.UserID = strconv.Itoa(id)
+	profile.Email = ""
+	ctx := context.WithValue(r.Context(), "user", &profile)
+	ctx = context.WithValue(ctx, "actor", uint(actor))
+	return r.WithContext(ctx), nil
+}
+
+// RecordAccess adds a request made on behalf of a profile to its audit trail.
+func (ds *DependentService) RecordAccess(r *http.Request, status int) {
+	actor, ok := ActorFromContext(r.Context())
+	if !ok {
+		return
+	}
+	profileID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching profile for audit: %v", err)
+		return
+	}
+	ds.record(uint(profileID), actor, r, status)
+}
+
+// record writes an audit entry, failures are logged and don't affect the response.
+func (ds *DependentService) record(profileID, actor uint, r *http.Request, status int) {
+	err := ds.DB.RecordAccess(&models.AuditEntry{
+		ProfileID: profileID,
+		ActorID:   &actor,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    status,
+	})
+	if err != nil {
+		log.Printf("Error recording profile access: %v", err)
+	}
+}
+
+// checkGroup checks that the user may attach profiles to the group.
+// Writes the error response and returns false otherwise.
+func (ds *DependentService) checkGroup(w http.ResponseWriter, userID uint, groupID *uint) bool {
+	if groupID == nil {
+		return true
+	}
+	member, err := ds.GroupDB.GetMembership(userID, *groupID)
+	if err != nil {
+		WriteLookupError(w, err, "group")
+		return false
+	}
+	if !member.Can(models.PermManageDependents) {
+		WriteRequestError(w, NewForbiddenError("your role does not allow managing profiles of the group"))
+		return false
+	}
+	return true
+}
+
+// managedDependent returns the profile from the path if the current user may manage it.
+// Writes the error response and returns nil otherwise.
+func (ds *DependentService) managedDependent(w http.ResponseWriter, r *http.Request) (uint, *models.Dependent) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("profile not found"))
+		return 0, nil
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return 0, nil
+	}
+
+	dependent, err := ds.DB.GetManagedDependent(uint(userID), uint(id))
+	if err != nil {
+		WriteLookupError(w, err, "profile")
+		return 0, nil
+	}
+	return uint(userID), dependent
+}
+
+// @Summary Get dependent profiles
+// @Description Returns the profiles the user manages: profiles they created and profiles of
+// @Description groups where they are the owner or a caregiver. Send a profile's ID in the
+// @Description X-On-Behalf-Of header to use the other /auth endpoints for it.
+// @Tags dependents
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]models.Dependent}
+// @Router /auth/dependents [get]
+func (ds *DependentService) Dependents(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	dependents, err := ds.DB.GetManagedDependents(uint(userID))
+	if err != nil {
+		log.Printf("Error fetching dependents: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: dependents})
+}
+
+// @Summary Create a dependent profile
+// @Description Creates a profile with an empty medical card for someone without an account.
+// @Description With group_id the owner and caregivers of the group manage it too.
+// @Tags dependents
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body DependentRequest true "profile"
+// @Success 200 {object} APIResponse{data=models.Dependent}
+// @Failure 403 {object} APIResponse "Role does not allow managing profiles of the group"
+// @Failure 404 {object} APIResponse "Group not found"
+// @Failure 422 {object} APIResponse "Empty name"
+// @Router /auth/dependents [post]
+func (ds *DependentService) AddDependent(w http.ResponseWriter, r *http.Request) {
+	request := &DependentRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+	if !ds.checkGroup(w, uint(userID), request.GroupID) {
+		return
+	}
+
+	creator := uint(userID)
+	dependent, err := ds.DB.CreateDependent(request.Name, &models.Dependent{
+		CreatedBy: &creator,
+		GroupID:   request.GroupID,
+		BirthDate: request.BirthDate,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error creating dependent in AddDependent: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	ds.record(dependent.UserID, creator, r, 200)
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: dependent})
+	log.Println("Successfully added a dependent profile!")
+}
+
+// @Summary Get dependent profile
+// @Tags dependents
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Profile ID"
+// @Success 200 {object} APIResponse{data=models.Dependent}
+// @Failure 404 {object} APIResponse "Profile not found"
+// @Router /auth/dependents/{id} [get]
+func (ds *DependentService) GetDependent(w http.ResponseWriter, r *http.Request) {
+	_, dependent := ds.managedDependent(w, r)
+	if dependent == nil {
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: dependent})
+}
+
+// @Summary Change a dependent profile
+// @Description Changes the name, date of birth and group of a profile.
+// @Tags dependents
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Profile ID"
+// @Param input body DependentRequest true "profile"
+// @Success 200 {object} APIResponse{data=models.Dependent}
+// @Failure 403 {object} APIResponse "Role does not allow managing profiles of the group"
+// @Failure 404 {object} APIResponse "Profile or group not found"
+// @Failure 422 {object} APIResponse "Empty name"
+// @Router /auth/dependents/{id} [put]
+func (ds *DependentService) UpdateDependent(w http.ResponseWriter, r *http.Request) {
+	userID, dependent := ds.managedDependent(w, r)
+	if dependent == nil {
+		return
+	}
+
+	request := &DependentRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	if !ds.checkGroup(w, userID, request.GroupID) {
+		return
+	}
+
+	dependent.Name = request.Name
+	dependent.BirthDate = request.BirthDate
+	dependent.GroupID = request.GroupID
+	if err := ds.DB.UpdateDependent(dependent); err != nil {
+		log.Printf("Error updating dependent in UpdateDependent: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	ds.record(dependent.UserID, userID, r, 200)
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: dependent})
+}
+
+// @Summary Remove a dependent profile
+// @Description Permanently deletes the profile with its medical card, drugs, documents and other data.
+// @Tags dependents
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Profile ID"
+// @Success 200 {object} APIResponse{data=models.DeletionSummary}
+// @Failure 404 {object} APIResponse "Profile not found"
+// @Router /auth/dependents/remove/{id} [post]
+func (ds *DependentService) RemoveDependent(w http.ResponseWriter, r *http.Request) {
+	_, dependent := ds.managedDependent(w, r)
+	if dependent == nil {
+		return
+	}
+
+	summary, err := ds.UserDB.DeleteUserCascade(dependent.UserID)
+	if err != nil {
+		log.Printf("Error removing dependent in RemoveDependent: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	ds.Documents.releaseBlobs(r.Context(), summary.BlobKeys)
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: summary})
+	log.Println("Successfully removed dependent profile!")
+}
+
+// @Summary Get profile audit trail
+// @Description Returns who accessed or changed the profile and when, newest first.
+// @Tags dependents
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Profile ID"
+// @Param limit query int false "Maximum number of entries, 50 by default, at most 500"
+// @Success 200 {object} APIResponse{data=[]models.AuditEntry}
+// @Failure 404 {object} APIResponse "Profile not found"
+// @Failure 422 {object} APIResponse "Invalid limit"
+// @Router /auth/dependents/{id}/audit [get]
+func (ds *DependentService) AuditTrail(w http.ResponseWriter, r *http.Request) {
+	_, dependent := ds.managedDependent(w, r)
+	if dependent == nil {
+		return
+	}
+
+	limit := defaultAuditEntries
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxAuditEntries {
+			WriteRequestError(w, NewValidationError("limit", "limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := ds.DB.GetAuditTrail(dependent.UserID, limit)
+	if err != nil {
+		log.Printf("Error fetching audit trail: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: entries})
+}
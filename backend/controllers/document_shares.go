@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"errors"
+	"first_aid_companion/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// SharePINHeader carries the PIN of a PIN-protected share link.
+const SharePINHeader = "X-Share-PIN"
+
+// Limits of share links
+const (
+	defaultShareHours = 24      // Lifetime of a link unless the user chooses one
+	maxShareHours     = 30 * 24 // Longest lifetime of a link
+	maxPINAttempts    = 5       // Wrong PINs after which a link is revoked
+)
+
+// ShareRequest represents a request to share a document.
+type ShareRequest struct {
+	ExpiresInHours int    `json:"expires_in_hours" example:"72"` // Lifetime of the link, 24 hours by default, 720 at most
+	PIN            string `json:"pin" example:"4821"`            // Optional PIN of 4 to 8 digits the viewer has to enter
+}
+
+// ShareResponse is a share link with its URL and state.
+type ShareResponse struct {
+	models.DocumentShare
+	URL          string `json:"url,omitempty" example:"https://example.com/shared/q3X9..."` // Link to send to the viewer, only returned when it is created
+	PINProtected bool   `json:"pin_protected"`
+	Active       bool   `json:"active"` // Whether the link still works
+}
+
+// SharedDocument is the read-only view of a shared document.
+type SharedDocument struct {
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	Date         time.Time `json:"date"`
+	Doctor       string    `json:"doctor"`
+	FileName     string    `json:"file_name" example:"blood_test.pdf"`
+	ContentType  string    `json:"content_type" example:"application/pdf"`
+	Size         int64     `json:"size" example:"48213"`
+	ExpiresAt    time.Time `json:"expires_at"`                                                 // When the link stops working
+	FileURL      string    `json:"file_url" example:"https://example.com/shared/q3X9.../file"` // Where the file is downloaded from
+	PINProtected bool      `json:"pin_protected"`
+}
+
+// Validate checks the lifetime and PIN of the link.
+func (req *ShareRequest) Validate() *RequestError {
+	if req.ExpiresInHours == 0 {
+		req.ExpiresInHours = defaultShareHours
+	}
+	if req.ExpiresInHours < 1 || req.ExpiresInHours > maxShareHours {
+		return NewValidationError("expires_in_hours", "expires_in_hours must be between 1 and "+strconv.Itoa(maxShareHours))
+	}
+	req.PIN = strings.TrimSpace(req.PIN)
+	if req.PIN != "" {
+		if len(req.PIN) < 4 || len(req.PIN) > 8 || strings.Trim(req.PIN, "0123456789") != "" {
+			return NewValidationError("pin", "pin must be 4 to 8 digits")
+		}
+	}
+	return nil
+}
+
+// shareResponse adds the state to a share link.
+func shareResponse(share *models.DocumentShare) *ShareResponse {
+	return &ShareResponse{
+		DocumentShare: *share,
+		PINProtected:  share.PINHash != "",
+		Active:        share.Active(time.Now()),
+	}
+}
+
+// @Summary Share a document
+// @Description Creates a read-only link to the document for someone without an account, e.g. a doctor.
+// @Description The link expires after expires_in_hours and can be revoked earlier. With a PIN the viewer
+// @Description has to send it in the X-Share-PIN header, the link is revoked after 5 wrong PINs.
+// @Description The url is only returned here, the server keeps a hash of its token.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Param input body ShareRequest false "Lifetime and PIN of the link"
+// @Success 200 {object} APIResponse{data=ShareResponse}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Failure 404 {object} APIResponse "Document not found"
+// @Failure 422 {object} APIResponse "Invalid lifetime or PIN"
+// @Router /auth/documents/{id}/share [post]
+func (ds *DocumentService) ShareDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("document not found"))
+		return
+	}
+
+	req := &ShareRequest{}
+	if err := ParseJSON(r, req); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	doc, err := ds.DB.GetUserDocument(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "document")
+		return
+	}
+
+	// Only the hash of the token is stored, the link cannot be shown again
+	token, err := newToken()
+	if err != nil {
+		log.Printf("Error generating share token: %v", err)
+		WriteError(w, 500, "failed to share document")
+		return
+	}
+	now := time.Now()
+	share := &models.DocumentShare{
+		DocumentID: doc.ID,
+		UserID:     doc.UserID,
+		TokenHash:  hashToken(token),
+		ExpiresAt:  now.Add(time.Duration(req.ExpiresInHours) * time.Hour),
+		CreatedAt:  now,
+	}
+	if req.PIN != "" {
+		if share.PINHash, err = HashPassword(req.PIN); err != nil {
+			log.Printf("Error hashing PIN in ShareDocument: %v", err)
+			WriteError(w, 500, "failed to share document")
+			return
+		}
+	}
+
+	if _, err := ds.DB.CreateShare(share); err != nil {
+		log.Printf("Error creating share in ShareDocument: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	response := shareResponse(share)
+	response.URL = publicLink(ds.PublicURL, "/shared/"+token)
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: response})
+}
+
+// @Summary Get share links of a document
+// @Description Lists the links of the document, newest first, with how often and when they were used.
+// @Description The URLs are not included, they are only returned when a link is created.
+// @Tags documents
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Success 200 {object} APIResponse{data=[]ShareResponse}
+// @Failure 404 {object} APIResponse "Document not found"
+// @Router /auth/documents/{id}/shares [get]
+func (ds *DocumentService) DocumentShares(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("document not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if _, err := ds.DB.GetUserDocument(uint(userID), id); err != nil {
+		WriteLookupError(w, err, "document")
+		return
+	}
+	shares, err := ds.DB.GetDocumentShares(uint(userID), id)
+	if err != nil {
+		log.Printf("Error fetching shares: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	responses := make([]*ShareResponse, len(shares))
+	for i := range shares {
+		responses[i] = shareResponse(&shares[i])
+	}
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: responses})
+}
+
+// @Summary Revoke a share link
+// @Description Stops the link from working before it expires. Revoking a revoked link does nothing.
+// @Tags documents
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Share link ID"
+// @Success 200 {object} APIResponse{data=ShareResponse}
+// @Failure 404 {object} APIResponse "Share link not found"
+// @Router /auth/documents/shares/revoke/{id} [post]
+func (ds *DocumentService) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("share link not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	share, err := ds.DB.RevokeUserShare(uint(userID), id, time.Now())
+	if err != nil {
+		WriteLookupError(w, err, "share link")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: shareResponse(share)})
+}
+
+// openShare finds the shared document of the token in the request and checks its PIN.
+// Writes the error response and returns nil if the link cannot be opened.
+func (ds *DocumentService) openShare(w http.ResponseWriter, r *http.Request) (*models.DocumentShare, *models.Document) {
+	// Medical data must not stay in shared caches or search engines, nor leak to linked sites
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	now := time.Now()
+	notFound := NewNotFoundError("shared document not found")
+	share, err := ds.DB.GetShareByHash(hashToken(mux.Vars(r)["token"]))
+	if err != nil {
+		WriteLookupError(w, err, "shared document")
+		return nil, nil
+	}
+	// Unknown, expired and revoked links look the same
+	if !share.Active(now) {
+		WriteRequestError(w, notFound)
+		return nil, nil
+	}
+
+	if share.PINHash != "" {
+		pin := strings.TrimSpace(r.Header.Get(SharePINHeader))
+		if pin == "" {
+			WriteRequestError(w, &RequestError{Status: http.StatusUnauthorized, Field: "pin", Message: "PIN is required"})
+			return nil, nil
+		}
+		// The attempt is counted before the slow hash comparison, so parallel guesses
+		// cannot get past the limit
+		if err := ds.DB.ReservePINAttempt(share.ID, maxPINAttempts, now); errors.Is(err, gorm.ErrRecordNotFound) {
+			WriteRequestError(w, notFound)
+			return nil, nil
+		} else if err != nil {
+			log.Printf("Error reserving PIN attempt of share %d: %v", share.ID, err)
+			WriteError(w, 500, "database error")
+			return nil, nil
+		}
+		if !CheckPasswordHash(pin, share.PINHash) {
+			if err := ds.DB.RevokeAfterFailedPINs(share.ID, maxPINAttempts, now); err != nil {
+				log.Printf("Error revoking share %d after failed PINs: %v", share.ID, err)
+			}
+			WriteRequestError(w, &RequestError{Status: http.StatusForbidden, Field: "pin", Message: "invalid PIN"})
+			return nil, nil
+		}
+		if err := ds.DB.ReleasePINAttempt(share.ID); err != nil {
+			log.Printf("Error releasing PIN attempt of share %d: %v", share.ID, err)
+		}
+	}
+
+	doc, err := ds.DB.GetUserDocument(share.UserID, int(share.DocumentID))
+	if err != nil {
+		WriteLookupError(w, err, "shared document")
+		return nil, nil
+	}
+
+	// Continuations of a download are not counted as another access
+	if rangeHeader := r.Header.Get("Range"); rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-") {
+		if err := ds.DB.RecordShareAccess(share.ID, now); err != nil {
+			log.Printf("Error recording access to share %d: %v", share.ID, err)
+		}
+	}
+	return share, doc
+}
+
+// @Summary Get shared document
+// @Description Read-only view of a document shared with a link, no authentication is required.
+// @Description PIN-protected links need the PIN in the X-Share-PIN header. Unknown, expired and
+// @Description revoked links are not found.
+// @Tags documents
+// @Produce json
+// @Param token path string true "Token from the share link"
+// @Param X-Share-PIN header string false "PIN of the link"
+// @Success 200 {object} APIResponse{data=SharedDocument}
+// @Failure 401 {object} APIResponse "PIN is required"
+// @Failure 403 {object} APIResponse "Invalid PIN"
+// @Failure 404 {object} APIResponse "Shared document not found"
+// @Router /shared/{token} [get]
+func (ds *DocumentService) SharedDocument(w http.ResponseWriter, r *http.Request) {
+	share, doc := ds.openShare(w, r)
+	if share == nil {
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: &SharedDocument{
+		Name:         doc.Name,
+		Type:         doc.Type,
+		Date:         doc.Date,
+		Doctor:       doc.Doctor,
+		FileName:     downloadName(doc),
+		ContentType:  doc.ContentType,
+		Size:         doc.Size,
+		ExpiresAt:    share.ExpiresAt,
+		FileURL:      publicLink(ds.PublicURL, "/shared/"+mux.Vars(r)["token"]+"/file"),
+		PINProtected: share.PINHash != "",
+	}})
+}
+
+// @Summary Download shared document file
+// @Description Streams the file of a shared document like /auth/documents/{id}/file does.
+// @Tags documents
+// @Produce octet-stream
+// @Param token path string true "Token from the share link"
+// @Param X-Share-PIN header string false "PIN of the link"
+// @Param download query bool false "Ask the browser to save the file instead of showing it"
+// @Success 200 {file} file "File contents"
+// @Success 206 {file} file "Requested range of the file"
+// @Failure 401 {object} APIResponse "PIN is required"
+// @Failure 403 {object} APIResponse "Invalid PIN"
+// @Failure 404 {object} APIResponse "Shared document not found"
+// @Router /shared/{token}/file [get]
+func (ds *DocumentService) SharedDocumentFile(w http.ResponseWriter, r *http.Request) {
+	share, doc := ds.openShare(w, r)
+	if share == nil {
+		return
+	}
+	ds.serveFile(w, r, doc, "no-store")
+}
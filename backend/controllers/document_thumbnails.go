@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"first_aid_companion/models"
+	"first_aid_companion/thumbnails"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Caching of thumbnails
+const (
+	thumbnailCacheControl = "private, max-age=86400" // Thumbnails only change when they are regenerated
+	thumbnailRetryAfter   = "5"                      // Seconds after which a pending thumbnail is worth asking for again
+)
+
+// ThumbnailQueue generates the thumbnails of new documents in the background, see services.ThumbnailWorker.
+type ThumbnailQueue interface {
+	// Wake asks for the thumbnails of new documents to be generated soon.
+	Wake()
+}
+
+// @Summary Get document thumbnail
+// @Description Returns a JPEG preview of at most 320 pixels on each side, generated in the background
+// @Description after upload. Images are scaled down, PDFs show their first page with images and shapes in place
+// @Description and text as grey lines. Shadings, patterns and content of form XObjects are not drawn.
+// @Description Text files and empty pages have no preview.
+// @Description Thumbnails may be cached privately for a day and revalidated with If-None-Match.
+// @Tags documents
+// @Produce jpeg
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Success 200 {file} file "JPEG image"
+// @Success 202 {object} APIResponse "The thumbnail is not generated yet, retry after the Retry-After header"
+// @Success 304 {string} string "Not modified"
+// @Failure 404 {object} APIResponse "Document not found or the file has no preview"
+// @Router /auth/documents/{id}/thumbnail [get]
+func (ds *DocumentService) DocumentThumbnail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("document not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	doc, err := ds.DB.GetUserDocument(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "document")
+		return
+	}
+
+	thumb, err := ds.DB.GetThumbnail(doc.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error fetching thumbnail: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	if thumb == nil || thumb.Pending() {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Retry-After", thumbnailRetryAfter)
+		WriteJSON(w, http.StatusAccepted, &APIResponse{Status: http.StatusAccepted})
+		return
+	}
+	if thumb.Status != models.ThumbnailReady {
+		WriteRequestError(w, NewNotFoundError("document has no thumbnail"))
+		return
+	}
+
+	data, err := ds.Keyring.DecryptFile(thumb.Data)
+	if err != nil {
+		log.Printf("Error decrypting thumbnail of document %d: %v", doc.ID, err)
+		WriteError(w, 500, "storage error")
+		return
+	}
+
+	w.Header().Set("Content-Type", thumbnails.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", thumbnailCacheControl)
+	w.Header().Set("ETag", `"`+thumb.SHA256+`"`)
+	http.ServeContent(w, r, "", thumb.CreatedAt, bytes.NewReader(data))
+}
@@ -1,35 +1,201 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"first_aid_companion/encryption"
 	"first_aid_companion/models"
+	"first_aid_companion/storage"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// DefaultMaxDocumentSize is the largest accepted document file unless MAX_DOCUMENT_MB is set.
+const DefaultMaxDocumentSize = 20 << 20
+
+// maxFormFieldSize limits the text fields of a multipart upload.
+const maxFormFieldSize = 4 << 10
+
+// documentTypes maps the MIME types accepted for documents to file extensions.
+// The type is detected from the file contents, the one sent by the client is ignored.
+var documentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"text/plain":      ".txt",
+}
+
 // DocumentService handles operations related to user's documents, interfacing with the database.
 type DocumentService struct {
-	DB *models.DocumentGorm
+	DB         *models.DocumentGorm
+	Blobs      storage.BlobStore   // Where the files are kept
+	Keyring    *encryption.Keyring // Encrypts the files with the data key of their owner
+	MaxSize    int64               // Largest accepted file in bytes, DefaultMaxDocumentSize if zero
+	PublicURL  string              // Base URL of share links
+	Thumbnails ThumbnailQueue      // Woken up after uploads to generate the thumbnails, may be nil
 }
 
 type DocumentUploadRequest struct {
-	Name     string    `json:"name"`                                // Name/title of the document
+	Name     string    `json:"name"`                                // Name/title of the document, the file name by default
 	Type     string    `json:"type"`                                // Type/category of document (e.g. prescription, report)
 	Date     time.Time `json:"date" example:"2025-07-12T23:45:00Z"` // Date the document was created or issued
 	Doctor   string    `json:"doctor"`                              // Name of the doctor associated with the document
+	FileName string    `json:"file_name" example:"blood_test.pdf"`  // Name of the file, used for downloads
 	FileData []byte    `json:"file_data"`                           // File contents (binary), base64-encoded when serialized to JSON
+
+	contentType string // Detected MIME type
+}
+
+// maxSize returns the largest accepted file in bytes.
+func (ds *DocumentService) maxSize() int64 {
+	if ds.MaxSize > 0 {
+		return ds.MaxSize
+	}
+	return DefaultMaxDocumentSize
+}
+
+// Validate checks the size and type of the file and fills in the name.
+func (req *DocumentUploadRequest) Validate(maxSize int64) *RequestError {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Type = strings.TrimSpace(req.Type)
+	req.Doctor = strings.TrimSpace(req.Doctor)
+	req.FileName = strings.TrimSpace(req.FileName)
+	if req.FileName != "" {
+		// Only the base name is kept from paths sent by some browsers
+		req.FileName = filepath.Base(strings.ReplaceAll(req.FileName, "\\", "/"))
+		if req.FileName == "." || req.FileName == "/" {
+			req.FileName = ""
+		}
+	}
+
+	if len(req.FileData) == 0 {
+		return NewValidationError("file", "file must not be empty")
+	}
+	if int64(len(req.FileData)) > maxSize {
+		return &RequestError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Field:   "file",
+			Message: fmt.Sprintf("file must not be larger than %d MB", maxSize>>20),
+		}
+	}
+
+	req.contentType = http.DetectContentType(req.FileData)
+	mediaType, _, _ := mime.ParseMediaType(req.contentType)
+	if _, ok := documentTypes[mediaType]; !ok {
+		return &RequestError{
+			Status:  http.StatusUnsupportedMediaType,
+			Field:   "file",
+			Message: "unsupported file type " + mediaType + ", upload a PDF, an image or a text file",
+		}
+	}
+
+	if req.Name == "" {
+		req.Name = strings.TrimSuffix(req.FileName, filepath.Ext(req.FileName))
+	}
+	if req.Name == "" {
+		return NewValidationError("name", "name must not be empty")
+	}
+	return nil
+}
+
+// readMultipart fills the request from a multipart/form-data body with the fields name, type,
+// date, doctor and file. The body is streamed, so files above maxSize are not read to the end.
+func readMultipart(r *http.Request, req *DocumentUploadRequest, maxSize int64) *RequestError {
+	invalid := &RequestError{Status: http.StatusBadRequest, Message: "invalid multipart form"}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return invalid
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return &RequestError{Status: http.StatusRequestEntityTooLarge, Message: "request is too large"}
+			}
+			return invalid
+		}
+
+		if part.FormName() == "file" {
+			req.FileName = part.FileName()
+			// One byte more than allowed is enough for Validate to reject the file
+			req.FileData, err = io.ReadAll(io.LimitReader(part, maxSize+1))
+			part.Close()
+			if err != nil {
+				return invalid
+			}
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize))
+		part.Close()
+		if err != nil {
+			return invalid
+		}
+		value := string(data)
+		switch part.FormName() {
+		case "name":
+			req.Name = value
+		case "type":
+			req.Type = value
+		case "doctor":
+			req.Doctor = value
+		case "date":
+			if req.Date, err = parseDocumentDate(value); err != nil {
+				return NewValidationError("date", "date must be in RFC 3339 or YYYY-MM-DD format")
+			}
+		}
+	}
+}
+
+// parseDocumentDate parses a form date such as "2025-07-12T23:45:00Z" or "2025-07-12".
+func parseDocumentDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// downloadName returns the file name offered to browsers for the document.
+func downloadName(doc *models.Document) string {
+	if doc.FileName != "" {
+		return doc.FileName
+	}
+	mediaType, _, _ := mime.ParseMediaType(doc.ContentType)
+	name := strings.TrimSpace(doc.Name)
+	if name == "" {
+		name = fmt.Sprintf("document-%d", doc.ID)
+	}
+	return name + documentTypes[mediaType]
 }
 
 // @Summary Get all documents
-// @Description Returns all user documents in json format
+// @Description Returns metadata of all user documents, files are downloaded from /auth/documents/{id}/file
 // @Tags documents
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} models.Document
+// @Success 200 {object} APIResponse{data=[]models.Document}
 // @Router /auth/documents [get]
 func (ds *DocumentService) Documents(w http.ResponseWriter, r *http.Request) {
 	// Get user id from request context
@@ -52,19 +218,71 @@ func (ds *DocumentService) Documents(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Add one document
+// @Description Adds a document with a base64-encoded file, /auth/documents/upload takes the file as multipart form data.
 // @Tags documents
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param input body models.Document true "document body"
-// @Success 200 {array} APIResponse
+// @Param input body DocumentUploadRequest true "document body"
+// @Success 200 {object} APIResponse{data=models.Document}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Failure 413 {object} APIResponse "File too large"
+// @Failure 415 {object} APIResponse "Unsupported file type"
+// @Failure 422 {object} APIResponse "Empty file or name"
 // @Router /auth/documents/add [post]
 func (ds *DocumentService) AddDocument(w http.ResponseWriter, r *http.Request) {
+	// Base64 takes 4 bytes for every 3, the rest is room for the other fields
+	r.Body = http.MaxBytesReader(w, r.Body, ds.maxSize()/3*4+1<<20)
+
 	newDoc := &DocumentUploadRequest{}
 	// Get document description from JSON
 	if err := ParseJSON(r, newDoc); err != nil {
-		log.Printf("Error parsing JSON in AddDocument: %v", err)
-		WriteError(w, 500, err.Error())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteRequestError(w, &RequestError{Status: http.StatusRequestEntityTooLarge, Message: "request is too large"})
+			return
+		}
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+
+	ds.createDocument(w, r, newDoc)
+}
+
+// @Summary Upload one document
+// @Description Adds a document from a multipart/form-data body. The file type is detected from its contents,
+// @Description PDF, JPEG, PNG, GIF, WebP and plain text files are accepted.
+// @Tags documents
+// @Accept mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Document file"
+// @Param name formData string false "Name of the document, the file name by default"
+// @Param type formData string false "Type of the document, e.g. prescription or report"
+// @Param date formData string false "Date the document was issued, RFC 3339 or YYYY-MM-DD"
+// @Param doctor formData string false "Name of the doctor"
+// @Success 200 {object} APIResponse{data=models.Document}
+// @Failure 400 {object} APIResponse "Invalid form"
+// @Failure 413 {object} APIResponse "File too large"
+// @Failure 415 {object} APIResponse "Unsupported file type"
+// @Failure 422 {object} APIResponse "Empty file or invalid date"
+// @Router /auth/documents/upload [post]
+func (ds *DocumentService) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, ds.maxSize()+1<<20)
+
+	newDoc := &DocumentUploadRequest{}
+	if err := readMultipart(r, newDoc, ds.maxSize()); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	ds.createDocument(w, r, newDoc)
+}
+
+// createDocument validates and stores an uploaded document for the current user.
+func (ds *DocumentService) createDocument(w http.ResponseWriter, r *http.Request, newDoc *DocumentUploadRequest) {
+	if err := newDoc.Validate(ds.maxSize()); err != nil {
+		WriteRequestError(w, err)
 		return
 	}
 
@@ -72,38 +290,140 @@ func (ds *DocumentService) AddDocument(w http.ResponseWriter, r *http.Request) {
 	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
 	if err != nil {
 		log.Printf("Error fetching user: %v", err)
-		WriteError(w, 500, "database error")
+		WriteError(w, 401, "database error")
 		return
 	}
 
 	document := &models.Document{
-		UserID:   uint(userID),
-		Name:     newDoc.Name,
-		Type:     newDoc.Type,
-		Date:     newDoc.Date,
-		Doctor:   newDoc.Doctor,
-		FileData: newDoc.FileData,
+		UserID:      uint(userID),
+		Name:        newDoc.Name,
+		Type:        newDoc.Type,
+		Date:        newDoc.Date,
+		Doctor:      newDoc.Doctor,
+		FileName:    newDoc.FileName,
+		ContentType: newDoc.contentType,
+		Size:        int64(len(newDoc.FileData)),
+		CreatedAt:   time.Now(),
+		SHA256:      storage.Key(newDoc.FileData),
+	}
+
+	// The blob is addressed by the encrypted contents, identical files of the user share it
+	data, dataKeyID, err := ds.Keyring.EncryptFile(document.UserID, newDoc.FileData)
+	if err != nil {
+		log.Printf("Error encrypting document in AddDocument: %v", err)
+		WriteError(w, 500, "failed to store document")
+		return
+	}
+	document.BlobKey = storage.Key(data)
+	if dataKeyID != 0 {
+		document.DataKeyID = &dataKeyID
 	}
 
-	// Create a record in DB
-	_, err = ds.DB.CreateDocument(document)
+	// Create a record in DB, the file is stored before the transaction commits
+	err = ds.DB.WithBlobLock(document.BlobKey, func(tx *models.DocumentGorm) error {
+		if _, err := tx.CreateDocument(document); err != nil {
+			return err
+		}
+		return ds.Blobs.Put(r.Context(), document.BlobKey, data)
+	})
 	if err != nil {
 		log.Printf("Error creating document in AddDocument: %v", err)
-		WriteError(w, 500, err.Error())
+		WriteError(w, 500, "failed to store document")
 		return
 	}
 
-	WriteJSON(w, 200, nil)
+	if ds.Thumbnails != nil {
+		ds.Thumbnails.Wake()
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: document})
 	log.Println("Successfully added a new document!")
 }
 
+// @Summary Download document file
+// @Description Streams the file with its detected Content-Type. Range requests are supported for
+// @Description resuming downloads and paging through large PDFs.
+// @Tags documents
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Param download query bool false "Ask the browser to save the file instead of showing it"
+// @Success 200 {file} file "File contents"
+// @Success 206 {file} file "Requested range of the file"
+// @Failure 404 {object} APIResponse "Document not found"
+// @Failure 416 {string} string "Invalid range"
+// @Router /auth/documents/{id}/file [get]
+func (ds *DocumentService) DocumentFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("document not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	doc, err := ds.DB.GetUserDocument(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "document")
+		return
+	}
+
+	ds.serveFile(w, r, doc, "private, no-cache")
+}
+
+// serveFile streams the file of the document with its type and name. Range and conditional
+// requests are handled by http.ServeContent, the ETag is the hash of the file.
+func (ds *DocumentService) serveFile(w http.ResponseWriter, r *http.Request, doc *models.Document, cacheControl string) {
+	disposition := "inline"
+	if download, _ := strconv.ParseBool(r.URL.Query().Get("download")); download {
+		disposition = "attachment"
+	}
+	if header := mime.FormatMediaType(disposition, map[string]string{"filename": downloadName(doc)}); header != "" {
+		disposition = header
+	}
+
+	// Files of documents added before the blob store are still in the database,
+	// blobs are decrypted while they are streamed
+	var content io.ReadSeeker = bytes.NewReader(doc.FileData)
+	if doc.BlobKey != "" {
+		blob, err := ds.Blobs.Open(r.Context(), doc.BlobKey)
+		if err == nil {
+			defer blob.Close()
+			content, err = ds.Keyring.OpenFile(blob)
+		}
+		if err != nil {
+			log.Printf("Error reading blob of document %d: %v", doc.ID, err)
+			WriteError(w, 500, "storage error")
+			return
+		}
+	}
+	key := doc.SHA256
+	if key == "" {
+		key = storage.Key(doc.FileData)
+	}
+
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", `"`+key+`"`)
+	http.ServeContent(w, r, "", doc.CreatedAt, content)
+}
+
 // @Summary Remove one document by id
 // @Tags documents
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param id path int true "Document ID"
 // @Success 200 {object} APIResponse
-// @Router /auth/document/remove/{id} [post]
+// @Failure 404 {object} APIResponse "Document not found"
+// @Router /auth/documents/remove/{id} [post]
 func (ds *DocumentService) RemoveDocument(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -113,18 +433,55 @@ func (ds *DocumentService) RemoveDocument(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, _, err = GetUserFromContext(r.Context(), ds.DB.DB)
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
 	if err != nil {
 		log.Printf("Error removing document in RemoveDocument: %v", err)
 		WriteError(w, 409, err.Error())
 		return
 	}
 
-	if err := ds.DB.DeleteDocumentById(id); err != nil {
-		log.Printf("Error removing document in RemoveDocument: %v", err)
-		WriteError(w, 500, err.Error())
+	// Only documents of the current user can be removed
+	doc, err := ds.DB.GetUserDocument(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "document")
+		return
+	}
+	if err := ds.DB.DeleteUserDocument(uint(userID), id); err != nil {
+		WriteLookupError(w, err, "document")
 		return
 	}
+	// The file is deleted once the document is gone, a failed deletion keeps the document
+	// with its file instead of a document without one
+	if doc.BlobKey != "" {
+		ds.releaseBlobs(r.Context(), []string{doc.BlobKey})
+	}
 
+	WriteJSON(w, 200, &APIResponse{Status: 200})
 	log.Println("Successfully removed document!")
 }
+
+// deleteUnreferenced deletes the blob if no document refers to it anymore.
+// It has to be called under the lock of the key, after the documents were deleted.
+func (ds *DocumentService) deleteUnreferenced(ctx context.Context, tx *models.DocumentGorm, key string) error {
+	if key == "" {
+		return nil
+	}
+	referenced, err := tx.BlobReferenced(key)
+	if err != nil || referenced {
+		return err
+	}
+	return ds.Blobs.Delete(ctx, key)
+}
+
+// releaseBlobs deletes the files of deleted documents unless other documents share them.
+// Failures are only logged, they leave unused files behind but lose no data.
+func (ds *DocumentService) releaseBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		err := ds.DB.WithBlobLock(key, func(tx *models.DocumentGorm) error {
+			return ds.deleteUnreferenced(ctx, tx, key)
+		})
+		if err != nil {
+			log.Printf("Error deleting blob %s: %v", key, err)
+		}
+	}
+}
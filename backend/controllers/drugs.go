@@ -1,13 +1,27 @@
 package controllers
 
 import (
+	"first_aid_companion/icd10"
+	"first_aid_companion/interactions"
 	"first_aid_companion/models"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// maxExpiredAge is how long ago a drug may have expired to still be accepted.
+// Older dates are almost certainly typos.
+const maxExpiredAge = 5 * 365 * 24 * time.Hour
+
+// Limits of the cabinet history
+const (
+	defaultHistoryEvents = 50
+	maxHistoryEvents     = 500
 )
 
 // Represents request for drug creation
@@ -21,20 +35,228 @@ type DrugCreationRequest struct {
 	Manufacturer string    `json:"manufacturer"`                          // Manufacturer of the drug
 	Dose         string    `json:"dose"`                                  // Dosage information
 	Amount       string    `json:"amount"`                                // Quantity of the drug available
+	Quantity     *float64  `json:"quantity" example:"30"`                 // Structured quantity, parsed from amount if omitted
+	Unit         string    `json:"unit" example:"tablets"`                // Unit of the quantity
+	LowStock     float64   `json:"low_stock" example:"5"`                 // Restock notification threshold, 0 disables it
+	KitID        *uint     `json:"kit_id"`                                // Kit to put the drug into, none if omitted
+	GroupID      *uint     `json:"group_id"`                              // Group to share the drug with, personal if omitted
+}
+
+// DrugUpdateRequest represents a partial update of a drug.
+// Only fields present in the JSON body are changed.
+type DrugUpdateRequest struct {
+	Name         *string    `json:"name"`                                  // Name of the drug
+	Type         *string    `json:"type"`                                  // Type or category of the drug
+	Description  *string    `json:"description"`                           // Description or purpose of the drug
+	Expiry       *time.Time `json:"expiry" example:"2025-07-12T23:45:00Z"` // Expiry date of the drug
+	Location     *string    `json:"location"`                              // Storage location of the drug
+	Manufacturer *string    `json:"manufacturer"`                          // Manufacturer of the drug
+	Dose         *string    `json:"dose"`                                  // Dosage information
+	Amount       *string    `json:"amount"`                                // Quantity of the drug available
+	Quantity     *float64   `json:"quantity" example:"30"`                 // Structured quantity
+	Unit         *string    `json:"unit" example:"tablets"`                // Unit of the quantity
+	LowStock     *float64   `json:"low_stock" example:"5"`                 // Restock notification threshold, 0 disables it
+	KitID        *uint      `json:"kit_id"`                                // Kit to move the drug into, use /auth/drugs/move to take it out
+}
+
+// validateDrugName checks that the drug has a name.
+func validateDrugName(name string) *RequestError {
+	if strings.TrimSpace(name) == "" {
+		return NewValidationError("name", "name must not be empty")
+	}
+	return nil
+}
+
+// validateDrugExpiry checks that the expiry date, if set, is not in the distant past.
+// Drugs without a known expiry date are allowed.
+func validateDrugExpiry(expiry time.Time) *RequestError {
+	if !expiry.IsZero() && expiry.Before(time.Now().Add(-maxExpiredAge)) {
+		return NewValidationError("expiry", "expiry date is too far in the past")
+	}
+	return nil
+}
+
+// validateDrugStock checks the structured quantity, unit and low-stock threshold.
+func validateDrugStock(quantity *float64, unit *string, lowStock *float64) *RequestError {
+	if quantity != nil && *quantity < 0 {
+		return NewValidationError("quantity", "quantity must not be negative")
+	}
+	if unit != nil && *unit != "" && !models.IsUnit(*unit) {
+		return NewValidationError("unit", "unit must be one of "+strings.Join(models.Units, ", "))
+	}
+	if lowStock != nil && *lowStock < 0 {
+		return NewValidationError("low_stock", "low stock threshold must not be negative")
+	}
+	return nil
+}
+
+// Validate checks the fields present in the update.
+func (req *DrugUpdateRequest) Validate() *RequestError {
+	if req.Name != nil {
+		if err := validateDrugName(*req.Name); err != nil {
+			return err
+		}
+	}
+	if req.Expiry != nil {
+		if err := validateDrugExpiry(*req.Expiry); err != nil {
+			return err
+		}
+	}
+	return validateDrugStock(req.Quantity, req.Unit, req.LowStock)
+}
+
+// Args converts the update into the map accepted by DrugGorm.UpdateDrug.
+func (req *DrugUpdateRequest) Args() map[string]interface{} {
+	args := map[string]interface{}{}
+	if req.Name != nil {
+		args["Name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Type != nil {
+		args["Type"] = *req.Type
+	}
+	if req.Description != nil {
+		args["Description"] = *req.Description
+	}
+	if req.Expiry != nil {
+		args["Expiry"] = *req.Expiry
+	}
+	if req.Location != nil {
+		args["Location"] = *req.Location
+	}
+	if req.Manufacturer != nil {
+		args["Manufacturer"] = *req.Manufacturer
+	}
+	if req.Dose != nil {
+		args["Dose"] = *req.Dose
+	}
+	if req.Amount != nil {
+		args["Amount"] = *req.Amount
+		// Clients that only know the free-text amount still update the stock
+		if req.Quantity == nil {
+			if value, unit, ok := models.ParseAmount(*req.Amount); ok {
+				args["Quantity"] = value
+				if req.Unit == nil {
+					args["Unit"] = unit
+				}
+			}
+		}
+	}
+	if req.Quantity != nil {
+		args["Quantity"] = *req.Quantity
+	}
+	if req.Unit != nil {
+		args["Unit"] = *req.Unit
+	}
+	if req.LowStock != nil {
+		args["LowStock"] = *req.LowStock
+	}
+	if req.KitID != nil {
+		args["KitID"] = *req.KitID
+	}
+	return args
+}
+
+// DrugCreationResponse is returned after adding a drug.
+type DrugCreationResponse struct {
+	Drug     *models.Drug           `json:"drug"`
+	Warnings []interactions.Warning `json:"warnings"` // Allergies and interactions with the user's other drugs
 }
 
 // DrugService handles operations related to drugs, interfacing with the database.
 type DrugService struct {
-	DB *models.DrugGorm // Database access object for drugs
+	DB           *models.DrugGorm        // Database access object for drugs
+	KitDB        *models.KitGorm         // Kits the drugs are kept in
+	GroupDB      *models.GroupGorm       // Groups sharing a cabinet
+	CardDB       *models.MedicalCardGorm // Medical cards, allergies are checked against new drugs
+	Interactions *interactions.Dataset   // Interaction rules, checks are skipped if nil
+	ICD10        *icd10.Dataset          // Titles of coded allergies, matched against the drugs as well
+}
+
+// checkInteractions returns warnings for the user's drugs and allergies.
+func (ds *DrugService) checkInteractions(userID uint, drugs []models.Drug) ([]interactions.Warning, error) {
+	if ds.Interactions == nil {
+		return []interactions.Warning{}, nil
+	}
+
+	entries, err := ds.CardDB.GetEntries(userID, models.EntryAllergy)
+	if err != nil {
+		return nil, err
+	}
+	allergies := make([]interactions.Allergy, 0, len(entries))
+	for _, entry := range entries {
+		text := entry.Text + " " + entry.Substance
+		if ds.ICD10 != nil {
+			if code, ok := ds.ICD10.Lookup(entry.ICD10Code); ok {
+				text += " " + code.Title
+			}
+		}
+		allergies = append(allergies, interactions.Allergy{Text: text, Reaction: entry.Reaction})
+	}
+
+	items := make([]interactions.Drug, 0, len(drugs))
+	for _, drug := range drugs {
+		items = append(items, interactions.Drug{ID: drug.ID, Name: drug.Name})
+	}
+
+	warnings := ds.Interactions.Check(items, allergies)
+	if warnings == nil {
+		warnings = []interactions.Warning{}
+	}
+	return warnings, nil
+}
+
+// parseCabinet parses the scope and group_id query parameters, see models.CabinetScope.
+// Without a scope parameter defaultScope is used.
+// Writes the error response and returns false if they are invalid.
+func (ds *DrugService) parseCabinet(w http.ResponseWriter, r *http.Request, userID uint, defaultScope string) (string, *uint, bool) {
+	query := r.URL.Query()
+	scope := query.Get("scope")
+	switch scope {
+	case "":
+		scope = defaultScope
+	case models.ScopePersonal, models.ScopeGroup, models.ScopeAll:
+	default:
+		WriteRequestError(w, NewValidationError("scope", "scope must be personal, group or all"))
+		return "", nil, false
+	}
+
+	value := query.Get("group_id")
+	if value == "" {
+		return scope, nil, true
+	}
+	if scope != models.ScopeGroup {
+		WriteRequestError(w, NewValidationError("group_id", "group_id requires scope=group"))
+		return "", nil, false
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("group not found"))
+		return "", nil, false
+	}
+	member, err := ds.GroupDB.GetMembership(userID, uint(id))
+	if err != nil {
+		WriteLookupError(w, err, "group")
+		return "", nil, false
+	}
+	return scope, &member.GroupID, true
 }
 
 // @Summary Get all drugs
-// @Description Returns all drugs in json format
+// @Description Returns the user's drugs. scope=group returns the shared cabinet of the user's groups instead
+// @Description (of one group with group_id), scope=all returns both.
+// @Description kit_id limits the list to one kit ("none" for drugs without a kit),
+// @Description group_by=kit returns the drugs grouped by kit with per-kit summaries instead.
 // @Tags drugs
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} models.Drug
+// @Param scope query string false "personal (default), group or all"
+// @Param group_id query int false "Group of the shared cabinet, requires scope=group"
+// @Param kit_id query string false "Kit ID or none"
+// @Param group_by query string false "kit"
+// @Success 200 {object} APIResponse{data=[]models.Drug}
+// @Failure 404 {object} APIResponse "Kit or group not found"
+// @Failure 422 {object} APIResponse "Invalid scope"
 // @Router /auth/drugs [get]
 func (ds *DrugService) Drugs(w http.ResponseWriter, r *http.Request) {
 	// Fetch user from request context
@@ -46,8 +268,38 @@ func (ds *DrugService) Drugs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Collect all user's drugs
-	drugs, err := ds.DB.GetDrugsByUserId(uint(userID))
+	scope, groupID, ok := ds.parseCabinet(w, r, uint(userID), models.ScopePersonal)
+	if !ok {
+		return
+	}
+	cabinet := models.CabinetScope(uint(userID), scope, groupID)
+
+	query := r.URL.Query()
+	if query.Get("group_by") == "kit" {
+		ds.drugsByKit(w, cabinet)
+		return
+	}
+
+	var drugs []models.Drug
+	switch kitParam := query.Get("kit_id"); kitParam {
+	case "":
+		// Collect all drugs of the cabinet
+		drugs, err = ds.DB.GetCabinetDrugs(cabinet)
+	case "none":
+		drugs, err = ds.DB.GetDrugsByKit(cabinet, nil)
+	default:
+		kitID, convErr := strconv.Atoi(kitParam)
+		if convErr != nil {
+			WriteRequestError(w, NewNotFoundError("kit not found"))
+			return
+		}
+		kit, lookupErr := ds.KitDB.GetUserKit(uint(userID), kitID)
+		if lookupErr != nil {
+			WriteLookupError(w, lookupErr, "kit")
+			return
+		}
+		drugs, err = ds.DB.GetDrugsByKit(cabinet, &kit.ID)
+	}
 	if err != nil {
 		log.Printf("Error fetching drugs: %v", err)
 		WriteError(w, 500, "database error")
@@ -56,13 +308,61 @@ func (ds *DrugService) Drugs(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, 200, &APIResponse{Status: 200, Data: drugs})
 }
 
+// drugsByKit writes the drugs of the cabinet grouped by kit, drugs without a kit come last.
+func (ds *DrugService) drugsByKit(w http.ResponseWriter, cabinet func(db *gorm.DB) *gorm.DB) {
+	kits, err := ds.KitDB.GetCabinetKits(cabinet)
+	if err != nil {
+		log.Printf("Error fetching kits: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	drugs, err := ds.DB.GetCabinetDrugs(cabinet)
+	if err != nil {
+		log.Printf("Error fetching drugs: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	summaries, err := ds.KitDB.GetKitSummaries(cabinet, time.Now())
+	if err != nil {
+		log.Printf("Error fetching kit summaries: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	byKit := summariesByKit(summaries)
+
+	groups := make([]KitDrugs, 0, len(kits)+1)
+	index := map[uint]int{}
+	for i := range kits {
+		index[kits[i].ID] = len(groups)
+		groups = append(groups, KitDrugs{Kit: &kits[i], Summary: kitSummary(byKit, kits[i].ID), Drugs: []models.Drug{}})
+	}
+	unassigned := KitDrugs{Summary: kitSummary(byKit, 0), Drugs: []models.Drug{}}
+
+	for _, drug := range drugs {
+		if drug.KitID != nil {
+			if i, ok := index[*drug.KitID]; ok {
+				groups[i].Drugs = append(groups[i].Drugs, drug)
+				continue
+			}
+		}
+		unassigned.Drugs = append(unassigned.Drugs, drug)
+	}
+	groups = append(groups, unassigned)
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: groups})
+}
+
 // @Summary Add one drug
+// @Description Adds a drug and returns warnings about the user's allergies and interactions with the other drugs in the cabinet.
+// @Description With group_id, or when put into a group kit, the drug is shared with the group.
 // @Tags drugs
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param input body DrugCreationRequest true "login body"
-// @Success 200 {array} APIResponse
+// @Success 200 {object} APIResponse{data=DrugCreationResponse}
+// @Failure 404 {object} APIResponse "Kit or group not found"
+// @Failure 422 {object} APIResponse "Invalid field value"
 // @Router /auth/drugs/add [post]
 func (ds *DrugService) AddDrug(w http.ResponseWriter, r *http.Request) {
 	request := &DrugCreationRequest{}
@@ -73,6 +373,20 @@ func (ds *DrugService) AddDrug(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate fields
+	if err := validateDrugName(request.Name); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	if err := validateDrugExpiry(request.Expiry); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	if err := validateDrugStock(request.Quantity, &request.Unit, &request.LowStock); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
 	// Get user id form request context
 	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
 	if err != nil {
@@ -91,10 +405,62 @@ func (ds *DrugService) AddDrug(w http.ResponseWriter, r *http.Request) {
 		Manufacturer: request.Manufacturer,
 		Dose:         request.Dose,
 		Amount:       request.Amount,
+		Quantity:     request.Quantity,
+		Unit:         request.Unit,
+		LowStock:     request.LowStock,
+	}
+
+	// Every member of the group may add to the shared cabinet
+	if request.GroupID != nil {
+		member, err := ds.GroupDB.GetMembership(uint(userID), *request.GroupID)
+		if err != nil {
+			WriteLookupError(w, err, "group")
+			return
+		}
+		drug.GroupID = &member.GroupID
+	}
+
+	// The kit replaces the free-text location, drugs put into a group kit are shared with the group
+	if request.KitID != nil {
+		kit, err := ds.KitDB.GetUserKit(uint(userID), int(*request.KitID))
+		if err != nil {
+			WriteLookupError(w, err, "kit")
+			return
+		}
+		if drug.GroupID == nil {
+			drug.GroupID = kit.GroupID
+		} else if kit.GroupID == nil || *kit.GroupID != *drug.GroupID {
+			WriteRequestError(w, NewValidationError("kit_id", "kit belongs to another cabinet"))
+			return
+		}
+		drug.KitID = &kit.ID
+		drug.Location = kit.Name
+	}
+
+	// Keep the structured quantity and the free-text amount consistent
+	if drug.Quantity == nil {
+		if value, unit, ok := models.ParseAmount(drug.Amount); ok {
+			drug.Quantity = &value
+			if drug.Unit == "" {
+				drug.Unit = unit
+			}
+		}
+	}
+	if drug.Quantity != nil {
+		if drug.Unit == "" {
+			drug.Unit = models.UnitPieces
+		}
+		if drug.Amount == "" {
+			drug.Amount = models.FormatQuantity(*drug.Quantity, drug.Unit)
+		}
 	}
 
 	// Cast int to unit
-	drug.UserId = uint(userID)
+	owner := uint(userID)
+	drug.AddedBy = &owner
+	if drug.GroupID == nil {
+		drug.UserId = &owner
+	}
 
 	// Create a record in DB
 	_, err = ds.DB.CreateDrug(drug)
@@ -104,17 +470,151 @@ func (ds *DrugService) AddDrug(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJSON(w, 200, nil)
+	// Warn about the new drug, the drug is added anyway.
+	// It is checked against the personal and shared drugs of the user adding it.
+	response := &DrugCreationResponse{Drug: drug, Warnings: []interactions.Warning{}}
+	drugs, err := ds.DB.GetCabinetDrugs(models.CabinetScope(owner, models.ScopeAll, nil))
+	if err == nil {
+		var warnings []interactions.Warning
+		warnings, err = ds.checkInteractions(owner, drugs)
+		for _, warning := range warnings {
+			if warning.Involves(drug.ID) {
+				response.Warnings = append(response.Warnings, warning)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Error checking interactions in AddDrug: %v", err)
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: response})
 	log.Println("Successfully added a new drug!")
 }
 
+// @Summary Check drug interactions
+// @Description Checks the drugs of the cabinet against each other and against the allergies in the medical card.
+// @Description The cabinet is chosen like for the drug list, by default personal and shared drugs are checked together.
+// @Description Warnings are ordered by severity: contraindicated, major, moderate, minor.
+// @Tags drugs
+// @Produce json
+// @Security BearerAuth
+// @Param scope query string false "personal, group or all (default)"
+// @Param group_id query int false "Group of the shared cabinet, requires scope=group"
+// @Success 200 {object} APIResponse{data=[]interactions.Warning}
+// @Failure 404 {object} APIResponse "Group not found"
+// @Failure 422 {object} APIResponse "Invalid scope"
+// @Router /auth/drugs/interactions [get]
+func (ds *DrugService) DrugInteractions(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	scope, groupID, ok := ds.parseCabinet(w, r, uint(userID), models.ScopeAll)
+	if !ok {
+		return
+	}
+	drugs, err := ds.DB.GetCabinetDrugs(models.CabinetScope(uint(userID), scope, groupID))
+	if err != nil {
+		log.Printf("Error fetching drugs: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	warnings, err := ds.checkInteractions(uint(userID), drugs)
+	if err != nil {
+		log.Printf("Error checking interactions: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: warnings})
+}
+
+// @Summary Update one drug by id
+// @Description Updates only the fields present in the body. The drug must belong to the current user or one of their groups.
+// @Description kit_id must be a kit of the drug's cabinet, moving group drugs requires the owner or caregiver role.
+// @Tags drugs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Drug ID"
+// @Param input body DrugUpdateRequest true "fields to update"
+// @Success 200 {object} APIResponse{data=models.Drug}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Failure 403 {object} APIResponse "Role does not allow moving shared drugs"
+// @Failure 404 {object} APIResponse "Drug not found"
+// @Failure 422 {object} APIResponse "Invalid field value or kit of another cabinet"
+// @Router /auth/drugs/{id} [put]
+func (ds *DrugService) UpdateDrug(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("drug not found"))
+		return
+	}
+
+	// Parse and validate the requested changes
+	request := &DrugUpdateRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		log.Printf("Error parsing JSON in UpdateDrug: %v", err)
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	// Get user id from request context
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	// Only the owner or members of the drug's group may update the drug
+	current, err := ds.DB.GetUserDrug(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "drug")
+		return
+	}
+
+	args := request.Args()
+	if request.KitID != nil {
+		kit, err := ds.KitDB.GetUserKit(uint(userID), int(*request.KitID))
+		if err != nil {
+			WriteLookupError(w, err, "kit")
+			return
+		}
+		if !canMoveDrugs(w, ds.GroupDB, uint(userID), []models.Drug{*current}, kit) {
+			return
+		}
+		args["Location"] = kit.Name
+	}
+
+	drug, err := ds.DB.UpdateDrug(id, uint(userID), args)
+	if err != nil {
+		log.Printf("Error updating drug in UpdateDrug: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: drug})
+	log.Println("Successfully updated drug!")
+}
+
 // @Summary Remove one drug by id
 // @Tags drugs
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param input body DrugCreationRequest true "login body"
+// @Param id path int true "Drug ID"
 // @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Drug not found"
 // @Router /auth/drugs/remove/{id} [post]
 func (ds *DrugService) RemoveDrug(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -125,11 +625,103 @@ func (ds *DrugService) RemoveDrug(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := ds.DB.DeleteDrugById(id); err != nil {
-		log.Printf("Error removing drug in RemoveDrug: %v", err)
-		WriteError(w, 500, err.Error())
+	// Get user id from request context
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	// Only drugs of the current user or their groups can be removed
+	if err := ds.DB.DeleteUserDrug(uint(userID), id); err != nil {
+		WriteLookupError(w, err, "drug")
 		return
 	}
 
 	log.Println("Successfully removed drug!")
 }
+
+// @Summary Get drug history
+// @Description Returns who added, used, adjusted and removed a drug, newest first.
+// @Tags drugs
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Drug ID"
+// @Success 200 {object} APIResponse{data=[]models.DrugEvent}
+// @Failure 404 {object} APIResponse "Drug not found"
+// @Router /auth/drugs/{id}/history [get]
+func (ds *DrugService) DrugHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("drug not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	drug, err := ds.DB.GetUserDrug(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "drug")
+		return
+	}
+	events, err := ds.DB.GetDrugEvents(drug.ID)
+	if err != nil {
+		log.Printf("Error fetching drug history: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: events})
+}
+
+// @Summary Get cabinet history
+// @Description Returns the latest additions, uses and removals in the cabinet, newest first.
+// @Description Removed drugs are included. scope and group_id work as for the drugs list.
+// @Tags drugs
+// @Produce json
+// @Security BearerAuth
+// @Param scope query string false "personal (default), group or all"
+// @Param group_id query int false "Group of the shared cabinet, requires scope=group"
+// @Param limit query int false "Maximum number of events, 50 by default, at most 500"
+// @Success 200 {object} APIResponse{data=[]models.DrugEvent}
+// @Failure 404 {object} APIResponse "Group not found"
+// @Failure 422 {object} APIResponse "Invalid scope or limit"
+// @Router /auth/drugs/history [get]
+func (ds *DrugService) CabinetHistory(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), ds.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	scope, groupID, ok := ds.parseCabinet(w, r, uint(userID), models.ScopePersonal)
+	if !ok {
+		return
+	}
+
+	limit := defaultHistoryEvents
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxHistoryEvents {
+			WriteRequestError(w, NewValidationError("limit", "limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+
+	events, err := ds.DB.GetCabinetEvents(uint(userID), scope, groupID, limit)
+	if err != nil {
+		log.Printf("Error fetching cabinet history: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: events})
+}
@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"errors"
+	"first_aid_companion/models"
+	"first_aid_companion/notify"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// Sizes of QR code PNGs in pixels
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 1024
+)
+
+// EmergencyContactRequest represents a request to add or change an emergency contact.
+// At least one of phone, email and webhook_url is required.
+type EmergencyContactRequest struct {
+	Name       string `json:"name" example:"Anna Ivanova"`                         // Name of the contact
+	Phone      string `json:"phone" example:"+79001234567"`                        // Phone number for SMS alerts
+	Email      string `json:"email" example:"anna@example.com"`                    // Email address for alerts
+	WebhookURL string `json:"webhook_url" example:"https://example.com/hooks/sos"` // URL alerts are posted to
+	Relation   string `json:"relation" example:"daughter"`                         // Optional relation to the user
+}
+
+// EmergencyProfileRequest chooses the fields published on the emergency ID.
+// Omitted fields keep their current value, everything is published by default.
+type EmergencyProfileRequest struct {
+	ShowName              *bool `json:"show_name"`
+	ShowBloodType         *bool `json:"show_blood_type"`
+	ShowAllergies         *bool `json:"show_allergies"`
+	ShowChronicConditions *bool `json:"show_chronic_conditions"`
+	ShowContacts          *bool `json:"show_contacts"`
+}
+
+// EmergencyProfileResponse is the emergency ID settings with the public URL.
+type EmergencyProfileResponse struct {
+	models.EmergencyProfile
+	URL string `json:"url,omitempty" example:"https://example.com/emergency/3q2-7w..."` // Public URL encoded in the QR code, only returned when it is issued
+}
+
+// EmergencyService manages emergency contacts and the public emergency ID.
+type EmergencyService struct {
+	DB        *models.EmergencyGorm // Database access object for emergency IDs and contacts
+	PublicURL string                // Base URL of public links
+}
+
+// Validate checks the name and phone of the contact.
+func (req *EmergencyContactRequest) Validate() *RequestError {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Phone = strings.TrimSpace(req.Phone)
+	req.Email = strings.TrimSpace(req.Email)
+	req.WebhookURL = strings.TrimSpace(req.WebhookURL)
+	req.Relation = strings.TrimSpace(req.Relation)
+	if req.Name == "" {
+		return NewValidationError("name", "name must not be empty")
+	}
+	if req.Phone == "" && req.Email == "" && req.WebhookURL == "" {
+		return NewValidationError("phone", "phone, email or webhook_url is required")
+	}
+	if req.Email != "" {
+		if address, err := mail.ParseAddress(req.Email); err != nil || address.Address != req.Email {
+			return NewValidationError("email", "invalid email address")
+		}
+	}
+	if req.WebhookURL != "" {
+		if err := notify.CheckWebhookURL(req.WebhookURL); errors.Is(err, notify.ErrForbiddenAddress) {
+			return NewValidationError("webhook_url", "webhook_url must point to a public address")
+		} else if err != nil {
+			return NewValidationError("webhook_url", "webhook_url must be an http or https URL")
+		}
+	}
+	return nil
+}
+
+// apply sets the chosen fields of the profile.
+func (req *EmergencyProfileRequest) apply(profile *models.EmergencyProfile) {
+	fields := []struct {
+		value *bool
+		field *bool
+	}{
+		{req.ShowName, &profile.ShowName},
+		{req.ShowBloodType, &profile.ShowBloodType},
+		{req.ShowAllergies, &profile.ShowAllergies},
+		{req.ShowChronicConditions, &profile.ShowChronicConditions},
+		{req.ShowContacts, &profile.ShowContacts},
+	}
+	for _, f := range fields {
+		if f.value != nil {
+			*f.field = *f.value
+		}
+	}
+}
+
+// publicURL returns the public URL of the emergency ID with the given token.
+func (es *EmergencyService) publicURL(token string) string {
+	return publicLink(es.PublicURL, "/emergency/"+token)
+}
+
+// response adds the public URL to the profile if a token was just issued.
+// Only the hash of the token is stored, so the URL cannot be shown again later.
+func (es *EmergencyService) response(profile *models.EmergencyProfile, token string) *EmergencyProfileResponse {
+	response := &EmergencyProfileResponse{EmergencyProfile: *profile}
+	if token != "" {
+		response.URL = es.publicURL(token)
+	}
+	return response
+}
+
+// @Summary Get emergency ID settings
+// @Description Returns which fields are published on the user's emergency ID.
+// @Description The public URL is not returned, the server only keeps a hash of its token.
+// @Tags emergency
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=EmergencyProfileResponse}
+// @Failure 404 {object} APIResponse "Emergency ID is not enabled"
+// @Router /auth/emergency [get]
+func (es *EmergencyService) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	profile, err := es.DB.GetProfile(uint(userID))
+	if err != nil {
+		WriteLookupError(w, err, "emergency ID")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: es.response(profile, "")})
+}
+
+// @Summary Enable or change the emergency ID
+// @Description Publishes the chosen fields of the user and medical card at an unguessable public URL,
+// @Description e.g. for a paramedic scanning the QR code. Enables the emergency ID on the first call,
+// @Description the url is only returned then.
+// @Tags emergency
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body EmergencyProfileRequest true "published fields"
+// @Success 200 {object} APIResponse{data=EmergencyProfileResponse}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Router /auth/emergency [put]
+func (es *EmergencyService) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	request := &EmergencyProfileRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	var token string
+	profile, err := es.DB.GetProfile(uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var tokenErr error
+		token, tokenErr = newToken()
+		if tokenErr != nil {
+			log.Printf("Error generating token in UpdateProfile: %v", tokenErr)
+			WriteError(w, 500, "failed to enable emergency ID")
+			return
+		}
+		now := time.Now()
+		profile = &models.EmergencyProfile{
+			UserID:                uint(userID),
+			TokenHash:             hashToken(token),
+			ShowName:              true,
+			ShowBloodType:         true,
+			ShowAllergies:         true,
+			ShowChronicConditions: true,
+			ShowContacts:          true,
+			CreatedAt:             now,
+			RotatedAt:             now,
+		}
+	} else if err != nil {
+		log.Printf("Error fetching emergency ID in UpdateProfile: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	request.apply(profile)
+	if err := es.DB.SaveProfile(profile); err != nil {
+		log.Printf("Error saving emergency ID in UpdateProfile: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: es.response(profile, token)})
+}
+
+// @Summary Rotate the emergency ID link
+// @Description Issues a new public URL, the old URL and printed QR codes stop working.
+// @Description The url is only returned here, the server keeps a hash of its token.
+// @Tags emergency
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=EmergencyProfileResponse}
+// @Failure 404 {object} APIResponse "Emergency ID is not enabled"
+// @Router /auth/emergency/rotate [post]
+func (es *EmergencyService) RotateLink(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Printf("Error generating token in RotateLink: %v", err)
+		WriteError(w, 500, "failed to rotate link")
+		return
+	}
+	if err := es.DB.RotateToken(uint(userID), hashToken(token), time.Now()); err != nil {
+		WriteLookupError(w, err, "emergency ID")
+		return
+	}
+
+	profile, err := es.DB.GetProfile(uint(userID))
+	if err != nil {
+		log.Printf("Error fetching emergency ID in RotateLink: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: es.response(profile, token)})
+}
+
+// @Summary Revoke the emergency ID
+// @Description Disables the public URL. Enabling the emergency ID again issues a new one.
+// @Tags emergency
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Emergency ID is not enabled"
+// @Router /auth/emergency/revoke [post]
+func (es *EmergencyService) RevokeLink(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if err := es.DB.DeleteProfile(uint(userID)); err != nil {
+		WriteLookupError(w, err, "emergency ID")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+}
+
+// @Summary Get emergency ID QR code
+// @Description Renders the public URL of the emergency ID as a QR code, e.g. to print on a card.
+// @Description The server only keeps a hash of the token, so the token from the url has to be passed.
+// @Tags emergency
+// @Produce png
+// @Produce image/svg+xml
+// @Security BearerAuth
+// @Param token query string true "Token from the public URL"
+// @Param format query string false "png (default) or svg"
+// @Param size query int false "Width of the PNG in pixels, 64 to 1024, 256 by default"
+// @Success 200 {file} binary
+// @Failure 404 {object} APIResponse "Emergency ID is not enabled or the token is not its current one"
+// @Failure 422 {object} APIResponse "Invalid format or size"
+// @Router /auth/emergency/qr [get]
+func (es *EmergencyService) QRCode(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		WriteRequestError(w, NewValidationError("format", "format must be png or svg"))
+		return
+	}
+	size := defaultQRSize
+	if value := r.URL.Query().Get("size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < minQRSize || n > maxQRSize {
+			WriteRequestError(w, NewValidationError("size", fmt.Sprintf("size must be between %d and %d", minQRSize, maxQRSize)))
+			return
+		}
+		size = n
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+	profile, err := es.DB.GetProfile(uint(userID))
+	if err != nil {
+		WriteLookupError(w, err, "emergency ID")
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" || hashToken(token) != profile.TokenHash {
+		WriteRequestError(w, NewNotFoundError("emergency ID not found"))
+		return
+	}
+
+	code, err := qrcode.New(es.publicURL(token), qrcode.Medium)
+	if err != nil {
+		log.Printf("Error encoding QR code: %v", err)
+		WriteError(w, 500, "failed to render QR code")
+		return
+	}
+
+	// The code changes when the link is rotated
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.WriteHeader(http.StatusOK)
+		writeSVG(w, code.Bitmap())
+		return
+	}
+
+	png, err := code.PNG(size)
+	if err != nil {
+		log.Printf("Error rendering QR code: %v", err)
+		WriteError(w, 500, "failed to render QR code")
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
+
+// writeSVG draws the QR code modules as one path, one unit per module.
+func writeSVG(w http.ResponseWriter, bitmap [][]bool) {
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`,
+		len(bitmap), len(bitmap), path.String())
+}
+
+// @Summary Get public emergency ID
+// @Description Returns the fields the user chose to publish. Does not require authentication,
+// @Description unknown, rotated and revoked links are not found.
+// @Tags emergency
+// @Produce json
+// @Param token path string true "Token from the public URL"
+// @Success 200 {object} APIResponse{data=models.EmergencyCard}
+// @Failure 404 {object} APIResponse "Emergency ID not found"
+// @Router /emergency/{token} [get]
+func (es *EmergencyService) PublicCard(w http.ResponseWriter, r *http.Request) {
+	card, err := es.DB.GetEmergencyCard(hashToken(mux.Vars(r)["token"]))
+	if err != nil {
+		WriteLookupError(w, err, "emergency ID")
+		return
+	}
+
+	// Medical data must not stay in shared caches or search engines
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: card})
+}
+
+// @Summary Get emergency contacts
+// @Tags emergency
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]models.EmergencyContact}
+// @Router /auth/emergency/contacts [get]
+func (es *EmergencyService) Contacts(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	contacts, err := es.DB.GetUserContacts(uint(userID))
+	if err != nil {
+		log.Printf("Error fetching emergency contacts: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: contacts})
+}
+
+// @Summary Add emergency contact
+// @Tags emergency
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body EmergencyContactRequest true "contact"
+// @Success 200 {object} APIResponse{data=models.EmergencyContact}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Failure 422 {object} APIResponse "Empty name, no address or invalid address"
+// @Router /auth/emergency/contacts [post]
+func (es *EmergencyService) AddContact(w http.ResponseWriter, r *http.Request) {
+	request := &EmergencyContactRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	contact, err := es.DB.CreateContact(&models.EmergencyContact{
+		UserID:     uint(userID),
+		Name:       request.Name,
+		Phone:      request.Phone,
+		Email:      request.Email,
+		WebhookURL: request.WebhookURL,
+		Relation:   request.Relation,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error creating emergency contact in AddContact: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: contact})
+}
+
+// @Summary Change emergency contact
+// @Tags emergency
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Contact ID"
+// @Param input body EmergencyContactRequest true "contact"
+// @Success 200 {object} APIResponse{data=models.EmergencyContact}
+// @Failure 404 {object} APIResponse "Contact not found"
+// @Failure 422 {object} APIResponse "Empty name, no address or invalid address"
+// @Router /auth/emergency/contacts/{id} [put]
+func (es *EmergencyService) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("contact not found"))
+		return
+	}
+
+	request := &EmergencyContactRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	contact, err := es.DB.GetUserContact(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "contact")
+		return
+	}
+	contact.Name = request.Name
+	contact.Phone = request.Phone
+	contact.Email = request.Email
+	contact.WebhookURL = request.WebhookURL
+	contact.Relation = request.Relation
+	if err := es.DB.UpdateContact(contact); err != nil {
+		log.Printf("Error updating emergency contact in UpdateContact: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: contact})
+}
+
+// @Summary Remove emergency contact
+// @Tags emergency
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Contact ID"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Contact not found"
+// @Router /auth/emergency/contacts/remove/{id} [post]
+func (es *EmergencyService) RemoveContact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("contact not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if err := es.DB.DeleteUserContact(uint(userID), id); err != nil {
+		WriteLookupError(w, err, "contact")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+}
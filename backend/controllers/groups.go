@@ -1,3 +1,671 @@
 package controllers
 
-// Will be added soon..
+import (
+	"errors"
+	"first_aid_companion/models"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// invitationTTL is how long an invitation can be accepted.
+const invitationTTL = 7 * 24 * time.Hour
+
+var inviteEmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// GroupRequest represents a request to create or rename a group (household).
+type GroupRequest struct {
+	Name        string `json:"name" example:"Smith household"` // Name of the group
+	Description string `json:"description"`                    // Optional description
+}
+
+// InviteRequest represents a request to invite a user to a group.
+type InviteRequest struct {
+	Email string `json:"email" example:"grandma@example.com"` // Email of the invited user
+	Role  string `json:"role" example:"member"`               // caregiver or member, member by default
+}
+
+// InvitationTokenRequest represents a request to accept or decline an invitation.
+type InvitationTokenRequest struct {
+	Token string `json:"token"` // Token received with the invitation
+}
+
+// RoleRequest represents a request to change the role of a member.
+type RoleRequest struct {
+	Role string `json:"role" example:"caregiver"` // caregiver or member
+}
+
+// TransferRequest represents a request to hand the group over to another member.
+type TransferRequest struct {
+	UserID uint `json:"user_id"` // Member who becomes the owner
+}
+
+// InvitationResponse is a created invitation with its token.
+// The token is only returned once and has to be passed to the invited user.
+type InvitationResponse struct {
+	models.GroupInvitation
+	Token string `json:"token"`
+}
+
+// GroupDetails is a group with its members and the role of the current user.
+type GroupDetails struct {
+	models.Group
+	Role    string                   `json:"role"`
+	Members []models.GroupMemberInfo `json:"members"`
+}
+
+// GroupService manages households: members, roles and invitations.
+type GroupService struct {
+	DB *models.GroupGorm // Database access object for groups
+}
+
+// Validate checks the group name.
+func (req *GroupRequest) Validate() *RequestError {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return NewValidationError("name", "name must not be empty")
+	}
+	return nil
+}
+
+// Validate checks the email and the role of the invitation.
+// Owners are never invited, ownership is transferred instead.
+func (req *InviteRequest) Validate() *RequestError {
+	req.Email = strings.TrimSpace(req.Email)
+	if !inviteEmailRegex.MatchString(req.Email) {
+		return NewValidationError("email", "invalid email format")
+	}
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+	if req.Role != models.RoleCaregiver && req.Role != models.RoleMember {
+		return NewValidationError("role", "role must be caregiver or member")
+	}
+	return nil
+}
+
+// Validate checks the new role.
+func (req *RoleRequest) Validate() *RequestError {
+	if req.Role != models.RoleCaregiver && req.Role != models.RoleMember {
+		return NewValidationError("role", "role must be caregiver or member, use transfer to change the owner")
+	}
+	return nil
+}
+
+// membership returns the group ID from the path and the current user's membership in it.
+// Writes the error response and returns nil if the user is not a member.
+func (gs *GroupService) membership(w http.ResponseWriter, r *http.Request) (uint, *models.GroupMember) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("group not found"))
+		return 0, nil
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), gs.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return 0, nil
+	}
+
+	member, err := gs.DB.GetMembership(uint(userID), uint(id))
+	if err != nil {
+		WriteLookupError(w, err, "group")
+		return 0, nil
+	}
+	return uint(userID), member
+}
+
+// pendingInvitation finds a pending invitation for the current user by its token.
+// Writes the error response and returns nil if there is none.
+func (gs *GroupService) pendingInvitation(w http.ResponseWriter, r *http.Request) (uint, *models.GroupInvitation) {
+	request := &InvitationTokenRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return 0, nil
+	}
+	if request.Token == "" {
+		WriteRequestError(w, NewValidationError("token", "token is required"))
+		return 0, nil
+	}
+
+	userID, email, err := GetUserFromContext(r.Context(), gs.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return 0, nil
+	}
+
+	invitation, err := gs.DB.GetInvitationByHash(hashToken(request.Token))
+	if err != nil {
+		WriteLookupError(w, err, "invitation")
+		return 0, nil
+	}
+	// Invitations for other users behave as missing
+	if !strings.EqualFold(invitation.Email, email) {
+		WriteRequestError(w, NewNotFoundError("invitation not found"))
+		return 0, nil
+	}
+	if !invitation.Pending(time.Now()) {
+		WriteRequestError(w, NewValidationError("token", "invitation has expired or was already answered"))
+		return 0, nil
+	}
+	return uint(userID), invitation
+}
+
+// @Summary Get groups
+// @Description Returns the groups (households) the user is a member of, with the user's role.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]models.UserGroup}
+// @Router /auth/groups [get]
+func (gs *GroupService) Groups(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), gs.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	groups, err := gs.DB.GetUserGroups(uint(userID))
+	if err != nil {
+		log.Printf("Error fetching groups: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: groups})
+}
+
+// @Summary Create a group
+// @Description Creates a household, the user becomes its owner.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body GroupRequest true "group"
+// @Success 200 {object} APIResponse{data=models.Group}
+// @Failure 422 {object} APIResponse "Empty name"
+// @Router /auth/groups [post]
+func (gs *GroupService) AddGroup(w http.ResponseWriter, r *http.Request) {
+	request := &GroupRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), gs.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	group := &models.Group{Name: request.Name, Description: request.Description, CreatedAt: time.Now()}
+	group, err = gs.DB.CreateOwnedGroup(group, uint(userID))
+	if err != nil {
+		log.Printf("Error creating group in AddGroup: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: group})
+	log.Println("Successfully added a new group!")
+}
+
+// @Summary Get group
+// @Description Returns a group with its members, the owner first.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Success 200 {object} APIResponse{data=GroupDetails}
+// @Failure 404 {object} APIResponse "Group not found"
+// @Router /auth/groups/{id} [get]
+func (gs *GroupService) GetGroup(w http.ResponseWriter, r *http.Request) {
+	_, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+
+	group, err := gs.DB.GetGroupByID(member.GroupID)
+	if err != nil {
+		WriteLookupError(w, err, "group")
+		return
+	}
+	members, err := gs.DB.GetMembers(member.GroupID)
+	if err != nil {
+		log.Printf("Error fetching members in GetGroup: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: GroupDetails{Group: *group, Role: member.Role, Members: members}})
+}
+
+// @Summary Rename a group
+// @Description Changes the name and description of a group. Only the owner can do this.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Param input body GroupRequest true "new name and description"
+// @Success 200 {object} APIResponse{data=models.Group}
+// @Failure 403 {object} APIResponse "Not the owner"
+// @Failure 404 {object} APIResponse "Group not found"
+// @Failure 422 {object} APIResponse "Empty name"
+// @Router /auth/groups/{id} [put]
+func (gs *GroupService) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	_, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+
+	request := &GroupRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	if !member.Can(models.PermManageGroup) {
+		WriteRequestError(w, NewForbiddenError("only the owner can rename the group"))
+		return
+	}
+
+	group, err := gs.DB.GetGroupByID(member.GroupID)
+	if err != nil {
+		WriteLookupError(w, err, "group")
+		return
+	}
+	group.Name = request.Name
+	group.Description = request.Description
+	if err := gs.DB.UpdateGroup(group); err != nil {
+		log.Printf("Error updating group in UpdateGroup: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: group})
+}
+
+// @Summary Remove a group
+// @Description Deletes a group with its memberships, invitations and shared kits. Only the owner can do this.
+// @Description Dependent profiles of the group stay with their creators, the owner takes over profiles whose creator deleted their account.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Success 200 {object} APIResponse
+// @Failure 403 {object} APIResponse "Not the owner"
+// @Failure 404 {object} APIResponse "Group not found"
+// @Router /auth/groups/remove/{id} [post]
+func (gs *GroupService) RemoveGroup(w http.ResponseWriter, r *http.Request) {
+	userID, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+	if !member.Can(models.PermManageGroup) {
+		WriteRequestError(w, NewForbiddenError("only the owner can remove the group"))
+		return
+	}
+
+	if err := gs.DB.DeleteGroup(member.GroupID, userID); err != nil {
+		log.Printf("Error removing group in RemoveGroup: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully removed group!")
+}
+
+// @Summary Invite to a group
+// @Description Invites a user by email. The returned token has to be passed to the invited user,
+// @Description who accepts or declines it within 7 days. Caregivers can only invite members.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Param input body InviteRequest true "invitation"
+// @Success 200 {object} APIResponse{data=InvitationResponse}
+// @Failure 403 {object} APIResponse "Role does not allow inviting"
+// @Failure 404 {object} APIResponse "Group not found"
+// @Failure 422 {object} APIResponse "Invalid email or role"
+// @Router /auth/groups/{id}/invite [post]
+func (gs *GroupService) Invite(w http.ResponseWriter, r *http.Request) {
+	userID, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+
+	request := &InviteRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	if !member.Can(models.PermInvite) {
+		WriteRequestError(w, NewForbiddenError("your role does not allow inviting"))
+		return
+	}
+	if request.Role == models.RoleCaregiver && !member.Can(models.PermManageGroup) {
+		WriteRequestError(w, NewForbiddenError("only the owner can invite caregivers"))
+		return
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Printf("Error generating invitation token: %v", err)
+		WriteError(w, 500, "internal error")
+		return
+	}
+	now := time.Now()
+	invitation, err := gs.DB.CreateInvitation(&models.GroupInvitation{
+		GroupID:   member.GroupID,
+		Email:     request.Email,
+		Role:      request.Role,
+		TokenHash: hashToken(token),
+		InvitedBy: &userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(invitationTTL),
+	})
+	if err != nil {
+		log.Printf("Error creating invitation in Invite: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: InvitationResponse{GroupInvitation: *invitation, Token: token}})
+	log.Println("Successfully sent a group invitation!")
+}
+
+// @Summary Get invitations
+// @Description Returns the pending invitations sent to the user's email.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]models.InvitationInfo}
+// @Router /auth/invitations [get]
+func (gs *GroupService) Invitations(w http.ResponseWriter, r *http.Request) {
+	_, email, err := GetUserFromContext(r.Context(), gs.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	invitations, err := gs.DB.GetPendingInvitations(email, time.Now())
+	if err != nil {
+		log.Printf("Error fetching invitations: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: invitations})
+}
+
+// @Summary Accept an invitation
+// @Description Joins the group with the role given in the invitation.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body InvitationTokenRequest true "invitation token"
+// @Success 200 {object} APIResponse{data=models.Group}
+// @Failure 404 {object} APIResponse "Invitation not found"
+// @Failure 409 {object} APIResponse "Already a member"
+// @Failure 422 {object} APIResponse "Invitation expired or already answered"
+// @Router /auth/invitations/accept [post]
+func (gs *GroupService) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, invitation := gs.pendingInvitation(w, r)
+	if invitation == nil {
+		return
+	}
+
+	if _, err := gs.DB.GetMembership(userID, invitation.GroupID); err == nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusConflict, Message: "already a member of the group"})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error checking membership in AcceptInvitation: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	if err := gs.DB.AcceptInvitation(invitation, userID, time.Now()); err != nil {
+		log.Printf("Error accepting invitation: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	group, err := gs.DB.GetGroupByID(invitation.GroupID)
+	if err != nil {
+		WriteLookupError(w, err, "group")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: group})
+	log.Println("Successfully joined a group!")
+}
+
+// @Summary Decline an invitation
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body InvitationTokenRequest true "invitation token"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Invitation not found"
+// @Failure 422 {object} APIResponse "Invitation expired or already answered"
+// @Router /auth/invitations/decline [post]
+func (gs *GroupService) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	_, invitation := gs.pendingInvitation(w, r)
+	if invitation == nil {
+		return
+	}
+
+	if err := gs.DB.DeclineInvitation(invitation, time.Now()); err != nil {
+		log.Printf("Error declining invitation: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+}
+
+// @Summary Leave a group
+// @Description Leaves the group. The owner has to transfer ownership first,
+// @Description unless they are the last member, in which case the group is deleted
+// @Description and they take over its dependent profiles whose creator deleted their account.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Group not found"
+// @Failure 409 {object} APIResponse "Owner has to transfer ownership first"
+// @Router /auth/groups/{id}/leave [post]
+func (gs *GroupService) Leave(w http.ResponseWriter, r *http.Request) {
+	userID, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+
+	if member.Role == models.RoleOwner {
+		members, err := gs.DB.GetMembers(member.GroupID)
+		if err != nil {
+			log.Printf("Error fetching members in Leave: %v", err)
+			WriteError(w, 500, "database error")
+			return
+		}
+		if len(members) > 1 {
+			WriteRequestError(w, &RequestError{Status: http.StatusConflict, Message: "transfer ownership before leaving the group"})
+			return
+		}
+		if err := gs.DB.DeleteGroup(member.GroupID, userID); err != nil {
+			log.Printf("Error removing group in Leave: %v", err)
+			WriteError(w, 500, "database error")
+			return
+		}
+		WriteJSON(w, 200, &APIResponse{Status: 200})
+		return
+	}
+
+	if err := gs.DB.RemoveMember(member.GroupID, userID); err != nil {
+		log.Printf("Error leaving group: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully left group!")
+}
+
+// @Summary Transfer group ownership
+// @Description Makes another member the owner. The previous owner becomes a caregiver.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Param input body TransferRequest true "new owner"
+// @Success 200 {object} APIResponse
+// @Failure 403 {object} APIResponse "Not the owner"
+// @Failure 404 {object} APIResponse "Group or member not found"
+// @Router /auth/groups/{id}/transfer [post]
+func (gs *GroupService) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	userID, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+
+	request := &TransferRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if member.Role != models.RoleOwner {
+		WriteRequestError(w, NewForbiddenError("only the owner can transfer ownership"))
+		return
+	}
+	if request.UserID == userID {
+		WriteRequestError(w, NewValidationError("user_id", "you already own the group"))
+		return
+	}
+
+	if err := gs.DB.TransferOwnership(member.GroupID, userID, request.UserID); err != nil {
+		WriteLookupError(w, err, "member")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully transferred group ownership!")
+}
+
+// @Summary Change member role
+// @Description Makes a member a caregiver or a regular member. Only the owner can do this.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Param user_id path int true "User ID of the member"
+// @Param input body RoleRequest true "new role"
+// @Success 200 {object} APIResponse
+// @Failure 403 {object} APIResponse "Not the owner"
+// @Failure 404 {object} APIResponse "Group or member not found"
+// @Failure 422 {object} APIResponse "Invalid role"
+// @Router /auth/groups/{id}/members/{user_id} [put]
+func (gs *GroupService) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	userID, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+	target, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("member not found"))
+		return
+	}
+
+	request := &RoleRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	if !member.Can(models.PermManageGroup) {
+		WriteRequestError(w, NewForbiddenError("only the owner can change roles"))
+		return
+	}
+	if uint(target) == userID {
+		WriteRequestError(w, NewValidationError("user_id", "transfer ownership to change your own role"))
+		return
+	}
+
+	if err := gs.DB.SetRole(member.GroupID, uint(target), request.Role); err != nil {
+		WriteLookupError(w, err, "member")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+}
+
+// @Summary Remove a member
+// @Description Removes a member from the group. Only the owner can do this.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Param user_id path int true "User ID of the member"
+// @Success 200 {object} APIResponse
+// @Failure 403 {object} APIResponse "Not the owner"
+// @Failure 404 {object} APIResponse "Group or member not found"
+// @Router /auth/groups/{id}/members/remove/{user_id} [post]
+func (gs *GroupService) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, member := gs.membership(w, r)
+	if member == nil {
+		return
+	}
+	target, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("member not found"))
+		return
+	}
+
+	if !member.Can(models.PermManageGroup) {
+		WriteRequestError(w, NewForbiddenError("only the owner can remove members"))
+		return
+	}
+	if uint(target) == userID {
+		WriteRequestError(w, NewValidationError("user_id", "use leave to leave the group"))
+		return
+	}
+
+	if err := gs.DB.RemoveMember(member.GroupID, uint(target)); err != nil {
+		WriteLookupError(w, err, "member")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully removed group member!")
+}
@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"errors"
+	"first_aid_companion/kittemplates"
+	"first_aid_companion/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// KitRequest represents a request to create or rename a kit.
+type KitRequest struct {
+	Name        string `json:"name" example:"Car kit"` // Name of the kit
+	Description string `json:"description"`            // Optional description
+	GroupID     *uint  `json:"group_id"`               // Group to create a shared kit for, personal kit if omitted
+}
+
+// MoveDrugsRequest represents a request to move drugs into a kit.
+type MoveDrugsRequest struct {
+	DrugIDs []uint `json:"drug_ids"` // Drugs to move
+	KitID   *uint  `json:"kit_id"`   // Target kit, null takes the drugs out of their kits
+}
+
+// KitInfo is a kit with a summary of its contents.
+type KitInfo struct {
+	models.Kit
+	Summary models.KitSummary `json:"summary"`
+}
+
+// KitDrugs is a group of drugs in the drugs list grouped by kit.
+// Kit is null for drugs without a kit.
+type KitDrugs struct {
+	Kit     *models.Kit       `json:"kit"`
+	Summary models.KitSummary `json:"summary"`
+	Drugs   []models.Drug     `json:"drugs"`
+}
+
+// KitService manages first-aid kits and the drugs in them.
+type KitService struct {
+	DB        *models.KitGorm   // Database access object for kits
+	DrugDB    *models.DrugGorm  // Drugs in the kits
+	GroupDB   *models.GroupGorm // Memberships, group kits are managed by owners and caregivers
+	Templates *kittemplates.Set // Templates kits are checked against
+}
+
+// Validate checks the kit name.
+func (req *KitRequest) Validate() *RequestError {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return NewValidationError("name", "name must not be empty")
+	}
+	return nil
+}
+
+// canManage checks that the user may create, rename or remove kits of the group.
+// Personal kits (groupID nil) are always allowed. Writes the error response otherwise.
+func (ks *KitService) canManage(w http.ResponseWriter, userID uint, groupID *uint) bool {
+	if groupID == nil {
+		return true
+	}
+	member, err := ks.GroupDB.GetMembership(userID, *groupID)
+	if err != nil {
+		WriteLookupError(w, err, "group")
+		return false
+	}
+	if !member.Can(models.PermManageCabinet) {
+		WriteRequestError(w, NewForbiddenError("your role does not allow managing shared kits"))
+		return false
+	}
+	return true
+}
+
+// canMoveDrugs checks that the drugs may be put into the kit, or taken out of their kits if kit is nil.
+// The kit must belong to the cabinet of every drug, and drugs of a group are only moved by members
+// whose role allows managing its cabinet. Writes the error response otherwise.
+func canMoveDrugs(w http.ResponseWriter, groups *models.GroupGorm, userID uint, drugs []models.Drug, kit *models.Kit) bool {
+	checked := map[uint]bool{}
+	for i := range drugs {
+		drug := &drugs[i]
+		if kit != nil && !kit.Holds(drug) {
+			WriteRequestError(w, NewValidationError("kit_id", models.ErrOtherCabinet.Error()))
+			return false
+		}
+		if drug.GroupID == nil || checked[*drug.GroupID] {
+			continue
+		}
+		member, err := groups.GetMembership(userID, *drug.GroupID)
+		if err != nil {
+			WriteLookupError(w, err, "drug")
+			return false
+		}
+		if !member.Can(models.PermManageCabinet) {
+			WriteRequestError(w, NewForbiddenError("your role does not allow moving shared drugs"))
+			return false
+		}
+		checked[*drug.GroupID] = true
+	}
+	return true
+}
+
+// summariesByKit maps kit IDs to summaries, key 0 holds drugs without a kit.
+func summariesByKit(summaries []models.KitSummary) map[uint]models.KitSummary {
+	result := map[uint]models.KitSummary{}
+	for _, summary := range summaries {
+		key := uint(0)
+		if summary.KitID != nil {
+			key = *summary.KitID
+		}
+		result[key] = summary
+	}
+	return result
+}
+
+// kitSummary returns the summary of a kit, an empty one if the kit has no drugs.
+func kitSummary(summaries map[uint]models.KitSummary, kitID uint) models.KitSummary {
+	if summary, ok := summaries[kitID]; ok {
+		return summary
+	}
+	summary := models.KitSummary{}
+	if kitID != 0 {
+		summary.KitID = &kitID
+	}
+	return summary
+}
+
+// @Summary Get kits
+// @Description Returns the user's personal kits and kits of their groups with item count and nearest expiry.
+// @Tags kits
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]KitInfo}
+// @Router /auth/kits [get]
+func (ks *KitService) Kits(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), ks.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	kits, err := ks.DB.GetUserKits(uint(userID))
+	if err != nil {
+		log.Printf("Error fetching kits: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	summaries, err := ks.DB.GetKitSummaries(models.OwnedOrShared(uint(userID)), time.Now())
+	if err != nil {
+		log.Printf("Error fetching kit summaries: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	byKit := summariesByKit(summaries)
+
+	response := make([]KitInfo, 0, len(kits))
+	for _, kit := range kits {
+		response = append(response, KitInfo{Kit: kit, Summary: kitSummary(byKit, kit.ID)})
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: response})
+}
+
+// @Summary Create a kit
+// @Description Creates a personal kit, or a shared kit of a group the user owns or is a caregiver of.
+// @Tags kits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body KitRequest true "kit"
+// @Success 200 {object} APIResponse{data=models.Kit}
+// @Failure 403 {object} APIResponse "Role does not allow managing shared kits"
+// @Failure 404 {object} APIResponse "Group not found"
+// @Failure 422 {object} APIResponse "Empty name"
+// @Router /auth/kits [post]
+func (ks *KitService) AddKit(w http.ResponseWriter, r *http.Request) {
+	request := &KitRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ks.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	kit := &models.Kit{Name: request.Name, Description: request.Description, CreatedAt: time.Now()}
+	if request.GroupID != nil {
+		if !ks.canManage(w, uint(userID), request.GroupID) {
+			return
+		}
+		kit.GroupID = request.GroupID
+	} else {
+		owner := uint(userID)
+		kit.UserID = &owner
+	}
+
+	kit, err = ks.DB.CreateKit(kit)
+	if err != nil {
+		log.Printf("Error creating kit in AddKit: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: kit})
+	log.Println("Successfully added a new kit!")
+}
+
+// @Summary Rename a kit
+// @Description Changes the name and description of a kit. The location of its drugs follows the new name.
+// @Tags kits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Kit ID"
+// @Param input body KitRequest true "new name and description"
+// @Success 200 {object} APIResponse{data=models.Kit}
+// @Failure 403 {object} APIResponse "Role does not allow managing shared kits"
+// @Failure 404 {object} APIResponse "Kit not found"
+// @Failure 422 {object} APIResponse "Empty name"
+// @Router /auth/kits/{id} [put]
+func (ks *KitService) UpdateKit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("kit not found"))
+		return
+	}
+
+	request := &KitRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ks.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	kit, err := ks.DB.GetUserKit(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "kit")
+		return
+	}
+	if !ks.canManage(w, uint(userID), kit.GroupID) {
+		return
+	}
+
+	kit.Name = request.Name
+	kit.Description = request.Description
+	if err := ks.DB.UpdateKit(kit); err != nil {
+		log.Printf("Error updating kit in UpdateKit: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: kit})
+}
+
+// @Summary Remove a kit
+// @Description Deletes a kit. Its drugs are kept without a kit and without a location.
+// @Tags kits
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Kit ID"
+// @Success 200 {object} APIResponse
+// @Failure 403 {object} APIResponse "Role does not allow managing shared kits"
+// @Failure 404 {object} APIResponse "Kit not found"
+// @Router /auth/kits/remove/{id} [post]
+func (ks *KitService) RemoveKit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("kit not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ks.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	kit, err := ks.DB.GetUserKit(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "kit")
+		return
+	}
+	if !ks.canManage(w, uint(userID), kit.GroupID) {
+		return
+	}
+	if err := ks.DB.DeleteKit(kit.ID); err != nil {
+		log.Printf("Error removing kit in RemoveKit: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully removed kit!")
+}
+
+// @Summary Move drugs between kits
+// @Description Moves the given drugs into a kit, or takes them out of their kits if kit_id is null.
+// @Description Either all drugs are moved or none. Drugs stay in their cabinet: personal drugs go into
+// @Description personal kits and group drugs into kits of their group. Moving group drugs requires the owner or caregiver role.
+// @Description The location of the drugs follows the kit name and is cleared when they are taken out of their kits.
+// @Tags kits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body MoveDrugsRequest true "drugs and target kit"
+// @Success 200 {object} APIResponse
+// @Failure 403 {object} APIResponse "Role does not allow moving shared drugs"
+// @Failure 404 {object} APIResponse "Drug or kit not found"
+// @Failure 422 {object} APIResponse "No drugs given or kit of another cabinet"
+// @Router /auth/drugs/move [post]
+func (ks *KitService) MoveDrugs(w http.ResponseWriter, r *http.Request) {
+	request := &MoveDrugsRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+
+	// Duplicates would break the check that every drug was moved
+	seen := map[uint]bool{}
+	drugIDs := []uint{}
+	for _, id := range request.DrugIDs {
+		if !seen[id] {
+			seen[id] = true
+			drugIDs = append(drugIDs, id)
+		}
+	}
+	if len(drugIDs) == 0 {
+		WriteRequestError(w, NewValidationError("drug_ids", "at least one drug is required"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ks.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	var kit *models.Kit
+	if request.KitID != nil {
+		kit, err = ks.DB.GetUserKit(uint(userID), int(*request.KitID))
+		if err != nil {
+			WriteLookupError(w, err, "kit")
+			return
+		}
+	}
+
+	drugs, err := ks.DrugDB.GetUserDrugs(uint(userID), drugIDs)
+	if err != nil {
+		WriteLookupError(w, err, "drug")
+		return
+	}
+	if len(drugs) != len(drugIDs) {
+		WriteRequestError(w, NewNotFoundError("drug not found"))
+		return
+	}
+	if !canMoveDrugs(w, ks.GroupDB, uint(userID), drugs, kit) {
+		return
+	}
+
+	if err := ks.DB.MoveDrugs(uint(userID), drugIDs, kit); errors.Is(err, models.ErrOtherCabinet) {
+		WriteRequestError(w, NewValidationError("kit_id", err.Error()))
+		return
+	} else if err != nil {
+		WriteLookupError(w, err, "drug")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully moved drugs!")
+}
+
+// @Summary Get kit templates
+// @Description Lists the templates kits can be checked against, e.g. the legal minimum of a car kit.
+// @Tags kits
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]kittemplates.Template}
+// @Router /auth/kit_templates [get]
+func (ks *KitService) KitTemplates(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: ks.Templates.List()})
+}
+
+// @Summary Check kit against a template
+// @Description Compares the drugs and supplies of a kit with a template and reports missing, expired and insufficient items.
+// @Tags kits
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Kit ID"
+// @Param template query string true "Template ID, e.g. car_ru"
+// @Success 200 {object} APIResponse{data=kittemplates.Report}
+// @Failure 404 {object} APIResponse "Kit or template not found"
+// @Failure 422 {object} APIResponse "No template given"
+// @Router /auth/kits/{id}/check [get]
+func (ks *KitService) CheckKit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("kit not found"))
+		return
+	}
+
+	templateID := r.URL.Query().Get("template")
+	if templateID == "" {
+		WriteRequestError(w, NewValidationError("template", "template is required"))
+		return
+	}
+	template, ok := ks.Templates.Get(templateID)
+	if !ok {
+		WriteRequestError(w, NewNotFoundError("template not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ks.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	kit, err := ks.DB.GetUserKit(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "kit")
+		return
+	}
+	drugs, err := ks.DrugDB.GetDrugsByKit(models.OwnedOrShared(uint(userID)), &kit.ID)
+	if err != nil {
+		log.Printf("Error fetching drugs in CheckKit: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	supplies := make([]kittemplates.Supply, 0, len(drugs))
+	for _, drug := range drugs {
+		supplies = append(supplies, kittemplates.Supply{
+			ID:       drug.ID,
+			Name:     drug.Name,
+			Quantity: drug.Quantity,
+			Unit:     drug.Unit,
+			Expiry:   drug.Expiry,
+		})
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: template.Check(supplies, time.Now())})
+}
@@ -12,7 +12,7 @@ type MedicalCardService struct {
 // It calls the DB layer to persist the card and returns the created card or an error.
 func (ms *MedicalCardService) CreateCard(userID uint) (*models.MedicalCard, error) {
 	// Initializes with empty fields
-	return ms.DB.CreateCard("", "", "", userID)
+	return ms.DB.CreateCard("", userID)
 }
 
 // GetCard fetches the medical card associated with the specified user ID.
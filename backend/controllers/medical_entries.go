@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"first_aid_companion/icd10"
+	"first_aid_companion/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Number of ICD-10 lookup results
+const (
+	defaultICD10Results = 20
+	maxICD10Results     = 50
+)
+
+// entryKinds maps the {kind} path segment to the kind of the entries.
+var entryKinds = map[string]string{
+	"allergies":  models.EntryAllergy,
+	"conditions": models.EntryCondition,
+}
+
+// MedicalEntryRequest represents a request to add or change an allergy or chronic condition.
+// Either text, substance or icd10_code is required, the text defaults to the title of the code.
+type MedicalEntryRequest struct {
+	ICD10Code string `json:"icd10_code" example:"Z88.0"`          // ICD-10 code, e.g. from /auth/icd10
+	Text      string `json:"text" example:"Penicillin allergy"`   // Name or free-text description
+	Substance string `json:"substance" example:"amoxicillin"`     // Allergen, for allergies
+	Reaction  string `json:"reaction" example:"anaphylaxis"`      // Reaction to the allergen
+	Severity  string `json:"severity" example:"life_threatening"` // mild, moderate, severe or life_threatening
+	OnsetDate string `json:"onset_date" example:"2015-06-01"`     // YYYY-MM-DD, optional
+
+	onset *time.Time // Parsed onset date
+}
+
+// MedicalEntryService manages coded allergies and chronic conditions.
+type MedicalEntryService struct {
+	DB    *models.MedicalCardGorm // Database access object for medical cards and entries
+	ICD10 *icd10.Dataset          // Codes the entries are checked against
+}
+
+// Validate checks the code, severity and onset date of the entry and fills in the text.
+func (req *MedicalEntryRequest) Validate(dataset *icd10.Dataset) *RequestError {
+	req.ICD10Code = icd10.Normalize(req.ICD10Code)
+	req.Text = strings.TrimSpace(req.Text)
+	req.Substance = strings.TrimSpace(req.Substance)
+	req.Reaction = strings.TrimSpace(req.Reaction)
+	req.Severity = strings.TrimSpace(req.Severity)
+	req.OnsetDate = strings.TrimSpace(req.OnsetDate)
+
+	if req.ICD10Code != "" {
+		if !icd10.Valid(req.ICD10Code) {
+			return NewValidationError("icd10_code", "icd10_code must look like J45 or J45.0")
+		}
+		// Codes missing from the dataset are accepted, it only covers the common ones
+		if code, ok := dataset.Lookup(req.ICD10Code); ok && req.Text == "" {
+			req.Text = code.Title
+		}
+	}
+	if req.Text == "" && req.Substance == "" && req.ICD10Code == "" {
+		return NewValidationError("text", "text, substance or icd10_code is required")
+	}
+	if req.Severity != "" && !models.IsSeverity(req.Severity) {
+		return NewValidationError("severity", "severity must be mild, moderate, severe or life_threatening")
+	}
+	req.onset = nil
+	if req.OnsetDate != "" {
+		onset, err := time.Parse("2006-01-02", req.OnsetDate)
+		if err != nil {
+			return NewValidationError("onset_date", "onset_date must be a date in YYYY-MM-DD format")
+		}
+		if onset.After(time.Now()) {
+			return NewValidationError("onset_date", "onset_date must not be in the future")
+		}
+		req.onset = &onset
+	}
+	return nil
+}
+
+// apply sets the fields of the entry from the validated request.
+func (req *MedicalEntryRequest) apply(entry *models.MedicalEntry) {
+	entry.ICD10Code = req.ICD10Code
+	entry.Text = req.Text
+	entry.Substance = req.Substance
+	entry.Reaction = req.Reaction
+	entry.Severity = req.Severity
+	entry.OnsetDate = req.onset
+}
+
+// @Summary List allergies or chronic conditions
+// @Tags medical card
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]models.MedicalEntry}
+// @Router /auth/allergies [get]
+// @Router /auth/conditions [get]
+func (es *MedicalEntryService) Entries(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	entries, err := es.DB.GetEntries(uint(userID), entryKinds[mux.Vars(r)["kind"]])
+	if err != nil {
+		log.Printf("Error fetching medical entries: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: entries})
+}
+
+// @Summary Add allergy or chronic condition
+// @Tags medical card
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body MedicalEntryRequest true "entry"
+// @Success 200 {object} APIResponse{data=models.MedicalEntry}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Failure 422 {object} APIResponse "Empty entry, invalid code, severity or date"
+// @Router /auth/allergies [post]
+// @Router /auth/conditions [post]
+func (es *MedicalEntryService) AddEntry(w http.ResponseWriter, r *http.Request) {
+	request := &MedicalEntryRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(es.ICD10); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	entry := &models.MedicalEntry{
+		UserID:    uint(userID),
+		Kind:      entryKinds[mux.Vars(r)["kind"]],
+		CreatedAt: time.Now(),
+	}
+	request.apply(entry)
+	if _, err := es.DB.CreateEntry(entry); err != nil {
+		log.Printf("Error creating medical entry in AddEntry: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: entry})
+}
+
+// @Summary Change allergy or chronic condition
+// @Tags medical card
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Entry ID"
+// @Param input body MedicalEntryRequest true "entry"
+// @Success 200 {object} APIResponse{data=models.MedicalEntry}
+// @Failure 404 {object} APIResponse "Entry not found"
+// @Failure 422 {object} APIResponse "Empty entry, invalid code, severity or date"
+// @Router /auth/allergies/{id} [put]
+// @Router /auth/conditions/{id} [put]
+func (es *MedicalEntryService) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("entry not found"))
+		return
+	}
+
+	request := &MedicalEntryRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(es.ICD10); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	entry, err := es.DB.GetUserEntry(uint(userID), entryKinds[mux.Vars(r)["kind"]], id)
+	if err != nil {
+		WriteLookupError(w, err, "entry")
+		return
+	}
+	request.apply(entry)
+	if err := es.DB.UpdateEntry(entry); err != nil {
+		log.Printf("Error updating medical entry in UpdateEntry: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: entry})
+}
+
+// @Summary Remove allergy or chronic condition
+// @Tags medical card
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Entry ID"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Entry not found"
+// @Router /auth/allergies/remove/{id} [post]
+// @Router /auth/conditions/remove/{id} [post]
+func (es *MedicalEntryService) RemoveEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("entry not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), es.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if err := es.DB.DeleteUserEntry(uint(userID), entryKinds[mux.Vars(r)["kind"]], id); err != nil {
+		WriteLookupError(w, err, "entry")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+}
+
+// @Summary Look up ICD-10 codes
+// @Description Searches the bundled ICD-10 subset by code prefix ("J45") or by words of the
+// @Description English or Russian title ("allergic asthma", "астма").
+// @Tags medical card
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Code or words of the title"
+// @Param limit query int false "Maximum number of results, 20 by default, at most 50"
+// @Success 200 {object} APIResponse{data=[]icd10.Code}
+// @Failure 422 {object} APIResponse "Empty query"
+// @Router /auth/icd10 [get]
+func (es *MedicalEntryService) ICD10Lookup(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		WriteRequestError(w, NewValidationError("q", "search query must not be empty"))
+		return
+	}
+
+	limit := defaultICD10Results
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxICD10Results {
+			WriteRequestError(w, NewValidationError("limit", "limit must be between 1 and 50"))
+			return
+		}
+		limit = n
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: es.ICD10.Search(query, limit)})
+}
@@ -1,17 +1,23 @@
 package controllers
 
 import (
-	"context"
 	"encoding/json"
+	"first_aid_companion/llm"
 	"first_aid_companion/models"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
-
-	"google.golang.org/genai"
+	"time"
 )
 
+// systemPrompt describes the assistant's role, the user's data is appended to it.
+const systemPrompt = `You are a first-aid assistant in the First-aid Helper app.
+Give short, clear and practical first-aid guidance. You do not replace a doctor:
+if the situation may be life-threatening, tell the user to call emergency services (112) first.
+Take the user's medical card and medicine cabinet below into account, never suggest drugs the user is allergic to
+and prefer drugs the user already has when they are appropriate and not expired.`
+
 // ChatMessage represents a single message content in a chat.
 type ChatMessage struct {
 	Content string `json:"content"`
@@ -25,8 +31,11 @@ type MessageRequest struct {
 
 // MessageService handles message-related operations, including persistence and AI responses.
 type MessageService struct {
-	ApiKey string              // API key for connecting to the AI service
-	DB     *models.MessageGorm // Database interface for message storage
+	Model  llm.ChatModel           // AI model used to generate replies
+	DB     *models.MessageGorm     // Database interface for message storage
+	ChatDB *models.ChatGorm        // Chats, used to check that the chat belongs to the user
+	CardDB *models.MedicalCardGorm // Medical cards included in the prompt
+	DrugDB *models.DrugGorm        // User's drugs included in the prompt
 }
 
 // NewMessage handles the submission of a user's chat message and streams an AI response.
@@ -38,13 +47,14 @@ type MessageService struct {
 // @Param input body MessageRequest true "Chat ID and user message"
 // @Success 200 {string} string "streamed AI response"
 // @Failure 400 {object} APIResponse "Invalid request body or empty message"
+// @Failure 404 {object} APIResponse "Chat not found"
 // @Failure 500 {object} APIResponse "Internal server or streaming error"
 // @Router /auth/send_message [post]
 // @Security BearerAuth
 func (ms *MessageService) NewMessage(w http.ResponseWriter, r *http.Request) {
 	var request MessageRequest
 
-	_, _, err := GetUserFromContext(r.Context(), ms.DB.DB)
+	userID, _, err := GetUserFromContext(r.Context(), ms.DB.DB)
 	if err != nil {
 		WriteError(w, http.StatusBadRequest, "nor authorized")
 		return
@@ -62,6 +72,12 @@ func (ms *MessageService) NewMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Messages can only be posted into the user's own chats
+	if _, err := ms.ChatDB.GetUserChat(uint(userID), request.ChatID); err != nil {
+		WriteLookupError(w, err, "chat")
+		return
+	}
+
 	// Save the user's message in the database (role 0 = user)
 	if _, err := ms.DB.AddMessage(request.ChatID, 0, request.Message); err != nil {
 		log.Printf("DB save error: %v", err)
@@ -81,41 +97,27 @@ func (ms *MessageService) NewMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create a context for API client
-	ctx := context.Background()
-
-	// Initialize the GenAI client with API key and backend choice
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  ms.ApiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	// Build the prompt from the user's data and the chat history (which includes the new message)
+	messages, err := ms.buildPrompt(uint(userID), request.ChatID)
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, "failed to initialize LLM client")
-		log.Fatal(err)
+		log.Printf("Error building prompt in NewMessage: %v", err)
+		WriteError(w, http.StatusInternalServerError, "failed to load chat history")
 		return
 	}
 
-	// Start streaming generation of AI response for the user's message
-	stream := client.Models.GenerateContentStream(
-		ctx,
-		"gemini-1.5-flash",          // AI model to use
-		genai.Text(request.Message), // Input prompt from user message
-		nil,                         // Optional generation params
-	)
-
-	aiResponse := ""
-
-	// Read streamed chunks of generated content from the AI
-	for chunk, _ := range stream {
-		// Extract text part from the chunk
-		part := chunk.Candidates[0].Content.Parts[0]
-		// Accumulate partial response
-		aiResponse += part.Text
-
+	// Stream the AI response chunk by chunk
+	aiResponse, err := ms.Model.Stream(r.Context(), messages, func(chunk string) error {
 		// Write partial text as an SSE data event to client
-		// fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(part.Text, "\n", "\\n"))
-		fmt.Fprintf(w, "data: %s\n\n", part.Text)
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
 		flusher.Flush() // Flush response to client immediately
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, so report the failure as an SSE event
+		log.Printf("LLM error in NewMessage: %v", err)
+		fmt.Fprintf(w, "event: error\ndata: failed to generate response\n\n")
+		flusher.Flush()
+		return
 	}
 
 	// Send a custom SSE event to indicate streaming is complete
@@ -124,10 +126,97 @@ func (ms *MessageService) NewMessage(w http.ResponseWriter, r *http.Request) {
 
 	// Save the complete AI-generated response in the database (role 1 = AI)
 	if _, err := ms.DB.AddMessage(request.ChatID, 1, aiResponse); err != nil {
-		WriteError(w, http.StatusInternalServerError, "failed to save LLM response")
-		log.Fatal(err)
+		log.Printf("DB save error: %v", err)
 		return
 	}
 
 	log.Println("Successfully generated the response!")
 }
+
+// buildPrompt assembles the messages sent to the model: the system prompt with the user's
+// medical card and drugs, followed by every stored message of the chat.
+func (ms *MessageService) buildPrompt(userID, chatID uint) ([]llm.Message, error) {
+	var system strings.Builder
+	system.WriteString(systemPrompt)
+
+	// The assistant can still help without a medical card, so errors are only logged
+	system.WriteString("\n\nUser's medical card:\n")
+	card, err := ms.CardDB.GetCardByUserID(userID)
+	if err != nil {
+		log.Printf("Error getting medical card for prompt: %v", err)
+	} else {
+		fmt.Fprintf(&system, "- Blood type: %s\n", orUnknown(card.BloodType))
+	}
+	for _, list := range []struct{ title, kind string }{
+		{"Allergies", models.EntryAllergy},
+		{"Chronic conditions", models.EntryCondition},
+	} {
+		entries, err := ms.CardDB.GetEntries(userID, list.kind)
+		if err != nil {
+			log.Printf("Error getting %s entries for prompt: %v", list.kind, err)
+			continue
+		}
+		if len(entries) == 0 {
+			fmt.Fprintf(&system, "- %s: %s\n", list.title, orUnknown(""))
+			continue
+		}
+		fmt.Fprintf(&system, "- %s:\n", list.title)
+		for _, entry := range entries {
+			fmt.Fprintf(&system, "  - %s\n", entry.Describe())
+		}
+	}
+
+	// The cabinet comes last, a prompt too long for the model is cut from its end
+	drugs, err := ms.DrugDB.GetDrugsByUserId(userID)
+	if err != nil {
+		log.Printf("Error getting drugs for prompt: %v", err)
+	} else {
+		system.WriteString("\nUser's medicine cabinet:\n")
+		if len(drugs) == 0 {
+			system.WriteString("- empty\n")
+		}
+		for _, drug := range drugs {
+			fmt.Fprintf(&system, "- %s", drug.Name)
+			if drug.Type != "" {
+				fmt.Fprintf(&system, " (%s)", drug.Type)
+			}
+			if drug.Dose != "" {
+				fmt.Fprintf(&system, ", dose %s", drug.Dose)
+			}
+			if drug.Amount != "" {
+				fmt.Fprintf(&system, ", amount %s", drug.Amount)
+			}
+			if !drug.Expiry.IsZero() {
+				fmt.Fprintf(&system, ", expires %s", drug.Expiry.Format("2006-01-02"))
+				if drug.Expiry.Before(time.Now()) {
+					system.WriteString(" (EXPIRED)")
+				}
+			}
+			system.WriteString("\n")
+		}
+	}
+
+	history, err := ms.DB.GetMessages(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []llm.Message{{Role: llm.RoleSystem, Content: system.String()}}
+	for _, message := range history {
+		role := llm.RoleUser
+		if message.Sender == 1 {
+			role = llm.RoleAssistant
+		}
+		messages = append(messages, llm.Message{Role: role, Content: message.Text})
+	}
+
+	return messages, nil
+}
+
+// orUnknown replaces empty medical card fields with a placeholder for the model.
+func orUnknown(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "not specified"
+	}
+	return value
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"first_aid_companion/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// NotificationService exposes notifications produced by background jobs such as the expiry scheduler.
+type NotificationService struct {
+	DB *models.NotificationGorm // Database access object for notifications
+}
+
+// @Summary Get notifications
+// @Description Returns the user's notifications, newest first. Unread ones have an empty read_at.
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "Only return unread notifications"
+// @Success 200 {object} APIResponse{data=[]models.Notification}
+// @Router /auth/notifications [get]
+func (ns *NotificationService) Notifications(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), ns.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+	notifications, err := ns.DB.GetUserNotifications(uint(userID), unreadOnly)
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: notifications})
+}
+
+// @Summary Mark notification as read
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Notification not found"
+// @Router /auth/notifications/read/{id} [post]
+func (ns *NotificationService) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("notification not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ns.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if err := ns.DB.MarkRead(uint(userID), id); err != nil {
+		WriteLookupError(w, err, "notification")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+}
+
+// @Summary Mark all notifications as read
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse
+// @Router /auth/notifications/read_all [post]
+func (ns *NotificationService) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), ns.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if err := ns.DB.MarkAllRead(uint(userID)); err != nil {
+		log.Printf("Error marking notifications as read: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+}
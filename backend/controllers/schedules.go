@@ -0,0 +1,516 @@
+package controllers
+
+import (
+	"first_aid_companion/models"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Limits of the upcoming doses window
+const (
+	defaultUpcomingHours = 24
+	maxUpcomingHours     = 7 * 24
+	overdueWindow        = 12 * time.Hour // Pending doses this old are still listed as overdue
+	defaultSnooze        = 15 * time.Minute
+)
+
+// ScheduleCreationRequest represents a request to create an intake schedule.
+type ScheduleCreationRequest struct {
+	DrugID   uint      `json:"drug_id"`                                         // Drug to take
+	RRule    string    `json:"rrule" example:"FREQ=HOURLY;INTERVAL=8;COUNT=15"` // Recurrence rule (FREQ, INTERVAL, COUNT, UNTIL, BYHOUR, BYDAY)
+	StartsAt time.Time `json:"starts_at" example:"2025-07-12T08:00:00Z"`        // First dose
+	Quantity float64   `json:"quantity" example:"1"`                            // Units per dose, 1 if omitted
+	Note     string    `json:"note"`                                            // Instructions, e.g. "after meals"
+}
+
+// DoseLogRequest represents a request to mark a scheduled dose.
+type DoseLogRequest struct {
+	ScheduleID    uint      `json:"schedule_id"`                                 // Schedule of the dose
+	ScheduledAt   time.Time `json:"scheduled_at" example:"2025-07-12T16:00:00Z"` // Occurrence being logged
+	Status        string    `json:"status" example:"taken"`                      // taken, skipped or snoozed
+	SnoozeMinutes int       `json:"snooze_minutes" example:"15"`                 // Delay for snoozed doses, 15 if omitted
+}
+
+// UpcomingDose is a single dose in the upcoming doses list.
+type UpcomingDose struct {
+	ScheduleID  uint      `json:"schedule_id"`
+	DrugID      uint      `json:"drug_id"`
+	DrugName    string    `json:"drug_name"`
+	ScheduledAt time.Time `json:"scheduled_at"` // Occurrence of the schedule, identifies the dose
+	DueAt       time.Time `json:"due_at"`       // When to take it, later than scheduled_at for snoozed doses
+	Quantity    float64   `json:"quantity"`
+	Note        string    `json:"note"`
+	Status      string    `json:"status"` // pending, snoozed, taken or skipped
+}
+
+// Adherence summarizes how well the user follows the schedules of a drug.
+type Adherence struct {
+	DrugID  uint             `json:"drug_id"`
+	Taken   int              `json:"taken"`
+	Skipped int              `json:"skipped"`
+	Missed  int              `json:"missed"` // Past doses without a taken or skipped entry
+	Rate    float64          `json:"rate"`   // Share of past doses that were taken, 0..1
+	History []models.DoseLog `json:"history"`
+}
+
+// ScheduleService manages intake schedules and dose logging.
+type ScheduleService struct {
+	DB      *models.ScheduleGorm     // Database access object for schedules and dose logs
+	DrugDB  *models.DrugGorm         // Drugs, schedules can only be created for the user's own drugs
+	NotifDB *models.NotificationGorm // Where restock notifications are stored
+}
+
+// @Summary Create intake schedule
+// @Description Creates a schedule for one of the user's drugs, e.g. every 8 hours for 5 days is "FREQ=HOURLY;INTERVAL=8;COUNT=15".
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body ScheduleCreationRequest true "schedule"
+// @Success 200 {object} APIResponse{data=models.IntakeSchedule}
+// @Failure 404 {object} APIResponse "Drug not found"
+// @Failure 422 {object} APIResponse "Invalid recurrence rule"
+// @Router /auth/schedules [post]
+func (ss *ScheduleService) AddSchedule(w http.ResponseWriter, r *http.Request) {
+	request := &ScheduleCreationRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+
+	// Validate the request
+	if _, err := models.ParseRRule(request.RRule); err != nil {
+		WriteRequestError(w, NewValidationError("rrule", err.Error()))
+		return
+	}
+	if request.StartsAt.IsZero() {
+		WriteRequestError(w, NewValidationError("starts_at", "start time is required"))
+		return
+	}
+	if request.Quantity < 0 {
+		WriteRequestError(w, NewValidationError("quantity", "quantity must not be negative"))
+		return
+	}
+	if request.Quantity == 0 {
+		request.Quantity = 1
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	// Schedules can only be created for the user's own drugs
+	if _, err := ss.DrugDB.GetUserDrug(uint(userID), int(request.DrugID)); err != nil {
+		WriteLookupError(w, err, "drug")
+		return
+	}
+
+	schedule, err := ss.DB.CreateSchedule(&models.IntakeSchedule{
+		UserID:    uint(userID),
+		DrugID:    request.DrugID,
+		RRule:     strings.TrimSpace(request.RRule),
+		StartsAt:  request.StartsAt.UTC(),
+		Quantity:  request.Quantity,
+		Note:      request.Note,
+		Active:    true,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error creating schedule in AddSchedule: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: schedule})
+	log.Println("Successfully added a new intake schedule!")
+}
+
+// @Summary Get intake schedules
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]models.IntakeSchedule}
+// @Router /auth/schedules [get]
+func (ss *ScheduleService) Schedules(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	schedules, err := ss.DB.GetUserSchedules(uint(userID), false)
+	if err != nil {
+		log.Printf("Error fetching schedules: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: schedules})
+}
+
+// @Summary Remove intake schedule
+// @Description Removes the schedule together with its dose history.
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Schedule not found"
+// @Router /auth/schedules/remove/{id} [post]
+func (ss *ScheduleService) RemoveSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("schedule not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if err := ss.DB.DeleteUserSchedule(uint(userID), id); err != nil {
+		WriteLookupError(w, err, "schedule")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully removed intake schedule!")
+}
+
+// @Summary Get upcoming doses
+// @Description Lists doses due in the next hours, including overdue doses of the last 12 hours, ordered by due time.
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param hours query int false "How many hours ahead to look, 24 by default, at most 168"
+// @Success 200 {object} APIResponse{data=[]UpcomingDose}
+// @Router /auth/doses/upcoming [get]
+func (ss *ScheduleService) UpcomingDoses(w http.ResponseWriter, r *http.Request) {
+	hours := defaultUpcomingHours
+	if value := r.URL.Query().Get("hours"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxUpcomingHours {
+			WriteRequestError(w, NewValidationError("hours", "hours must be between 1 and 168"))
+			return
+		}
+		hours = n
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	now := time.Now().UTC()
+	from, to := now.Add(-overdueWindow), now.Add(time.Duration(hours)*time.Hour)
+
+	schedules, err := ss.DB.GetUserSchedules(uint(userID), true)
+	if err != nil {
+		log.Printf("Error fetching schedules: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	logs, err := ss.DB.GetUserDoseLogs(uint(userID), from, to)
+	if err != nil {
+		log.Printf("Error fetching dose logs: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	drugs, err := ss.DrugDB.GetCabinetDrugs(models.OwnedOrShared(uint(userID)))
+	if err != nil {
+		log.Printf("Error fetching drugs: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	// Index logs by dose and drug names by ID
+	type doseKey struct {
+		schedule uint
+		at       int64
+	}
+	logged := map[doseKey]models.DoseLog{}
+	for _, entry := range logs {
+		logged[doseKey{entry.ScheduleID, entry.ScheduledAt.Unix()}] = entry
+	}
+	drugNames := map[uint]string{}
+	for _, drug := range drugs {
+		drugNames[drug.ID] = drug.Name
+	}
+
+	doses := []UpcomingDose{}
+	for _, schedule := range schedules {
+		times, err := schedule.Doses(from, to)
+		if err != nil {
+			log.Printf("Skipping schedule %d with invalid rule: %v", schedule.ID, err)
+			continue
+		}
+
+		for _, at := range times {
+			dose := UpcomingDose{
+				ScheduleID:  schedule.ID,
+				DrugID:      schedule.DrugID,
+				DrugName:    drugNames[schedule.DrugID],
+				ScheduledAt: at,
+				DueAt:       at,
+				Quantity:    schedule.Quantity,
+				Note:        schedule.Note,
+				Status:      models.DosePending,
+			}
+			if entry, ok := logged[doseKey{schedule.ID, at.Unix()}]; ok {
+				dose.Status = entry.Status
+				if entry.SnoozedUntil != nil {
+					dose.DueAt = *entry.SnoozedUntil
+				}
+			}
+
+			// Past doses that were dealt with are not upcoming anymore
+			if at.Before(now) && (dose.Status == models.DoseTaken || dose.Status == models.DoseSkipped) {
+				continue
+			}
+			doses = append(doses, dose)
+		}
+	}
+	sort.Slice(doses, func(i, j int) bool { return doses[i].DueAt.Before(doses[j].DueAt) })
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: doses})
+}
+
+// @Summary Log a dose
+// @Description Marks a scheduled dose as taken, skipped or snoozed. Logging the same dose again replaces its status.
+// @Description Taking a dose decrements the drug's quantity, undoing it restores the quantity.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body DoseLogRequest true "dose status"
+// @Success 200 {object} APIResponse{data=models.DoseLog}
+// @Failure 404 {object} APIResponse "Schedule not found"
+// @Failure 422 {object} APIResponse "Invalid status or not a scheduled time"
+// @Router /auth/doses/log [post]
+func (ss *ScheduleService) LogDose(w http.ResponseWriter, r *http.Request) {
+	request := &DoseLogRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+
+	switch request.Status {
+	case models.DoseTaken, models.DoseSkipped, models.DoseSnoozed:
+	default:
+		WriteRequestError(w, NewValidationError("status", "status must be taken, skipped or snoozed"))
+		return
+	}
+	if request.SnoozeMinutes < 0 {
+		WriteRequestError(w, NewValidationError("snooze_minutes", "snooze must not be negative"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	schedule, err := ss.DB.GetUserSchedule(uint(userID), request.ScheduleID)
+	if err != nil {
+		WriteLookupError(w, err, "schedule")
+		return
+	}
+
+	// Only real occurrences of the schedule can be logged
+	scheduledAt := request.ScheduledAt.UTC()
+	if !schedule.HasDose(scheduledAt) {
+		WriteRequestError(w, NewValidationError("scheduled_at", "no dose is scheduled at this time"))
+		return
+	}
+
+	now := time.Now().UTC()
+	entry := &models.DoseLog{
+		UserID:      uint(userID),
+		ScheduleID:  schedule.ID,
+		DrugID:      schedule.DrugID,
+		ScheduledAt: scheduledAt,
+		Status:      request.Status,
+		ActedAt:     now,
+	}
+	if request.Status == models.DoseSnoozed {
+		snooze := defaultSnooze
+		if request.SnoozeMinutes > 0 {
+			snooze = time.Duration(request.SnoozeMinutes) * time.Minute
+		}
+		until := now.Add(snooze)
+		entry.SnoozedUntil = &until
+	}
+
+	// The stock changes only when "taken" is set or unset, in the transaction logging the dose
+	var before float64
+	var drug *models.Drug
+	entry, err = ss.DB.LogDose(entry, func(tx *gorm.DB, previous string) error {
+		wasTaken, isTaken := previous == models.DoseTaken, entry.Status == models.DoseTaken
+		if isTaken == wasTaken {
+			return nil
+		}
+		delta := schedule.Quantity
+		if isTaken {
+			delta = -delta
+		}
+		var err error
+		before, drug, err = models.NewDrugGorm(tx).AdjustStock(schedule.DrugID, uint(userID), delta)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error logging dose in LogDose: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	if drug != nil {
+		if err := ss.notifyStock(schedule, drug, before, scheduledAt); err != nil {
+			log.Printf("Error notifying about stock in LogDose: %v", err)
+		}
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: entry})
+}
+
+// notifyStock notifies the owner of the drug, or every member of a shared drug's group,
+// when a dose made its quantity fall to the low-stock threshold or run out.
+func (ss *ScheduleService) notifyStock(schedule *models.IntakeSchedule, drug *models.Drug, before float64, scheduledAt time.Time) error {
+	if drug.Quantity == nil {
+		return nil
+	}
+	after := *drug.Quantity
+
+	var title string
+	switch {
+	case before > 0 && after == 0:
+		title = drug.Name + " has run out"
+	case drug.LowStock > 0 && before > drug.LowStock && after <= drug.LowStock:
+		title = drug.Name + " is running low"
+	default:
+		return nil
+	}
+
+	recipients, err := ss.DrugDB.Recipients(drug)
+	if err != nil {
+		return err
+	}
+	drugID := drug.ID
+	for _, recipient := range recipients {
+		_, err = ss.NotifDB.CreateNotification(&models.Notification{
+			UserID: recipient,
+			Kind:   models.NotificationLowStock,
+			DrugID: &drugID,
+			Title:  title,
+			Body:   fmt.Sprintf("%s left, time to restock.", models.FormatQuantity(after, drug.Unit)),
+			// Undoing and retaking the same dose does not notify twice
+			DedupKey: fmt.Sprintf("low_stock:%d:%d:%d", drug.ID, schedule.ID, scheduledAt.Unix()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// @Summary Get drug adherence
+// @Description Returns the dose history of a drug with counts of taken, skipped and missed doses.
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Drug ID"
+// @Success 200 {object} APIResponse{data=Adherence}
+// @Failure 404 {object} APIResponse "Drug not found"
+// @Router /auth/drugs/{id}/adherence [get]
+func (ss *ScheduleService) DrugAdherence(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("drug not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	if _, err := ss.DrugDB.GetUserDrug(uint(userID), id); err != nil {
+		WriteLookupError(w, err, "drug")
+		return
+	}
+
+	schedules, err := ss.DB.GetDrugSchedules(uint(userID), uint(id))
+	if err != nil {
+		log.Printf("Error fetching schedules: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	history, err := ss.DB.GetDrugDoseLogs(uint(userID), uint(id))
+	if err != nil {
+		log.Printf("Error fetching dose logs: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	adherence := Adherence{DrugID: uint(id), History: history}
+
+	// Doses dealt with, by schedule and time
+	now := time.Now().UTC()
+	resolved := map[uint]map[int64]bool{}
+	for _, entry := range history {
+		if entry.ScheduledAt.After(now) {
+			continue
+		}
+		switch entry.Status {
+		case models.DoseTaken:
+			adherence.Taken++
+		case models.DoseSkipped:
+			adherence.Skipped++
+		default:
+			continue
+		}
+		if resolved[entry.ScheduleID] == nil {
+			resolved[entry.ScheduleID] = map[int64]bool{}
+		}
+		resolved[entry.ScheduleID][entry.ScheduledAt.Unix()] = true
+	}
+
+	// Every past occurrence without a taken or skipped entry was missed
+	for _, schedule := range schedules {
+		times, err := schedule.Doses(schedule.StartsAt, now)
+		if err != nil {
+			continue
+		}
+		for _, at := range times {
+			if !resolved[schedule.ID][at.Unix()] {
+				adherence.Missed++
+			}
+		}
+	}
+
+	if total := adherence.Taken + adherence.Skipped + adherence.Missed; total > 0 {
+		adherence.Rate = float64(adherence.Taken) / float64(total)
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: adherence})
+}
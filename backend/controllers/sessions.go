@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"first_aid_companion/models"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// AuthResponse is returned on signup, login and refresh.
+// Data holds the access token to stay compatible with clients that only read it.
+type AuthResponse struct {
+	Status       int    `json:"status"`
+	Data         string `json:"data"`          // Short-lived access token
+	RefreshToken string `json:"refresh_token"` // Token to obtain a new access token via /auth/refresh
+	ExpiresIn    int    `json:"expires_in"`    // Access token lifetime in seconds
+}
+
+// RefreshRequest represents the body of a refresh request.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// SessionInfo describes a logged in device for the sessions list.
+type SessionInfo struct {
+	models.Session
+	Current bool `json:"current"` // True for the session of the request
+}
+
+// SessionService manages login sessions and refresh tokens.
+type SessionService struct {
+	DB     *models.SessionGorm // Database access object for sessions
+	UserDB *models.UserGorm    // Users, needed to issue tokens on refresh
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a refresh token.
+// Only hashes are stored, so a database leak does not expose usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken generates a random opaque token, used for refresh tokens, invitations, share links and emergency IDs.
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// clientIP returns the address of the client without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// issueTokens creates an access token for the session and wraps it with the refresh token.
+func issueTokens(user *models.User, session *models.Session, refreshToken string) (*AuthResponse, error) {
+	accessToken, err := GenerateJWT(strconv.Itoa(int(user.ID)), user.Email, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Status:       200,
+		Data:         accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(authConfig.AccessTTL.Seconds()),
+	}, nil
+}
+
+// StartSession creates a new session for the user logging in from the request's device.
+func (ss *SessionService) StartSession(r *http.Request, user *models.User) (*AuthResponse, error) {
+	refreshToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session, err := ss.DB.CreateSession(&models.Session{
+		UserID:      user.ID,
+		RefreshHash: hashToken(refreshToken),
+		UserAgent:   r.UserAgent(),
+		IP:          clientIP(r),
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(authConfig.RefreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return issueTokens(user, session, refreshToken)
+}
+
+// CheckSession returns an error if the session of the token was revoked or has expired.
+func (ss *SessionService) CheckSession(claims *Claims) error {
+	if claims.SessionID == 0 {
+		return errors.New("token has no session")
+	}
+
+	session, err := ss.DB.GetSessionByID(claims.SessionID)
+	if err != nil {
+		return errors.New("session not found")
+	}
+	if strconv.Itoa(int(session.UserID)) != claims.UserID || !session.Active() {
+		return errors.New("session revoked")
+	}
+	return nil
+}
+
+// @Summary Refresh access token
+// @Description Exchanges a refresh token for a new access token and a new refresh token.
+// @Description The old refresh token stops working, presenting it again revokes the whole session.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body RefreshRequest true "refresh token"
+// @Success 200 {object} AuthResponse
+// @Failure 401 {object} APIResponse "Invalid, expired or revoked refresh token"
+// @Router /auth/refresh [post]
+func (ss *SessionService) Refresh(w http.ResponseWriter, r *http.Request) {
+	request := &RefreshRequest{}
+	if err := ParseJSON(r, request); err != nil || request.RefreshToken == "" {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "refresh token required"})
+		return
+	}
+	hash := hashToken(request.RefreshToken)
+
+	session, err := ss.DB.GetSessionByRefreshHash(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// A rotated out token being used again means it was stolen, so the session is ended
+		if reused, err := ss.DB.GetSessionByPreviousHash(hash); err == nil {
+			log.Printf("Refresh token reuse detected for session %d, revoking it", reused.ID)
+			if err := ss.DB.RevokeSession(reused.UserID, reused.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Error revoking session in Refresh: %v", err)
+			}
+		}
+		WriteError(w, 401, "invalid refresh token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching session in Refresh: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	if !session.Active() {
+		WriteError(w, 401, "session expired or revoked")
+		return
+	}
+
+	user, err := ss.UserDB.GetUserByID(int(session.UserID))
+	if err != nil {
+		log.Printf("Error fetching user in Refresh: %v", err)
+		WriteError(w, 401, "invalid refresh token")
+		return
+	}
+
+	// Rotate the refresh token
+	refreshToken, err := newToken()
+	if err != nil {
+		log.Printf("Error generating refresh token: %v", err)
+		WriteError(w, 500, err.Error())
+		return
+	}
+	if err := ss.DB.RotateRefreshToken(session, hashToken(refreshToken), time.Now().Add(authConfig.RefreshTTL)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			WriteError(w, 401, "invalid refresh token")
+			return
+		}
+		log.Printf("Error rotating refresh token: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	response, err := issueTokens(user, session, refreshToken)
+	if err != nil {
+		log.Printf("Error generating JWT in Refresh: %v", err)
+		WriteError(w, 500, err.Error())
+		return
+	}
+
+	WriteJSON(w, 200, response)
+}
+
+// @Summary Log out
+// @Description Revokes the current session, its access and refresh tokens stop working.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse
+// @Router /auth/logout [post]
+func (ss *SessionService) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("user").(*Claims)
+	if !ok || claims == nil {
+		WriteError(w, 401, "no user in context")
+		return
+	}
+
+	userID, _ := strconv.Atoi(claims.UserID)
+	if err := ss.DB.RevokeSession(uint(userID), claims.SessionID); err != nil {
+		WriteLookupError(w, err, "session")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully logged out!")
+}
+
+// @Summary List active sessions
+// @Description Returns the devices the user is logged in on.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIResponse{data=[]SessionInfo}
+// @Router /auth/sessions [get]
+func (ss *SessionService) Sessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("user").(*Claims)
+	if !ok || claims == nil {
+		WriteError(w, 401, "no user in context")
+		return
+	}
+
+	userID, _ := strconv.Atoi(claims.UserID)
+	sessions, err := ss.DB.GetActiveUserSessions(uint(userID))
+	if err != nil {
+		log.Printf("Error fetching sessions: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	response := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionInfo{Session: session, Current: session.ID == claims.SessionID})
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: response})
+}
+
+// @Summary Revoke a session
+// @Description Logs out one of the user's devices.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse "Session not found"
+// @Router /auth/sessions/revoke/{id} [post]
+func (ss *SessionService) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("session not found"))
+		return
+	}
+
+	claims, ok := r.Context().Value("user").(*Claims)
+	if !ok || claims == nil {
+		WriteError(w, 401, "no user in context")
+		return
+	}
+
+	userID, _ := strconv.Atoi(claims.UserID)
+	if err := ss.DB.RevokeSession(uint(userID), uint(id)); err != nil {
+		WriteLookupError(w, err, "session")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200})
+	log.Println("Successfully revoked session!")
+}
@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"first_aid_companion/models"
+	"first_aid_companion/notify"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// sosTimeout limits how long an SOS request waits for the notifiers.
+const sosTimeout = 20 * time.Second
+
+// maxSOSMessage is the maximum length of the user's message in runes.
+const maxSOSMessage = 500
+
+// Limits of the alert history
+const (
+	defaultSOSAlerts = 20
+	maxSOSAlerts     = 100
+)
+
+// SOSRequest represents an SOS alert. The location is optional.
+type SOSRequest struct {
+	Message   string   `json:"message" example:"Fell down the stairs"` // Optional text for the contacts
+	Latitude  *float64 `json:"latitude" example:"55.7558"`
+	Longitude *float64 `json:"longitude" example:"37.6173"`
+	Accuracy  *float64 `json:"accuracy" example:"25"` // Radius of the location in meters
+}
+
+// SOSService sends SOS alerts to emergency contacts.
+type SOSService struct {
+	DB       *models.EmergencyGorm // Contacts and alert history
+	UserDB   *models.UserGorm      // Name of the user in the alert
+	Notifier *notify.Dispatcher    // Configured delivery channels
+}
+
+// Validate checks the message and location.
+func (req *SOSRequest) Validate() *RequestError {
+	req.Message = strings.TrimSpace(req.Message)
+	if len([]rune(req.Message)) > maxSOSMessage {
+		return NewValidationError("message", "message must be at most 500 characters")
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return NewValidationError("latitude", "latitude and longitude must be given together")
+	}
+	if req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90) {
+		return NewValidationError("latitude", "latitude must be between -90 and 90")
+	}
+	if req.Longitude != nil && (*req.Longitude < -180 || *req.Longitude > 180) {
+		return NewValidationError("longitude", "longitude must be between -180 and 180")
+	}
+	if req.Accuracy != nil && (*req.Accuracy < 0 || req.Latitude == nil) {
+		return NewValidationError("accuracy", "accuracy must be a positive radius of a given location")
+	}
+	return nil
+}
+
+// deliveries lists a pending delivery for every address of every contact.
+func deliveries(contacts []models.EmergencyContact) []models.SOSDelivery {
+	var result []models.SOSDelivery
+	for _, contact := range contacts {
+		addresses := []struct {
+			channel notify.Channel
+			address string
+		}{
+			{notify.ChannelSMS, contact.Phone},
+			{notify.ChannelEmail, contact.Email},
+			{notify.ChannelWebhook, contact.WebhookURL},
+		}
+		for _, a := range addresses {
+			if a.address == "" {
+				continue
+			}
+			id := contact.ID
+			result = append(result, models.SOSDelivery{
+				ContactID:   &id,
+				ContactName: contact.Name,
+				Channel:     string(a.channel),
+				Address:     a.address,
+				Status:      models.DeliveryPending,
+			})
+		}
+	}
+	return result
+}
+
+// dispatch sends the alert over every delivery in parallel and records the results.
+func (ss *SOSService) dispatch(ctx context.Context, alert *models.SOSAlert, message notify.Alert) {
+	var wg sync.WaitGroup
+	for i := range alert.Deliveries {
+		wg.Add(1)
+		go func(delivery *models.SOSDelivery) {
+			defer wg.Done()
+
+			err := ss.Notifier.Send(ctx, notify.Channel(delivery.Channel),
+				notify.Recipient{Name: delivery.ContactName, Address: delivery.Address}, message)
+			now := time.Now()
+			delivery.AttemptedAt = &now
+			switch {
+			case errors.Is(err, notify.ErrNotConfigured):
+				delivery.Status = models.DeliverySkipped
+				delivery.Error = err.Error()
+			case err != nil:
+				log.Printf("Error sending SOS alert %d over %s: %v", alert.ID, delivery.Channel, err)
+				delivery.Status = models.DeliveryFailed
+				delivery.Error = err.Error()
+			default:
+				delivery.Status = models.DeliverySent
+			}
+
+			if err := ss.DB.UpdateDelivery(delivery); err != nil {
+				log.Printf("Error saving SOS delivery %d: %v", delivery.ID, err)
+			}
+		}(&alert.Deliveries[i])
+	}
+	wg.Wait()
+}
+
+// @Summary Send SOS alert
+// @Description Sends an alert with the optional message and location to every emergency contact:
+// @Description SMS to the phone, email and a POST to the webhook URL, depending on what the contact has
+// @Description and which channels the server is configured with. Returns the delivery status per contact and channel.
+// @Tags emergency
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body SOSRequest true "message and location"
+// @Success 200 {object} APIResponse{data=models.SOSAlert}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Failure 422 {object} APIResponse "Invalid location or no emergency contacts"
+// @Router /auth/sos [post]
+func (ss *SOSService) SOS(w http.ResponseWriter, r *http.Request) {
+	request := &SOSRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+	user, err := ss.UserDB.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Error fetching user in SOS: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	contacts, err := ss.DB.GetUserContacts(uint(userID))
+	if err != nil {
+		log.Printf("Error fetching emergency contacts in SOS: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+	if len(contacts) == 0 {
+		WriteRequestError(w, NewValidationError("contacts", "add emergency contacts first"))
+		return
+	}
+
+	alert, err := ss.DB.CreateAlert(&models.SOSAlert{
+		UserID:     uint(userID),
+		Message:    request.Message,
+		Latitude:   request.Latitude,
+		Longitude:  request.Longitude,
+		Accuracy:   request.Accuracy,
+		CreatedAt:  time.Now(),
+		Deliveries: deliveries(contacts),
+	})
+	if err != nil {
+		log.Printf("Error creating SOS alert: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	message := notify.Alert{ID: alert.ID, UserName: user.Name, Message: alert.Message, SentAt: alert.CreatedAt}
+	if alert.Latitude != nil {
+		message.Location = &notify.Location{Latitude: *alert.Latitude, Longitude: *alert.Longitude, Accuracy: alert.Accuracy}
+	}
+
+	// Not bound to the request, the alert must go out even if the client disconnects
+	ctx, cancel := context.WithTimeout(context.Background(), sosTimeout)
+	defer cancel()
+	ss.dispatch(ctx, alert, message)
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: alert})
+	log.Printf("SOS alert %d sent to %d contacts", alert.ID, len(contacts))
+}
+
+// @Summary Get SOS alerts
+// @Description Returns the latest alerts of the user with their delivery status, newest first.
+// @Tags emergency
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum number of alerts, 20 by default, at most 100"
+// @Success 200 {object} APIResponse{data=[]models.SOSAlert}
+// @Failure 422 {object} APIResponse "Invalid limit"
+// @Router /auth/sos [get]
+func (ss *SOSService) Alerts(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSOSAlerts
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSOSAlerts {
+			WriteRequestError(w, NewValidationError("limit", "limit must be between 1 and 100"))
+			return
+		}
+		limit = n
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	alerts, err := ss.DB.GetUserAlerts(uint(userID), limit)
+	if err != nil {
+		log.Printf("Error fetching SOS alerts: %v", err)
+		WriteError(w, 500, "database error")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: alerts})
+}
+
+// @Summary Get SOS alert
+// @Tags emergency
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Alert ID"
+// @Success 200 {object} APIResponse{data=models.SOSAlert}
+// @Failure 404 {object} APIResponse "Alert not found"
+// @Router /auth/sos/{id} [get]
+func (ss *SOSService) GetAlert(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		WriteRequestError(w, NewNotFoundError("alert not found"))
+		return
+	}
+
+	userID, _, err := GetUserFromContext(r.Context(), ss.DB.DB)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		WriteError(w, 401, "database error")
+		return
+	}
+
+	alert, err := ss.DB.GetUserAlert(uint(userID), id)
+	if err != nil {
+		WriteLookupError(w, err, "alert")
+		return
+	}
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: alert})
+}
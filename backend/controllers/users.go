@@ -6,7 +6,7 @@ import (
 	"log"
 	"net/http"
 	"regexp"
-	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -23,15 +23,22 @@ type UserUpdates struct {
 	Passport    string `json:"passport"`
 	Snils       string `json:"snils"`
 	Address     string `json:"address"`
-	Allergies   string `json:"allergies"`
-	ChronicCond string `json:"chronic_cond"`
+	Allergies   string `json:"allergies"`    // Deprecated: replaces the free-text allergy, use /auth/allergies
+	ChronicCond string `json:"chronic_cond"` // Deprecated: replaces the free-text condition, use /auth/conditions
 	BloodType   string `json:"blood_type"`
 }
 
+// DeleteAccountRequest confirms account deletion with the user's password.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
 // UserService provides methods to interact with user data and related services.
 type UserService struct {
 	DB          *models.UserGorm    // Database interface for user data
 	CardService *MedicalCardService // Service to handle medical card related logic
+	Sessions    *SessionService     // Service issuing tokens for new sessions
+	Documents   *DocumentService    // Deletes the files of removed accounts
 }
 
 // Validate checks the User struct fields for basic validity.
@@ -59,12 +66,12 @@ func (u *User) Validate(withoutName, withoutEmail bool) error {
 }
 
 // @Summary Sign up a new user
-// @Description Creates a new user account
+// @Description Creates a new user account and logs it in
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param input body User true "signup body"
-// @Success 200 {object} APIResponse
+// @Success 200 {object} AuthResponse
 // @Router /signup [post]
 func (us *UserService) SignUp(w http.ResponseWriter, r *http.Request) {
 	newUser := &User{}
@@ -129,11 +136,8 @@ func (us *UserService) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert user ID to string for JWT generation
-	idAsString := strconv.Itoa(int(createdUser.ID))
-
-	// Generate JWT token for the newly created user
-	token, err := GenerateJWT(idAsString, createdUser.Email)
+	// Start a session and generate tokens for the newly created user
+	tokens, err := us.Sessions.StartSession(r, createdUser)
 	if err != nil {
 		log.Printf("Error generating JWT in SignUp: %v", err)
 		WriteError(w, 500, err.Error())
@@ -142,20 +146,17 @@ func (us *UserService) SignUp(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("User signed up successfully: %s", createdUser.Email)
 
-	// Return JWT token in the response
-	WriteJSON(w, 200, &APIResponse{
-		Status: 200,
-		Data:   token,
-	})
+	// Return tokens in the response
+	WriteJSON(w, 200, tokens)
 }
 
 // @Summary Log in a user
-// @Description Authenticates a user and returns a JWT
+// @Description Authenticates a user and returns an access token and a refresh token
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param input body User true "login body"
-// @Success 200 {object} APIResponse
+// @Success 200 {object} AuthResponse
 // @Router /login [post]
 func (us *UserService) LogIn(w http.ResponseWriter, r *http.Request) {
 	user := &User{}
@@ -189,11 +190,8 @@ func (us *UserService) LogIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert user ID to string for token generation
-	idAsString := strconv.Itoa(int(found.ID))
-
-	// Generate JWT token upon successful authentication
-	token, err := GenerateJWT(idAsString, found.Email)
+	// Start a session and generate tokens upon successful authentication
+	tokens, err := us.Sessions.StartSession(r, found)
 	if err != nil {
 		log.Printf("Error generating JWT in LogIn: %v", err)
 		WriteError(w, 500, err.Error())
@@ -202,11 +200,8 @@ func (us *UserService) LogIn(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("User logged in successfully: %s", found.Email)
 
-	// Return the JWT token in the response
-	WriteJSON(w, 200, &APIResponse{
-		Status: 200,
-		Data:   token,
-	})
+	// Return the tokens in the response
+	WriteJSON(w, 200, tokens)
 }
 
 // @Summary Get current user
@@ -218,7 +213,7 @@ func (us *UserService) LogIn(w http.ResponseWriter, r *http.Request) {
 // @Router /me [get]
 func (us *UserService) Me(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from request context (set by authentication middleware)
-	userID, userEmail, err := GetUserFromContext(r.Context(), us.DB.DB)
+	userID, _, err := GetUserFromContext(r.Context(), us.DB.DB)
 	if err != nil || userID == -1 {
 		log.Printf("Error getting user from context in Me: %v", err)
 		WriteError(w, 500, err.Error())
@@ -226,7 +221,7 @@ func (us *UserService) Me(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch user details by ID
-	user, err := us.DB.GetUserByEmail(userEmail)
+	user, err := us.DB.GetUserByID(userID)
 	if err != nil {
 		log.Printf("Error getting user by ID in Me: %v", err)
 		WriteError(w, 500, err.Error())
@@ -241,6 +236,20 @@ func (us *UserService) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fetch user's allergies and chronic conditions
+	allergies, err := us.CardService.DB.GetEntries(user.ID, models.EntryAllergy)
+	if err != nil {
+		log.Printf("Error getting allergies in Me: %v", err)
+		WriteError(w, 500, err.Error())
+		return
+	}
+	conditions, err := us.CardService.DB.GetEntries(user.ID, models.EntryCondition)
+	if err != nil {
+		log.Printf("Error getting chronic conditions in Me: %v", err)
+		WriteError(w, 500, err.Error())
+		return
+	}
+
 	// Prepare response data combining user and medical card info
 	userData := map[string]interface{}{
 		"name":               user.Name,
@@ -248,8 +257,8 @@ func (us *UserService) Me(w http.ResponseWriter, r *http.Request) {
 		"snils":              user.SNILS,
 		"passport":           user.Passport,
 		"address":            user.Address,
-		"allergies":          medCard.Allergies,
-		"chronic_conditions": medCard.ChronicCond,
+		"allergies":          allergies,
+		"chronic_conditions": conditions,
 		"blood_type":         medCard.BloodType,
 	}
 
@@ -273,7 +282,7 @@ func (us *UserService) Me(w http.ResponseWriter, r *http.Request) {
 // @Router /auth/me [post]
 func (us *UserService) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	// Get user id from request context
-	userID, userEmail, err := GetUserFromContext(r.Context(), us.DB.DB)
+	userID, _, err := GetUserFromContext(r.Context(), us.DB.DB)
 	if err != nil || userID == -1 {
 		log.Printf("Error getting user from context in Me: %v", err)
 		WriteError(w, 500, err.Error())
@@ -281,7 +290,7 @@ func (us *UserService) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user by id
-	user, err := us.DB.GetUserByEmail(userEmail)
+	user, err := us.DB.GetUserByID(userID)
 	if err != nil {
 		log.Printf("Error getting user from context in Me: %v", err)
 		WriteError(w, 500, err.Error())
@@ -323,11 +332,18 @@ func (us *UserService) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Replace non-empty fields
-	if userUpdates.Allergies != "" {
-		medCard.Allergies = userUpdates.Allergies
-	}
-	if userUpdates.ChronicCond != "" {
-		medCard.ChronicCond = userUpdates.ChronicCond
+	for kind, text := range map[string]string{
+		models.EntryAllergy:   strings.TrimSpace(userUpdates.Allergies),
+		models.EntryCondition: strings.TrimSpace(userUpdates.ChronicCond),
+	} {
+		if text == "" {
+			continue
+		}
+		if err := us.CardService.DB.ReplaceFreeText(user.ID, kind, text); err != nil {
+			log.Printf("Error updating %s entries in Me: %v", kind, err)
+			WriteError(w, 500, err.Error())
+			return
+		}
 	}
 	if userUpdates.BloodType != "" {
 		medCard.BloodType = userUpdates.BloodType
@@ -344,3 +360,55 @@ func (us *UserService) UpdateMe(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("Successfully updated user and med card")
 }
+
+// @Summary Delete account
+// @Description Permanently deletes the user with all drugs, documents, chats, messages, the medical card,
+// @Description group memberships, sessions, emergency contacts, SOS alerts and encryption keys. Requires the current password.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body DeleteAccountRequest true "password confirmation"
+// @Success 200 {object} APIResponse{data=models.DeletionSummary}
+// @Failure 400 {object} APIResponse "Invalid JSON"
+// @Failure 403 {object} APIResponse "Wrong password"
+// @Router /auth/me [delete]
+func (us *UserService) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	// Get user from request context
+	userID, userEmail, err := GetUserFromContext(r.Context(), us.DB.DB)
+	if err != nil {
+		log.Printf("Error getting user from context in DeleteMe: %v", err)
+		WriteError(w, 401, err.Error())
+		return
+	}
+
+	request := &DeleteAccountRequest{}
+	if err := ParseJSON(r, request); err != nil {
+		WriteRequestError(w, &RequestError{Status: http.StatusBadRequest, Message: "invalid JSON format"})
+		return
+	}
+
+	user, err := us.DB.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Error getting user in DeleteMe: %v", err)
+		WriteError(w, 500, err.Error())
+		return
+	}
+
+	// Deleting is irreversible, so the password has to be confirmed
+	if request.Password == "" || !CheckPasswordHash(request.Password, user.PasswordHash) {
+		WriteRequestError(w, &RequestError{Status: http.StatusForbidden, Field: "password", Message: "wrong password"})
+		return
+	}
+
+	summary, err := us.DB.DeleteUserCascade(uint(userID))
+	if err != nil {
+		log.Printf("Error deleting user in DeleteMe: %v", err)
+		WriteError(w, 500, "failed to delete account")
+		return
+	}
+	us.Documents.releaseBlobs(r.Context(), summary.BlobKeys)
+
+	WriteJSON(w, 200, &APIResponse{Status: 200, Data: summary})
+	log.Printf("User deleted: %s", userEmail)
+}
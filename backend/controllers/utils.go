@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"first_aid_companion/models"
@@ -26,17 +27,81 @@ type APIResponse struct {
 // Error represents an error message returned by the API.
 type Error struct {
 	Message string `json:"message"`
+	Field   string `json:"field,omitempty"` // Request field that caused the error, if any
+}
+
+// RequestError is an error caused by the client's request and reported with a 4xx status code.
+type RequestError struct {
+	Status  int    // HTTP status code
+	Field   string // Request field that caused the error, if any
+	Message string // Human readable description
+}
+
+// Error implements the error interface.
+func (e *RequestError) Error() string {
+	if e.Field != "" {
+		return e.Field + ": " + e.Message
+	}
+	return e.Message
+}
+
+// NewValidationError creates an error for an invalid request field.
+func NewValidationError(field, message string) *RequestError {
+	return &RequestError{Status: http.StatusUnprocessableEntity, Field: field, Message: message}
+}
+
+// NewNotFoundError creates an error for a resource that does not exist or belongs to another user.
+func NewNotFoundError(message string) *RequestError {
+	return &RequestError{Status: http.StatusNotFound, Message: message}
+}
+
+// NewForbiddenError creates an error for an action the user's role does not allow.
+func NewForbiddenError(message string) *RequestError {
+	return &RequestError{Status: http.StatusForbidden, Message: message}
 }
 
 // Claims represents the JWT claims structure including user information.
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID uint   `json:"sid"` // Session the token was issued for
 	jwt.RegisteredClaims
 }
 
-// jwtKey is the secret key used to sign JWT tokens.
-var jwtKey = "GOIDA"
+// AuthConfig configures token signing and lifetimes.
+type AuthConfig struct {
+	Secret     []byte        // Key used to sign access tokens
+	AccessTTL  time.Duration // Lifetime of access tokens
+	RefreshTTL time.Duration // Lifetime of refresh tokens, extended on every refresh
+}
+
+// authConfig is the active token configuration, set once at startup by ConfigureAuth.
+var authConfig = AuthConfig{
+	AccessTTL:  15 * time.Minute,
+	RefreshTTL: 30 * 24 * time.Hour,
+}
+
+// ConfigureAuth sets the token signing key and lifetimes. Zero lifetimes keep the defaults.
+// Without a secret a random one is generated, so tokens do not survive a restart.
+func ConfigureAuth(cfg AuthConfig) error {
+	if len(cfg.Secret) == 0 {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		log.Println("JWT secret is not configured, using a random one")
+		cfg.Secret = secret
+	}
+	if cfg.AccessTTL <= 0 {
+		cfg.AccessTTL = authConfig.AccessTTL
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = authConfig.RefreshTTL
+	}
+
+	authConfig = cfg
+	return nil
+}
 
 // ParseJSON parses the JSON payload from the request body into the given destination struct.
 // Returns an error if decoding fails. Ignores if ContentLength is 0.
@@ -70,6 +135,33 @@ func WriteError(w http.ResponseWriter, status int, message string) {
 	)
 }
 
+// WriteRequestError writes a client error as a JSON response with its status code.
+func WriteRequestError(w http.ResponseWriter, err *RequestError) {
+	WriteJSON(w, err.Status,
+		&APIResponse{
+			Status: err.Status,
+			Data:   Error{Message: err.Message, Field: err.Field},
+		},
+	)
+}
+
+// WriteLookupError reports a failed lookup of a user's resource.
+// Missing rows and rows owned by other users both result in 404, so foreign IDs cannot be probed.
+func WriteLookupError(w http.ResponseWriter, err error, resource string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		WriteRequestError(w, NewNotFoundError(resource+" not found"))
+		return
+	}
+	log.Printf("Error fetching %s: %v", resource, err)
+	WriteError(w, 500, "database error")
+}
+
+// publicLink returns the absolute URL of a public path under the configured base URL.
+// The Host header is never used, clients could make links point to another site with it.
+func publicLink(base, path string) string {
+	return strings.TrimRight(base, "/") + path
+}
+
 // HashPassword generates a bcrypt hash of the password for secure storage.
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -82,13 +174,14 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateJWT generates a signed JWT token string for a user with a 24-hour expiry.
-func GenerateJWT(userID, email string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateJWT generates a signed short-lived access token for a user's session.
+func GenerateJWT(userID, email string, sessionID uint) (string, error) {
+	expirationTime := time.Now().Add(authConfig.AccessTTL)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -99,7 +192,7 @@ func GenerateJWT(userID, email string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtKey))
+	return token.SignedString(authConfig.Secret)
 }
 
 // ParseJWT parses and validates a JWT token string, returning the Claims if valid.
@@ -107,8 +200,8 @@ func ParseJWT(tokenStr string) (*Claims, error) {
 	claims := Claims{}
 	token, err := jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
 		// Provide the secret key for verification
-		return []byte(jwtKey), nil
-	})
+		return authConfig.Secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil || !token.Valid {
 		return nil, fmt.Errorf("invalid token: %v", err)
@@ -146,13 +239,14 @@ func GetUserFromContext(ctx context.Context, db *gorm.DB) (int, string, error) {
 		return 0, "", err
 	}
 
+	// Looked up by ID, dependent profiles have no email
 	var user models.User
-	err = db.Table("users").Where("email = ?", claims.Email).First(&user).Error
+	err = db.Table("users").Where("id = ?", id).First(&user).Error
 	if err != nil {
 		return 0, "", err
 	}
 
-	return id, claims.Email, nil
+	return id, user.Email, nil
 }
 
 // Custom time to suit flutter format
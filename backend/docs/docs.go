@@ -15,32 +15,40 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/chats": {
+        "/auth/allergies": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns a list of chat IDs and titles associated with the current user.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "chats"
+                    "medical card"
                 ],
-                "summary": "Get user's chat sessions",
+                "summary": "List allergies or chronic conditions",
                 "responses": {
                     "200": {
-                        "description": "[ {title: string, id: caht_id}, ...]",
-                        "schema": {
-                            "$ref": "#/definitions/controllers.APIResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Failed to fetch user's chats",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.APIResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.MedicalEntry"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
@@ -51,23 +59,54 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new chat with a temporary title for the current user and returns the chat ID.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "chats"
+                    "medical card"
+                ],
+                "summary": "Add allergy or chronic condition",
+                "parameters": [
+                    {
+                        "description": "entry",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.MedicalEntryRequest"
+                        }
+                    }
                 ],
-                "summary": "Create a new chat",
                 "responses": {
                     "200": {
-                        "description": "Data: chatID",
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.MedicalEntry"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
                     },
-                    "500": {
-                        "description": "Server or database error",
+                    "422": {
+                        "description": "Empty entry, invalid code, severity or date",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
@@ -75,25 +114,24 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/chats/{id}": {
-            "get": {
+        "/auth/allergies/remove/{id}": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns the list of messages in a given chat, including message ID, sender, and text.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "chats"
+                    "medical card"
                 ],
-                "summary": "Get messages from a chat",
+                "summary": "Remove allergy or chronic condition",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Chat ID",
+                        "description": "Entry ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -101,13 +139,13 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "[ {id: message_id, sender: 0/1, text: message_text}, ...]",
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to fetch chat or messages",
+                    "404": {
+                        "description": "Entry not found",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
@@ -115,14 +153,13 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/documents": {
-            "get": {
+        "/auth/allergies/{id}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns all user documents in json format",
                 "consumes": [
                     "application/json"
                 ],
@@ -130,166 +167,233 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "documents"
+                    "medical card"
+                ],
+                "summary": "Change allergy or chronic condition",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "entry",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.MedicalEntryRequest"
+                        }
+                    }
                 ],
-                "summary": "Get all documents",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Document"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.MedicalEntry"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Entry not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty entry, invalid code, severity or date",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/documents/add": {
-            "post": {
+        "/auth/catalog/barcode/{gtin}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns a prefilled drug for a GTIN/EAN barcode, ready to be sent to /auth/drugs/add.\nThe expiry date is estimated from the shelf life if the catalog knows it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "documents"
+                    "catalog"
                 ],
-                "summary": "Add one document",
+                "summary": "Look up drug by barcode",
                 "parameters": [
                     {
-                        "description": "document body",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Document"
-                        }
+                        "type": "string",
+                        "description": "GTIN-8, UPC, EAN-13 or GTIN-14 barcode",
+                        "name": "gtin",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/controllers.APIResponse"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.DrugCreationRequest"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Barcode not in catalog",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid barcode",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/drugs": {
+        "/auth/catalog/search": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns all drugs in json format",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Finds catalog entries by name, tolerating typos. Best matches come first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "drugs"
+                    "catalog"
+                ],
+                "summary": "Search drug catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name or part of it",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results, 20 by default, at most 50",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all drugs",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Drug"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.CatalogDrug"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Empty query",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/drugs/add": {
-            "post": {
+        "/auth/chats": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns a list of chat IDs and titles associated with the current user.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "drugs"
-                ],
-                "summary": "Add one drug",
-                "parameters": [
-                    {
-                        "description": "login body",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controllers.DrugCreationRequest"
-                        }
-                    }
+                    "chats"
                 ],
+                "summary": "Get user's chat sessions",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "[ {title: string, id: caht_id}, ...]",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch user's chats",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/controllers.APIResponse"
-                            }
+                            "$ref": "#/definitions/controllers.APIResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/drugs/remove/{id}": {
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Creates a new chat with a temporary title for the current user and returns the chat ID.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "drugs"
-                ],
-                "summary": "Remove one drug by id",
-                "parameters": [
-                    {
-                        "description": "login body",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controllers.DrugCreationRequest"
-                        }
-                    }
+                    "chats"
                 ],
+                "summary": "Create a new chat",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Data: chatID",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Server or database error",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
@@ -297,50 +401,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/me": {
-            "post": {
+        "/auth/chats/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates user's personal details and medical card info",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the list of messages in a given chat, including message ID, sender, and text.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "chats"
                 ],
-                "summary": "Update user's profile",
+                "summary": "Get messages from a chat",
                 "parameters": [
                     {
-                        "description": "Fields to update",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controllers.UserUpdates"
-                        }
+                        "type": "integer",
+                        "description": "Chat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "[ {id: message_id, sender: 0/1, text: message_text}, ...]",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad request",
+                    "404": {
+                        "description": "Chat not found",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "Failed to fetch chat or messages",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
@@ -348,50 +447,98 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/send_message": {
+        "/auth/conditions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "medical card"
+                ],
+                "summary": "List allergies or chronic conditions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.MedicalEntry"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Stores the user's message, streams a response from the AI model, and stores the AI reply.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "text/event-stream"
+                    "application/json"
                 ],
                 "tags": [
-                    "chats"
+                    "medical card"
                 ],
-                "summary": "Send a message and receive AI response via SSE",
+                "summary": "Add allergy or chronic condition",
                 "parameters": [
                     {
-                        "description": "Chat ID and user message",
+                        "description": "entry",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controllers.MessageRequest"
+                            "$ref": "#/definitions/controllers.MedicalEntryRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "streamed AI response",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.MedicalEntry"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
-                        "description": "Invalid request body or empty message",
+                        "description": "Invalid JSON",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server or streaming error",
+                    "422": {
+                        "description": "Empty entry, invalid code, severity or date",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
@@ -399,33 +546,107 @@ const docTemplate = `{
                 }
             }
         },
-        "/login": {
+        "/auth/conditions/remove/{id}": {
             "post": {
-                "description": "Authenticates a user and returns a JWT",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "medical card"
                 ],
-                "summary": "Log in a user",
+                "summary": "Remove allergy or chronic condition",
                 "parameters": [
                     {
-                        "description": "login body",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "Entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.User"
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Entry not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/conditions/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "medical card"
+                ],
+                "summary": "Change allergy or chronic condition",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "entry",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.MedicalEntryRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.MedicalEntry"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Entry not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty entry, invalid code, severity or date",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
@@ -433,9 +654,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/me": {
+        "/auth/dependents": {
             "get": {
-                "description": "Retrieves the authenticated user's details",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the profiles the user manages: profiles they created and profiles of\ngroups where they are the owner or a caregiver. Send a profile's ID in the\nX-On-Behalf-Of header to use the other /auth endpoints for it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dependents"
+                ],
+                "summary": "Get dependent profiles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.Dependent"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a profile with an empty medical card for someone without an account.\nWith group_id the owner and caregivers of the group manage it too.",
                 "consumes": [
                     "application/json"
                 ],
@@ -443,12 +707,53 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "dependents"
+                ],
+                "summary": "Create a dependent profile",
+                "parameters": [
+                    {
+                        "description": "profile",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DependentRequest"
+                        }
+                    }
                 ],
-                "summary": "Get current user",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Dependent"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow managing profiles of the group",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name",
                         "schema": {
                             "$ref": "#/definitions/controllers.APIResponse"
                         }
@@ -456,9 +761,115 @@ const docTemplate = `{
                 }
             }
         },
-        "/signup": {
+        "/auth/dependents/remove/{id}": {
             "post": {
-                "description": "Creates a new user account",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently deletes the profile with its medical card, drugs, documents and other data.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dependents"
+                ],
+                "summary": "Remove a dependent profile",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.DeletionSummary"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Profile not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/dependents/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dependents"
+                ],
+                "summary": "Get dependent profile",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Dependent"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Profile not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Changes the name, date of birth and group of a profile.",
                 "consumes": [
                     "application/json"
                 ],
@@ -466,204 +877,5809 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "dependents"
                 ],
-                "summary": "Sign up a new user",
+                "summary": "Change a dependent profile",
                 "parameters": [
                     {
-                        "description": "signup body",
+                        "type": "integer",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "profile",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controllers.User"
+                            "$ref": "#/definitions/controllers.DependentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Dependent"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow managing profiles of the group",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Profile or group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/dependents/{id}/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns who accessed or changed the profile and when, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dependents"
+                ],
+                "summary": "Get profile audit trail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries, 50 by default, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.AuditEntry"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Profile not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
                         }
+                    },
+                    "422": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns metadata of all user documents, files are downloaded from /auth/documents/{id}/file",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Get all documents",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.Document"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/add": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a document with a base64-encoded file, /auth/documents/upload takes the file as multipart form data.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Add one document",
+                "parameters": [
+                    {
+                        "description": "document body",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DocumentUploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Document"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "File too large",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty file or name",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/remove/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Remove one document by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Document not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/shares/revoke/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stops the link from working before it expires. Revoking a revoked link does nothing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Revoke a share link",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Share link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.ShareResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Share link not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/upload": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a document from a multipart/form-data body. The file type is detected from its contents,\nPDF, JPEG, PNG, GIF, WebP and plain text files are accepted.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Upload one document",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Document file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the document, the file name by default",
+                        "name": "name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Type of the document, e.g. prescription or report",
+                        "name": "type",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date the document was issued, RFC 3339 or YYYY-MM-DD",
+                        "name": "date",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the doctor",
+                        "name": "doctor",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Document"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid form",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "File too large",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty file or invalid date",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/{id}/file": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams the file with its detected Content-Type. Range requests are supported for\nresuming downloads and paging through large PDFs.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Download document file",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Ask the browser to save the file instead of showing it",
+                        "name": "download",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File contents",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Requested range of the file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Document not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "416": {
+                        "description": "Invalid range",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/{id}/share": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a read-only link to the document for someone without an account, e.g. a doctor.\nThe link expires after expires_in_hours and can be revoked earlier. With a PIN the viewer\nhas to send it in the X-Share-PIN header, the link is revoked after 5 wrong PINs.\nThe url is only returned here, the server keeps a hash of its token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Share a document",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lifetime and PIN of the link",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.ShareResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Document not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid lifetime or PIN",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/{id}/shares": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the links of the document, newest first, with how often and when they were used.\nThe URLs are not included, they are only returned when a link is created.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Get share links of a document",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/controllers.ShareResponse"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Document not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/documents/{id}/thumbnail": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns a JPEG preview of at most 320 pixels on each side, generated in the background\nafter upload. Images are scaled down, PDFs show their first page with images and shapes in place\nand text as grey lines. Shadings, patterns and content of form XObjects are not drawn.\nText files and empty pages have no preview.\nThumbnails may be cached privately for a day and revalidated with If-None-Match.",
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Get document thumbnail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "JPEG image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "202": {
+                        "description": "The thumbnail is not generated yet, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Document not found or the file has no preview",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/doses/log": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Marks a scheduled dose as taken, skipped or snoozed. Logging the same dose again replaces its status.\nTaking a dose decrements the drug's quantity, undoing it restores the quantity.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Log a dose",
+                "parameters": [
+                    {
+                        "description": "dose status",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DoseLogRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.DoseLog"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid status or not a scheduled time",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/doses/upcoming": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists doses due in the next hours, including overdue doses of the last 12 hours, ordered by due time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Get upcoming doses",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "How many hours ahead to look, 24 by default, at most 168",
+                        "name": "hours",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/controllers.UpcomingDose"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the user's drugs. scope=group returns the shared cabinet of the user's groups instead\n(of one group with group_id), scope=all returns both.\nkit_id limits the list to one kit (\"none\" for drugs without a kit),\ngroup_by=kit returns the drugs grouped by kit with per-kit summaries instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drugs"
+                ],
+                "summary": "Get all drugs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "personal (default), group or all",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Group of the shared cabinet, requires scope=group",
+                        "name": "group_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Kit ID or none",
+                        "name": "kit_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "kit",
+                        "name": "group_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.Drug"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Kit or group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid scope",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/add": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a drug and returns warnings about the user's allergies and interactions with the other drugs in the cabinet.\nWith group_id, or when put into a group kit, the drug is shared with the group.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drugs"
+                ],
+                "summary": "Add one drug",
+                "parameters": [
+                    {
+                        "description": "login body",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DrugCreationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.DrugCreationResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Kit or group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid field value",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the latest additions, uses and removals in the cabinet, newest first.\nRemoved drugs are included. scope and group_id work as for the drugs list.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drugs"
+                ],
+                "summary": "Get cabinet history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "personal (default), group or all",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Group of the shared cabinet, requires scope=group",
+                        "name": "group_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of events, 50 by default, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.DrugEvent"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid scope or limit",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/interactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the drugs of the cabinet against each other and against the allergies in the medical card.\nThe cabinet is chosen like for the drug list, by default personal and shared drugs are checked together.\nWarnings are ordered by severity: contraindicated, major, moderate, minor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drugs"
+                ],
+                "summary": "Check drug interactions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "personal, group or all (default)",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Group of the shared cabinet, requires scope=group",
+                        "name": "group_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/interactions.Warning"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid scope",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/move": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Moves the given drugs into a kit, or takes them out of their kits if kit_id is null.\nEither all drugs are moved or none. Drugs stay in their cabinet: personal drugs go into\npersonal kits and group drugs into kits of their group. Moving group drugs requires the owner or caregiver role.\nThe location of the drugs follows the kit name and is cleared when they are taken out of their kits.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kits"
+                ],
+                "summary": "Move drugs between kits",
+                "parameters": [
+                    {
+                        "description": "drugs and target kit",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.MoveDrugsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow moving shared drugs",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Drug or kit not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "No drugs given or kit of another cabinet",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/remove/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drugs"
+                ],
+                "summary": "Remove one drug by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drug ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Drug not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates only the fields present in the body. The drug must belong to the current user or one of their groups.\nkit_id must be a kit of the drug's cabinet, moving group drugs requires the owner or caregiver role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drugs"
+                ],
+                "summary": "Update one drug by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drug ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "fields to update",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DrugUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Drug"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow moving shared drugs",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Drug not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid field value or kit of another cabinet",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/{id}/adherence": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the dose history of a drug with counts of taken, skipped and missed doses.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Get drug adherence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drug ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.Adherence"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Drug not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/drugs/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns who added, used, adjusted and removed a drug, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drugs"
+                ],
+                "summary": "Get drug history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drug ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.DrugEvent"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Drug not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/emergency": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns which fields are published on the user's emergency ID.\nThe public URL is not returned, the server only keeps a hash of its token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Get emergency ID settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.EmergencyProfileResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Emergency ID is not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Publishes the chosen fields of the user and medical card at an unguessable public URL,\ne.g. for a paramedic scanning the QR code. Enables the emergency ID on the first call,\nthe url is only returned then.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Enable or change the emergency ID",
+                "parameters": [
+                    {
+                        "description": "published fields",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EmergencyProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.EmergencyProfileResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/emergency/contacts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Get emergency contacts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.EmergencyContact"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Add emergency contact",
+                "parameters": [
+                    {
+                        "description": "contact",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EmergencyContactRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.EmergencyContact"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name, no address or invalid address",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/emergency/contacts/remove/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Remove emergency contact",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Contact ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/emergency/contacts/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Change emergency contact",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Contact ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "contact",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EmergencyContactRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.EmergencyContact"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Contact not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name, no address or invalid address",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/emergency/qr": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Renders the public URL of the emergency ID as a QR code, e.g. to print on a card.\nThe server only keeps a hash of the token, so the token from the url has to be passed.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Get emergency ID QR code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token from the public URL",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "png (default) or svg",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Width of the PNG in pixels, 64 to 1024, 256 by default",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Emergency ID is not enabled or the token is not its current one",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid format or size",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/emergency/revoke": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disables the public URL. Enabling the emergency ID again issues a new one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Revoke the emergency ID",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Emergency ID is not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/emergency/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issues a new public URL, the old URL and printed QR codes stop working.\nThe url is only returned here, the server keeps a hash of its token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Rotate the emergency ID link",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.EmergencyProfileResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Emergency ID is not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the groups (households) the user is a member of, with the user's role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Get groups",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.UserGroup"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a household, the user becomes its owner.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Create a group",
+                "parameters": [
+                    {
+                        "description": "group",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.GroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Group"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups/remove/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a group with its memberships, invitations and shared kits. Only the owner can do this.\nDependent profiles of the group stay with their creators, the owner takes over profiles whose creator deleted their account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Remove a group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns a group with its members, the owner first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Get group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.GroupDetails"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Changes the name and description of a group. Only the owner can do this.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Rename a group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new name and description",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.GroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Group"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups/{id}/invite": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Invites a user by email. The returned token has to be passed to the invited user,\nwho accepts or declines it within 7 days. Caregivers can only invite members.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Invite to a group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "invitation",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.InviteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.InvitationResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow inviting",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid email or role",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups/{id}/leave": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Leaves the group. The owner has to transfer ownership first,\nunless they are the last member, in which case the group is deleted\nand they take over its dependent profiles whose creator deleted their account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Leave a group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Owner has to transfer ownership first",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups/{id}/members/remove/{user_id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a member from the group. Only the owner can do this.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Remove a member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID of the member",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group or member not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups/{id}/members/{user_id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Makes a member a caregiver or a regular member. Only the owner can do this.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Change member role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID of the member",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new role",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.RoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group or member not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid role",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/groups/{id}/transfer": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Makes another member the owner. The previous owner becomes a caregiver.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Transfer group ownership",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new owner",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group or member not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/icd10": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Searches the bundled ICD-10 subset by code prefix (\"J45\") or by words of the\nEnglish or Russian title (\"allergic asthma\", \"астма\").",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "medical card"
+                ],
+                "summary": "Look up ICD-10 codes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Code or words of the title",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results, 20 by default, at most 50",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/icd10.Code"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Empty query",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/invitations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the pending invitations sent to the user's email.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Get invitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.InvitationInfo"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/invitations/accept": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Joins the group with the role given in the invitation.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Accept an invitation",
+                "parameters": [
+                    {
+                        "description": "invitation token",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.InvitationTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Group"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Invitation not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Already a member",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invitation expired or already answered",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/invitations/decline": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Decline an invitation",
+                "parameters": [
+                    {
+                        "description": "invitation token",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.InvitationTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Invitation not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invitation expired or already answered",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/kit_templates": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the templates kits can be checked against, e.g. the legal minimum of a car kit.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kits"
+                ],
+                "summary": "Get kit templates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/kittemplates.Template"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/kits": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the user's personal kits and kits of their groups with item count and nearest expiry.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kits"
+                ],
+                "summary": "Get kits",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/controllers.KitInfo"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a personal kit, or a shared kit of a group the user owns or is a caregiver of.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kits"
+                ],
+                "summary": "Create a kit",
+                "parameters": [
+                    {
+                        "description": "kit",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.KitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Kit"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow managing shared kits",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/kits/remove/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a kit. Its drugs are kept without a kit and without a location.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kits"
+                ],
+                "summary": "Remove a kit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Kit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow managing shared kits",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Kit not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/kits/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Changes the name and description of a kit. The location of its drugs follows the new name.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kits"
+                ],
+                "summary": "Rename a kit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Kit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new name and description",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.KitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Kit"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow managing shared kits",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Kit not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Empty name",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/kits/{id}/check": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Compares the drugs and supplies of a kit with a template and reports missing, expired and insufficient items.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kits"
+                ],
+                "summary": "Check kit against a template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Kit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Template ID, e.g. car_ru",
+                        "name": "template",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/kittemplates.Report"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Kit or template not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "No template given",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the current session, its access and refresh tokens stop working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Log out",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/me": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates user's personal details and medical card info",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user's profile",
+                "parameters": [
+                    {
+                        "description": "Fields to update",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserUpdates"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently deletes the user with all drugs, documents, chats, messages, the medical card,\ngroup memberships, sessions, emergency contacts, SOS alerts and encryption keys. Requires the current password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete account",
+                "parameters": [
+                    {
+                        "description": "password confirmation",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.DeletionSummary"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Wrong password",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the user's notifications, newest first. Unread ones have an empty read_at.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Get notifications",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only return unread notifications",
+                        "name": "unread",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.Notification"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/notifications/read/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Mark notification as read",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Notification ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Notification not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/notifications/read_all": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Mark all notifications as read",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Exchanges a refresh token for a new access token and a new refresh token.\nThe old refresh token stops working, presenting it again revokes the whole session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Refresh access token",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AuthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid, expired or revoked refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/schedules": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Get intake schedules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.IntakeSchedule"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a schedule for one of the user's drugs, e.g. every 8 hours for 5 days is \"FREQ=HOURLY;INTERVAL=8;COUNT=15\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Create intake schedule",
+                "parameters": [
+                    {
+                        "description": "schedule",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ScheduleCreationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.IntakeSchedule"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Drug not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid recurrence rule",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/schedules/remove/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the schedule together with its dose history.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Remove intake schedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Schedule not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/send_message": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stores the user's message, streams a response from the AI model, and stores the AI reply.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "chats"
+                ],
+                "summary": "Send a message and receive AI response via SSE",
+                "parameters": [
+                    {
+                        "description": "Chat ID and user message",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.MessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "streamed AI response",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or empty message",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server or streaming error",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the devices the user is logged in on.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List active sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/controllers.SessionInfo"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sessions/revoke/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Logs out one of the user's devices.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Revoke a session",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sos": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the latest alerts of the user with their delivery status, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Get SOS alerts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of alerts, 20 by default, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.SOSAlert"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sends an alert with the optional message and location to every emergency contact:\nSMS to the phone, email and a POST to the webhook URL, depending on what the contact has\nand which channels the server is configured with. Returns the delivery status per contact and channel.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Send SOS alert",
+                "parameters": [
+                    {
+                        "description": "message and location",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SOSRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.SOSAlert"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid location or no emergency contacts",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sos/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Get SOS alert",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Alert ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.SOSAlert"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Alert not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/emergency/{token}": {
+            "get": {
+                "description": "Returns the fields the user chose to publish. Does not require authentication,\nunknown, rotated and revoked links are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "emergency"
+                ],
+                "summary": "Get public emergency ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token from the public URL",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.EmergencyCard"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Emergency ID not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Authenticates a user and returns an access token and a refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Log in a user",
+                "parameters": [
+                    {
+                        "description": "login body",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AuthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me": {
+            "get": {
+                "description": "Retrieves the authenticated user's details",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/shared/{token}": {
+            "get": {
+                "description": "Read-only view of a document shared with a link, no authentication is required.\nPIN-protected links need the PIN in the X-Share-PIN header. Unknown, expired and\nrevoked links are not found.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Get shared document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token from the share link",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "PIN of the link",
+                        "name": "X-Share-PIN",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/controllers.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controllers.SharedDocument"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "PIN is required",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid PIN",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Shared document not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/shared/{token}/file": {
+            "get": {
+                "description": "Streams the file of a shared document like /auth/documents/{id}/file does.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Download shared document file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token from the share link",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "PIN of the link",
+                        "name": "X-Share-PIN",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Ask the browser to save the file instead of showing it",
+                        "name": "download",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File contents",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Requested range of the file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "PIN is required",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid PIN",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Shared document not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/signup": {
+            "post": {
+                "description": "Creates a new user account and logs it in",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Sign up a new user",
+                "parameters": [
+                    {
+                        "description": "signup body",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AuthResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.APIResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.Adherence": {
+            "type": "object",
+            "properties": {
+                "drug_id": {
+                    "type": "integer"
+                },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DoseLog"
+                    }
+                },
+                "missed": {
+                    "description": "Past doses without a taken or skipped entry",
+                    "type": "integer"
+                },
+                "rate": {
+                    "description": "Share of past doses that were taken, 0..1",
+                    "type": "number"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "taken": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Short-lived access token",
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "Access token lifetime in seconds",
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "description": "Token to obtain a new access token via /auth/refresh",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.DeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.DependentRequest": {
+            "type": "object",
+            "properties": {
+                "birth_date": {
+                    "description": "Optional date of birth",
+                    "type": "string",
+                    "example": "1950-03-01T00:00:00Z"
+                },
+                "group_id": {
+                    "description": "Household whose owner and caregivers also manage the profile",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the profile",
+                    "type": "string",
+                    "example": "Grandma"
+                }
+            }
+        },
+        "controllers.DocumentUploadRequest": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Date the document was created or issued",
+                    "type": "string",
+                    "example": "2025-07-12T23:45:00Z"
+                },
+                "doctor": {
+                    "description": "Name of the doctor associated with the document",
+                    "type": "string"
+                },
+                "file_data": {
+                    "description": "File contents (binary), base64-encoded when serialized to JSON",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "file_name": {
+                    "description": "Name of the file, used for downloads",
+                    "type": "string",
+                    "example": "blood_test.pdf"
+                },
+                "name": {
+                    "description": "Name/title of the document, the file name by default",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Type/category of document (e.g. prescription, report)",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.DoseLogRequest": {
+            "type": "object",
+            "properties": {
+                "schedule_id": {
+                    "description": "Schedule of the dose",
+                    "type": "integer"
+                },
+                "scheduled_at": {
+                    "description": "Occurrence being logged",
+                    "type": "string",
+                    "example": "2025-07-12T16:00:00Z"
+                },
+                "snooze_minutes": {
+                    "description": "Delay for snoozed doses, 15 if omitted",
+                    "type": "integer",
+                    "example": 15
+                },
+                "status": {
+                    "description": "taken, skipped or snoozed",
+                    "type": "string",
+                    "example": "taken"
+                }
+            }
+        },
+        "controllers.DrugCreationRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Quantity of the drug available",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description or purpose of the drug",
+                    "type": "string"
+                },
+                "dose": {
+                    "description": "Dosage information",
+                    "type": "string"
+                },
+                "expiry": {
+                    "description": "Expiry date of the drug",
+                    "type": "string",
+                    "example": "2025-07-12T23:45:00Z"
+                },
+                "group_id": {
+                    "description": "Group to share the drug with, personal if omitted",
+                    "type": "integer"
+                },
+                "kit_id": {
+                    "description": "Kit to put the drug into, none if omitted",
+                    "type": "integer"
+                },
+                "location": {
+                    "description": "Storage location of the drug",
+                    "type": "string"
+                },
+                "low_stock": {
+                    "description": "Restock notification threshold, 0 disables it",
+                    "type": "number",
+                    "example": 5
+                },
+                "manufacturer": {
+                    "description": "Manufacturer of the drug",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the drug",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Structured quantity, parsed from amount if omitted",
+                    "type": "number",
+                    "example": 30
+                },
+                "type": {
+                    "description": "Type or category of the drug",
+                    "type": "string"
+                },
+                "unit": {
+                    "description": "Unit of the quantity",
+                    "type": "string",
+                    "example": "tablets"
+                }
+            }
+        },
+        "controllers.DrugCreationResponse": {
+            "type": "object",
+            "properties": {
+                "drug": {
+                    "$ref": "#/definitions/models.Drug"
+                },
+                "warnings": {
+                    "description": "Allergies and interactions with the user's other drugs",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/interactions.Warning"
+                    }
+                }
+            }
+        },
+        "controllers.DrugUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Quantity of the drug available",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description or purpose of the drug",
+                    "type": "string"
+                },
+                "dose": {
+                    "description": "Dosage information",
+                    "type": "string"
+                },
+                "expiry": {
+                    "description": "Expiry date of the drug",
+                    "type": "string",
+                    "example": "2025-07-12T23:45:00Z"
+                },
+                "kit_id": {
+                    "description": "Kit to move the drug into, use /auth/drugs/move to take it out",
+                    "type": "integer"
+                },
+                "location": {
+                    "description": "Storage location of the drug",
+                    "type": "string"
+                },
+                "low_stock": {
+                    "description": "Restock notification threshold, 0 disables it",
+                    "type": "number",
+                    "example": 5
+                },
+                "manufacturer": {
+                    "description": "Manufacturer of the drug",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the drug",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Structured quantity",
+                    "type": "number",
+                    "example": 30
+                },
+                "type": {
+                    "description": "Type or category of the drug",
+                    "type": "string"
+                },
+                "unit": {
+                    "description": "Unit of the quantity",
+                    "type": "string",
+                    "example": "tablets"
+                }
+            }
+        },
+        "controllers.EmergencyContactRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Email address for alerts",
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "name": {
+                    "description": "Name of the contact",
+                    "type": "string",
+                    "example": "Anna Ivanova"
+                },
+                "phone": {
+                    "description": "Phone number for SMS alerts",
+                    "type": "string",
+                    "example": "+79001234567"
+                },
+                "relation": {
+                    "description": "Optional relation to the user",
+                    "type": "string",
+                    "example": "daughter"
+                },
+                "webhook_url": {
+                    "description": "URL alerts are posted to",
+                    "type": "string",
+                    "example": "https://example.com/hooks/sos"
+                }
+            }
+        },
+        "controllers.EmergencyProfileRequest": {
+            "type": "object",
+            "properties": {
+                "show_allergies": {
+                    "type": "boolean"
+                },
+                "show_blood_type": {
+                    "type": "boolean"
+                },
+                "show_chronic_conditions": {
+                    "type": "boolean"
+                },
+                "show_contacts": {
+                    "type": "boolean"
+                },
+                "show_name": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controllers.EmergencyProfileResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "description": "When the current token was issued",
+                    "type": "string"
+                },
+                "show_allergies": {
+                    "type": "boolean"
+                },
+                "show_blood_type": {
+                    "type": "boolean"
+                },
+                "show_chronic_conditions": {
+                    "type": "boolean"
+                },
+                "show_contacts": {
+                    "type": "boolean"
+                },
+                "show_name": {
+                    "type": "boolean"
+                },
+                "url": {
+                    "description": "Public URL encoded in the QR code, only returned when it is issued",
+                    "type": "string",
+                    "example": "https://example.com/emergency/3q2-7w..."
+                }
+            }
+        },
+        "controllers.GroupDetails": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "When the group was created",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Optional description of the group's purpose",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier for the group",
+                    "type": "integer"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.GroupMemberInfo"
+                    }
+                },
+                "name": {
+                    "description": "Name of the group",
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.GroupRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "Optional description",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the group",
+                    "type": "string",
+                    "example": "Smith household"
+                }
+            }
+        },
+        "controllers.InvitationResponse": {
+            "type": "object",
+            "properties": {
+                "accepted_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the invitation was sent",
+                    "type": "string"
+                },
+                "declined_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "description": "Email of the invited user",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "The invitation can't be accepted after this time",
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Group the user is invited to",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier of the invitation",
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "description": "Member who sent the invitation",
+                    "type": "integer"
+                },
+                "role": {
+                    "description": "Role the user gets on accepting",
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.InvitationTokenRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Token received with the invitation",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.InviteRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Email of the invited user",
+                    "type": "string",
+                    "example": "grandma@example.com"
+                },
+                "role": {
+                    "description": "caregiver or member, member by default",
+                    "type": "string",
+                    "example": "member"
+                }
+            }
+        },
+        "controllers.KitInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "When the kit was created",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Optional description",
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Owner of a group kit, null for personal kits",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier of the kit",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the kit",
+                    "type": "string"
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.KitSummary"
+                },
+                "user_id": {
+                    "description": "Owner of a personal kit, null for group kits",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.KitRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "Optional description",
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Group to create a shared kit for, personal kit if omitted",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the kit",
+                    "type": "string",
+                    "example": "Car kit"
+                }
+            }
+        },
+        "controllers.MedicalEntryRequest": {
+            "type": "object",
+            "properties": {
+                "icd10_code": {
+                    "description": "ICD-10 code, e.g. from /auth/icd10",
+                    "type": "string",
+                    "example": "Z88.0"
+                },
+                "onset_date": {
+                    "description": "YYYY-MM-DD, optional",
+                    "type": "string",
+                    "example": "2015-06-01"
+                },
+                "reaction": {
+                    "description": "Reaction to the allergen",
+                    "type": "string",
+                    "example": "anaphylaxis"
+                },
+                "severity": {
+                    "description": "mild, moderate, severe or life_threatening",
+                    "type": "string",
+                    "example": "life_threatening"
+                },
+                "substance": {
+                    "description": "Allergen, for allergies",
+                    "type": "string",
+                    "example": "amoxicillin"
+                },
+                "text": {
+                    "description": "Name or free-text description",
+                    "type": "string",
+                    "example": "Penicillin allergy"
+                }
+            }
+        },
+        "controllers.MessageRequest": {
+            "type": "object",
+            "properties": {
+                "chat_id": {
+                    "description": "ID of the chat session",
+                    "type": "integer"
+                },
+                "text": {
+                    "description": "Text content of the message sent by user",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.MoveDrugsRequest": {
+            "type": "object",
+            "properties": {
+                "drug_ids": {
+                    "description": "Drugs to move",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "kit_id": {
+                    "description": "Target kit, null takes the drugs out of their kits",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.RoleRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "description": "caregiver or member",
+                    "type": "string",
+                    "example": "caregiver"
+                }
+            }
+        },
+        "controllers.SOSRequest": {
+            "type": "object",
+            "properties": {
+                "accuracy": {
+                    "description": "Radius of the location in meters",
+                    "type": "number",
+                    "example": 25
+                },
+                "latitude": {
+                    "type": "number",
+                    "example": 55.7558
+                },
+                "longitude": {
+                    "type": "number",
+                    "example": 37.6173
+                },
+                "message": {
+                    "description": "Optional text for the contacts",
+                    "type": "string",
+                    "example": "Fell down the stairs"
+                }
+            }
+        },
+        "controllers.ScheduleCreationRequest": {
+            "type": "object",
+            "properties": {
+                "drug_id": {
+                    "description": "Drug to take",
+                    "type": "integer"
+                },
+                "note": {
+                    "description": "Instructions, e.g. \"after meals\"",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Units per dose, 1 if omitted",
+                    "type": "number",
+                    "example": 1
+                },
+                "rrule": {
+                    "description": "Recurrence rule (FREQ, INTERVAL, COUNT, UNTIL, BYHOUR, BYDAY)",
+                    "type": "string",
+                    "example": "FREQ=HOURLY;INTERVAL=8;COUNT=15"
+                },
+                "starts_at": {
+                    "description": "First dose",
+                    "type": "string",
+                    "example": "2025-07-12T08:00:00Z"
+                }
+            }
+        },
+        "controllers.SessionInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Login time",
+                    "type": "string"
+                },
+                "current": {
+                    "description": "True for the session of the request",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "description": "Refresh token expiry",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the session",
+                    "type": "integer"
+                },
+                "ip": {
+                    "description": "Address the session was created from",
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "description": "Last time the refresh token was used",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "Set when the user logs out or revokes the device",
+                    "type": "string"
+                },
+                "user_agent": {
+                    "description": "Client that created the session",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.ShareRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in_hours": {
+                    "description": "Lifetime of the link, 24 hours by default, 720 at most",
+                    "type": "integer",
+                    "example": 72
+                },
+                "pin": {
+                    "description": "Optional PIN of 4 to 8 digits the viewer has to enter",
+                    "type": "string",
+                    "example": "4821"
+                }
+            }
+        },
+        "controllers.ShareResponse": {
+            "type": "object",
+            "properties": {
+                "access_count": {
+                    "description": "Number of times the document was opened or downloaded",
+                    "type": "integer",
+                    "example": 2
+                },
+                "active": {
+                    "description": "Whether the link still works",
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "document_id": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "description": "The link stops working at this time",
+                    "type": "string",
+                    "example": "2025-07-15T12:00:00Z"
+                },
+                "failed_pin_attempts": {
+                    "description": "Wrong PINs entered",
+                    "type": "integer"
+                },
+                "first_accessed_at": {
+                    "description": "When the link was first used",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_accessed_at": {
+                    "description": "When the link was last used",
+                    "type": "string"
+                },
+                "pin_protected": {
+                    "type": "boolean"
+                },
+                "revoked_at": {
+                    "description": "When the link was revoked, by the user or after too many wrong PINs",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "Link to send to the viewer, only returned when it is created",
+                    "type": "string",
+                    "example": "https://example.com/shared/q3X9..."
+                }
+            }
+        },
+        "controllers.SharedDocument": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string",
+                    "example": "application/pdf"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "doctor": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "When the link stops working",
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "blood_test.pdf"
+                },
+                "file_url": {
+                    "description": "Where the file is downloaded from",
+                    "type": "string",
+                    "example": "https://example.com/shared/q3X9.../file"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "pin_protected": {
+                    "type": "boolean"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 48213
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.TransferRequest": {
+            "type": "object",
+            "properties": {
+                "user_id": {
+                    "description": "Member who becomes the owner",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.UpcomingDose": {
+            "type": "object",
+            "properties": {
+                "drug_id": {
+                    "type": "integer"
+                },
+                "drug_name": {
+                    "type": "string"
+                },
+                "due_at": {
+                    "description": "When to take it, later than scheduled_at for snoozed doses",
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "schedule_id": {
+                    "type": "integer"
+                },
+                "scheduled_at": {
+                    "description": "Occurrence of the schedule, identifies the dose",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, snoozed, taken or skipped",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.User": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "Aspirin@asperinovish.ru"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Aspirin"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "Aspirin"
+                }
+            }
+        },
+        "controllers.UserUpdates": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "allergies": {
+                    "description": "Deprecated: replaces the free-text allergy, use /auth/allergies",
+                    "type": "string"
+                },
+                "blood_type": {
+                    "type": "string"
+                },
+                "chronic_cond": {
+                    "description": "Deprecated: replaces the free-text condition, use /auth/conditions",
+                    "type": "string"
+                },
+                "passport": {
+                    "type": "string"
+                },
+                "snils": {
+                    "type": "string"
+                }
+            }
+        },
+        "icd10.Code": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "J45.0"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Predominantly allergic asthma"
+                },
+                "title_ru": {
+                    "type": "string",
+                    "example": "Астма с преобладанием аллергического компонента"
+                }
+            }
+        },
+        "interactions.Warning": {
+            "type": "object",
+            "properties": {
+                "drug_ids": {
+                    "description": "Drugs the warning is about",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "drugs": {
+                    "description": "Names of these drugs",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "kind": {
+                    "description": "allergy, cross_reaction, interaction or duplicate",
+                    "type": "string",
+                    "example": "interaction"
+                },
+                "message": {
+                    "description": "Explanation for the user",
+                    "type": "string"
+                },
+                "rule": {
+                    "description": "Rule of the dataset that triggered the warning",
+                    "type": "string",
+                    "example": "interaction:anticoagulants+nsaids"
+                },
+                "severity": {
+                    "description": "contraindicated, major, moderate or minor",
+                    "type": "string",
+                    "example": "major"
+                }
+            }
+        },
+        "kittemplates.Item": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "description": "Names the item is recognised by in the kit",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "no_expiry": {
+                    "description": "Items such as scissors never expire",
+                    "type": "boolean"
+                },
+                "quantity": {
+                    "description": "Required amount",
+                    "type": "number"
+                },
+                "unit": {
+                    "description": "Unit of the amount, see models.Units",
+                    "type": "string"
+                }
+            }
+        },
+        "kittemplates.ItemReport": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "Unexpired amount in the kit",
+                    "type": "number"
+                },
+                "drug_ids": {
+                    "description": "Unexpired drugs counted for the item",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "expired_drug_ids": {
+                    "description": "Expired drugs that should be replaced",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "item_id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "number"
+                },
+                "status": {
+                    "description": "ok, missing, expired or insufficient",
+                    "type": "string",
+                    "example": "insufficient"
+                },
+                "unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "kittemplates.Report": {
+            "type": "object",
+            "properties": {
+                "complete": {
+                    "description": "True if every item is ok",
+                    "type": "boolean"
+                },
+                "expired": {
+                    "description": "IDs of items with only expired drugs",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "extra": {
+                    "description": "Drugs of the kit not required by the template",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "insufficient": {
+                    "description": "IDs of items with too few unexpired drugs",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/kittemplates.ItemReport"
+                    }
+                },
+                "missing": {
+                    "description": "IDs of missing items",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "template_id": {
+                    "type": "string"
+                },
+                "template_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "kittemplates.Template": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/kittemplates.Item"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "description": "Caregiver who made the request, null if the account was deleted",
+                    "type": "integer"
+                },
+                "actor_name": {
+                    "description": "Name of the caregiver",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "GET"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "/auth/drugs"
+                },
+                "profile_id": {
+                    "description": "Profile that was accessed",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "models.CatalogDrug": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Pack size as free text",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description or purpose of the drug",
+                    "type": "string"
+                },
+                "dose": {
+                    "description": "Dosage information",
+                    "type": "string"
+                },
+                "gtin": {
+                    "description": "Barcode as 14-digit GTIN, null if unknown",
+                    "type": "string",
+                    "example": "04601234567893"
+                },
+                "id": {
+                    "description": "Unique identifier of the entry",
+                    "type": "integer"
+                },
+                "manufacturer": {
+                    "description": "Manufacturer of the drug",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Trade name",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Pack size, null if unknown",
+                    "type": "number"
+                },
+                "shelf_life_months": {
+                    "description": "Shelf life from production, 0 if unknown",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "Type or category of the drug",
+                    "type": "string"
+                },
+                "unit": {
+                    "description": "Unit of the pack size",
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeletionSummary": {
+            "type": "object",
+            "properties": {
+                "chats": {
+                    "type": "integer"
+                },
+                "data_keys": {
+                    "description": "Keys of the encrypted data, nothing deleted can be decrypted anymore",
+                    "type": "integer"
+                },
+                "dependents": {
+                    "description": "Profiles no one else could manage, their data is counted above",
+                    "type": "integer"
+                },
+                "documents": {
+                    "type": "integer"
+                },
+                "dose_logs": {
+                    "type": "integer"
+                },
+                "drug_events": {
+                    "type": "integer"
+                },
+                "drugs": {
+                    "type": "integer"
+                },
+                "emergency_contacts": {
+                    "type": "integer"
+                },
+                "emergency_profiles": {
+                    "type": "integer"
+                },
+                "group_memberships": {
+                    "type": "integer"
+                },
+                "groups": {
+                    "description": "Groups deleted because the user was their last member",
+                    "type": "integer"
+                },
+                "intake_schedules": {
+                    "type": "integer"
+                },
+                "kits": {
+                    "type": "integer"
+                },
+                "medical_cards": {
+                    "type": "integer"
+                },
+                "medical_entries": {
+                    "description": "Allergies and chronic conditions",
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "integer"
+                },
+                "notifications": {
+                    "type": "integer"
+                },
+                "profile_audit": {
+                    "description": "Requests made on behalf of the profile",
+                    "type": "integer"
+                },
+                "sessions": {
+                    "type": "integer"
+                },
+                "sos_alerts": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Dependent": {
+            "type": "object",
+            "properties": {
+                "birth_date": {
+                    "description": "Optional date of birth",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the profile was created",
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "Caregiver who created the profile",
+                    "type": "integer"
+                },
+                "group_id": {
+                    "description": "Household whose owner and caregivers also manage the profile",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "User ID of the profile, sent in X-On-Behalf-Of",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the profile, read from the users table",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Document": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "MIME type detected from the contents",
+                    "type": "string",
+                    "example": "application/pdf"
+                },
+                "created_at": {
+                    "description": "When the document was uploaded",
+                    "type": "string",
+                    "example": "2025-07-12T23:45:00Z"
+                },
+                "date": {
+                    "description": "Date the document was created or issued",
+                    "type": "string",
+                    "example": "2025-07-12T23:45:00Z"
+                },
+                "doctor": {
+                    "description": "Name of the doctor associated with the document",
+                    "type": "string"
+                },
+                "file_name": {
+                    "description": "Name of the uploaded file",
+                    "type": "string",
+                    "example": "blood_test.pdf"
+                },
+                "id": {
+                    "description": "Unique document ID (hidden from JSON)",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name/title of the document",
+                    "type": "string"
+                },
+                "sha256": {
+                    "description": "Hash of the uploaded file",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "File size in bytes",
+                    "type": "integer",
+                    "example": 48213
+                },
+                "type": {
+                    "description": "Type/category of document (e.g. prescription, report)",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "ID of the user the document belongs to (hidden from JSON)",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DoseLog": {
+            "type": "object",
+            "properties": {
+                "acted_at": {
+                    "description": "When the user logged the status",
+                    "type": "string"
+                },
+                "drug_id": {
+                    "description": "Drug of the schedule",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier of the log entry",
+                    "type": "integer"
+                },
+                "schedule_id": {
+                    "description": "Schedule the dose belongs to",
+                    "type": "integer"
+                },
+                "scheduled_at": {
+                    "description": "Occurrence of the schedule",
+                    "type": "string"
+                },
+                "snoozed_until": {
+                    "description": "When a snoozed dose is due again",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "taken, skipped or snoozed",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Drug": {
+            "type": "object",
+            "properties": {
+                "added_by": {
+                    "description": "User who added the drug",
+                    "type": "integer"
+                },
+                "amount": {
+                    "description": "Quantity of the drug available as free text",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description or purpose of the drug",
+                    "type": "string"
+                },
+                "dose": {
+                    "description": "Dosage information",
+                    "type": "string"
+                },
+                "expiry": {
+                    "description": "Expiry date of the drug",
+                    "type": "string",
+                    "example": "2025-07-12T23:45:00Z"
+                },
+                "group_id": {
+                    "description": "Group sharing the drug, null for personal drugs",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier for the drug (hidden from JSON)",
+                    "type": "integer"
+                },
+                "kit_id": {
+                    "description": "Kit the drug is kept in, null if none",
+                    "type": "integer"
+                },
+                "location": {
+                    "description": "Storage location of the drug",
+                    "type": "string"
+                },
+                "low_stock": {
+                    "description": "Restock notification threshold, 0 disables it",
+                    "type": "number",
+                    "example": 5
+                },
+                "manufacturer": {
+                    "description": "Manufacturer of the drug",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the drug",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Structured quantity, null if unknown",
+                    "type": "number",
+                    "example": 30
+                },
+                "type": {
+                    "description": "Type or category of the drug",
+                    "type": "string"
+                },
+                "unit": {
+                    "description": "Unit of the quantity, see Units",
+                    "type": "string",
+                    "example": "tablets"
+                },
+                "user_id": {
+                    "description": "Owner of a personal drug, null for group drugs",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DrugEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "added, used, returned, adjusted or removed",
+                    "type": "string",
+                    "example": "used"
+                },
+                "created_at": {
+                    "description": "When it happened",
+                    "type": "string"
+                },
+                "delta": {
+                    "description": "Amount added, used, adjusted or removed, null if unknown",
+                    "type": "number"
+                },
+                "drug_id": {
+                    "type": "integer"
+                },
+                "drug_name": {
+                    "description": "Name of the drug at the time of the event",
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Group of a shared drug, null for personal drugs",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "unit": {
+                    "description": "Unit of the amount",
+                    "type": "string",
+                    "example": "tablets"
+                },
+                "user_id": {
+                    "description": "Who did it, null if the account was deleted",
+                    "type": "integer"
+                },
+                "user_name": {
+                    "description": "Name of the user, read from the users table",
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmergencyCard": {
+            "type": "object",
+            "properties": {
+                "allergies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MedicalEntry"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/controllers.APIResponse"
-                        }
+                },
+                "blood_type": {
+                    "type": "string"
+                },
+                "chronic_conditions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MedicalEntry"
+                    }
+                },
+                "contacts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PublicContact"
                     }
+                },
+                "name": {
+                    "type": "string"
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.APIResponse": {
+        },
+        "models.EmergencyContact": {
             "type": "object",
             "properties": {
-                "data": {},
-                "status": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "id": {
                     "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Anna Ivanova"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+79001234567"
+                },
+                "relation": {
+                    "type": "string",
+                    "example": "daughter"
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/sos"
                 }
             }
         },
-        "controllers.DrugCreationRequest": {
+        "models.Group": {
             "type": "object",
             "properties": {
-                "amount": {
-                    "description": "Quantity of the drug available",
+                "created_at": {
+                    "description": "When the group was created",
                     "type": "string"
                 },
                 "description": {
-                    "description": "Description or purpose of the drug",
+                    "description": "Optional description of the group's purpose",
                     "type": "string"
                 },
-                "dose": {
-                    "description": "Dosage information",
+                "id": {
+                    "description": "Unique identifier for the group",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the group",
+                    "type": "string"
+                }
+            }
+        },
+        "models.GroupMemberInfo": {
+            "type": "object",
+            "properties": {
+                "email": {
                     "type": "string"
                 },
-                "expiry": {
-                    "description": "Expiry date of the drug",
+                "group_id": {
+                    "type": "integer"
+                },
+                "joined_at": {
+                    "description": "When the user joined the group",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "owner, caregiver or member",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.IntakeSchedule": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Inactive schedules produce no doses",
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "description": "When the schedule was created",
+                    "type": "string"
+                },
+                "drug_id": {
+                    "description": "Drug to take",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier of the schedule",
+                    "type": "integer"
+                },
+                "note": {
+                    "description": "Instructions, e.g. \"after meals\"",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Units taken per dose, e.g. 1 tablet",
+                    "type": "number",
+                    "example": 1
+                },
+                "rrule": {
+                    "description": "Recurrence rule, see Recurrence",
                     "type": "string",
-                    "example": "2025-07-12T23:45:00Z"
+                    "example": "FREQ=HOURLY;INTERVAL=8;COUNT=15"
                 },
-                "location": {
-                    "description": "Storage location of the drug",
+                "starts_at": {
+                    "description": "First dose",
+                    "type": "string",
+                    "example": "2025-07-12T08:00:00Z"
+                }
+            }
+        },
+        "models.InvitationInfo": {
+            "type": "object",
+            "properties": {
+                "accepted_at": {
                     "type": "string"
                 },
-                "manufacturer": {
-                    "description": "Manufacturer of the drug",
+                "created_at": {
+                    "description": "When the invitation was sent",
                     "type": "string"
                 },
-                "name": {
-                    "description": "Name of the drug",
+                "declined_at": {
                     "type": "string"
                 },
-                "type": {
-                    "description": "Type or category of the drug",
+                "email": {
+                    "description": "Email of the invited user",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "The invitation can't be accepted after this time",
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Group the user is invited to",
+                    "type": "integer"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the invitation",
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "description": "Member who sent the invitation",
+                    "type": "integer"
+                },
+                "role": {
+                    "description": "Role the user gets on accepting",
                     "type": "string"
                 }
             }
         },
-        "controllers.MessageRequest": {
+        "models.Kit": {
             "type": "object",
             "properties": {
-                "chat_id": {
-                    "description": "ID of the chat session",
+                "created_at": {
+                    "description": "When the kit was created",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Optional description",
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Owner of a group kit, null for personal kits",
                     "type": "integer"
                 },
-                "text": {
-                    "description": "Text content of the message sent by user",
+                "id": {
+                    "description": "Unique identifier of the kit",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the kit",
                     "type": "string"
+                },
+                "user_id": {
+                    "description": "Owner of a personal kit, null for group kits",
+                    "type": "integer"
                 }
             }
         },
-        "controllers.User": {
+        "models.KitSummary": {
             "type": "object",
             "properties": {
-                "email": {
+                "expired_count": {
+                    "description": "Number of expired drugs",
+                    "type": "integer"
+                },
+                "item_count": {
+                    "description": "Number of drugs in the kit",
+                    "type": "integer"
+                },
+                "kit_id": {
+                    "type": "integer"
+                },
+                "nearest_expiry": {
+                    "description": "Earliest expiry date, null for kits without dated drugs",
+                    "type": "string"
+                }
+            }
+        },
+        "models.MedicalEntry": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "icd10_code": {
+                    "description": "Optional ICD-10 code",
                     "type": "string",
-                    "example": "Aspirin@asperinovish.ru"
+                    "example": "Z88.0"
                 },
-                "name": {
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "allergy or condition",
                     "type": "string",
-                    "example": "Aspirin"
+                    "example": "allergy"
                 },
-                "password": {
+                "onset_date": {
+                    "description": "When it started, if known",
+                    "type": "string"
+                },
+                "reaction": {
+                    "description": "Reaction to the allergen",
                     "type": "string",
-                    "example": "Aspirin"
+                    "example": "anaphylaxis"
+                },
+                "severity": {
+                    "description": "mild, moderate, severe or life_threatening",
+                    "type": "string",
+                    "example": "life_threatening"
+                },
+                "substance": {
+                    "description": "Allergen, for allergies",
+                    "type": "string",
+                    "example": "amoxicillin"
+                },
+                "text": {
+                    "description": "Name or free-text description",
+                    "type": "string",
+                    "example": "Penicillin allergy"
                 }
             }
         },
-        "controllers.UserUpdates": {
+        "models.Notification": {
             "type": "object",
             "properties": {
-                "address": {
+                "body": {
+                    "description": "Details",
                     "type": "string"
                 },
-                "allergies": {
+                "created_at": {
+                    "description": "When the notification was produced",
                     "type": "string"
                 },
-                "blood_type": {
+                "drug_id": {
+                    "description": "Related drug, if any",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier of the notification",
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "What the notification is about, e.g. drug_expiry",
                     "type": "string"
                 },
-                "chronic_cond": {
+                "read_at": {
+                    "description": "When the user read it, null while unread",
                     "type": "string"
                 },
-                "passport": {
+                "title": {
+                    "description": "Short summary",
+                    "type": "string"
+                }
+            }
+        },
+        "models.PublicContact": {
+            "type": "object",
+            "properties": {
+                "name": {
                     "type": "string"
                 },
-                "snils": {
+                "phone": {
+                    "type": "string"
+                },
+                "relation": {
                     "type": "string"
                 }
             }
         },
-        "models.Document": {
+        "models.SOSAlert": {
             "type": "object",
             "properties": {
-                "date": {
-                    "description": "Date the document was created or issued",
-                    "type": "string",
-                    "example": "2025-07-12T23:45:00Z"
+                "accuracy": {
+                    "description": "Radius of the location in meters",
+                    "type": "number"
                 },
-                "doctor": {
-                    "description": "Name of the doctor associated with the document",
+                "created_at": {
                     "type": "string"
                 },
-                "file_data": {
-                    "description": "File contents (binary), base64-encoded when serialized to JSON",
+                "deliveries": {
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "$ref": "#/definitions/models.SOSDelivery"
                     }
                 },
-                "name": {
-                    "description": "Name/title of the document",
-                    "type": "string"
+                "id": {
+                    "type": "integer"
                 },
-                "type": {
-                    "description": "Type/category of document (e.g. prescription, report)",
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "message": {
                     "type": "string"
                 }
             }
         },
-        "models.Drug": {
+        "models.SOSDelivery": {
             "type": "object",
             "properties": {
-                "amount": {
-                    "description": "Quantity of the drug available",
-                    "type": "string"
+                "address": {
+                    "type": "string",
+                    "example": "+79001234567"
                 },
-                "description": {
-                    "description": "Description or purpose of the drug",
-                    "type": "string"
+                "alert_id": {
+                    "type": "integer"
                 },
-                "dose": {
-                    "description": "Dosage information",
+                "attempted_at": {
                     "type": "string"
                 },
-                "expiry": {
-                    "description": "Expiry date of the drug",
+                "channel": {
                     "type": "string",
-                    "example": "2025-07-12T23:45:00Z"
+                    "example": "sms"
                 },
-                "id": {
-                    "description": "Unique identifier for the drug (hidden from JSON)",
+                "contact_id": {
+                    "description": "Null if the contact was removed since",
                     "type": "integer"
                 },
-                "location": {
-                    "description": "Storage location of the drug",
+                "contact_name": {
+                    "description": "Name of the contact when the alert was sent",
                     "type": "string"
                 },
-                "manufacturer": {
-                    "description": "Manufacturer of the drug",
+                "error": {
                     "type": "string"
                 },
-                "name": {
-                    "description": "Name of the drug",
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "sent"
+                }
+            }
+        },
+        "models.UserGroup": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "When the group was created",
                     "type": "string"
                 },
-                "type": {
-                    "description": "Type or category of the drug",
+                "description": {
+                    "description": "Optional description of the group's purpose",
                     "type": "string"
                 },
-                "user_id": {
-                    "description": "ID of the user who owns the drug (hidden from JSON)",
+                "id": {
+                    "description": "Unique identifier for the group",
+                    "type": "integer"
+                },
+                "member_count": {
                     "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the group",
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
                 }
             }
         }
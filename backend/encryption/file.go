@@ -0,0 +1,224 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// FileChunkSize is the size of the chunks files are encrypted in. Every chunk is sealed on its
+// own, so a range of a file is decrypted without reading the chunks before it.
+const FileChunkSize = 64 << 10
+
+// errMalformedFile is returned for files with the prefix that EncryptFile did not make.
+var errMalformedFile = errors.New("malformed encrypted file")
+
+// EncryptFile encrypts a file with the active key of the user and returns the ID of the key.
+// The header is followed by a nonce and the sealed chunks of the file, the nonce of a chunk
+// is the nonce of the file with the chunk number added. The nonce is derived from the contents,
+// so identical files of a user encrypt identically and share a blob. Files of different users never do.
+func (k *Keyring) EncryptFile(userID uint, data []byte) ([]byte, uint, error) {
+	if !k.Enabled() {
+		return data, 0, nil
+	}
+	id, key, err := k.activeKey(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	nonce := mac.Sum(nil)[:aead.NonceSize()]
+
+	chunks := len(data)/FileChunkSize + 1
+	sealed := make([]byte, 0, len(Prefix)+20+len(nonce)+len(data)+chunks*aead.Overhead())
+	sealed = append(append(sealed, header(id)...), nonce...)
+	for i := 0; i < chunks; i++ {
+		start, end := i*FileChunkSize, min((i+1)*FileChunkSize, len(data))
+		sealed = aead.Seal(sealed, chunkNonce(nonce, i), data[start:end], chunkContext(i == chunks-1))
+	}
+	return sealed, id, nil
+}
+
+// DecryptFile decrypts a file made by EncryptFile, plaintext files are returned as they are.
+func (k *Keyring) DecryptFile(data []byte) ([]byte, error) {
+	file, err := k.OpenFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(file)
+}
+
+// OpenFile returns a reader of the decrypted contents of a file made by EncryptFile, chunks
+// are decrypted as they are read. Plaintext files are read as they are.
+func (k *Keyring) OpenFile(file io.ReadSeeker) (io.ReadSeeker, error) {
+	head := make([]byte, len(Prefix)+21)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.HasPrefix(head[:n], []byte(Prefix)) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return file, nil
+	}
+	end := bytes.IndexByte(head[len(Prefix):n], ':')
+	if end < 0 {
+		return nil, errMalformedFile
+	}
+	id, err := strconv.ParseUint(string(head[len(Prefix):len(Prefix)+end]), 10, 64)
+	if err != nil {
+		return nil, errMalformedFile
+	}
+
+	if !k.Enabled() {
+		return nil, ErrNoMasterKey
+	}
+	key, err := k.dataKey(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	r := &fileReader{
+		file:  file,
+		id:    uint(id),
+		aead:  aead,
+		nonce: make([]byte, aead.NonceSize()),
+		start: int64(len(Prefix) + end + 1 + aead.NonceSize()),
+		chunk: -1,
+	}
+	body := size - r.start
+	if body < int64(aead.Overhead()) {
+		return nil, errMalformedFile
+	}
+	if _, err := file.Seek(r.start-int64(len(r.nonce)), io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(file, r.nonce); err != nil {
+		return nil, errMalformedFile
+	}
+	sealedChunk := int64(FileChunkSize + aead.Overhead())
+	r.chunks = int((body + sealedChunk - 1) / sealedChunk)
+	if body-int64(r.chunks-1)*sealedChunk < int64(aead.Overhead()) {
+		return nil, errMalformedFile
+	}
+	r.size = body - int64(r.chunks*aead.Overhead())
+	// An empty last chunk is never read, it is checked here so a cut file is noticed
+	if r.size == int64(r.chunks-1)*FileChunkSize {
+		if err := r.decrypt(r.chunks - 1); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// fileReader decrypts a file made by EncryptFile chunk by chunk.
+type fileReader struct {
+	file   io.ReadSeeker
+	id     uint // ID of the data key
+	aead   cipher.AEAD
+	nonce  []byte // Nonce of the file
+	start  int64  // Offset of the first chunk in the file
+	chunks int    // Number of chunks
+	size   int64  // Size of the decrypted file
+
+	offset int64  // Read offset in the decrypted file
+	chunk  int    // Number of the decrypted chunk in plain, -1 if none
+	plain  []byte // The decrypted chunk
+	sealed []byte // Buffer for reading chunks
+}
+
+// Read decrypts the chunk at the offset if it is not decrypted yet and copies from it.
+func (r *fileReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	chunk := int(r.offset / FileChunkSize)
+	if chunk != r.chunk {
+		if err := r.decrypt(chunk); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain[r.offset-int64(chunk)*FileChunkSize:])
+	n = int(min(int64(n), r.size-r.offset))
+	r.offset += int64(n)
+	return n, nil
+}
+
+// decrypt reads and decrypts a chunk into plain.
+func (r *fileReader) decrypt(chunk int) error {
+	length := FileChunkSize + r.aead.Overhead()
+	if chunk == r.chunks-1 {
+		length = int(r.size-int64(chunk)*FileChunkSize) + r.aead.Overhead()
+	}
+	if _, err := r.file.Seek(r.start+int64(chunk)*int64(FileChunkSize+r.aead.Overhead()), io.SeekStart); err != nil {
+		return err
+	}
+	if cap(r.sealed) < length {
+		r.sealed = make([]byte, length)
+	}
+	r.sealed = r.sealed[:length]
+	if _, err := io.ReadFull(r.file, r.sealed); err != nil {
+		return err
+	}
+	plain, err := r.aead.Open(r.plain[:0], chunkNonce(r.nonce, chunk), r.sealed, chunkContext(chunk == r.chunks-1))
+	if err != nil {
+		r.chunk = -1
+		return fmt.Errorf("cannot decrypt data with key %d: %w", r.id, err)
+	}
+	r.plain, r.chunk = plain, chunk
+	return nil
+}
+
+// Seek moves the offset in the decrypted file, the chunk is decrypted on the next read.
+func (r *fileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	case io.SeekStart:
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// chunkNonce returns the nonce of a chunk: the nonce of the file with the chunk number
+// added to its last four bytes.
+func chunkNonce(nonce []byte, chunk int) []byte {
+	result := bytes.Clone(nonce)
+	tail := result[len(result)-4:]
+	binary.BigEndian.PutUint32(tail, binary.BigEndian.Uint32(tail)+uint32(chunk))
+	return result
+}
+
+// chunkContext is the additional data authenticated with a chunk. Marking the last chunk
+// makes a file cut at a chunk boundary fail to decrypt.
+func chunkContext(last bool) []byte {
+	if last {
+		return []byte("last")
+	}
+	return []byte("chunk")
+}
@@ -0,0 +1,77 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestOpenFile(t *testing.T) {
+	keyring := NewKeyring(newMemoryKeys(), []MasterKey{masterKey(t, 1)})
+	random := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{0, 1, FileChunkSize - 1, FileChunkSize, FileChunkSize + 1, 3*FileChunkSize + 100} {
+		file := make([]byte, size)
+		random.Read(file)
+		encrypted, _, err := keyring.EncryptFile(7, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plain, err := keyring.DecryptFile(encrypted); err != nil || !bytes.Equal(plain, file) {
+			t.Fatalf("DecryptFile of %d bytes = %d bytes, %v", size, len(plain), err)
+		}
+
+		reader, err := keyring.OpenFile(bytes.NewReader(encrypted))
+		if err != nil {
+			t.Fatalf("OpenFile of %d bytes = %v", size, err)
+		}
+		if end, err := reader.Seek(0, io.SeekEnd); err != nil || end != int64(size) {
+			t.Errorf("size of %d bytes = %d, %v", size, end, err)
+		}
+		// Ranges are read from the middle of chunks and across their boundaries
+		for _, offset := range []int{size / 2, max(size-FileChunkSize/2, 0), max(size-1, 0)} {
+			if _, err := reader.Seek(int64(offset), io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]byte, min(FileChunkSize, size-offset))
+			if _, err := io.ReadFull(reader, got); err != nil || !bytes.Equal(got, file[offset:offset+len(got)]) {
+				t.Errorf("%d bytes at %d of %d: %v", len(got), offset, size, err)
+			}
+		}
+	}
+}
+
+func TestOpenFileTampered(t *testing.T) {
+	keyring := NewKeyring(newMemoryKeys(), []MasterKey{masterKey(t, 1)})
+	file := bytes.Repeat([]byte("%PDF"), FileChunkSize/2)
+	encrypted, _, err := keyring.EncryptFile(7, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file cut after the first chunk has no last chunk
+	cut := encrypted[:len(encrypted)-(len(encrypted)-len(Prefix)-2-12)/2]
+	if _, err := keyring.DecryptFile(cut); err == nil {
+		t.Error("DecryptFile of a cut file succeeded")
+	}
+	flipped := bytes.Clone(encrypted)
+	flipped[len(flipped)-1] ^= 1
+	if _, err := keyring.DecryptFile(flipped); err == nil {
+		t.Error("DecryptFile of a changed file succeeded")
+	}
+	// Plaintext files are read as they are
+	reader, err := keyring.OpenFile(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := io.ReadAll(reader); err != nil || !bytes.Equal(plain, file) {
+		t.Errorf("OpenFile(plaintext) = %d bytes, %v", len(plain), err)
+	}
+
+	disabled := NewKeyring(newMemoryKeys(), nil)
+	if _, err := disabled.OpenFile(bytes.NewReader(encrypted)); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("OpenFile without a master key = %v, want ErrNoMasterKey", err)
+	}
+}
@@ -0,0 +1,360 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"first_aid_companion/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Prefix marks encrypted values. It is followed by the ID of the data key and a colon,
+// then by the nonce and ciphertext: base64-encoded in text columns, raw and in chunks in files.
+// Values without the prefix are plaintext written before encryption was enabled.
+const Prefix = "enc:v1:"
+
+// ActiveKeyTTL is how long the active key of a user is cached. Keys retired by "keys rotate"
+// in another process may still encrypt new data for this long.
+const ActiveKeyTTL = time.Minute
+
+// ErrNoMasterKey is returned when encrypted data is read without a master key configured.
+var ErrNoMasterKey = errors.New("data is encrypted but no master key is configured")
+
+// KeyStore stores the wrapped data keys, implemented by models.DataKeyGorm.
+type KeyStore interface {
+	GetKey(id uint) (*models.DataKey, error)
+	// GetActiveKey returns gorm.ErrRecordNotFound if the user has no active key.
+	GetActiveKey(userID uint) (*models.DataKey, error)
+	// CreateKey fails if the user already has an active key.
+	CreateKey(key *models.DataKey) error
+	RetireActiveKeys() (int64, error)
+	GetKeysNotWrappedWith(masterKeyID string, limit int) ([]models.DataKey, error)
+	UpdateWrapping(key *models.DataKey) error
+}
+
+// Keyring encrypts data with per-user data keys (envelope encryption). Data keys are created
+// on first use and stored wrapped with the current master key. Unwrapped keys are cached.
+// A keyring without master keys is disabled: it stores new data in plaintext.
+type Keyring struct {
+	keys    KeyStore
+	masters map[string]MasterKey
+	current string // ID of the master key wrapping new data keys
+
+	mu       sync.Mutex
+	dataKeys map[uint][]byte      // Unwrapped keys by ID
+	active   map[uint]activeEntry // Active key by user ID
+}
+
+// activeEntry is a cached active key of a user.
+type activeEntry struct {
+	id       uint
+	loadedAt time.Time
+}
+
+// NewKeyring creates a keyring, the first master key wraps new data keys.
+func NewKeyring(keys KeyStore, masterKeys []MasterKey) *Keyring {
+	k := &Keyring{
+		keys:     keys,
+		masters:  map[string]MasterKey{},
+		dataKeys: map[uint][]byte{},
+		active:   map[uint]activeEntry{},
+	}
+	for i, master := range masterKeys {
+		if i == 0 {
+			k.current = master.ID
+		}
+		k.masters[master.ID] = master
+	}
+	return k
+}
+
+// Enabled reports whether the keyring has a master key to encrypt data with.
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.current != ""
+}
+
+// CurrentMasterKeyID returns the ID of the master key wrapping new data keys.
+func (k *Keyring) CurrentMasterKeyID() string {
+	return k.current
+}
+
+// Forget drops the cached active keys, so the next write looks them up again.
+func (k *Keyring) Forget() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.active = map[uint]activeEntry{}
+}
+
+// EncryptString encrypts a text value with the active key of the user.
+// Empty values stay empty, so queries for missing values keep working.
+func (k *Keyring) EncryptString(userID uint, value string) (string, error) {
+	if value == "" || !k.Enabled() {
+		return value, nil
+	}
+	id, key, err := k.activeKey(userID)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(key, []byte(value), nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return header(id) + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString decrypts a value made by EncryptString, plaintext values are returned as they are.
+func (k *Keyring) DecryptString(value string) (string, error) {
+	id, rest, ok := parseHeader(value)
+	if !ok {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(rest)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	plain, err := k.open(id, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// KeyID returns the ID of the data key a text value is encrypted with, false for plaintext.
+func KeyID(value string) (uint, bool) {
+	id, _, ok := parseHeader(value)
+	return id, ok
+}
+
+// activeKey returns the active key of the user, creating one if the user has none.
+func (k *Keyring) activeKey(userID uint) (uint, []byte, error) {
+	if userID == 0 {
+		return 0, nil, errors.New("cannot encrypt data without an owner")
+	}
+
+	k.mu.Lock()
+	entry, ok := k.active[userID]
+	k.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < ActiveKeyTTL {
+		key, err := k.dataKey(entry.id)
+		return entry.id, key, err
+	}
+
+	stored, err := k.keys.GetActiveKey(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stored, err = k.createKey(userID)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	key, err := k.unwrap(stored)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	k.mu.Lock()
+	k.dataKeys[stored.ID] = key
+	k.active[userID] = activeEntry{id: stored.ID, loadedAt: time.Now()}
+	k.mu.Unlock()
+	return stored.ID, key, nil
+}
+
+// createKey generates and stores a new active key for the user. A key created meanwhile
+// by a concurrent request wins, the unique index allows only one active key per user.
+func (k *Keyring) createKey(userID uint) (*models.DataKey, error) {
+	key := make([]byte, MasterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	stored := &models.DataKey{UserID: userID, Active: true, CreatedAt: time.Now()}
+	if err := k.wrap(stored, key); err != nil {
+		return nil, err
+	}
+	if err := k.keys.CreateKey(stored); err != nil {
+		if existing, lookupErr := k.keys.GetActiveKey(userID); lookupErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("cannot create data key: %w", err)
+	}
+	return stored, nil
+}
+
+// dataKey returns an unwrapped key by its ID.
+func (k *Keyring) dataKey(id uint) ([]byte, error) {
+	k.mu.Lock()
+	key, ok := k.dataKeys[id]
+	k.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	stored, err := k.keys.GetKey(id)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load data key %d: %w", id, err)
+	}
+	if key, err = k.unwrap(stored); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.dataKeys[id] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+// open decrypts a nonce and ciphertext with a data key.
+func (k *Keyring) open(id uint, sealed []byte) ([]byte, error) {
+	if !k.Enabled() {
+		return nil, ErrNoMasterKey
+	}
+	key, err := k.dataKey(id)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := unseal(key, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt data with key %d: %w", id, err)
+	}
+	return plain, nil
+}
+
+// wrap encrypts the data key with the current master key. The owner is authenticated
+// as well, so a key moved to another user's row cannot be unwrapped.
+func (k *Keyring) wrap(stored *models.DataKey, key []byte) error {
+	wrapped, err := seal(k.masters[k.current].key, key, nil, wrapContext(stored.UserID))
+	if err != nil {
+		return err
+	}
+	stored.MasterKeyID = k.current
+	stored.WrappedKey = wrapped
+	return nil
+}
+
+// unwrap decrypts a stored data key with the master key it was wrapped with.
+func (k *Keyring) unwrap(stored *models.DataKey) ([]byte, error) {
+	master, ok := k.masters[stored.MasterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("data key %d is wrapped with master key %s, which is not configured", stored.ID, stored.MasterKeyID)
+	}
+	key, err := unseal(master.key, stored.WrappedKey, wrapContext(stored.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("cannot unwrap data key %d: %w", stored.ID, err)
+	}
+	return key, nil
+}
+
+// Rewrap wraps every data key wrapped with a previous master key with the current one.
+// Afterwards the previous master keys can be removed from the configuration.
+// Returns the number of rewrapped keys.
+func (k *Keyring) Rewrap() (int, error) {
+	if !k.Enabled() {
+		return 0, ErrNoMasterKey
+	}
+	rewrapped := 0
+	for {
+		batch, err := k.keys.GetKeysNotWrappedWith(k.current, 100)
+		if err != nil || len(batch) == 0 {
+			return rewrapped, err
+		}
+		for _, stored := range batch {
+			key, err := k.unwrap(&stored)
+			if err != nil {
+				return rewrapped, err
+			}
+			if err := k.wrap(&stored, key); err != nil {
+				return rewrapped, err
+			}
+			if err := k.keys.UpdateWrapping(&stored); err != nil {
+				return rewrapped, err
+			}
+			rewrapped++
+		}
+	}
+}
+
+// Rotate retires the active data key of every user and rewraps the remaining keys with the
+// current master key. New data is encrypted with new keys, existing data is re-encrypted
+// in the background by the server. Returns the number of retired and rewrapped keys.
+func (k *Keyring) Rotate() (int64, int, error) {
+	if !k.Enabled() {
+		return 0, 0, ErrNoMasterKey
+	}
+	retired, err := k.keys.RetireActiveKeys()
+	if err != nil {
+		return 0, 0, err
+	}
+	k.Forget()
+	rewrapped, err := k.Rewrap()
+	return retired, rewrapped, err
+}
+
+// header returns the prefix of values encrypted with a data key.
+func header(id uint) string {
+	return Prefix + strconv.FormatUint(uint64(id), 10) + ":"
+}
+
+// parseHeader splits an encrypted text value into the key ID and the encoded ciphertext.
+func parseHeader(value string) (uint, string, bool) {
+	rest, ok := strings.CutPrefix(value, Prefix)
+	if !ok {
+		return 0, "", false
+	}
+	idText, sealed, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, "", false
+	}
+	id, err := strconv.ParseUint(idText, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint(id), sealed, true
+}
+
+// wrapContext is the additional data authenticated with a wrapped key.
+func wrapContext(userID uint) []byte {
+	return []byte("data_key:" + strconv.FormatUint(uint64(userID), 10))
+}
+
+// seal encrypts with AES-256-GCM and returns the nonce followed by the ciphertext.
+// The nonce is taken from the start of nonceSource if given, otherwise it is random.
+func seal(key, plain, nonceSource, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if nonceSource != nil {
+		copy(nonce, nonceSource)
+	} else if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, additional), nil
+}
+
+// unseal decrypts the nonce and ciphertext made by seal.
+func unseal(key, sealed, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+}
+
+// newGCM creates an AES-GCM cipher with the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,360 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"first_aid_companion/models"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryKeys is a KeyStore in memory, allowing one active key per user like the unique index.
+type memoryKeys struct {
+	keys   map[uint]*models.DataKey
+	nextID uint
+}
+
+func newMemoryKeys() *memoryKeys {
+	return &memoryKeys{keys: map[uint]*models.DataKey{}, nextID: 1}
+}
+
+func (m *memoryKeys) GetKey(id uint) (*models.DataKey, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	stored := *key
+	return &stored, nil
+}
+
+func (m *memoryKeys) GetActiveKey(userID uint) (*models.DataKey, error) {
+	for _, key := range m.keys {
+		if key.UserID == userID && key.Active {
+			stored := *key
+			return &stored, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryKeys) CreateKey(key *models.DataKey) error {
+	if _, err := m.GetActiveKey(key.UserID); err == nil {
+		return errors.New("duplicate key value violates unique constraint")
+	}
+	key.ID = m.nextID
+	m.nextID++
+	stored := *key
+	m.keys[key.ID] = &stored
+	return nil
+}
+
+func (m *memoryKeys) RetireActiveKeys() (int64, error) {
+	var retired int64
+	now := time.Now()
+	for _, key := range m.keys {
+		if key.Active {
+			key.Active = false
+			key.RetiredAt = &now
+			retired++
+		}
+	}
+	return retired, nil
+}
+
+func (m *memoryKeys) GetKeysNotWrappedWith(masterKeyID string, limit int) ([]models.DataKey, error) {
+	keys := []models.DataKey{}
+	for _, key := range m.keys {
+		if key.MasterKeyID != masterKeyID {
+			keys = append(keys, *key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+func (m *memoryKeys) UpdateWrapping(key *models.DataKey) error {
+	stored, ok := m.keys[key.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	stored.MasterKeyID = key.MasterKeyID
+	stored.WrappedKey = append([]byte(nil), key.WrappedKey...)
+	return nil
+}
+
+// masterKey returns a master key made of a repeated byte.
+func masterKey(t *testing.T, b byte) MasterKey {
+	t.Helper()
+	keys, err := ParseMasterKeys(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, MasterKeySize)))
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("ParseMasterKeys = %v, %v", keys, err)
+	}
+	return keys[0]
+}
+
+func TestEncryptString(t *testing.T) {
+	keyring := NewKeyring(newMemoryKeys(), []MasterKey{masterKey(t, 1)})
+
+	encrypted, err := keyring.EncryptString(7, "Penicillin allergy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, Prefix+"1:") || strings.Contains(encrypted, "Penicillin") {
+		t.Fatalf("EncryptString = %q, want a value encrypted with key 1", encrypted)
+	}
+	if id, ok := KeyID(encrypted); !ok || id != 1 {
+		t.Errorf("KeyID = %d, %v, want 1", id, ok)
+	}
+	if plain, err := keyring.DecryptString(encrypted); err != nil || plain != "Penicillin allergy" {
+		t.Errorf("DecryptString = %q, %v", plain, err)
+	}
+
+	// Nonces are random, equal values do not give away they are equal
+	again, _ := keyring.EncryptString(7, "Penicillin allergy")
+	if again == encrypted {
+		t.Error("the same value encrypted twice is identical")
+	}
+
+	// Empty values stay empty, values written before encryption are read as they are
+	if empty, err := keyring.EncryptString(7, ""); err != nil || empty != "" {
+		t.Errorf("EncryptString(\"\") = %q, %v", empty, err)
+	}
+	if plain, err := keyring.DecryptString("Asthma"); err != nil || plain != "Asthma" {
+		t.Errorf("DecryptString(plaintext) = %q, %v", plain, err)
+	}
+	if _, ok := KeyID("Asthma"); ok {
+		t.Error("KeyID(plaintext) reports a key")
+	}
+
+	// Data needs an owner to pick the key
+	if _, err := keyring.EncryptString(0, "x"); err == nil {
+		t.Error("EncryptString without an owner succeeded")
+	}
+}
+
+func TestDecryptStringTampered(t *testing.T) {
+	keyring := NewKeyring(newMemoryKeys(), []MasterKey{masterKey(t, 1)})
+	encrypted, err := keyring.EncryptString(7, "Penicillin allergy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, Prefix+"1:"))
+	sealed[len(sealed)-1] ^= 1
+
+	for _, value := range []string{
+		Prefix + "1:" + base64.StdEncoding.EncodeToString(sealed),
+		Prefix + "1:not base64!",
+		Prefix + "99:" + strings.TrimPrefix(encrypted, Prefix+"1:"),
+	} {
+		if _, err := keyring.DecryptString(value); err == nil {
+			t.Errorf("DecryptString(%q) succeeded", value)
+		}
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	keys := newMemoryKeys()
+	keyring := NewKeyring(keys, []MasterKey{masterKey(t, 1)})
+	file := []byte("%PDF-1.4 blood test")
+
+	encrypted, id, err := keyring.EncryptFile(7, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || !bytes.HasPrefix(encrypted, []byte(Prefix+"1:")) || bytes.Contains(encrypted, file) {
+		t.Fatalf("EncryptFile = %q, %d", encrypted, id)
+	}
+	if plain, err := keyring.DecryptFile(encrypted); err != nil || !bytes.Equal(plain, file) {
+		t.Errorf("DecryptFile = %q, %v", plain, err)
+	}
+
+	// The nonce comes from the contents, so a user's copies of a file share a blob
+	again, _, _ := keyring.EncryptFile(7, file)
+	if !bytes.Equal(again, encrypted) {
+		t.Error("the same file of a user encrypted differently")
+	}
+	other, _, _ := keyring.EncryptFile(7, []byte("%PDF-1.4 x-ray"))
+	if bytes.Equal(other[len(Prefix)+2:][:12], encrypted[len(Prefix)+2:][:12]) {
+		t.Error("different files got the same nonce")
+	}
+	// Files of different users never do
+	foreign, foreignID, _ := keyring.EncryptFile(8, file)
+	if foreignID == id || bytes.Equal(foreign[len(Prefix)+2:], encrypted[len(Prefix)+2:]) {
+		t.Error("the same file of two users encrypted identically")
+	}
+
+	if plain, err := keyring.DecryptFile(file); err != nil || !bytes.Equal(plain, file) {
+		t.Errorf("DecryptFile(plaintext) = %q, %v", plain, err)
+	}
+	for _, broken := range []string{Prefix + "1", Prefix + "x:abc", Prefix + "1:short"} {
+		if _, err := keyring.DecryptFile([]byte(broken)); err == nil {
+			t.Errorf("DecryptFile(%q) succeeded", broken)
+		}
+	}
+}
+
+func TestWrappedKeyOwner(t *testing.T) {
+	keys := newMemoryKeys()
+	master := masterKey(t, 1)
+	encrypted, err := NewKeyring(keys, []MasterKey{master}).EncryptString(7, "Penicillin allergy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapped := keys.keys[1].WrappedKey; bytes.Contains(wrapped, []byte("data_key")) || len(wrapped) != 12+MasterKeySize+16 {
+		t.Fatalf("wrapped key has %d bytes", len(wrapped))
+	}
+
+	// A key moved to another user's row cannot be unwrapped, the owner is authenticated
+	keys.keys[1].UserID = 8
+	if _, err := NewKeyring(keys, []MasterKey{master}).DecryptString(encrypted); err == nil {
+		t.Fatal("a key of another owner was unwrapped")
+	}
+	keys.keys[1].UserID = 7
+	if _, err := NewKeyring(keys, []MasterKey{master}).DecryptString(encrypted); err != nil {
+		t.Fatalf("DecryptString = %v", err)
+	}
+
+	// Nor can keys wrapped with another master key under the same ID
+	forged := masterKey(t, 2)
+	forged.ID = master.ID
+	if _, err := NewKeyring(keys, []MasterKey{forged}).DecryptString(encrypted); err == nil {
+		t.Fatal("a key was unwrapped with the wrong master key")
+	}
+}
+
+func TestNoMasterKey(t *testing.T) {
+	keys := newMemoryKeys()
+	encrypted, err := NewKeyring(keys, []MasterKey{masterKey(t, 1)}).EncryptString(7, "Penicillin allergy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, _, err := NewKeyring(keys, []MasterKey{masterKey(t, 1)}).EncryptFile(7, []byte("%PDF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disabled := NewKeyring(keys, nil)
+	if disabled.Enabled() {
+		t.Fatal("a keyring without master keys is enabled")
+	}
+	// New data is stored in plaintext, existing encrypted data cannot be read
+	if value, err := disabled.EncryptString(7, "Asthma"); err != nil || value != "Asthma" {
+		t.Errorf("EncryptString = %q, %v", value, err)
+	}
+	if data, id, err := disabled.EncryptFile(7, []byte("%PDF")); err != nil || id != 0 || string(data) != "%PDF" {
+		t.Errorf("EncryptFile = %q, %d, %v", data, id, err)
+	}
+	if _, err := disabled.DecryptString(encrypted); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("DecryptString = %v, want ErrNoMasterKey", err)
+	}
+	if _, err := disabled.DecryptFile(file); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("DecryptFile = %v, want ErrNoMasterKey", err)
+	}
+	if _, err := disabled.Rewrap(); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("Rewrap = %v, want ErrNoMasterKey", err)
+	}
+	if _, _, err := disabled.Rotate(); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("Rotate = %v, want ErrNoMasterKey", err)
+	}
+	var missing *Keyring
+	if missing.Enabled() {
+		t.Error("a nil keyring is enabled")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	keys := newMemoryKeys()
+	old, current := masterKey(t, 1), masterKey(t, 2)
+	keyring := NewKeyring(keys, []MasterKey{old})
+
+	before, err := keyring.EncryptString(7, "Penicillin allergy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, _, err := keyring.EncryptFile(8, []byte("%PDF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new master key comes first, the old one is kept to unwrap the existing keys
+	keyring = NewKeyring(keys, []MasterKey{current, old})
+	if keyring.CurrentMasterKeyID() != current.ID {
+		t.Fatalf("CurrentMasterKeyID = %s, want %s", keyring.CurrentMasterKeyID(), current.ID)
+	}
+	retired, rewrapped, err := keyring.Rotate()
+	if err != nil || retired != 2 || rewrapped != 2 {
+		t.Fatalf("Rotate = %d, %d, %v, want 2 retired and 2 rewrapped keys", retired, rewrapped, err)
+	}
+
+	// New data gets new keys wrapped with the new master key
+	after, err := keyring.EncryptString(7, "Penicillin allergy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := KeyID(after); id != 3 {
+		t.Errorf("key after rotation = %d, want 3", id)
+	}
+	for id, key := range keys.keys {
+		if key.MasterKeyID != current.ID {
+			t.Errorf("key %d is wrapped with %s, want %s", id, key.MasterKeyID, current.ID)
+		}
+		if key.Active != (id == 3) {
+			t.Errorf("key %d active = %v", id, key.Active)
+		}
+	}
+	if again, _ := keyring.Rewrap(); again != 0 {
+		t.Errorf("second Rewrap = %d, want 0", again)
+	}
+
+	// Old values are still readable once the old master key is removed
+	keyring = NewKeyring(keys, []MasterKey{current})
+	for _, value := range []string{before, after} {
+		if plain, err := keyring.DecryptString(value); err != nil || plain != "Penicillin allergy" {
+			t.Errorf("DecryptString(%q) = %q, %v", value, plain, err)
+		}
+	}
+	if plain, err := keyring.DecryptFile(file); err != nil || string(plain) != "%PDF" {
+		t.Errorf("DecryptFile = %q, %v", plain, err)
+	}
+
+	// Without rewrapping they are not
+	unwrapped := newMemoryKeys()
+	value, _ := NewKeyring(unwrapped, []MasterKey{old}).EncryptString(7, "Asthma")
+	if _, err := NewKeyring(unwrapped, []MasterKey{current}).DecryptString(value); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("DecryptString with a removed master key = %v", err)
+	}
+}
+
+func TestActiveKeyCreatedConcurrently(t *testing.T) {
+	keys := newMemoryKeys()
+	master := masterKey(t, 1)
+	first := NewKeyring(keys, []MasterKey{master})
+	second := NewKeyring(keys, []MasterKey{master})
+
+	// The second keyring has not cached the key and finds it when creating its own fails
+	a, err := first.EncryptString(7, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := keys.GetActiveKey(7)
+	if _, err := second.createKey(7); err != nil {
+		t.Fatalf("createKey = %v", err)
+	}
+	b, err := second.EncryptString(7, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idA, _ := KeyID(a)
+	idB, _ := KeyID(b)
+	if idA != stored.ID || idB != stored.ID || len(keys.keys) != 1 {
+		t.Errorf("keys %d and %d of %d stored, want one key", idA, idB, len(keys.keys))
+	}
+}
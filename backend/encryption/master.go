@@ -0,0 +1,68 @@
+package encryption
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MasterKeySize is the length of master and data keys, AES-256 is used for both.
+const MasterKeySize = 32
+
+// MasterKey wraps the data keys. It is only kept in the configuration, never in the database.
+type MasterKey struct {
+	ID  string // Hex prefix of the SHA-256 hash of the key, stored with the keys it wraps
+	key []byte
+}
+
+// ParseMasterKeys parses base64-encoded 32-byte keys separated by commas or newlines,
+// e.g. generated with "openssl rand -base64 32". The first key wraps new data keys,
+// the others are previous keys still needed to unwrap existing ones until "keys rotate" runs.
+func ParseMasterKeys(spec string) ([]MasterKey, error) {
+	var keys []MasterKey
+	seen := map[string]bool{}
+	for _, field := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		field = strings.TrimSpace(field)
+		if field == "" || strings.HasPrefix(field, "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("master key %d is not valid base64", len(keys)+1)
+		}
+		if len(key) != MasterKeySize {
+			return nil, fmt.Errorf("master key %d has %d bytes, %d are required", len(keys)+1, len(key), MasterKeySize)
+		}
+		sum := sha256.Sum256(key)
+		id := hex.EncodeToString(sum[:8])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		keys = append(keys, MasterKey{ID: id, key: key})
+	}
+	return keys, nil
+}
+
+// LoadMasterKeys reads the master keys from the value of MASTER_KEY or, if it is empty,
+// from the file named by MASTER_KEY_FILE, e.g. a Docker secret. Returns no keys if neither is set.
+func LoadMasterKeys(value, file string) ([]MasterKey, error) {
+	if value != "" && file != "" {
+		return nil, errors.New("set either the master key or the master key file, not both")
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read master key file: %w", err)
+		}
+		value = string(data)
+		if strings.TrimSpace(value) == "" {
+			return nil, errors.New("master key file is empty")
+		}
+	}
+	return ParseMasterKeys(value)
+}
@@ -0,0 +1,97 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is the GORM serializer encrypting model fields, e.g.
+//
+//	Passport string `gorm:"serializer:encrypted"`
+//
+// Values are encrypted with the data key of the owner: the UserID field of the model,
+// or the primary key for users themselves.
+const SerializerName = "encrypted"
+
+// registered is the keyring used by the serializer, nil until Register is called.
+var registered atomic.Pointer[Keyring]
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Register makes the serializer encrypt with the keyring. GORM copies serializers into
+// the parsed schemas, so the keyring is kept in a package variable rather than in them.
+func Register(k *Keyring) {
+	registered.Store(k)
+}
+
+// Serializer encrypts string fields on write and decrypts them on read, so models and
+// controllers handle plaintext only.
+type Serializer struct{}
+
+// Scan decrypts the database value into the field.
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("cannot decrypt %s from %T", field.Name, dbValue)
+	}
+
+	plain, err := registered.Load().DecryptString(value)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt %s: %w", field.Name, err)
+	}
+	return field.Set(ctx, dst, plain)
+}
+
+// Value encrypts the field with the data key of the record's owner.
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot encrypt %s of type %T", field.Name, fieldValue)
+	}
+	// Without a master key values are stored as they are
+	keyring := registered.Load()
+	if value == "" || !keyring.Enabled() {
+		return value, nil
+	}
+
+	owner, err := ownerOf(ctx, field, dst)
+	if err != nil {
+		return nil, err
+	}
+	return keyring.EncryptString(owner, value)
+}
+
+// ownerOf returns the ID of the user owning the record.
+func ownerOf(ctx context.Context, field *schema.Field, dst reflect.Value) (uint, error) {
+	ownerField := field.Schema.LookUpField("UserID")
+	if ownerField == nil {
+		ownerField = field.Schema.PrioritizedPrimaryField
+	}
+	if ownerField == nil {
+		return 0, fmt.Errorf("cannot encrypt %s: %s has no owner", field.Name, field.Schema.Name)
+	}
+
+	value, zero := ownerField.ValueOf(ctx, reflect.Indirect(dst))
+	if zero {
+		return 0, fmt.Errorf("cannot encrypt %s of %s without an owner", field.Name, field.Schema.Name)
+	}
+	switch id := value.(type) {
+	case uint:
+		return id, nil
+	case int:
+		return uint(id), nil
+	}
+	return 0, fmt.Errorf("cannot encrypt %s: unexpected owner ID type %T", field.Name, value)
+}
@@ -18,7 +18,8 @@ import (
 var CorsMiddleware = cors.New(cors.Options{
 	AllowedOrigins:   []string{"*"}, // Allow all origins (use specific domains in production)
 	AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-	AllowedHeaders:   []string{"Content-Type", "Authorization"},
+	AllowedHeaders:   []string{"Content-Type", "Authorization", "Range", controllers.OnBehalfHeader, controllers.SharePINHeader},
+	ExposedHeaders:   []string{"Content-Disposition", "Content-Range", "Accept-Ranges"}, // Needed by browsers downloading documents
 	AllowCredentials: true,
 	Debug:            true, // Set to false in production to disable CORS debugging logs
 })
@@ -44,33 +45,78 @@ func (w *wrapWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return nil, nil, fmt.Errorf("underlying ResponseWriter does not support Hijacker")
 }
 
-// RequireUserMiddleware ensures the incoming request has a valid JWT token.
+// RequireUserMiddleware ensures the incoming request has a valid JWT token
+// issued for a session that has not been revoked.
 // If valid, user claims are added to the request context.
-func RequireUserMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extract JWT token from the Authorization header
-		tokenStr, err := controllers.ExtractTokenFromHeader(r)
-		if err != nil {
-			controllers.WriteError(w, 409, "no token")
-			return
-		}
-
-		// Validate and parse the JWT token
-		claims, err := controllers.ParseJWT(tokenStr)
-		if err != nil {
-			controllers.WriteError(w, 409, "Invalid token: "+err.Error())
-			return
-		}
-
-		// Add claims to the request context
-		ctx := context.WithValue(r.Context(), "user", claims)
-
-		// Use custom wrapWriter for flush/hijack support
-		ww := &wrapWriter{ResponseWriter: w}
-
-		// Call the next handler with updated context
-		next.ServeHTTP(ww, r.WithContext(ctx))
-	})
+func RequireUserMiddleware(sessions *controllers.SessionService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Extract JWT token from the Authorization header
+			tokenStr, err := controllers.ExtractTokenFromHeader(r)
+			if err != nil {
+				controllers.WriteError(w, http.StatusUnauthorized, "no token")
+				return
+			}
+
+			// Validate and parse the JWT token
+			claims, err := controllers.ParseJWT(tokenStr)
+			if err != nil {
+				controllers.WriteError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+				return
+			}
+
+			// Reject tokens of sessions the user logged out of
+			if err := sessions.CheckSession(claims); err != nil {
+				controllers.WriteError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			// Add claims to the request context
+			ctx := context.WithValue(r.Context(), "user", claims)
+
+			// Use custom wrapWriter for flush/hijack support
+			ww := &wrapWriter{ResponseWriter: w}
+
+			// Call the next handler with updated context
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// statusWriter captures the status code while keeping flush/hijack support.
+type statusWriter struct {
+	wrapWriter
+	statusCode int // Captured status code
+}
+
+// WriteHeader stores the status code before writing the header.
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.statusCode = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// OnBehalfMiddleware lets caregivers call the API for a dependent profile by sending its ID
+// in the X-On-Behalf-Of header. The profile becomes the current user and the request is
+// recorded in the profile's audit trail. Requests without the header pass through unchanged.
+func OnBehalfMiddleware(dependents *controllers.DependentService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(controllers.OnBehalfHeader) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r, reqErr := dependents.ActOnBehalf(r)
+			if reqErr != nil {
+				controllers.WriteRequestError(w, reqErr)
+				return
+			}
+
+			sw := &statusWriter{wrapWriter: wrapWriter{ResponseWriter: w}, statusCode: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			dependents.RecordAccess(r, sw.statusCode)
+		})
+	}
 }
 
 // loggingResponseWriter is a wrapper that captures the HTTP status code for logging purposes.
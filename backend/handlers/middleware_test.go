@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"first_aid_companion/controllers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequireUserMiddlewareRejectsTokens(t *testing.T) {
+	secret := []byte("test secret")
+	if err := controllers.ConfigureAuth(controllers.AuthConfig{Secret: secret}); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &controllers.Claims{
+		UserID: "1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &controllers.Claims{
+		UserID: "1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}).SignedString([]byte("other secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The session store is only reached with a valid token
+	handler := RequireUserMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called without a valid token")
+	}))
+	for name, header := range map[string]string{
+		"missing":   "",
+		"malformed": "Bearer not-a-jwt",
+		"expired":   "Bearer " + expired,
+		"forged":    "Bearer " + forged,
+	} {
+		r := httptest.NewRequest("GET", "/auth/me", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: status %d, want %d", name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
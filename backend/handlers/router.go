@@ -21,36 +21,172 @@ func HomePage(w http.ResponseWriter, r *http.Request) {
 func AddRoutes(r *mux.Router, service *services.DBService) {
 
 	// Services initialization
-	drugsService := controllers.DrugService{DB: service.DrugDB}
+	drugsService := controllers.DrugService{
+		DB:           service.DrugDB,
+		KitDB:        service.KitDB,
+		CardDB:       service.MedCardDB,
+		GroupDB:      service.GroupDB,
+		Interactions: service.Interactions,
+		ICD10:        service.ICD10,
+	}
 	medCardService := controllers.MedicalCardService{DB: service.MedCardDB}
-	userService := controllers.UserService{DB: service.UserDB, CardService: &medCardService}
+	medEntryService := controllers.MedicalEntryService{DB: service.MedCardDB, ICD10: service.ICD10}
+	sessionService := controllers.SessionService{DB: service.SessionDB, UserDB: service.UserDB}
+	documentsService := controllers.DocumentService{
+		DB:         service.DocsDB,
+		Blobs:      service.Blobs,
+		Keyring:    service.Keyring,
+		MaxSize:    service.MaxDocumentSize,
+		PublicURL:  service.PublicURL,
+		Thumbnails: service.Thumbnails,
+	}
+	userService := controllers.UserService{
+		DB:          service.UserDB,
+		CardService: &medCardService,
+		Sessions:    &sessionService,
+		Documents:   &documentsService,
+	}
 	chatService := controllers.ChatService{DB: service.ChatDB}
-	messageService := controllers.MessageService{ApiKey: service.ApiKey, DB: service.MessageDB}
-	documentsService := controllers.DocumentService{DB: service.DocsDB}
+	messageService := controllers.MessageService{
+		Model:  service.ChatModel,
+		DB:     service.MessageDB,
+		ChatDB: service.ChatDB,
+		CardDB: service.MedCardDB,
+		DrugDB: service.DrugDB,
+	}
+	notificationService := controllers.NotificationService{DB: service.NotifDB}
+	catalogService := controllers.CatalogService{DB: service.CatalogDB}
+	kitService := controllers.KitService{
+		DB:        service.KitDB,
+		DrugDB:    service.DrugDB,
+		GroupDB:   service.GroupDB,
+		Templates: service.KitTemplates,
+	}
+	groupService := controllers.GroupService{DB: service.GroupDB}
+	dependentService := controllers.DependentService{
+		DB:        service.DependentDB,
+		UserDB:    service.UserDB,
+		GroupDB:   service.GroupDB,
+		Documents: &documentsService,
+	}
+	emergencyService := controllers.EmergencyService{DB: service.EmergencyDB, PublicURL: service.PublicURL}
+	sosService := controllers.SOSService{DB: service.EmergencyDB, UserDB: service.UserDB, Notifier: service.Notifier}
+	scheduleService := controllers.ScheduleService{DB: service.ScheduleDB, DrugDB: service.DrugDB, NotifDB: service.NotifDB}
 
 	// Non-auth related endpoints
 	r.HandleFunc("/", HomePage).Methods("GET")
 	r.HandleFunc("/signup", userService.SignUp).Methods("POST")
 	r.HandleFunc("/login", userService.LogIn).Methods("POST")
+	r.HandleFunc("/auth/refresh", sessionService.Refresh).Methods("POST")          // Access token may already be expired
+	r.HandleFunc("/emergency/{token}", emergencyService.PublicCard).Methods("GET") // Opened by paramedics from the QR code
+	r.HandleFunc("/shared/{token}", documentsService.SharedDocument).Methods("GET")
+	r.HandleFunc("/shared/{token}/file", documentsService.SharedDocumentFile).Methods("GET")
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
 	// Auth related endpoints
 	authRoute := r.PathPrefix("/auth").Subrouter()
-	authRoute.Use(RequireUserMiddleware)
+	authRoute.Use(RequireUserMiddleware(&sessionService))
+	authRoute.Use(OnBehalfMiddleware(&dependentService))
 
 	// Personal info
 	authRoute.HandleFunc("/me", userService.Me).Methods("GET")
 	authRoute.HandleFunc("/me", userService.UpdateMe).Methods("POST")
+	authRoute.HandleFunc("/me", userService.DeleteMe).Methods("DELETE")
+
+	// Allergies and chronic conditions
+	authRoute.HandleFunc("/{kind:allergies|conditions}", medEntryService.Entries).Methods("GET")
+	authRoute.HandleFunc("/{kind:allergies|conditions}", medEntryService.AddEntry).Methods("POST")
+	authRoute.HandleFunc("/{kind:allergies|conditions}/{id:[0-9]+}", medEntryService.UpdateEntry).Methods("PUT")
+	authRoute.HandleFunc("/{kind:allergies|conditions}/remove/{id:[0-9]+}", medEntryService.RemoveEntry).Methods("POST")
+	authRoute.HandleFunc("/icd10", medEntryService.ICD10Lookup).Methods("GET")
+
+	// Sessions (logged in devices)
+	authRoute.HandleFunc("/logout", sessionService.Logout).Methods("POST")
+	authRoute.HandleFunc("/sessions", sessionService.Sessions).Methods("GET")
+	authRoute.HandleFunc("/sessions/revoke/{id:[0-9]+}", sessionService.RevokeSession).Methods("POST")
 
 	// Drugs storage
 	authRoute.HandleFunc("/drugs", drugsService.Drugs).Methods("GET")
 	authRoute.HandleFunc("/drugs/add", drugsService.AddDrug).Methods("POST")
+	authRoute.HandleFunc("/drugs/interactions", drugsService.DrugInteractions).Methods("GET")
+	authRoute.HandleFunc("/drugs/history", drugsService.CabinetHistory).Methods("GET")
+	authRoute.HandleFunc("/drugs/{id:[0-9]+}", drugsService.UpdateDrug).Methods("PUT")
 	authRoute.HandleFunc("/drugs/remove/{id:[0-9]+}", drugsService.RemoveDrug).Methods("POST")
+	authRoute.HandleFunc("/drugs/{id:[0-9]+}/adherence", scheduleService.DrugAdherence).Methods("GET")
+	authRoute.HandleFunc("/drugs/{id:[0-9]+}/history", drugsService.DrugHistory).Methods("GET")
+
+	// First-aid kits
+	authRoute.HandleFunc("/kits", kitService.Kits).Methods("GET")
+	authRoute.HandleFunc("/kits", kitService.AddKit).Methods("POST")
+	authRoute.HandleFunc("/kits/{id:[0-9]+}", kitService.UpdateKit).Methods("PUT")
+	authRoute.HandleFunc("/kits/remove/{id:[0-9]+}", kitService.RemoveKit).Methods("POST")
+	authRoute.HandleFunc("/kits/{id:[0-9]+}/check", kitService.CheckKit).Methods("GET")
+	authRoute.HandleFunc("/kit_templates", kitService.KitTemplates).Methods("GET")
+	authRoute.HandleFunc("/drugs/move", kitService.MoveDrugs).Methods("POST")
+
+	// Groups (households), members and invitations
+	authRoute.HandleFunc("/groups", groupService.Groups).Methods("GET")
+	authRoute.HandleFunc("/groups", groupService.AddGroup).Methods("POST")
+	authRoute.HandleFunc("/groups/{id:[0-9]+}", groupService.GetGroup).Methods("GET")
+	authRoute.HandleFunc("/groups/{id:[0-9]+}", groupService.UpdateGroup).Methods("PUT")
+	authRoute.HandleFunc("/groups/remove/{id:[0-9]+}", groupService.RemoveGroup).Methods("POST")
+	authRoute.HandleFunc("/groups/{id:[0-9]+}/invite", groupService.Invite).Methods("POST")
+	authRoute.HandleFunc("/groups/{id:[0-9]+}/leave", groupService.Leave).Methods("POST")
+	authRoute.HandleFunc("/groups/{id:[0-9]+}/transfer", groupService.TransferOwnership).Methods("POST")
+	authRoute.HandleFunc("/groups/{id:[0-9]+}/members/{user_id:[0-9]+}", groupService.SetMemberRole).Methods("PUT")
+	authRoute.HandleFunc("/groups/{id:[0-9]+}/members/remove/{user_id:[0-9]+}", groupService.RemoveMember).Methods("POST")
+	authRoute.HandleFunc("/invitations", groupService.Invitations).Methods("GET")
+	authRoute.HandleFunc("/invitations/accept", groupService.AcceptInvitation).Methods("POST")
+	authRoute.HandleFunc("/invitations/decline", groupService.DeclineInvitation).Methods("POST")
+
+	// Dependent profiles
+	authRoute.HandleFunc("/dependents", dependentService.Dependents).Methods("GET")
+	authRoute.HandleFunc("/dependents", dependentService.AddDependent).Methods("POST")
+	authRoute.HandleFunc("/dependents/{id:[0-9]+}", dependentService.GetDependent).Methods("GET")
+	authRoute.HandleFunc("/dependents/{id:[0-9]+}", dependentService.UpdateDependent).Methods("PUT")
+	authRoute.HandleFunc("/dependents/remove/{id:[0-9]+}", dependentService.RemoveDependent).Methods("POST")
+	authRoute.HandleFunc("/dependents/{id:[0-9]+}/audit", dependentService.AuditTrail).Methods("GET")
+
+	// Emergency ID, contacts and SOS alerts
+	authRoute.HandleFunc("/emergency", emergencyService.GetProfile).Methods("GET")
+	authRoute.HandleFunc("/emergency", emergencyService.UpdateProfile).Methods("PUT")
+	authRoute.HandleFunc("/emergency/rotate", emergencyService.RotateLink).Methods("POST")
+	authRoute.HandleFunc("/emergency/revoke", emergencyService.RevokeLink).Methods("POST")
+	authRoute.HandleFunc("/emergency/qr", emergencyService.QRCode).Methods("GET")
+	authRoute.HandleFunc("/emergency/contacts", emergencyService.Contacts).Methods("GET")
+	authRoute.HandleFunc("/emergency/contacts", emergencyService.AddContact).Methods("POST")
+	authRoute.HandleFunc("/emergency/contacts/{id:[0-9]+}", emergencyService.UpdateContact).Methods("PUT")
+	authRoute.HandleFunc("/emergency/contacts/remove/{id:[0-9]+}", emergencyService.RemoveContact).Methods("POST")
+	authRoute.HandleFunc("/sos", sosService.SOS).Methods("POST")
+	authRoute.HandleFunc("/sos", sosService.Alerts).Methods("GET")
+	authRoute.HandleFunc("/sos/{id:[0-9]+}", sosService.GetAlert).Methods("GET")
+
+	// Offline drug catalog
+	authRoute.HandleFunc("/catalog/search", catalogService.Search).Methods("GET")
+	authRoute.HandleFunc("/catalog/barcode/{gtin}", catalogService.Barcode).Methods("GET")
+
+	// Intake schedules and dose logging
+	authRoute.HandleFunc("/schedules", scheduleService.Schedules).Methods("GET")
+	authRoute.HandleFunc("/schedules", scheduleService.AddSchedule).Methods("POST")
+	authRoute.HandleFunc("/schedules/remove/{id:[0-9]+}", scheduleService.RemoveSchedule).Methods("POST")
+	authRoute.HandleFunc("/doses/upcoming", scheduleService.UpcomingDoses).Methods("GET")
+	authRoute.HandleFunc("/doses/log", scheduleService.LogDose).Methods("POST")
+
+	// Notifications (e.g. expiring drugs)
+	authRoute.HandleFunc("/notifications", notificationService.Notifications).Methods("GET")
+	authRoute.HandleFunc("/notifications/read/{id:[0-9]+}", notificationService.MarkRead).Methods("POST")
+	authRoute.HandleFunc("/notifications/read_all", notificationService.MarkAllRead).Methods("POST")
 
 	// Personal documents (medical prescriptions, illness records etc)
 	authRoute.HandleFunc("/documents", documentsService.Documents).Methods("GET")
 	authRoute.HandleFunc("/documents/add", documentsService.AddDocument).Methods("POST")
+	authRoute.HandleFunc("/documents/upload", documentsService.UploadDocument).Methods("POST")
+	authRoute.HandleFunc("/documents/{id:[0-9]+}/file", documentsService.DocumentFile).Methods("GET")
+	authRoute.HandleFunc("/documents/{id:[0-9]+}/thumbnail", documentsService.DocumentThumbnail).Methods("GET")
 	authRoute.HandleFunc("/documents/remove/{id:[0-9]+}", documentsService.RemoveDocument).Methods("POST")
+	authRoute.HandleFunc("/documents/{id:[0-9]+}/share", documentsService.ShareDocument).Methods("POST")
+	authRoute.HandleFunc("/documents/{id:[0-9]+}/shares", documentsService.DocumentShares).Methods("GET")
+	authRoute.HandleFunc("/documents/shares/revoke/{id:[0-9]+}", documentsService.RevokeShare).Methods("POST")
 
 	// Chat with AI
 	authRoute.HandleFunc("/chats", chatService.GetUsersChats).Methods("GET")
@@ -0,0 +1,137 @@
+package icd10
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed data/icd10.json
+var bundled []byte
+
+// codePattern matches ICD-10 codes such as "J45" or "T78.4".
+var codePattern = regexp.MustCompile(`^[A-Z][0-9]{2}(\.[0-9A-Z]{1,4})?$`)
+
+// Code is an ICD-10 code with its titles.
+type Code struct {
+	Code    string `json:"code" example:"J45.0"`
+	Title   string `json:"title" example:"Predominantly allergic asthma"`
+	TitleRu string `json:"title_ru" example:"Астма с преобладанием аллергического компонента"`
+}
+
+// Dataset is a list of ICD-10 codes to look up. The bundled one covers common allergies
+// and chronic conditions, a complete classification can be loaded from a file.
+type Dataset struct {
+	Version string `json:"version"`
+	Codes   []Code `json:"codes"`
+
+	byCode map[string]*Code // Codes by normalized code
+	texts  []string         // Normalized code and titles of every code, same order as Codes
+}
+
+// Default returns the dataset bundled with the binary.
+func Default() (*Dataset, error) {
+	return Load(bytes.NewReader(bundled))
+}
+
+// LoadFile reads a dataset from a JSON file in the format of the bundled one.
+func LoadFile(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load parses and validates a dataset.
+func Load(r io.Reader) (*Dataset, error) {
+	dataset := &Dataset{}
+	if err := json.NewDecoder(r).Decode(dataset); err != nil {
+		return nil, fmt.Errorf("invalid ICD-10 dataset: %w", err)
+	}
+
+	// Codes are sorted after normalizing, search results are returned in this order
+	for i := range dataset.Codes {
+		dataset.Codes[i].Code = Normalize(dataset.Codes[i].Code)
+	}
+	sort.Slice(dataset.Codes, func(i, j int) bool { return dataset.Codes[i].Code < dataset.Codes[j].Code })
+	dataset.byCode = map[string]*Code{}
+	for i := range dataset.Codes {
+		code := &dataset.Codes[i]
+		if !Valid(code.Code) {
+			return nil, fmt.Errorf("invalid ICD-10 code %q", code.Code)
+		}
+		if _, ok := dataset.byCode[code.Code]; ok {
+			return nil, fmt.Errorf("duplicate ICD-10 code %q", code.Code)
+		}
+		dataset.byCode[code.Code] = code
+		dataset.texts = append(dataset.texts, normalizeText(code.Title+" "+code.TitleRu))
+	}
+	return dataset, nil
+}
+
+// Normalize uppercases a code and removes spaces, e.g. " j45.0" becomes "J45.0".
+func Normalize(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(code, " ", ""))
+}
+
+// Valid reports whether the normalized code has the ICD-10 format.
+// The code does not have to be in a dataset.
+func Valid(code string) bool {
+	return codePattern.MatchString(code)
+}
+
+// Lookup returns the code from the dataset.
+func (d *Dataset) Lookup(code string) (*Code, bool) {
+	found, ok := d.byCode[Normalize(code)]
+	return found, ok
+}
+
+// Search returns up to limit codes starting with the query, e.g. "J45",
+// or with titles containing every word of it, e.g. "allergic asthma" or "астма".
+func (d *Dataset) Search(query string, limit int) []Code {
+	result := []Code{}
+
+	prefix := Normalize(query)
+	words := strings.Fields(normalizeText(query))
+	if len(words) == 0 {
+		return result
+	}
+
+	for i, code := range d.Codes {
+		if len(result) == limit {
+			break
+		}
+		if strings.HasPrefix(code.Code, prefix) || containsAll(d.texts[i], words) {
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+// containsAll reports whether the text contains a word starting with each of the words.
+func containsAll(text string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(text, " "+word) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeText lowercases text and replaces everything but letters and digits with single spaces.
+// The result starts with a space, so word prefixes can be matched with strings.Contains.
+func normalizeText(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(fields, " ")
+}
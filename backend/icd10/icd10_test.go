@@ -0,0 +1,130 @@
+package icd10
+
+import (
+	"strings"
+	"testing"
+)
+
+// testDataset is a small dataset, deliberately out of order.
+const testDataset = `{
+  "version": "test",
+  "codes": [
+    {"code": "J45.0", "title": "Predominantly allergic asthma", "title_ru": "Астма с преобладанием аллергического компонента"},
+    {"code": "j45", "title": "Asthma", "title_ru": "Астма"},
+    {"code": "E11", "title": "Type 2 diabetes mellitus", "title_ru": "Сахарный диабет 2 типа"},
+    {"code": "T78.4", "title": "Allergy, unspecified", "title_ru": "Аллергия неуточненная"},
+    {"code": "I10", "title": "Essential (primary) hypertension", "title_ru": "Эссенциальная (первичная) гипертензия"}
+  ]
+}`
+
+func loadTest(t *testing.T) *Dataset {
+	t.Helper()
+	dataset, err := Load(strings.NewReader(testDataset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dataset
+}
+
+// codes returns the codes of the search results joined with commas.
+func codes(result []Code) string {
+	var list []string
+	for _, code := range result {
+		list = append(list, code.Code)
+	}
+	return strings.Join(list, ",")
+}
+
+func TestSearch(t *testing.T) {
+	dataset := loadTest(t)
+	for query, want := range map[string]string{
+		"J45":             "J45,J45.0", // Code prefixes, results are sorted by code
+		"j45.":            "J45.0",
+		" t78":            "T78.4",
+		"asthma":          "J45,J45.0",
+		"Allergic ASTHMA": "J45.0",
+		"asthma allergic": "J45.0", // Words match in any order
+		"allerg":          "J45.0,T78.4",
+		"астма":           "J45,J45.0",
+		"диабет 2":        "E11",
+		"(primary)":       "I10",
+		"sthma":           "", // Words match at their start only
+		"asthma diabetes": "",
+		"Z99":             "",
+		"":                "",
+		"  ,. ":           "",
+	} {
+		if got := codes(dataset.Search(query, 10)); got != want {
+			t.Errorf("Search(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	dataset := loadTest(t)
+	if got := codes(dataset.Search("a", 2)); got != "J45,J45.0" {
+		t.Errorf("Search limited to 2 = %q", got)
+	}
+	if result := dataset.Search("nothing", 10); result == nil {
+		t.Error("Search returned null instead of an empty list")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dataset := loadTest(t)
+	code, ok := dataset.Lookup(" j45.0")
+	if !ok || code.Code != "J45.0" || code.Title != "Predominantly allergic asthma" {
+		t.Errorf("Lookup = %+v, %v", code, ok)
+	}
+	// Codes of the file are normalized
+	if _, ok := dataset.Lookup("J45"); !ok {
+		t.Error("J45 not found")
+	}
+	if _, ok := dataset.Lookup("J45.1"); ok {
+		t.Error("J45.1 found")
+	}
+}
+
+func TestValid(t *testing.T) {
+	for code, want := range map[string]bool{
+		"J45":       true,
+		"J45.0":     true,
+		"T78.4":     true,
+		"M54.16":    true,
+		"S72.001A":  true,
+		"J4":        false,
+		"J45.":      false,
+		"45.0":      false,
+		"j45":       false, // Codes have to be normalized first
+		"J45.12345": false,
+	} {
+		if got := Valid(code); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"invalid JSON":   `{"codes": [`,
+		"invalid code":   `{"codes": [{"code": "asthma"}]}`,
+		"duplicate code": `{"codes": [{"code": "J45"}, {"code": " j45"}]}`,
+	} {
+		if _, err := Load(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	dataset, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataset.Codes) == 0 || dataset.Version == "" {
+		t.Errorf("bundled dataset has %d codes, version %q", len(dataset.Codes), dataset.Version)
+	}
+	if result := dataset.Search("asthma", 5); len(result) == 0 {
+		t.Error("no asthma in the bundled dataset")
+	}
+}
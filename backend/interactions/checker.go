@@ -0,0 +1,202 @@
+package interactions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of warnings
+const (
+	KindAllergy       = "allergy"        // The drug contains something the user is allergic to
+	KindCrossReaction = "cross_reaction" // The drug is related to something the user is allergic to
+	KindInteraction   = "interaction"    // Two drugs interact
+	KindDuplicate     = "duplicate"      // Two drugs share an active ingredient
+)
+
+// Drug is a drug of the user as seen by the checker.
+type Drug struct {
+	ID   uint
+	Name string
+}
+
+// Warning describes a potential problem with one or two drugs.
+type Warning struct {
+	Kind     string   `json:"kind" example:"interaction"`                       // allergy, cross_reaction, interaction or duplicate
+	Severity string   `json:"severity" example:"major"`                         // contraindicated, major, moderate or minor
+	Rule     string   `json:"rule" example:"interaction:anticoagulants+nsaids"` // Rule of the dataset that triggered the warning
+	DrugIDs  []uint   `json:"drug_ids"`                                         // Drugs the warning is about
+	Drugs    []string `json:"drugs"`                                            // Names of these drugs
+	Message  string   `json:"message"`                                          // Explanation for the user
+}
+
+// Involves reports whether the warning is about the given drug.
+func (w Warning) Involves(drugID uint) bool {
+	for _, id := range w.DrugIDs {
+		if id == drugID {
+			return true
+		}
+	}
+	return false
+}
+
+// Allergy is an entry of the user's allergy list as seen by the checker.
+type Allergy struct {
+	Text     string // Substance, name or free text, e.g. "penicillin" or "rash after aspirin"
+	Reaction string // Optional reaction, mentioned in the warning
+}
+
+// identified is a drug with the ingredients and classes found in its name.
+type identified struct {
+	Drug
+	ingredients []string
+	names       map[string]bool // Ingredients and their classes
+}
+
+// Check returns the warnings for a set of drugs taken by a user with the given allergies,
+// most severe first.
+func (d *Dataset) Check(drugs []Drug, allergies []Allergy) []Warning {
+	items := make([]identified, 0, len(drugs))
+	for _, drug := range drugs {
+		items = append(items, d.identify(drug))
+	}
+
+	warnings := d.checkAllergies(items, allergies)
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			warnings = append(warnings, d.checkPair(items[i], items[j])...)
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return severityRank[warnings[i].Severity] > severityRank[warnings[j].Severity]
+	})
+	return warnings
+}
+
+// identify finds the ingredients of the drug and their classes.
+func (d *Dataset) identify(drug Drug) identified {
+	item := identified{Drug: drug, ingredients: d.Identify(drug.Name), names: map[string]bool{}}
+	for _, ingredient := range item.ingredients {
+		item.names[ingredient] = true
+		for _, class := range d.ingredients[ingredient] {
+			item.names[class] = true
+		}
+	}
+	return item
+}
+
+// checkAllergies warns about drugs containing allergens or classes that cross-react with them.
+func (d *Dataset) checkAllergies(items []identified, allergies []Allergy) []Warning {
+	// Allergens mentioned in the entries with the reaction of the first entry that has one
+	var allergicIngredients, allergicClasses []string
+	reactions := map[string]string{}
+	for _, allergy := range allergies {
+		ingredients, classes := d.match(allergy.Text)
+		for _, name := range append(append([]string{}, ingredients...), classes...) {
+			reaction, seen := reactions[name]
+			if !seen {
+				if d.classes[name] {
+					allergicClasses = append(allergicClasses, name)
+				} else {
+					allergicIngredients = append(allergicIngredients, name)
+				}
+			}
+			if reaction == "" {
+				reactions[name] = allergy.Reaction
+			}
+		}
+	}
+	if len(allergicIngredients) == 0 && len(allergicClasses) == 0 {
+		return nil
+	}
+
+	// Allergy to an ingredient counts as allergy to its classes for cross reactions
+	allergic := map[string]bool{}
+	for _, class := range allergicClasses {
+		allergic[class] = true
+	}
+	for _, ingredient := range allergicIngredients {
+		for _, class := range d.ingredients[ingredient] {
+			allergic[class] = true
+		}
+	}
+
+	allergens := append(append([]string{}, allergicIngredients...), allergicClasses...)
+
+	var warnings []Warning
+	for _, item := range items {
+		direct := false
+		for _, allergen := range allergens {
+			if !item.names[allergen] {
+				continue
+			}
+			direct = true
+			listed := allergen
+			if reactions[allergen] != "" {
+				listed += ", reaction: " + reactions[allergen]
+			}
+			warnings = append(warnings, Warning{
+				Kind:     KindAllergy,
+				Severity: SeverityContraindicated,
+				Rule:     "allergy:" + allergen,
+				DrugIDs:  []uint{item.ID},
+				Drugs:    []string{item.Name},
+				Message:  fmt.Sprintf("%s contains %s, which is listed in your allergies (%s).", item.Name, strings.Join(item.ingredients, ", "), listed),
+			})
+		}
+		if direct {
+			continue
+		}
+
+		for _, rule := range d.CrossReactions {
+			if !allergic[rule.Allergy] || !item.names[rule.Target] {
+				continue
+			}
+			warnings = append(warnings, Warning{
+				Kind:     KindCrossReaction,
+				Severity: rule.Severity,
+				Rule:     "cross_reaction:" + rule.Allergy + ">" + rule.Target,
+				DrugIDs:  []uint{item.ID},
+				Drugs:    []string{item.Name},
+				Message:  rule.Description,
+			})
+		}
+	}
+	return warnings
+}
+
+// checkPair warns about interactions and shared ingredients of two drugs.
+func (d *Dataset) checkPair(a, b identified) []Warning {
+	var warnings []Warning
+	pair := func(kind, severity, rule, message string) {
+		warnings = append(warnings, Warning{
+			Kind:     kind,
+			Severity: severity,
+			Rule:     rule,
+			DrugIDs:  []uint{a.ID, b.ID},
+			Drugs:    []string{a.Name, b.Name},
+			Message:  message,
+		})
+	}
+
+	shared := false
+	for _, ingredient := range a.ingredients {
+		if b.names[ingredient] {
+			shared = true
+			pair(KindDuplicate, SeverityModerate, "duplicate:"+ingredient,
+				fmt.Sprintf("Both drugs contain %s, taking them together may lead to an overdose.", ingredient))
+		}
+	}
+
+	for _, rule := range d.Interactions {
+		// Two drugs with the same ingredient are already reported as duplicates
+		if shared && rule.A == rule.B {
+			continue
+		}
+		if (a.names[rule.A] && b.names[rule.B]) || (a.names[rule.B] && b.names[rule.A]) {
+			pair(KindInteraction, rule.Severity, "interaction:"+rule.A+"+"+rule.B, rule.Description)
+		}
+	}
+	return warnings
+}
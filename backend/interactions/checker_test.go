@@ -0,0 +1,108 @@
+package interactions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rules returns the rules of the warnings in order.
+func rules(warnings []Warning) []string {
+	result := []string{}
+	for _, warning := range warnings {
+		result = append(result, warning.Rule)
+	}
+	return result
+}
+
+func TestCheckPair(t *testing.T) {
+	dataset := loadTestDataset(t)
+	for _, test := range []struct {
+		a, b  string
+		rules []string
+	}{
+		{"Warfarin", "Nurofen", []string{"interaction:anticoagulants+nsaids"}},
+		{"Nurofen", "Warfarin", []string{"interaction:anticoagulants+nsaids"}},
+		{"Warfarin", "Panadol", []string{"interaction:warfarin+paracetamol"}},
+		// Two NSAIDs interact, the same NSAID twice is a duplicate only
+		{"Nurofen", "Aspirin", []string{"interaction:nsaids+nsaids"}},
+		{"Nurofen", "Ibuprofen 200", []string{"duplicate:ibuprofen"}},
+		{"Amoxil", "Warfarin", []string{}},
+		{"Vitamin C", "Warfarin", []string{}},
+	} {
+		warnings := dataset.checkPair(dataset.identify(Drug{ID: 1, Name: test.a}), dataset.identify(Drug{ID: 2, Name: test.b}))
+		if got := rules(warnings); !reflect.DeepEqual(got, test.rules) {
+			t.Errorf("checkPair(%s, %s) = %v, want %v", test.a, test.b, got, test.rules)
+		}
+		for _, warning := range warnings {
+			if !reflect.DeepEqual(warning.DrugIDs, []uint{1, 2}) || !reflect.DeepEqual(warning.Drugs, []string{test.a, test.b}) {
+				t.Errorf("checkPair(%s, %s) warning is about %v %v", test.a, test.b, warning.DrugIDs, warning.Drugs)
+			}
+		}
+	}
+
+	duplicate := dataset.checkPair(dataset.identify(Drug{ID: 1, Name: "Nurofen"}), dataset.identify(Drug{ID: 2, Name: "Ibuprofen"}))
+	if duplicate[0].Kind != KindDuplicate || duplicate[0].Severity != SeverityModerate || !strings.Contains(duplicate[0].Message, "ibuprofen") {
+		t.Errorf("duplicate warning = %+v", duplicate[0])
+	}
+}
+
+func TestCheckAllergies(t *testing.T) {
+	dataset := loadTestDataset(t)
+	drugs := []Drug{{ID: 1, Name: "Amoxil"}, {ID: 2, Name: "Keflex"}, {ID: 3, Name: "Nurofen"}}
+	items := []identified{}
+	for _, drug := range drugs {
+		items = append(items, dataset.identify(drug))
+	}
+
+	for _, test := range []struct {
+		name      string
+		allergies []Allergy
+		rules     []string
+	}{
+		{"no allergies", nil, []string{}},
+		{"unknown allergen", []Allergy{{Text: "pollen"}}, []string{}},
+		// A class allergy warns about its ingredients and the classes it cross-reacts with
+		{"class", []Allergy{{Text: "Penicillin"}}, []string{"allergy:penicillins", "cross_reaction:penicillins>cephalosporins"}},
+		// An ingredient allergy counts for the classes of the ingredient in cross reactions
+		{"ingredient", []Allergy{{Text: "rash after amoxicillin"}}, []string{"allergy:amoxicillin", "cross_reaction:penicillins>cephalosporins"}},
+		{"two allergies", []Allergy{{Text: "penicillin"}, {Text: "NSAID"}},
+			[]string{"allergy:penicillins", "cross_reaction:penicillins>cephalosporins", "allergy:nsaids"}},
+	} {
+		warnings := dataset.checkAllergies(items, test.allergies)
+		if got := rules(warnings); !reflect.DeepEqual(got, test.rules) {
+			t.Errorf("%s: checkAllergies = %v, want %v", test.name, got, test.rules)
+		}
+	}
+
+	// The reaction of the first entry that has one is mentioned
+	warnings := dataset.checkAllergies(items[:1], []Allergy{{Text: "penicillin"}, {Text: "penicillins", Reaction: "hives"}})
+	if len(warnings) != 1 || warnings[0].Kind != KindAllergy || warnings[0].Severity != SeverityContraindicated ||
+		!strings.Contains(warnings[0].Message, "reaction: hives") || !reflect.DeepEqual(warnings[0].DrugIDs, []uint{1}) {
+		t.Errorf("checkAllergies = %+v", warnings)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dataset := loadTestDataset(t)
+	drugs := []Drug{{ID: 1, Name: "Panadol"}, {ID: 2, Name: "Warfarin"}, {ID: 3, Name: "Nurofen"}, {ID: 4, Name: "Amoxil"}}
+
+	warnings := dataset.Check(drugs, []Allergy{{Text: "penicillin"}})
+	want := []string{"allergy:penicillins", "interaction:anticoagulants+nsaids", "interaction:warfarin+paracetamol"}
+	if got := rules(warnings); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Check = %v, want %v", got, want)
+	}
+	// Most severe first
+	for i := 1; i < len(warnings); i++ {
+		if severityRank[warnings[i].Severity] > severityRank[warnings[i-1].Severity] {
+			t.Errorf("warning %d (%s) is more severe than warning %d (%s)", i, warnings[i].Severity, i-1, warnings[i-1].Severity)
+		}
+	}
+	if !warnings[1].Involves(3) || warnings[1].Involves(1) {
+		t.Errorf("Involves of %v is wrong", warnings[1].DrugIDs)
+	}
+
+	if got := dataset.Check(nil, nil); len(got) != 0 {
+		t.Errorf("Check without drugs = %v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package interactions
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Severities of warnings, from the most to the least dangerous
+const (
+	SeverityContraindicated = "contraindicated" // Must not be combined
+	SeverityMajor           = "major"           // Avoid unless a doctor says otherwise
+	SeverityModerate        = "moderate"        // Use with caution
+	SeverityMinor           = "minor"           // Usually harmless, worth knowing
+)
+
+// severityRank orders severities, higher is more dangerous.
+var severityRank = map[string]int{
+	SeverityContraindicated: 4,
+	SeverityMajor:           3,
+	SeverityModerate:        2,
+	SeverityMinor:           1,
+}
+
+//go:embed data/interactions.json
+var bundled []byte
+
+// Class is a group of related ingredients, e.g. penicillins or NSAIDs.
+type Class struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// Ingredient is an active ingredient with the brand and local names it is sold under.
+type Ingredient struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	Classes []string `json:"classes"`
+}
+
+// CrossReaction warns that an allergy to one class may extend to another.
+type CrossReaction struct {
+	Allergy     string `json:"allergy"` // Class the user is allergic to
+	Target      string `json:"target"`  // Class that may cause a reaction as well
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Interaction is a pairwise rule between ingredients or classes.
+type Interaction struct {
+	A           string `json:"a"` // Ingredient or class name
+	B           string `json:"b"` // Ingredient or class name
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Dataset holds the rules the checker works with.
+type Dataset struct {
+	Version        string          `json:"version"`
+	Classes        []Class         `json:"classes"`
+	Ingredients    []Ingredient    `json:"ingredients"`
+	CrossReactions []CrossReaction `json:"cross_reactions"`
+	Interactions   []Interaction   `json:"interactions"`
+
+	classes     map[string]bool     // Known class names
+	ingredients map[string][]string // Ingredient name to its classes
+	terms       []term              // Names and aliases to look for in text
+}
+
+// term is a searchable name of an ingredient or a class.
+type term struct {
+	text    string // Normalized text, e.g. " acetylsalicylic acid "
+	name    string // Ingredient or class it refers to
+	isClass bool
+}
+
+// Default returns the dataset bundled with the binary.
+func Default() (*Dataset, error) {
+	return Load(bytes.NewReader(bundled))
+}
+
+// LoadFile reads a dataset from a JSON file, used to ship updated rules without a rebuild.
+func LoadFile(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load parses and validates a dataset.
+func Load(r io.Reader) (*Dataset, error) {
+	dataset := &Dataset{}
+	if err := json.NewDecoder(r).Decode(dataset); err != nil {
+		return nil, fmt.Errorf("invalid interactions dataset: %w", err)
+	}
+	if err := dataset.index(); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// index builds the lookup tables and checks that every rule refers to known names.
+func (d *Dataset) index() error {
+	d.classes = map[string]bool{}
+	d.ingredients = map[string][]string{}
+	d.terms = nil
+
+	for _, class := range d.Classes {
+		d.classes[class.Name] = true
+		for _, text := range append([]string{class.Name}, class.Aliases...) {
+			d.terms = append(d.terms, term{text: normalize(text), name: class.Name, isClass: true})
+		}
+	}
+	for _, ingredient := range d.Ingredients {
+		for _, class := range ingredient.Classes {
+			if !d.classes[class] {
+				return fmt.Errorf("ingredient %q refers to unknown class %q", ingredient.Name, class)
+			}
+		}
+		d.ingredients[ingredient.Name] = ingredient.Classes
+		for _, text := range append([]string{ingredient.Name}, ingredient.Aliases...) {
+			d.terms = append(d.terms, term{text: normalize(text), name: ingredient.Name})
+		}
+	}
+
+	for _, rule := range d.CrossReactions {
+		if !d.classes[rule.Allergy] || !d.classes[rule.Target] {
+			return fmt.Errorf("cross reaction %s>%s refers to an unknown class", rule.Allergy, rule.Target)
+		}
+		if severityRank[rule.Severity] == 0 {
+			return fmt.Errorf("cross reaction %s>%s has unknown severity %q", rule.Allergy, rule.Target, rule.Severity)
+		}
+	}
+	for _, rule := range d.Interactions {
+		for _, name := range []string{rule.A, rule.B} {
+			if _, ok := d.ingredients[name]; !ok && !d.classes[name] {
+				return fmt.Errorf("interaction %s+%s refers to unknown name %q", rule.A, rule.B, name)
+			}
+		}
+		if severityRank[rule.Severity] == 0 {
+			return fmt.Errorf("interaction %s+%s has unknown severity %q", rule.A, rule.B, rule.Severity)
+		}
+	}
+	return nil
+}
+
+// normalize lowercases text and replaces everything but letters and digits with single spaces.
+// The result is padded with spaces, so whole words can be matched with strings.Contains.
+func normalize(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
+
+// match returns the ingredient and class names mentioned in the text.
+func (d *Dataset) match(text string) (ingredients, classes []string) {
+	normalized := normalize(text)
+	seen := map[string]bool{}
+	for _, t := range d.terms {
+		if seen[t.name] || !strings.Contains(normalized, t.text) {
+			continue
+		}
+		seen[t.name] = true
+		if t.isClass {
+			classes = append(classes, t.name)
+		} else {
+			ingredients = append(ingredients, t.name)
+		}
+	}
+	return ingredients, classes
+}
+
+// Identify returns the active ingredients a drug name refers to, e.g. "Nurofen Forte" is ibuprofen.
+func (d *Dataset) Identify(name string) []string {
+	ingredients, _ := d.match(name)
+	return ingredients
+}
@@ -0,0 +1,108 @@
+package interactions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testDataset is a small dataset with one rule of every kind.
+const testDataset = `{
+	"version": "test",
+	"classes": [
+		{"name": "penicillins", "aliases": ["penicillin"]},
+		{"name": "cephalosporins"},
+		{"name": "nsaids", "aliases": ["nsaid"]},
+		{"name": "anticoagulants"}
+	],
+	"ingredients": [
+		{"name": "amoxicillin", "aliases": ["amoxil", "флемоксин"], "classes": ["penicillins"]},
+		{"name": "cefalexin", "aliases": ["keflex"], "classes": ["cephalosporins"]},
+		{"name": "ibuprofen", "aliases": ["nurofen"], "classes": ["nsaids"]},
+		{"name": "acetylsalicylic acid", "aliases": ["aspirin"], "classes": ["nsaids"]},
+		{"name": "warfarin", "classes": ["anticoagulants"]},
+		{"name": "paracetamol", "aliases": ["panadol"], "classes": []}
+	],
+	"cross_reactions": [
+		{"allergy": "penicillins", "target": "cephalosporins", "severity": "moderate", "description": "Cross-reactivity is possible."}
+	],
+	"interactions": [
+		{"a": "anticoagulants", "b": "nsaids", "severity": "major", "description": "Risk of bleeding."},
+		{"a": "nsaids", "b": "nsaids", "severity": "moderate", "description": "Two NSAIDs at once."},
+		{"a": "warfarin", "b": "paracetamol", "severity": "minor", "description": "May raise INR."}
+	]
+}`
+
+// loadTestDataset parses testDataset.
+func loadTestDataset(t *testing.T) *Dataset {
+	t.Helper()
+	dataset, err := Load(strings.NewReader(testDataset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dataset
+}
+
+func TestDefault(t *testing.T) {
+	dataset, err := Default()
+	if err != nil {
+		t.Fatalf("bundled dataset: %v", err)
+	}
+	if dataset.Version == "" || len(dataset.Interactions) == 0 || len(dataset.CrossReactions) == 0 {
+		t.Errorf("bundled dataset %q has %d interactions and %d cross reactions", dataset.Version, len(dataset.Interactions), len(dataset.CrossReactions))
+	}
+	if got := dataset.Identify("Nurofen Forte"); !reflect.DeepEqual(got, []string{"ibuprofen"}) {
+		t.Errorf("Identify(Nurofen Forte) = %v, want [ibuprofen]", got)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		dataset string
+		err     string
+	}{
+		{"invalid JSON", `{"classes": [`, "invalid interactions dataset"},
+		{"unknown class of an ingredient", `{"ingredients": [{"name": "ibuprofen", "classes": ["nsaids"]}]}`, `unknown class "nsaids"`},
+		{"unknown class of a cross reaction",
+			`{"classes": [{"name": "penicillins"}], "cross_reactions": [{"allergy": "penicillins", "target": "cephalosporins", "severity": "moderate"}]}`,
+			"cross reaction penicillins>cephalosporins refers to an unknown class"},
+		{"unknown severity of a cross reaction",
+			`{"classes": [{"name": "penicillins"}], "cross_reactions": [{"allergy": "penicillins", "target": "penicillins", "severity": "severe"}]}`,
+			`unknown severity "severe"`},
+		{"unknown name in an interaction",
+			`{"classes": [{"name": "nsaids"}], "interactions": [{"a": "nsaids", "b": "warfarin", "severity": "major"}]}`,
+			`unknown name "warfarin"`},
+		{"unknown severity of an interaction",
+			`{"classes": [{"name": "nsaids"}], "interactions": [{"a": "nsaids", "b": "nsaids", "severity": ""}]}`,
+			`unknown severity ""`},
+	} {
+		_, err := Load(strings.NewReader(test.dataset))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: Load = %v, want an error containing %q", test.name, err, test.err)
+		}
+	}
+
+	if _, err := Load(strings.NewReader(testDataset)); err != nil {
+		t.Errorf("Load(testDataset) = %v", err)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	dataset := loadTestDataset(t)
+	for name, want := range map[string][]string{
+		"Nurofen Forte 400mg":     {"ibuprofen"},
+		"AMOXIL":                  {"amoxicillin"},
+		"Флемоксин Солютаб":       {"amoxicillin"},
+		"Aspirin-Cardio":          {"acetylsalicylic acid"},
+		"Acetylsalicylic   acid":  {"acetylsalicylic acid"},
+		"Panadol + Nurofen":       {"ibuprofen", "paracetamol"},
+		"Nurofenix":               nil, // Only whole words match
+		"Penicillin (class only)": nil, // Classes are no ingredients
+		"":                        nil,
+	} {
+		if got := dataset.Identify(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("Identify(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"first_aid_companion/encryption"
+	"first_aid_companion/services"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// keysUsage describes the keys subcommand.
+const keysUsage = `usage: main keys <command>
+
+commands:
+  status     list data keys by state and by the master key wrapping them
+  rotate     retire every data key and rewrap the rest with the current master key,
+             the server re-encrypts the data with new keys in the background
+  rewrap     rewrap data keys with the current master key, e.g. after adding a new one
+  reencrypt  re-encrypt data of retired keys now instead of waiting for the server`
+
+// setupKeyring creates the keyring from the master keys and makes the models encrypt with it.
+// Without master keys data is stored in plaintext, unless some of it is encrypted already.
+func setupKeyring(dbService *services.DBService, masterKeys []encryption.MasterKey) error {
+	dbService.Keyring = encryption.NewKeyring(dbService.KeyDB, masterKeys)
+	encryption.Register(dbService.Keyring)
+	if dbService.Keyring.Enabled() {
+		return nil
+	}
+
+	stats, err := dbService.KeyDB.Stats()
+	if err != nil {
+		return err
+	}
+	if stats.Active+stats.Retired > 0 {
+		return errors.New("data is encrypted, set MASTER_KEY or MASTER_KEY_FILE")
+	}
+	log.Println("No master key is configured, sensitive data is stored unencrypted")
+	return nil
+}
+
+// runKeys executes the "keys" subcommand with the given arguments.
+func runKeys(dbService *services.DBService, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("invalid arguments\n%s", keysUsage)
+	}
+	keyring := dbService.Keyring
+	if !keyring.Enabled() {
+		return errors.New("no master key is configured")
+	}
+
+	switch args[0] {
+	case "status":
+		stats, err := dbService.KeyDB.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Current master key: %s\n", keyring.CurrentMasterKeyID())
+		fmt.Printf("Data keys: %d active, %d retired\n", stats.Active, stats.Retired)
+		masterIDs := make([]string, 0, len(stats.ByMasterKeys))
+		for id := range stats.ByMasterKeys {
+			masterIDs = append(masterIDs, id)
+		}
+		sort.Strings(masterIDs)
+		for _, id := range masterIDs {
+			fmt.Printf("  wrapped with %s: %d\n", id, stats.ByMasterKeys[id])
+		}
+		return nil
+	case "rotate":
+		retired, rewrapped, err := keyring.Rotate()
+		log.Printf("Retired %d data keys, rewrapped %d with master key %s", retired, rewrapped, keyring.CurrentMasterKeyID())
+		return err
+	case "rewrap":
+		rewrapped, err := keyring.Rewrap()
+		log.Printf("Rewrapped %d data keys with master key %s", rewrapped, keyring.CurrentMasterKeyID())
+		return err
+	case "reencrypt":
+		reencryptor := services.NewReencryptor(keyring, dbService.KeyDB, dbService.DocsDB, dbService.Blobs, 0)
+		result, err := reencryptor.Pass(context.Background())
+		if result != nil {
+			log.Printf("Re-encrypted %d rows, %d files and %d thumbnails, %d failed, %d retired keys deleted",
+				result.Rows, result.Files, result.Thumbnails, result.Failed, result.DeletedKeys)
+		}
+		return err
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], keysUsage)
+}
@@ -0,0 +1,132 @@
+package kittemplates
+
+import (
+	"strings"
+	"time"
+)
+
+// Statuses of template items in a check report
+const (
+	StatusOK           = "ok"           // Enough unexpired items
+	StatusMissing      = "missing"      // Nothing matching the item
+	StatusExpired      = "expired"      // Only expired items
+	StatusInsufficient = "insufficient" // Some unexpired items, but not enough
+)
+
+// Supply is a drug or supply of a kit as seen by the check.
+type Supply struct {
+	ID       uint
+	Name     string
+	Quantity *float64 // Unknown quantities count as one item
+	Unit     string
+	Expiry   time.Time // Zero if unknown, such supplies never count as expired
+}
+
+// ItemReport is the state of one template item in a kit.
+type ItemReport struct {
+	ItemID         string  `json:"item_id"`
+	Name           string  `json:"name"`
+	Status         string  `json:"status" example:"insufficient"` // ok, missing, expired or insufficient
+	Required       float64 `json:"required"`
+	Available      float64 `json:"available"` // Unexpired amount in the kit
+	Unit           string  `json:"unit"`
+	DrugIDs        []uint  `json:"drug_ids"`         // Unexpired drugs counted for the item
+	ExpiredDrugIDs []uint  `json:"expired_drug_ids"` // Expired drugs that should be replaced
+}
+
+// Report is the result of comparing a kit with a template.
+type Report struct {
+	TemplateID   string       `json:"template_id"`
+	TemplateName string       `json:"template_name"`
+	Complete     bool         `json:"complete"`     // True if every item is ok
+	Missing      []string     `json:"missing"`      // IDs of missing items
+	Expired      []string     `json:"expired"`      // IDs of items with only expired drugs
+	Insufficient []string     `json:"insufficient"` // IDs of items with too few unexpired drugs
+	Items        []ItemReport `json:"items"`
+	Extra        []uint       `json:"extra"` // Drugs of the kit not required by the template
+}
+
+// Check compares the supplies of a kit with the template at the given time.
+// Every supply counts for the item whose alias matches its name most specifically,
+// so "plaster roll" is not counted as wound plasters.
+func (t *Template) Check(supplies []Supply, now time.Time) *Report {
+	report := &Report{
+		TemplateID:   t.ID,
+		TemplateName: t.Name,
+		Missing:      []string{},
+		Expired:      []string{},
+		Insufficient: []string{},
+		Items:        make([]ItemReport, 0, len(t.Items)),
+		Extra:        []uint{},
+	}
+
+	matched := make([][]Supply, len(t.Items))
+	for _, supply := range supplies {
+		if i := t.match(supply.Name); i >= 0 {
+			matched[i] = append(matched[i], supply)
+		} else {
+			report.Extra = append(report.Extra, supply.ID)
+		}
+	}
+
+	for i, item := range t.Items {
+		itemReport := ItemReport{
+			ItemID:         item.ID,
+			Name:           item.Name,
+			Required:       item.Quantity,
+			Unit:           item.Unit,
+			DrugIDs:        []uint{},
+			ExpiredDrugIDs: []uint{},
+		}
+		for _, supply := range matched[i] {
+			if !item.NoExpiry && !supply.Expiry.IsZero() && supply.Expiry.Before(now) {
+				itemReport.ExpiredDrugIDs = append(itemReport.ExpiredDrugIDs, supply.ID)
+				continue
+			}
+			itemReport.DrugIDs = append(itemReport.DrugIDs, supply.ID)
+			itemReport.Available += amount(supply, item)
+		}
+
+		switch {
+		case itemReport.Available >= item.Quantity:
+			itemReport.Status = StatusOK
+		case len(matched[i]) == 0:
+			itemReport.Status = StatusMissing
+			report.Missing = append(report.Missing, item.ID)
+		case len(itemReport.DrugIDs) == 0:
+			itemReport.Status = StatusExpired
+			report.Expired = append(report.Expired, item.ID)
+		default:
+			itemReport.Status = StatusInsufficient
+			report.Insufficient = append(report.Insufficient, item.ID)
+		}
+		report.Items = append(report.Items, itemReport)
+	}
+
+	report.Complete = len(report.Missing)+len(report.Expired)+len(report.Insufficient) == 0
+	return report
+}
+
+// match returns the index of the item with the longest alias found in the name, -1 if none.
+func (t *Template) match(name string) int {
+	normalized := normalize(name)
+	best, bestLen := -1, 0
+	for i, item := range t.Items {
+		for _, alias := range item.Aliases {
+			alias = normalize(alias)
+			if len(alias) > bestLen && strings.Contains(normalized, alias) {
+				best, bestLen = i, len(alias)
+			}
+		}
+	}
+	return best
+}
+
+// amount returns how much of the item a supply provides.
+// Quantities in another unit, e.g. a bottle of antiseptic counted in pieces, count as one item.
+func amount(supply Supply, item Item) float64 {
+	if supply.Quantity != nil && supply.Unit == item.Unit {
+		return *supply.Quantity
+	}
+	return 1
+}
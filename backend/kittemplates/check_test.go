@@ -0,0 +1,169 @@
+package kittemplates
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testTemplate is a small template covering the statuses of the check.
+var testTemplate = &Template{
+	ID:   "test",
+	Name: "Test kit",
+	Items: []Item{
+		{ID: "plaster_roll", Name: "Plaster roll", Aliases: []string{"plaster roll"}, Quantity: 1, Unit: "pieces"},
+		{ID: "plasters", Name: "Wound plasters", Aliases: []string{"plaster", "band aid"}, Quantity: 10, Unit: "pieces"},
+		{ID: "antiseptic", Name: "Antiseptic", Aliases: []string{"chlorhexidine", "антисептик"}, Quantity: 50, Unit: "ml"},
+		{ID: "painkiller", Name: "Painkiller", Aliases: []string{"ibuprofen"}, Quantity: 10, Unit: "tablets"},
+		{ID: "scissors", Name: "Scissors", Aliases: []string{"scissors"}, Quantity: 1, Unit: "pieces", NoExpiry: true},
+		{ID: "tourniquet", Name: "Tourniquet", Aliases: []string{"tourniquet"}, Quantity: 1, Unit: "pieces"},
+	},
+}
+
+func quantity(q float64) *float64 {
+	return &q
+}
+
+// item returns the report of the item with the given ID.
+func item(t *testing.T, report *Report, id string) ItemReport {
+	t.Helper()
+	for _, item := range report.Items {
+		if item.ItemID == id {
+			return item
+		}
+	}
+	t.Fatalf("no report for item %s", id)
+	return ItemReport{}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	past, future := now.AddDate(0, -1, 0), now.AddDate(1, 0, 0)
+	supplies := []Supply{
+		{ID: 1, Name: "Plaster roll 5 m", Expiry: future},
+		{ID: 2, Name: "Band-Aid plasters", Quantity: quantity(6), Unit: "pieces", Expiry: future},
+		{ID: 3, Name: "Kids plaster", Quantity: quantity(8), Unit: "pieces", Expiry: past},
+		{ID: 4, Name: "Chlorhexidine 0.05%", Quantity: quantity(100), Unit: "ml", Expiry: past},
+		{ID: 5, Name: "Ibuprofen 200 mg", Quantity: quantity(20), Unit: "tablets"},
+		{ID: 6, Name: "Scissors", Expiry: past},
+		{ID: 7, Name: "Vitamin C", Quantity: quantity(30), Unit: "tablets", Expiry: future},
+	}
+
+	report := testTemplate.Check(supplies, now)
+
+	if report.TemplateID != "test" || report.TemplateName != "Test kit" || report.Complete {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Items) != len(testTemplate.Items) {
+		t.Fatalf("%d item reports, want %d", len(report.Items), len(testTemplate.Items))
+	}
+	for i, item := range report.Items {
+		if item.ItemID != testTemplate.Items[i].ID {
+			t.Errorf("item %d is %s, want template order", i, item.ItemID)
+		}
+	}
+
+	// The plaster roll is not counted as wound plasters, the longest alias wins
+	roll := item(t, report, "plaster_roll")
+	if roll.Status != StatusOK || roll.Available != 1 || !reflect.DeepEqual(roll.DrugIDs, []uint{1}) {
+		t.Errorf("plaster_roll = %+v", roll)
+	}
+	// Expired plasters do not count towards the quantity
+	plasters := item(t, report, "plasters")
+	if plasters.Status != StatusInsufficient || plasters.Available != 6 || plasters.Required != 10 ||
+		!reflect.DeepEqual(plasters.DrugIDs, []uint{2}) || !reflect.DeepEqual(plasters.ExpiredDrugIDs, []uint{3}) {
+		t.Errorf("plasters = %+v", plasters)
+	}
+	antiseptic := item(t, report, "antiseptic")
+	if antiseptic.Status != StatusExpired || antiseptic.Available != 0 || !reflect.DeepEqual(antiseptic.ExpiredDrugIDs, []uint{4}) {
+		t.Errorf("antiseptic = %+v", antiseptic)
+	}
+	// Supplies without an expiry date never expire
+	if painkiller := item(t, report, "painkiller"); painkiller.Status != StatusOK || painkiller.Available != 20 {
+		t.Errorf("painkiller = %+v", painkiller)
+	}
+	// Items without expiry count even past the date of the supply
+	if scissors := item(t, report, "scissors"); scissors.Status != StatusOK || len(scissors.ExpiredDrugIDs) != 0 {
+		t.Errorf("scissors = %+v", scissors)
+	}
+	if tourniquet := item(t, report, "tourniquet"); tourniquet.Status != StatusMissing || len(tourniquet.DrugIDs) != 0 {
+		t.Errorf("tourniquet = %+v", tourniquet)
+	}
+
+	if !reflect.DeepEqual(report.Missing, []string{"tourniquet"}) ||
+		!reflect.DeepEqual(report.Expired, []string{"antiseptic"}) ||
+		!reflect.DeepEqual(report.Insufficient, []string{"plasters"}) ||
+		!reflect.DeepEqual(report.Extra, []uint{7}) {
+		t.Errorf("missing %v, expired %v, insufficient %v, extra %v",
+			report.Missing, report.Expired, report.Insufficient, report.Extra)
+	}
+}
+
+func TestCheckUnits(t *testing.T) {
+	now := time.Now()
+	report := testTemplate.Check([]Supply{
+		// A bottle counted in pieces counts as one item, not as 2 ml
+		{ID: 1, Name: "Антисептик", Quantity: quantity(2), Unit: "pieces"},
+		{ID: 2, Name: "Chlorhexidine", Quantity: quantity(30), Unit: "ml"},
+		// Unknown quantities count as one item
+		{ID: 3, Name: "Ibuprofen"},
+	}, now)
+
+	if antiseptic := item(t, report, "antiseptic"); antiseptic.Available != 31 || antiseptic.Status != StatusInsufficient {
+		t.Errorf("antiseptic = %+v", antiseptic)
+	}
+	if painkiller := item(t, report, "painkiller"); painkiller.Available != 1 {
+		t.Errorf("painkiller = %+v", painkiller)
+	}
+}
+
+func TestCheckComplete(t *testing.T) {
+	var supplies []Supply
+	for i, item := range testTemplate.Items {
+		supplies = append(supplies, Supply{ID: uint(i + 1), Name: item.Aliases[0], Quantity: quantity(item.Quantity), Unit: item.Unit})
+	}
+
+	report := testTemplate.Check(supplies, time.Now())
+	if !report.Complete || len(report.Missing)+len(report.Expired)+len(report.Insufficient)+len(report.Extra) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+
+	// An empty kit misses everything, the lists are empty rather than null
+	report = testTemplate.Check(nil, time.Now())
+	if report.Complete || len(report.Missing) != len(testTemplate.Items) || report.Extra == nil || report.Expired == nil {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestMatchWholeWords(t *testing.T) {
+	for name, want := range map[string]int{
+		"Plaster roll":      0,
+		"PLASTER-ROLL":      0,
+		"plasters":          -1, // Aliases match whole words only
+		"band aid":          1,
+		"bandaid":           -1,
+		"Scissors, medical": 4,
+		"":                  -1,
+	} {
+		if got := testTemplate.match(name); got != want {
+			t.Errorf("match(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	set, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, template := range set.List() {
+		ids = append(ids, template.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"car_eu", "car_ru", "home", "workplace"}) {
+		t.Errorf("templates = %v", ids)
+	}
+	if _, ok := set.Get("home"); !ok {
+		t.Error("home template not found")
+	}
+}
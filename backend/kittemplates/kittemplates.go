@@ -0,0 +1,119 @@
+package kittemplates
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed data/*.json
+var bundled embed.FS
+
+// Item is something a kit must contain, a drug or a supply.
+type Item struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases"`   // Names the item is recognised by in the kit
+	Quantity float64  `json:"quantity"`  // Required amount
+	Unit     string   `json:"unit"`      // Unit of the amount, see models.Units
+	NoExpiry bool     `json:"no_expiry"` // Items such as scissors never expire
+}
+
+// Template is a list of items a kit of some kind is expected to contain.
+type Template struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Items       []Item `json:"items"`
+}
+
+// Set holds the available templates by ID.
+type Set struct {
+	templates map[string]*Template
+}
+
+// Default returns the templates bundled with the binary.
+func Default() (*Set, error) {
+	set := &Set{templates: map[string]*Template{}}
+	files, err := bundled.ReadDir("data")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := bundled.ReadFile("data/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := set.add(file.Name(), data); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// LoadDir adds the templates from the JSON files of a directory.
+// A template with the ID of an existing one replaces it.
+func (s *Set) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := s.add(filepath.Base(path), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add parses and validates a template file.
+func (s *Set) add(name string, data []byte) error {
+	template := &Template{}
+	if err := json.Unmarshal(data, template); err != nil {
+		return fmt.Errorf("invalid kit template %s: %w", name, err)
+	}
+	if template.ID == "" || len(template.Items) == 0 {
+		return fmt.Errorf("kit template %s needs an id and items", name)
+	}
+	for _, item := range template.Items {
+		if item.ID == "" || len(item.Aliases) == 0 || item.Quantity <= 0 {
+			return fmt.Errorf("kit template %s: item %q needs an id, aliases and a positive quantity", name, item.Name)
+		}
+	}
+	s.templates[template.ID] = template
+	return nil
+}
+
+// Get returns the template with the given ID.
+func (s *Set) Get(id string) (*Template, bool) {
+	template, ok := s.templates[id]
+	return template, ok
+}
+
+// List returns all templates ordered by ID.
+func (s *Set) List() []*Template {
+	list := make([]*Template, 0, len(s.templates))
+	for _, template := range s.templates {
+		list = append(list, template)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// normalize lowercases text and replaces everything but letters and digits with single spaces.
+// The result is padded with spaces, so whole words can be matched with strings.Contains.
+func normalize(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultContextTokens is the prompt budget used when none is configured.
+const defaultContextTokens = 8000
+
+// messageOverhead approximates the tokens every message costs besides its text.
+const messageOverhead = 4
+
+// EstimateTokens roughly estimates how many tokens a text takes.
+// Most tokenizers average about four characters per token.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// truncatedNote ends a system prompt that was cut to fit the budget.
+const truncatedNote = "(truncated)\n"
+
+// FitToBudget drops the oldest conversation turns until the prompt fits into
+// the given number of tokens. System messages and the newest message are always kept,
+// the newest message is cut if it does not fit on its own. System messages leave at least
+// half of the budget to the newest message, their last lines are cut otherwise.
+func FitToBudget(messages []Message, budget int) []Message {
+	var system, conversation []Message
+	used := 0
+	for _, message := range messages {
+		if message.Role == RoleSystem {
+			system = append(system, message)
+			used += messageCost(message)
+		} else {
+			conversation = append(conversation, message)
+		}
+	}
+
+	if len(conversation) > 0 {
+		reserve := min(messageCost(conversation[len(conversation)-1]), budget/2)
+		if used+reserve > budget {
+			system = trimSystem(system, budget-reserve)
+			used = 0
+			for _, message := range system {
+				used += messageCost(message)
+			}
+		}
+	}
+
+	// Walk from the newest message back and keep as many turns as fit
+	start := len(conversation)
+	for start > 0 {
+		cost := messageCost(conversation[start-1])
+		if used+cost > budget && start < len(conversation) {
+			break
+		}
+		used += cost
+		start--
+	}
+	kept := append([]Message{}, conversation[start:]...)
+
+	// The newest message alone may be too long, keep only its beginning then
+	if used > budget && len(kept) == 1 {
+		left := budget - (used - EstimateTokens(kept[0].Content))
+		kept[0].Content = truncateRunes(kept[0].Content, max(left, 0)*4)
+	}
+
+	return append(system, kept...)
+}
+
+// messageCost estimates the tokens of a message including its overhead.
+func messageCost(message Message) int {
+	return EstimateTokens(message.Content) + messageOverhead
+}
+
+// trimSystem keeps the system messages within the budget. The first message that does not fit
+// keeps its leading lines, so data listed at the end of a prompt goes before the instructions
+// at its start. Later messages are dropped.
+func trimSystem(system []Message, budget int) []Message {
+	var kept []Message
+	used := 0
+	for _, message := range system {
+		cost := messageCost(message)
+		if used+cost <= budget {
+			kept = append(kept, message)
+			used += cost
+			continue
+		}
+
+		// One character is kept for the line break ending the cut content
+		left := (budget-used-messageOverhead-EstimateTokens(truncatedNote))*4 - 1
+		if left <= 0 {
+			break
+		}
+		var content strings.Builder
+		length := 0
+		for _, line := range strings.SplitAfter(message.Content, "\n") {
+			length += utf8.RuneCountInString(line)
+			if length > left {
+				break
+			}
+			content.WriteString(line)
+		}
+		if content.Len() == 0 {
+			// Even the first line is too long
+			content.WriteString(truncateRunes(message.Content, left) + "\n")
+		} else if !strings.HasSuffix(content.String(), "\n") {
+			content.WriteString("\n")
+		}
+		message.Content = content.String() + truncatedNote
+		kept = append(kept, message)
+		break
+	}
+	return kept
+}
+
+// truncateRunes cuts text to at most n characters.
+func truncateRunes(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n])
+}
+
+// budgeted trims the prompt to the configured budget before calling the wrapped model.
+type budgeted struct {
+	model  ChatModel // Wrapped provider
+	tokens int       // Maximum number of prompt tokens
+}
+
+// WithBudget wraps a model so that prompts never exceed the given number of tokens.
+func WithBudget(model ChatModel, tokens int) ChatModel {
+	if tokens <= 0 {
+		tokens = defaultContextTokens
+	}
+	return &budgeted{model: model, tokens: tokens}
+}
+
+// Stream fits the messages into the budget and delegates to the wrapped model.
+func (b *budgeted) Stream(ctx context.Context, messages []Message, onChunk func(string) error) (string, error) {
+	return b.model.Stream(ctx, FitToBudget(messages, b.tokens), onChunk)
+}
@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+// promptTokens estimates the tokens of a prompt the way FitToBudget counts them.
+func promptTokens(messages []Message) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += messageCost(message)
+	}
+	return tokens
+}
+
+// words returns a text of n four-letter words, about n+n/4 tokens.
+func words(word string, n int) string {
+	return strings.TrimSpace(strings.Repeat(word+" ", n))
+}
+
+func TestEstimateTokens(t *testing.T) {
+	for text, want := range map[string]int{
+		"":        0,
+		"a":       1,
+		"abcd":    1,
+		"abcde":   2,
+		"аптечка": 2, // Characters are counted, not bytes
+		"🩹🩹🩹🩹🩹":   2,
+	} {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestFitToBudgetKeepsFittingPrompt(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "Be helpful."},
+		{Role: RoleUser, Content: "I cut my finger"},
+		{Role: RoleAssistant, Content: "Rinse it with water"},
+		{Role: RoleUser, Content: "Thanks"},
+	}
+	got := FitToBudget(messages, 1000)
+	if len(got) != len(messages) {
+		t.Fatalf("FitToBudget kept %d of %d messages", len(got), len(messages))
+	}
+	for i := range messages {
+		if got[i] != messages[i] {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], messages[i])
+		}
+	}
+}
+
+func TestFitToBudgetDropsOldestTurns(t *testing.T) {
+	messages := []Message{{Role: RoleSystem, Content: "Be helpful."}}
+	for i := 0; i < 10; i++ {
+		messages = append(messages,
+			Message{Role: RoleUser, Content: words("ache", 20)},
+			Message{Role: RoleAssistant, Content: words("rest", 20)},
+		)
+	}
+	messages = append(messages, Message{Role: RoleUser, Content: "What now?"})
+
+	got := FitToBudget(messages, 100)
+	if promptTokens(got) > 100 {
+		t.Errorf("prompt has %d tokens, budget is 100", promptTokens(got))
+	}
+	if got[0].Role != RoleSystem {
+		t.Errorf("first message is %s, want the system prompt", got[0].Role)
+	}
+	if len(got) < 3 || len(got) >= len(messages) {
+		t.Fatalf("FitToBudget kept %d of %d messages", len(got), len(messages))
+	}
+	// The kept turns are the newest ones, in order
+	tail := messages[len(messages)-(len(got)-1):]
+	for i, message := range got[1:] {
+		if message != tail[i] {
+			t.Errorf("message %d = %+v, want %+v", i+1, message, tail[i])
+		}
+	}
+}
+
+func TestFitToBudgetTruncatesNewest(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "Be helpful."},
+		{Role: RoleUser, Content: "Hello"},
+		{Role: RoleUser, Content: words("pain", 400)},
+	}
+
+	got := FitToBudget(messages, 100)
+	if len(got) != 2 || got[1].Role != RoleUser {
+		t.Fatalf("FitToBudget = %+v, want the system prompt and the newest message", got)
+	}
+	if !strings.HasPrefix(messages[2].Content, got[1].Content) || got[1].Content == "" {
+		t.Errorf("newest message = %q, want the beginning of it", got[1].Content)
+	}
+	if tokens := promptTokens(got); tokens > 100 || tokens < 90 {
+		t.Errorf("prompt has %d tokens, want the budget of 100 used", tokens)
+	}
+}
+
+func TestFitToBudgetOversizedSystem(t *testing.T) {
+	system := "You are a first-aid assistant.\n\nUser's medical card:\n- Allergies: penicillin\n\nUser's medicine cabinet:\n"
+	for i := 0; i < 200; i++ {
+		system += "- Ibuprofen (tablets), dose 200 mg, expires 2030-01-01\n"
+	}
+	question := "My child has a fever of 39.5, what can I give?"
+	messages := []Message{
+		{Role: RoleSystem, Content: system},
+		{Role: RoleUser, Content: "Hello"},
+		{Role: RoleAssistant, Content: "Hi, how can I help?"},
+		{Role: RoleUser, Content: question},
+	}
+
+	got := FitToBudget(messages, 300)
+	if tokens := promptTokens(got); tokens > 300 {
+		t.Errorf("prompt has %d tokens, budget is 300", tokens)
+	}
+	// The question is kept whole, the cabinet at the end of the system prompt is cut
+	if last := got[len(got)-1]; last.Content != question {
+		t.Errorf("newest message = %q, want %q", last.Content, question)
+	}
+	if got[0].Role != RoleSystem || !strings.HasPrefix(got[0].Content, "You are a first-aid assistant.\n\nUser's medical card:\n- Allergies: penicillin\n") {
+		t.Fatalf("system prompt = %q, want its beginning", got[0].Content)
+	}
+	if !strings.HasSuffix(got[0].Content, "\n"+truncatedNote) || strings.Count(got[0].Content, "Ibuprofen") >= 200 {
+		t.Errorf("system prompt = %q, want the cabinet cut at a line", got[0].Content)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(got[0].Content, truncatedNote), "\n") {
+		if strings.HasPrefix(line, "- Ibuprofen") && line != "- Ibuprofen (tablets), dose 200 mg, expires 2030-01-01" {
+			t.Errorf("cabinet line cut in the middle: %q", line)
+		}
+	}
+
+	// A long newest message shares the budget with the system prompt
+	long := words("help", 400)
+	got = FitToBudget([]Message{{Role: RoleSystem, Content: system}, {Role: RoleUser, Content: long}}, 300)
+	if tokens := promptTokens(got); tokens > 300 {
+		t.Errorf("prompt has %d tokens, budget is 300", tokens)
+	}
+	if len(got) != 2 || messageCost(got[1]) < 150-messageOverhead || !strings.HasPrefix(long, got[1].Content) {
+		t.Errorf("newest message has %d tokens, want at least half of the budget", messageCost(got[1]))
+	}
+
+	// A first line longer than the budget is cut as well
+	got = FitToBudget([]Message{{Role: RoleSystem, Content: words("rule", 1000)}, {Role: RoleUser, Content: question}}, 100)
+	if tokens := promptTokens(got); tokens > 100 || got[0].Content == truncatedNote || got[len(got)-1].Content != question {
+		t.Errorf("FitToBudget = %+v (%d tokens)", got, tokens)
+	}
+}
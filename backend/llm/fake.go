@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// Fake is a deterministic ChatModel that does not need network access.
+// It is meant for tests and local development.
+type Fake struct{}
+
+// NewFake creates a fake model.
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// Stream replies by echoing the last user message word by word.
+func (f *Fake) Stream(ctx context.Context, messages []Message, onChunk func(string) error) (string, error) {
+	question := ""
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			question = messages[i].Content
+			break
+		}
+	}
+
+	words := strings.Fields("This is a test reply to: " + question)
+
+	var reply strings.Builder
+	for i, word := range words {
+		if err := ctx.Err(); err != nil {
+			return reply.String(), err
+		}
+
+		if i > 0 {
+			word = " " + word
+		}
+		reply.WriteString(word)
+		if err := onChunk(word); err != nil {
+			return reply.String(), err
+		}
+	}
+
+	return reply.String(), nil
+}
@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFakeEchoesLastUserMessage(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "Be helpful."},
+		{Role: RoleUser, Content: "first question"},
+		{Role: RoleAssistant, Content: "an answer"},
+		{Role: RoleUser, Content: "I cut  my finger"},
+	}
+	reply, chunks, err := collect(NewFake(), messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "This is a test reply to: I cut my finger"; reply != want {
+		t.Errorf("reply = %q, want %q", reply, want)
+	}
+	if strings.Join(chunks, "") != reply || len(chunks) != 10 {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestFakeWithoutUserMessage(t *testing.T) {
+	reply, _, err := collect(NewFake(), []Message{{Role: RoleSystem, Content: "Be helpful."}})
+	if err != nil || reply != "This is a test reply to:" {
+		t.Errorf("reply = %q, err = %v", reply, err)
+	}
+}
+
+func TestFakeStops(t *testing.T) {
+	messages := []Message{{Role: RoleUser, Content: "hello"}}
+
+	stop := errors.New("client went away")
+	reply, err := NewFake().Stream(context.Background(), messages, func(string) error { return stop })
+	if !errors.Is(err, stop) || reply != "This" {
+		t.Errorf("reply = %q, err = %v", reply, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reply, err = NewFake().Stream(ctx, messages, func(string) error { return nil })
+	if !errors.Is(err, context.Canceled) || reply != "" {
+		t.Errorf("reply = %q, err = %v", reply, err)
+	}
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// defaultGeminiModel is used when no model name is configured.
+const defaultGeminiModel = "gemini-1.5-flash"
+
+// Gemini talks to Google's Gemini API.
+type Gemini struct {
+	client *genai.Client // Client is created once and shared between requests
+	model  string        // Model used for generation
+}
+
+// NewGemini creates a Gemini client with the given API key.
+func NewGemini(ctx context.Context, apiKey, model string) (*Gemini, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return &Gemini{client: client, model: model}, nil
+}
+
+// Stream sends the conversation to Gemini and streams the generated reply.
+func (g *Gemini) Stream(ctx context.Context, messages []Message, onChunk func(string) error) (string, error) {
+	var config *genai.GenerateContentConfig
+	var contents []*genai.Content
+
+	// Gemini accepts system prompts separately from the conversation
+	// and calls the assistant role "model"
+	for _, message := range messages {
+		switch message.Role {
+		case RoleSystem:
+			if config == nil {
+				config = &genai.GenerateContentConfig{
+					SystemInstruction: genai.NewContentFromText(message.Content, genai.RoleUser),
+				}
+			} else {
+				config.SystemInstruction.Parts = append(config.SystemInstruction.Parts, genai.NewPartFromText(message.Content))
+			}
+		case RoleAssistant:
+			contents = append(contents, genai.NewContentFromText(message.Content, genai.RoleModel))
+		default:
+			contents = append(contents, genai.NewContentFromText(message.Content, genai.RoleUser))
+		}
+	}
+
+	var reply strings.Builder
+	for chunk, err := range g.client.Models.GenerateContentStream(ctx, g.model, contents, config) {
+		if err != nil {
+			return reply.String(), err
+		}
+
+		text := chunk.Text()
+		if text == "" {
+			continue
+		}
+		reply.WriteString(text)
+		if err := onChunk(text); err != nil {
+			return reply.String(), err
+		}
+	}
+
+	return reply.String(), nil
+}
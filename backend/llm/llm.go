@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Role identifies the author of a message in a conversation.
+type Role string
+
+const (
+	RoleSystem    Role = "system"    // Instructions for the model
+	RoleUser      Role = "user"      // Message written by the user
+	RoleAssistant Role = "assistant" // Reply generated by the model
+)
+
+// Message represents a single turn of a conversation sent to the model.
+type Message struct {
+	Role    Role   // Author of the message
+	Content string // Text of the message
+}
+
+// ChatModel is implemented by every LLM provider the backend can talk to.
+type ChatModel interface {
+	// Stream generates a reply for the conversation and calls onChunk for every
+	// piece of text as soon as it is received. It returns the complete reply.
+	// If onChunk returns an error, generation stops and the error is returned.
+	Stream(ctx context.Context, messages []Message, onChunk func(string) error) (string, error)
+}
+
+// Config describes which provider and model should be used.
+type Config struct {
+	Provider string // gemini, openai or fake
+	Model    string // Model name, provider specific default when empty
+	BaseURL  string // Server address for OpenAI-compatible providers
+	APIKey   string // API key, optional for self-hosted servers
+
+	ContextTokens int // Maximum number of prompt tokens, older turns are dropped above it
+}
+
+// New creates a ChatModel for the provider selected in the config.
+// Prompts sent through the returned model are trimmed to cfg.ContextTokens.
+func New(ctx context.Context, cfg Config) (ChatModel, error) {
+	var model ChatModel
+
+	switch strings.ToLower(cfg.Provider) {
+	case "", "gemini":
+		gemini, err := NewGemini(ctx, cfg.APIKey, cfg.Model)
+		if err != nil {
+			return nil, err
+		}
+		model = gemini
+	case "openai", "ollama", "llamacpp":
+		model = NewOpenAI(cfg.BaseURL, cfg.APIKey, cfg.Model)
+	case "fake":
+		model = NewFake()
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+	}
+
+	return WithBudget(model, cfg.ContextTokens), nil
+}
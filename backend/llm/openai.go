@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultOpenAIURL is used when no base URL is configured.
+const defaultOpenAIURL = "http://localhost:11434/v1"
+
+// OpenAI talks to any server implementing the OpenAI chat completions API,
+// including llama.cpp and Ollama servers.
+type OpenAI struct {
+	baseURL string       // Address of the API, e.g. http://ollama:11434/v1
+	apiKey  string       // Optional bearer token
+	model   string       // Model used for generation
+	client  *http.Client // HTTP client used for requests
+}
+
+// openAIMessage is a message in the chat completions request format.
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIRequest is the body of a chat completions request.
+type openAIRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+}
+
+// openAIChunk is a single streamed event of a chat completions response.
+type openAIChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// NewOpenAI creates a client for an OpenAI-compatible server.
+func NewOpenAI(baseURL, apiKey, model string) *OpenAI {
+	if baseURL == "" {
+		baseURL = defaultOpenAIURL
+	}
+	return &OpenAI{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{},
+	}
+}
+
+// Stream sends the conversation to the server and streams the generated reply.
+func (o *OpenAI) Stream(ctx context.Context, messages []Message, onChunk func(string) error) (string, error) {
+	request := openAIRequest{Model: o.model, Stream: true}
+	for _, message := range messages {
+		request.Messages = append(request.Messages, openAIMessage{
+			Role:    string(message.Role),
+			Content: message.Content,
+		})
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("LLM server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	// The response is a stream of "data: {...}" lines terminated by "data: [DONE]"
+	var reply strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return reply.String(), fmt.Errorf("failed to decode LLM chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		text := chunk.Choices[0].Delta.Content
+		reply.WriteString(text)
+		if err := onChunk(text); err != nil {
+			return reply.String(), err
+		}
+	}
+
+	return reply.String(), scanner.Err()
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sseServer serves the body as an event stream to chat completions requests and records the last request.
+func sseServer(t *testing.T, status int, body string) (*httptest.Server, *openAIRequest, *http.Header) {
+	t.Helper()
+	var request openAIRequest
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		header = r.Header.Clone()
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("cannot decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &request, &header
+}
+
+// event formats a streamed chunk with the text.
+func event(text string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{"delta": map[string]string{"content": text}}},
+	})
+	return "data: " + string(data) + "\n\n"
+}
+
+// collect streams a reply and returns it with the received chunks.
+func collect(model ChatModel, messages []Message) (string, []string, error) {
+	var chunks []string
+	reply, err := model.Stream(context.Background(), messages, func(text string) error {
+		chunks = append(chunks, text)
+		return nil
+	})
+	return reply, chunks, err
+}
+
+func TestOpenAIStream(t *testing.T) {
+	body := ": keep-alive comment\n\n" +
+		event("Rinse") +
+		"event: ping\n\n" +
+		`data: {"choices":[]}` + "\n\n" +
+		event("") +
+		event(" with water") +
+		"data: [DONE]\n\n"
+	server, request, header := sseServer(t, http.StatusOK, body)
+
+	model := NewOpenAI(server.URL+"/v1/", "secret", "llama3")
+	messages := []Message{
+		{Role: RoleSystem, Content: "Be helpful."},
+		{Role: RoleUser, Content: "I cut my finger"},
+	}
+	reply, chunks, err := collect(model, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Rinse with water" {
+		t.Errorf("reply = %q", reply)
+	}
+	if strings.Join(chunks, "|") != "Rinse| with water" {
+		t.Errorf("chunks = %q", chunks)
+	}
+
+	if request.Model != "llama3" || !request.Stream {
+		t.Errorf("request = %+v", request)
+	}
+	if len(request.Messages) != 2 || request.Messages[0].Role != "system" || request.Messages[1].Content != "I cut my finger" {
+		t.Errorf("messages = %+v", request.Messages)
+	}
+	if got := header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestOpenAIStreamWithoutAPIKey(t *testing.T) {
+	server, _, header := sseServer(t, http.StatusOK, event("Hi")+"data: [DONE]\n\n")
+
+	if _, _, err := collect(NewOpenAI(server.URL+"/v1", "", "llama3"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none", got)
+	}
+}
+
+func TestOpenAIStreamStopsAtDone(t *testing.T) {
+	body := event("Rest") + "data: [DONE]\n\n" + event(" ignored") + "data: not json\n\n"
+	server, _, _ := sseServer(t, http.StatusOK, body)
+
+	reply, chunks, err := collect(NewOpenAI(server.URL+"/v1", "", "llama3"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Rest" || len(chunks) != 1 {
+		t.Errorf("reply = %q, chunks = %q", reply, chunks)
+	}
+}
+
+func TestOpenAIStreamEndsWithoutDone(t *testing.T) {
+	server, _, _ := sseServer(t, http.StatusOK, event("Rest"))
+
+	reply, _, err := collect(NewOpenAI(server.URL+"/v1", "", "llama3"), nil)
+	if err != nil || reply != "Rest" {
+		t.Errorf("reply = %q, err = %v", reply, err)
+	}
+}
+
+func TestOpenAIStreamMalformedChunk(t *testing.T) {
+	server, _, _ := sseServer(t, http.StatusOK, event("Rest")+"data: {not json\n\n")
+
+	reply, _, err := collect(NewOpenAI(server.URL+"/v1", "", "llama3"), nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to decode LLM chunk") {
+		t.Errorf("err = %v", err)
+	}
+	if reply != "Rest" {
+		t.Errorf("partial reply = %q", reply)
+	}
+}
+
+func TestOpenAIStreamServerError(t *testing.T) {
+	server, _, _ := sseServer(t, http.StatusTooManyRequests, "  rate limited\n")
+
+	reply, chunks, err := collect(NewOpenAI(server.URL+"/v1", "", "llama3"), nil)
+	if err == nil || err.Error() != "LLM server returned 429: rate limited" {
+		t.Errorf("err = %v", err)
+	}
+	if reply != "" || len(chunks) != 0 {
+		t.Errorf("reply = %q, chunks = %q", reply, chunks)
+	}
+}
+
+func TestOpenAIStreamUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	if _, _, err := collect(NewOpenAI(server.URL+"/v1", "", "llama3"), nil); err == nil {
+		t.Error("expected an error for an unreachable server")
+	}
+}
+
+func TestOpenAIStreamCallbackError(t *testing.T) {
+	server, _, _ := sseServer(t, http.StatusOK, event("one")+event(" two")+"data: [DONE]\n\n")
+	stop := errors.New("client went away")
+
+	reply, err := NewOpenAI(server.URL+"/v1", "", "llama3").Stream(context.Background(), nil, func(string) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want %v", err, stop)
+	}
+	if reply != "one" {
+		t.Errorf("reply = %q", reply)
+	}
+}
+
+func TestOpenAIDefaultURL(t *testing.T) {
+	if model := NewOpenAI("", "", "llama3"); model.baseURL != defaultOpenAIURL {
+		t.Errorf("baseURL = %q, want %q", model.baseURL, defaultOpenAIURL)
+	}
+}
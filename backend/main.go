@@ -1,11 +1,24 @@
 package main
 
 import (
+	"context"
+	"first_aid_companion/controllers"
+	"first_aid_companion/encryption"
 	"first_aid_companion/handlers"
+	"first_aid_companion/icd10"
+	"first_aid_companion/interactions"
+	"first_aid_companion/kittemplates"
+	"first_aid_companion/llm"
+	"first_aid_companion/notify"
 	"first_aid_companion/services"
+	"first_aid_companion/storage"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -21,23 +34,231 @@ func main() {
 	// }
 
 	// Get variables
-	apiKey := os.Getenv("GEMINI_API_KEY")
+	llmConfig := llm.Config{
+		Provider: os.Getenv("LLM_PROVIDER"),
+		Model:    os.Getenv("LLM_MODEL"),
+		BaseURL:  os.Getenv("LLM_BASE_URL"),
+		APIKey:   os.Getenv("LLM_API_KEY"),
+	}
+	if llmConfig.Provider == "" {
+		llmConfig.Provider = "gemini"
+	}
+	if llmConfig.APIKey == "" {
+		llmConfig.APIKey = os.Getenv("GEMINI_API_KEY")
+	}
+	if tokens, err := strconv.Atoi(os.Getenv("LLM_CONTEXT_TOKENS")); err == nil {
+		llmConfig.ContextTokens = tokens
+	}
 	dsn := "host=postgres  user=postgres password=your_secure_password dbname=firstaid port=5432 sslmode=disable"
+	if env := os.Getenv("DATABASE_DSN"); env != "" {
+		dsn = env
+	}
+
+	// Document files are kept in a local directory unless an S3 bucket is configured
+	storageConfig := storage.Config{
+		Backend:     os.Getenv("BLOB_STORE"),
+		Dir:         os.Getenv("BLOB_DIR"),
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+	}
+	if storageConfig.Dir == "" {
+		storageConfig.Dir = "blobs"
+	}
+
+	// Sensitive fields and document files are encrypted with data keys wrapped by the master key
+	masterKeys, err := encryption.LoadMasterKeys(os.Getenv("MASTER_KEY"), os.Getenv("MASTER_KEY_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load master keys: %v", err)
+	}
+
+	// "migrate" subcommand manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		dbService, err := services.NewDBService(nil, dsn)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		if err := runMigrate(dbService, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// "catalog" subcommand imports drug registry files and exits
+	if len(os.Args) > 1 && os.Args[1] == "catalog" {
+		dbService, err := services.NewDBService(nil, dsn)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		if err := runCatalog(dbService, os.Args[2:]); err != nil {
+			log.Fatalf("Catalog import failed: %v", err)
+		}
+		return
+	}
+
+	// "blobs" subcommand moves document files between the database and the blob store and exits
+	if len(os.Args) > 1 && os.Args[1] == "blobs" {
+		dbService, err := services.NewDBService(nil, dsn)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		if dbService.Blobs, err = storage.New(storageConfig); err != nil {
+			log.Fatalf("Failed to configure blob store: %v", err)
+		}
+		if err := setupKeyring(dbService, masterKeys); err != nil {
+			log.Fatalf("Failed to configure encryption: %v", err)
+		}
+		if err := runBlobs(dbService, os.Args[2:]); err != nil {
+			log.Fatalf("Moving document files failed: %v", err)
+		}
+		return
+	}
+
+	// "keys" subcommand rotates the encryption keys and exits
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		dbService, err := services.NewDBService(nil, dsn)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		if dbService.Blobs, err = storage.New(storageConfig); err != nil {
+			log.Fatalf("Failed to configure blob store: %v", err)
+		}
+		if err := setupKeyring(dbService, masterKeys); err != nil {
+			log.Fatalf("Failed to configure encryption: %v", err)
+		}
+		if err := runKeys(dbService, os.Args[2:]); err != nil {
+			log.Fatalf("Key command failed: %v", err)
+		}
+		return
+	}
+
+	// Configure token signing
+	authConfig := controllers.AuthConfig{Secret: []byte(os.Getenv("JWT_SECRET"))}
+	if ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL")); err == nil {
+		authConfig.AccessTTL = ttl
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil {
+		authConfig.RefreshTTL = ttl
+	}
+	if err := controllers.ConfigureAuth(authConfig); err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+
+	// Initialize AI model
+	chatModel, err := llm.New(context.Background(), llmConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM provider: %v", err)
+	}
+	log.Printf("Using LLM provider %q", llmConfig.Provider)
 
 	// Initialize DB service
-	dbService, _ := services.NewDBService(apiKey, dsn)
+	dbService, _ := services.NewDBService(chatModel, dsn)
+
+	// Refuse to start on an outdated schema
+	if err := dbService.CheckSchema(); err != nil {
+		log.Fatalf("Database schema check failed: %v", err)
+	}
+	log.Println("Database schema is up to date")
+
+	// Load drug interaction rules, an updated dataset can replace the bundled one
+	if path := os.Getenv("INTERACTIONS_FILE"); path != "" {
+		dbService.Interactions, err = interactions.LoadFile(path)
+	} else {
+		dbService.Interactions, err = interactions.Default()
+	}
+	if err != nil {
+		log.Fatalf("Failed to load drug interactions: %v", err)
+	}
+	log.Printf("Drug interactions dataset version %s loaded", dbService.Interactions.Version)
+
+	// Load ICD-10 codes, a complete classification can replace the bundled subset
+	if path := os.Getenv("ICD10_FILE"); path != "" {
+		dbService.ICD10, err = icd10.LoadFile(path)
+	} else {
+		dbService.ICD10, err = icd10.Default()
+	}
+	if err != nil {
+		log.Fatalf("Failed to load ICD-10 codes: %v", err)
+	}
+	log.Printf("ICD-10 dataset version %s loaded", dbService.ICD10.Version)
+
+	// Load kit templates, files in KIT_TEMPLATES_DIR add to or replace the bundled ones
+	dbService.KitTemplates, err = kittemplates.Default()
+	if err == nil && os.Getenv("KIT_TEMPLATES_DIR") != "" {
+		err = dbService.KitTemplates.LoadDir(os.Getenv("KIT_TEMPLATES_DIR"))
+	}
+	if err != nil {
+		log.Fatalf("Failed to load kit templates: %v", err)
+	}
 
-	// Automigrate DB
-	if err := dbService.Automigrate(); err != nil {
-		log.Fatalf("Failed to automigrate database: %v", err)
+	// Public links are built from the configured address, never from the Host header of a request
+	dbService.PublicURL = os.Getenv("PUBLIC_URL")
+	if dbService.PublicURL == "" {
+		dbService.PublicURL = "http://localhost:8080"
+		log.Printf("PUBLIC_URL is not set, public links point to %s", dbService.PublicURL)
 	}
-	log.Println("Database automigration completed successfully")
+	if u, err := url.Parse(dbService.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Fatalf("Invalid PUBLIC_URL %q", dbService.PublicURL)
+	}
+
+	// Open the blob store of document files
+	if dbService.Blobs, err = storage.New(storageConfig); err != nil {
+		log.Fatalf("Failed to configure blob store: %v", err)
+	}
+	if count, err := dbService.DocsDB.CountDatabaseFiles(); err == nil && count > 0 {
+		log.Printf("%d document files are still kept in the database, run \"main blobs move\" to move them", count)
+	}
+
+	// Configure encryption at rest, data written earlier or with retired keys is re-encrypted in the background
+	if err := setupKeyring(dbService, masterKeys); err != nil {
+		log.Fatalf("Failed to configure encryption: %v", err)
+	}
+	if dbService.Keyring.Enabled() {
+		reencryptInterval, _ := time.ParseDuration(os.Getenv("REENCRYPT_INTERVAL"))
+		reencryptor := services.NewReencryptor(dbService.Keyring, dbService.KeyDB, dbService.DocsDB, dbService.Blobs, reencryptInterval)
+		go reencryptor.Run(context.Background())
+		log.Printf("Encryption enabled with master key %s", dbService.Keyring.CurrentMasterKeyID())
+	}
+
+	// Generate document thumbnails in the background, uploads wake the worker up
+	thumbnailInterval, _ := time.ParseDuration(os.Getenv("THUMBNAIL_INTERVAL"))
+	dbService.Thumbnails = services.NewThumbnailWorker(dbService.DocsDB, dbService.Blobs, dbService.Keyring, thumbnailInterval)
+	go dbService.Thumbnails.Run(context.Background())
+
+	// Limit the size of uploaded documents
+	dbService.MaxDocumentSize = controllers.DefaultMaxDocumentSize
+	if mb, err := strconv.Atoi(os.Getenv("MAX_DOCUMENT_MB")); err == nil && mb > 0 {
+		dbService.MaxDocumentSize = int64(mb) << 20
+	}
+
+	// Configure SOS alert channels, NOTIFIER=fake keeps alerts in memory
+	smtpPort, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	dbService.Notifier = notify.New(notify.Config{
+		Fake:            os.Getenv("NOTIFIER") == "fake",
+		SMTPHost:        os.Getenv("SMTP_HOST"),
+		SMTPPort:        smtpPort,
+		SMTPUsername:    os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:    os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:        os.Getenv("SMTP_FROM"),
+		SMSGatewayURL:   os.Getenv("SMS_GATEWAY_URL"),
+		SMSGatewayToken: os.Getenv("SMS_GATEWAY_TOKEN"),
+		WebhookSecret:   os.Getenv("WEBHOOK_SECRET"),
+	})
+	log.Printf("SOS alert channels: %v", dbService.Notifier.Channels())
 
-	// Rest DB
-	if err := dbService.ResetDB(); err != nil {
-		log.Fatalf("Failed to reset database: %v", err)
+	// Start drug expiry monitoring in the background
+	var expiryWindows []int
+	for _, field := range strings.Split(os.Getenv("EXPIRY_WINDOWS"), ",") {
+		if days, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+			expiryWindows = append(expiryWindows, days)
+		}
 	}
-	log.Println("Database reset completed successfully")
+	scanInterval, _ := time.ParseDuration(os.Getenv("EXPIRY_SCAN_INTERVAL"))
+	expiryScheduler := services.NewExpiryScheduler(dbService.DrugDB, dbService.NotifDB, expiryWindows, scanInterval)
+	go expiryScheduler.Run(context.Background())
+	log.Printf("Expiry monitoring started with windows %v", expiryScheduler.Windows)
 
 	// Set up router
 	router := mux.NewRouter()
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"first_aid_companion/services"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// migrateUsage describes the migrate subcommand.
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  status     list migrations and whether they are applied
+  up [n]     apply n pending migrations (all by default)
+  down [n]   revert n most recent migrations (1 by default)`
+
+// runMigrate executes the "migrate" subcommand with the given arguments.
+func runMigrate(dbService *services.DBService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", migrateUsage)
+	}
+
+	// Optional number of steps
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of steps %q", args[1])
+		}
+		steps = n
+	}
+
+	migrator, err := services.NewMigrator(dbService.DB)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+	case "up":
+		applied, err := migrator.Up(steps)
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Database schema is already up to date")
+		}
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			log.Printf("Reverted migration %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], migrateUsage)
+	}
+
+	return nil
+}
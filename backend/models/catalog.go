@@ -0,0 +1,129 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CatalogDrug is an entry of the drug registry used to prefill drugs by name or barcode.
+type CatalogDrug struct {
+	ID              uint     `gorm:"primaryKey" json:"id"`                             // Unique identifier of the entry
+	GTIN            *string  `gorm:"column:gtin" json:"gtin" example:"04601234567893"` // Barcode as 14-digit GTIN, null if unknown
+	Name            string   `json:"name"`                                             // Trade name
+	Type            string   `json:"type"`                                             // Type or category of the drug
+	Description     string   `json:"description"`                                      // Description or purpose of the drug
+	Manufacturer    string   `json:"manufacturer"`                                     // Manufacturer of the drug
+	Dose            string   `json:"dose"`                                             // Dosage information
+	Amount          string   `json:"amount"`                                           // Pack size as free text
+	Quantity        *float64 `json:"quantity"`                                         // Pack size, null if unknown
+	Unit            string   `json:"unit"`                                             // Unit of the pack size
+	ShelfLifeMonths int      `json:"shelf_life_months"`                                // Shelf life from production, 0 if unknown
+}
+
+// CatalogGorm wraps a GORM DB instance for operations on the drug catalog.
+type CatalogGorm struct {
+	DB *gorm.DB
+}
+
+// NewCatalogGorm creates a new instance of CatalogGorm.
+func NewCatalogGorm(db *gorm.DB) *CatalogGorm {
+	return &CatalogGorm{DB: db}
+}
+
+// NormalizeGTIN validates a GTIN-8, GTIN-12 (UPC), GTIN-13 (EAN) or GTIN-14 barcode
+// and returns it zero-padded to 14 digits, so all formats of the same product match.
+func NormalizeGTIN(code string) (string, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return "", errors.New("barcode must have 8, 12, 13 or 14 digits")
+	}
+
+	code = strings.Repeat("0", 14-len(code)) + code
+	sum := 0
+	for i, r := range code {
+		if r < '0' || r > '9' {
+			return "", errors.New("barcode must contain only digits")
+		}
+		if i == 13 {
+			break
+		}
+		// Weights alternate 3 and 1, starting with 3 for the leftmost digit of a GTIN-14
+		digit := int(r - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	if int(code[13]-'0') != (10-sum%10)%10 {
+		return "", errors.New("invalid barcode check digit")
+	}
+	return code, nil
+}
+
+// UpsertCatalogDrugs stores registry entries. Entries with a GTIN replace the entry with the same
+// GTIN, entries without one replace the entry with the same name, manufacturer, dose and amount,
+// so importing a registry again does not duplicate it.
+func (cg *CatalogGorm) UpsertCatalogDrugs(drugs []CatalogDrug) error {
+	var withGTIN, withoutGTIN []CatalogDrug
+	for _, drug := range drugs {
+		if drug.GTIN != nil {
+			withGTIN = append(withGTIN, drug)
+		} else {
+			withoutGTIN = append(withoutGTIN, drug)
+		}
+	}
+
+	return cg.DB.Transaction(func(tx *gorm.DB) error {
+		if len(withGTIN) > 0 {
+			err := tx.Table("catalog_drugs").Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "gtin"}},
+				DoUpdates: clause.AssignmentColumns([]string{
+					"name", "type", "description", "manufacturer", "dose", "amount", "quantity", "unit", "shelf_life_months",
+				}),
+			}).CreateInBatches(withGTIN, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(withoutGTIN) > 0 {
+			// Matches the partial unique index of entries without a GTIN
+			err := tx.Table("catalog_drugs").Clauses(clause.OnConflict{
+				Columns:     []clause.Column{{Name: "name"}, {Name: "manufacturer"}, {Name: "dose"}, {Name: "amount"}},
+				TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "gtin IS NULL"}}},
+				DoUpdates:   clause.AssignmentColumns([]string{"type", "description", "quantity", "unit", "shelf_life_months"}),
+			}).CreateInBatches(withoutGTIN, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SearchCatalog finds entries whose name contains the query or is similar to it,
+// so typos like "ibuprofn" still match. Best matches come first.
+func (cg *CatalogGorm) SearchCatalog(query string, limit int) ([]CatalogDrug, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query)
+
+	var drugs []CatalogDrug
+	err := cg.DB.Table("catalog_drugs").
+		Where("name ILIKE ? OR word_similarity(?, name) > 0.4", "%"+escaped+"%", query).
+		Order(clause.Expr{SQL: "name ILIKE ? DESC, word_similarity(?, name) DESC, name ASC", Vars: []interface{}{escaped + "%", query}}).
+		Limit(limit).
+		Find(&drugs).Error
+	return drugs, err
+}
+
+// GetCatalogDrugByGTIN retrieves the entry with the given normalized GTIN.
+func (cg *CatalogGorm) GetCatalogDrugByGTIN(gtin string) (*CatalogDrug, error) {
+	var drug CatalogDrug
+	if err := cg.DB.Table("catalog_drugs").Where("gtin = ?", gtin).First(&drug).Error; err != nil {
+		return nil, err
+	}
+	return &drug, nil
+}
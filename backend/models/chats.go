@@ -42,10 +42,23 @@ func (cg *ChatGorm) GetChatByID(chatID uint) (*Chat, error) {
 	return &chat, err
 }
 
+// GetUserChat retrieves a chat with its messages if it belongs to the given user.
+// Returns gorm.ErrRecordNotFound for chats of other users.
+func (cg *ChatGorm) GetUserChat(userID, chatID uint) (*Chat, error) {
+	var chat Chat
+	err := cg.DB.Scopes(OwnedBy(userID)).Preload("Messages", func(db *gorm.DB) *gorm.DB {
+		return db.Order("timestamp asc, id asc")
+	}).First(&chat, chatID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
 // GetUserChats retrieves all chats that belong to a given user by their user ID.
 // Returns a slice of Chat objects or an error.
 func (cg *ChatGorm) GetUserChats(userID uint) ([]Chat, error) {
 	var chats []Chat
-	err := cg.DB.Where("user_id = ?", userID).Find(&chats).Error
+	err := cg.DB.Scopes(OwnedBy(userID)).Find(&chats).Error
 	return chats, err
 }
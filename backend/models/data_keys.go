@@ -0,0 +1,163 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DataKey is a user's key encrypting their sensitive fields and document files.
+// The key itself is stored wrapped (encrypted) with a master key, which never reaches the database.
+type DataKey struct {
+	ID          uint       `gorm:"primaryKey"`
+	UserID      uint       // Owner of the encrypted data
+	MasterKeyID string     // Master key the key is wrapped with
+	WrappedKey  []byte     // Nonce and ciphertext of the key
+	Active      bool       // New data is encrypted with the user's active key
+	CreatedAt   time.Time  //
+	RetiredAt   *time.Time // When the key was replaced, data is re-encrypted and the key deleted afterwards
+}
+
+// DataKeyStats counts the data keys by state for the "keys status" command.
+type DataKeyStats struct {
+	Active       int64
+	Retired      int64
+	ByMasterKeys map[string]int64 // Keys by the ID of the master key wrapping them
+}
+
+// DataKeyGorm wraps a GORM DB instance for data keys.
+type DataKeyGorm struct {
+	DB *gorm.DB
+}
+
+// NewDataKeyGorm creates a new instance of DataKeyGorm.
+func NewDataKeyGorm(db *gorm.DB) *DataKeyGorm {
+	return &DataKeyGorm{DB: db}
+}
+
+// GetKey retrieves a data key by its ID.
+func (kg *DataKeyGorm) GetKey(id uint) (*DataKey, error) {
+	var key DataKey
+	if err := kg.DB.Table("data_keys").Where("id = ?", id).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetActiveKey retrieves the active data key of the user.
+func (kg *DataKeyGorm) GetActiveKey(userID uint) (*DataKey, error) {
+	var key DataKey
+	if err := kg.DB.Table("data_keys").Where("user_id = ? AND active", userID).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateKey inserts a new active key. It fails if the user already has one,
+// so concurrent requests cannot create two.
+func (kg *DataKeyGorm) CreateKey(key *DataKey) error {
+	return kg.DB.Table("data_keys").Create(key).Error
+}
+
+// RetireActiveKeys retires the active key of every user, new ones are created on the next write.
+// Returns the number of retired keys.
+func (kg *DataKeyGorm) RetireActiveKeys() (int64, error) {
+	result := kg.DB.Table("data_keys").Where("active").
+		Updates(map[string]interface{}{"active": false, "retired_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
+
+// GetKeysNotWrappedWith returns up to limit keys wrapped with other master keys than the given one.
+func (kg *DataKeyGorm) GetKeysNotWrappedWith(masterKeyID string, limit int) ([]DataKey, error) {
+	keys := []DataKey{}
+	err := kg.DB.Table("data_keys").Where("master_key_id <> ?", masterKeyID).Order("id asc").Limit(limit).Find(&keys).Error
+	return keys, err
+}
+
+// UpdateWrapping replaces the wrapped key and its master key.
+func (kg *DataKeyGorm) UpdateWrapping(key *DataKey) error {
+	return kg.DB.Table("data_keys").Where("id = ?", key.ID).Updates(map[string]interface{}{
+		"master_key_id": key.MasterKeyID,
+		"wrapped_key":   key.WrappedKey,
+	}).Error
+}
+
+// GetRetiredKeys returns all retired keys without the key material.
+func (kg *DataKeyGorm) GetRetiredKeys() ([]DataKey, error) {
+	keys := []DataKey{}
+	err := kg.DB.Table("data_keys").Omit("wrapped_key").Where("NOT active").Order("id asc").Find(&keys).Error
+	return keys, err
+}
+
+// DeleteKeys deletes retired keys, documents and thumbnails still encrypted with one of them make it fail.
+func (kg *DataKeyGorm) DeleteKeys(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return kg.DB.Table("data_keys").Where("id IN ? AND NOT active", ids).Delete(&DataKey{}).Error
+}
+
+// GetStaleRows returns up to limit IDs above afterID of rows having a value in one of the
+// columns that is plaintext or encrypted with one of the keys. Encrypted values start with
+// prefix followed by the key ID and a colon.
+func (kg *DataKeyGorm) GetStaleRows(table string, columns []string, prefix string, keyIDs []uint, afterID uint, limit int) ([]uint, error) {
+	keys := make([]string, len(keyIDs))
+	for i, id := range keyIDs {
+		keys[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	stale := kg.DB.Where("1 = 0")
+	for _, column := range columns {
+		column = kg.DB.Statement.Quote(column)
+		stale = stale.Or(column+" <> '' AND NOT starts_with("+column+", ?)", prefix)
+		if len(keys) > 0 {
+			stale = stale.Or("starts_with("+column+", ?) AND split_part("+column+", ':', 3) IN ?", prefix, keys)
+		}
+	}
+
+	ids := []uint{}
+	err := kg.DB.Table(table).Where("id > ?", afterID).Where(stale).Order("id asc").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ResaveRow loads a row into the model and saves the columns again, so encrypted fields are
+// encrypted with the owner's active key. The row is locked meanwhile, concurrent changes are kept.
+func (kg *DataKeyGorm) ResaveRow(table string, columns []string, id uint, model interface{}) error {
+	return kg.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Table(table).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(model).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Table(table).Model(model).Select(columns).Updates(model).Error
+	})
+}
+
+// Stats counts the keys by state and master key.
+func (kg *DataKeyGorm) Stats() (*DataKeyStats, error) {
+	stats := &DataKeyStats{ByMasterKeys: map[string]int64{}}
+	var rows []struct {
+		MasterKeyID string
+		Active      bool
+		Count       int64
+	}
+	err := kg.DB.Table("data_keys").Select("master_key_id, active, COUNT(*) AS count").
+		Group("master_key_id, active").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Active {
+			stats.Active += row.Count
+		} else {
+			stats.Retired += row.Count
+		}
+		stats.ByMasterKeys[row.MasterKeyID] += row.Count
+	}
+	return stats, nil
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dependent is a profile of someone without an account, e.g. a child or an elderly relative,
+// managed by caregivers. The profile is a user without credentials, so drugs, documents and the
+// medical card are stored for it like for any other user.
+type Dependent struct {
+	UserID    uint       `gorm:"primaryKey" json:"id"` // User ID of the profile, sent in X-On-Behalf-Of
+	Name      string     `gorm:"->" json:"name"`       // Name of the profile, read from the users table
+	CreatedBy *uint      `json:"created_by"`           // Caregiver who created the profile
+	GroupID   *uint      `json:"group_id"`             // Household whose owner and caregivers also manage the profile
+	BirthDate *time.Time `json:"birth_date"`           // Optional date of birth
+	CreatedAt time.Time  `json:"created_at"`           // When the profile was created
+}
+
+// AuditEntry records a request made on behalf of a profile or a change to it.
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProfileID uint      `json:"profile_id"`           // Profile that was accessed
+	ActorID   *uint     `json:"actor_id"`             // Caregiver who made the request, null if the account was deleted
+	ActorName string    `gorm:"->" json:"actor_name"` // Name of the caregiver
+	Method    string    `json:"method" example:"GET"`
+	Path      string    `json:"path" example:"/auth/drugs"`
+	Status    int       `json:"status" example:"200"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DependentGorm wraps a GORM DB instance for operations on dependent profiles.
+type DependentGorm struct {
+	DB *gorm.DB
+}
+
+// NewDependentGorm creates a new instance of DependentGorm.
+func NewDependentGorm(db *gorm.DB) *DependentGorm {
+	return &DependentGorm{DB: db}
+}
+
+// ManagedBy is a GORM scope limiting a dependents query to profiles the user may manage:
+// profiles they created and profiles of groups where they are the owner or a caregiver.
+func ManagedBy(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("dependents.created_by = ? OR dependents.group_id IN (SELECT group_id FROM user_groups WHERE user_id = ? AND role IN ?)",
+			userID, userID, rolesAllowedTo(PermManageDependents))
+	}
+}
+
+// dependents selects dependents together with their names.
+func (dg *DependentGorm) dependents() *gorm.DB {
+	return dg.DB.Table("dependents").
+		Select("dependents.*, users.name").
+		Joins("JOIN users ON users.id = dependents.user_id")
+}
+
+// CreateDependent creates the profile's user with an empty medical card and the dependent record.
+func (dg *DependentGorm) CreateDependent(name string, dependent *Dependent) (*Dependent, error) {
+	err := dg.DB.Transaction(func(tx *gorm.DB) error {
+		user := &User{Name: name}
+		if err := tx.Table("users").Omit("MedicalCard", "Documents", "Groups").Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("medical_cards").Create(&MedicalCard{UserID: user.ID}).Error; err != nil {
+			return err
+		}
+		dependent.UserID = user.ID
+		return tx.Table("dependents").Omit("Name").Create(dependent).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	dependent.Name = name
+	return dependent, nil
+}
+
+// GetManagedDependent retrieves a profile the user may manage.
+// Returns gorm.ErrRecordNotFound for other profiles and for regular users.
+func (dg *DependentGorm) GetManagedDependent(userID, id uint) (*Dependent, error) {
+	var dependent Dependent
+	if err := dg.dependents().Scopes(ManagedBy(userID)).Where("dependents.user_id = ?", id).Take(&dependent).Error; err != nil {
+		return nil, err
+	}
+	return &dependent, nil
+}
+
+// GetManagedDependents lists the profiles the user may manage, ordered by ID.
+func (dg *DependentGorm) GetManagedDependents(userID uint) ([]Dependent, error) {
+	var dependents []Dependent
+	err := dg.dependents().Scopes(ManagedBy(userID)).Order("dependents.user_id asc").Scan(&dependents).Error
+	return dependents, err
+}
+
+// UpdateDependent saves the name, birth date and group of a profile.
+func (dg *DependentGorm) UpdateDependent(dependent *Dependent) error {
+	return dg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("users").Where("id = ?", dependent.UserID).Update("name", dependent.Name).Error; err != nil {
+			return err
+		}
+		return tx.Table("dependents").Where("user_id = ?", dependent.UserID).Updates(map[string]interface{}{
+			"birth_date": dependent.BirthDate,
+			"group_id":   dependent.GroupID,
+		}).Error
+	})
+}
+
+// RecordAccess adds an entry to the audit trail of a profile.
+func (dg *DependentGorm) RecordAccess(entry *AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return dg.DB.Table("profile_audit").Omit("ActorName").Create(entry).Error
+}
+
+// GetAuditTrail lists the latest audit entries of a profile, newest first.
+func (dg *DependentGorm) GetAuditTrail(profileID uint, limit int) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := dg.DB.Table("profile_audit").
+		Select("profile_audit.*, COALESCE(users.name, '') AS actor_name").
+		Joins("LEFT JOIN users ON users.id = profile_audit.actor_id").
+		Where("profile_audit.profile_id = ?", profileID).
+		Order("profile_audit.created_at desc, profile_audit.id desc").
+		Limit(limit).
+		Scan(&entries).Error
+	return entries, err
+}
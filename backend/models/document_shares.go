@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DocumentShare is a read-only link to a document for someone without an account, e.g. a doctor.
+// Links expire, can be revoked early and may require a PIN.
+type DocumentShare struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	DocumentID        uint       `json:"document_id"`
+	UserID            uint       `json:"-"`
+	TokenHash         string     `json:"-"`                                                     // SHA-256 hash of the token in the link
+	PINHash           string     `gorm:"column:pin_hash" json:"-"`                              // bcrypt hash of the PIN, empty if none is required
+	ExpiresAt         time.Time  `json:"expires_at" example:"2025-07-15T12:00:00Z"`             // The link stops working at this time
+	RevokedAt         *time.Time `json:"revoked_at"`                                            // When the link was revoked, by the user or after too many wrong PINs
+	AccessCount       int        `json:"access_count" example:"2"`                              // Number of times the document was opened or downloaded
+	FirstAccessedAt   *time.Time `json:"first_accessed_at"`                                     // When the link was first used
+	LastAccessedAt    *time.Time `json:"last_accessed_at"`                                      // When the link was last used
+	FailedPINAttempts int        `gorm:"column:failed_pin_attempts" json:"failed_pin_attempts"` // Wrong PINs entered
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// Active reports whether the link can be opened at the given time.
+func (s *DocumentShare) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// CreateShare inserts a new share link.
+func (dg *DocumentGorm) CreateShare(share *DocumentShare) (*DocumentShare, error) {
+	if err := dg.DB.Table("document_shares").Create(share).Error; err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// GetShareByHash retrieves a share link by the hash of its token.
+func (dg *DocumentGorm) GetShareByHash(hash string) (*DocumentShare, error) {
+	var share DocumentShare
+	if err := dg.DB.Table("document_shares").Where("token_hash = ?", hash).First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetShare retrieves a share link by its ID.
+func (dg *DocumentGorm) GetShare(id uint) (*DocumentShare, error) {
+	var share DocumentShare
+	if err := dg.DB.Table("document_shares").Where("id = ?", id).First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetDocumentShares lists the share links of a document of the user, newest first.
+func (dg *DocumentGorm) GetDocumentShares(userID uint, documentID int) ([]DocumentShare, error) {
+	shares := []DocumentShare{}
+	err := dg.DB.Table("document_shares").Scopes(OwnedBy(userID)).Where("document_id = ?", documentID).
+		Order("id desc").Find(&shares).Error
+	return shares, err
+}
+
+// RevokeUserShare revokes a share link of the user. Revoking a revoked link keeps the first revocation time.
+// Returns gorm.ErrRecordNotFound if the link does not exist or belongs to another user.
+func (dg *DocumentGorm) RevokeUserShare(userID uint, id int, now time.Time) (*DocumentShare, error) {
+	result := dg.DB.Table("document_shares").Scopes(OwnedBy(userID)).Where("id = ?", id).
+		Update("revoked_at", gorm.Expr("COALESCE(revoked_at, ?)", now))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return dg.GetShare(uint(id))
+}
+
+// RecordShareAccess counts an access to the shared document.
+func (dg *DocumentGorm) RecordShareAccess(id uint, now time.Time) error {
+	return dg.DB.Table("document_shares").Where("id = ?", id).Updates(map[string]interface{}{
+		"access_count":      gorm.Expr("access_count + 1"),
+		"first_accessed_at": gorm.Expr("COALESCE(first_accessed_at, ?)", now),
+		"last_accessed_at":  now,
+	}).Error
+}
+
+// ReservePINAttempt counts an attempt to enter the PIN of an active link before the PIN is checked,
+// so concurrent requests cannot guess more than maxAttempts PINs. Returns gorm.ErrRecordNotFound
+// if the link is revoked, expired or has no attempts left.
+func (dg *DocumentGorm) ReservePINAttempt(id uint, maxAttempts int, now time.Time) error {
+	result := dg.DB.Table("document_shares").
+		Where("id = ? AND failed_pin_attempts < ? AND revoked_at IS NULL AND expires_at > ?", id, maxAttempts, now).
+		Update("failed_pin_attempts", gorm.Expr("failed_pin_attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReleasePINAttempt gives back the attempt reserved for a PIN that turned out to be correct.
+func (dg *DocumentGorm) ReleasePINAttempt(id uint) error {
+	return dg.DB.Table("document_shares").Where("id = ? AND failed_pin_attempts > 0", id).
+		Update("failed_pin_attempts", gorm.Expr("failed_pin_attempts - 1")).Error
+}
+
+// RevokeAfterFailedPINs revokes the link once maxAttempts wrong PINs were entered,
+// so short PINs cannot be guessed.
+func (dg *DocumentGorm) RevokeAfterFailedPINs(id uint, maxAttempts int, now time.Time) error {
+	return dg.DB.Table("document_shares").Where("id = ? AND failed_pin_attempts >= ?", id, maxAttempts).
+		Update("revoked_at", gorm.Expr("COALESCE(revoked_at, ?)", now)).Error
+}
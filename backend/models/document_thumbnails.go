@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// States of a document thumbnail. Documents without a thumbnail row are waiting for one.
+const (
+	ThumbnailReady       = "ready"       // The thumbnail can be downloaded
+	ThumbnailUnavailable = "unavailable" // The file has no preview, e.g. a text file or an empty page
+	ThumbnailFailed      = "failed"      // Generating failed, it is retried up to MaxThumbnailAttempts times
+)
+
+// MaxThumbnailAttempts is how many times generating a thumbnail is tried before giving up.
+const MaxThumbnailAttempts = 3
+
+// DocumentThumbnail is the preview image of a document, encrypted like the file itself.
+type DocumentThumbnail struct {
+	DocumentID uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"->"` // Owner of the document, read with the stale thumbnails
+	Status     string    // One of the Thumbnail* states
+	Data       []byte    // JPEG image, encrypted with the data key if DataKeyID is set
+	DataKeyID  *uint     // Data key the image is encrypted with, nil for plaintext images
+	SHA256     string    `gorm:"column:sha256"` // Hash of the plaintext image
+	Width      int       // Width in pixels
+	Height     int       // Height in pixels
+	Attempts   int       // Failed attempts to generate the thumbnail
+	Error      string    // Why the last attempt failed
+	CreatedAt  time.Time // When the thumbnail was generated
+}
+
+// Pending reports whether the thumbnail is still to be generated.
+func (t *DocumentThumbnail) Pending() bool {
+	return t.Status == ThumbnailFailed && t.Attempts < MaxThumbnailAttempts
+}
+
+// GetThumbnail retrieves the thumbnail of a document.
+// Returns gorm.ErrRecordNotFound if it was not generated yet.
+func (dg *DocumentGorm) GetThumbnail(documentID uint) (*DocumentThumbnail, error) {
+	var thumb DocumentThumbnail
+	if err := dg.DB.Table("document_thumbnails").Where("document_id = ?", documentID).First(&thumb).Error; err != nil {
+		return nil, err
+	}
+	return &thumb, nil
+}
+
+// GetDocumentsWithoutThumbnails returns up to limit documents with IDs above afterID that have
+// no thumbnail yet or whose previous attempts failed fewer than MaxThumbnailAttempts times.
+func (dg *DocumentGorm) GetDocumentsWithoutThumbnails(afterID uint, limit int) ([]Document, error) {
+	docs := []Document{}
+	err := dg.DB.Table("documents").Omit("file_data").
+		Joins("LEFT JOIN document_thumbnails ON document_thumbnails.document_id = documents.id").
+		Where("documents.id > ?", afterID).
+		Where("document_thumbnails.document_id IS NULL OR (document_thumbnails.status = ? AND document_thumbnails.attempts < ?)",
+			ThumbnailFailed, MaxThumbnailAttempts).
+		Order("documents.id asc").Limit(limit).Find(&docs).Error
+	return docs, err
+}
+
+// SaveThumbnail stores the thumbnail of a document, replacing a failed attempt.
+func (dg *DocumentGorm) SaveThumbnail(thumb *DocumentThumbnail) error {
+	return dg.DB.Table("document_thumbnails").Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "document_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"status", "data", "data_key_id", "sha256", "width", "height", "error", "created_at",
+		}),
+	}).Create(thumb).Error
+}
+
+// RecordThumbnailFailure counts a failed attempt to generate the thumbnail of a document.
+func (dg *DocumentGorm) RecordThumbnailFailure(documentID uint, reason string, now time.Time) error {
+	thumb := &DocumentThumbnail{DocumentID: documentID, Status: ThumbnailFailed, Attempts: 1, Error: reason, CreatedAt: now}
+	return dg.DB.Table("document_thumbnails").Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "document_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "attempts"}, Value: clause.Expr{SQL: "document_thumbnails.attempts + 1"}},
+			{Column: clause.Column{Name: "error"}, Value: reason},
+			{Column: clause.Column{Name: "created_at"}, Value: now},
+		},
+	}).Create(thumb).Error
+}
+
+// GetStaleThumbnails returns up to limit ready thumbnails with document IDs above afterID
+// that are not encrypted or are encrypted with one of the retired keys.
+func (dg *DocumentGorm) GetStaleThumbnails(retiredKeys []uint, afterID uint, limit int) ([]DocumentThumbnail, error) {
+	thumbs := []DocumentThumbnail{}
+	query := dg.DB.Table("document_thumbnails").Select("document_thumbnails.*, documents.user_id").
+		Joins("JOIN documents ON documents.id = document_thumbnails.document_id").
+		Where("document_thumbnails.status = ? AND document_thumbnails.document_id > ?", ThumbnailReady, afterID)
+	if len(retiredKeys) > 0 {
+		query = query.Where("document_thumbnails.data_key_id IS NULL OR document_thumbnails.data_key_id IN ?", retiredKeys)
+	} else {
+		query = query.Where("document_thumbnails.data_key_id IS NULL")
+	}
+	err := query.Order("document_thumbnails.document_id asc").Limit(limit).Find(&thumbs).Error
+	return thumbs, err
+}
+
+// ReplaceThumbnailData stores the re-encrypted image of a thumbnail.
+// Nothing changes if the thumbnail was regenerated meanwhile.
+func (dg *DocumentGorm) ReplaceThumbnailData(thumb *DocumentThumbnail, data []byte, dataKeyID *uint) error {
+	query := dg.DB.Table("document_thumbnails").Where("document_id = ? AND sha256 = ?", thumb.DocumentID, thumb.SHA256)
+	if thumb.DataKeyID != nil {
+		query = query.Where("data_key_id = ?", *thumb.DataKeyID)
+	} else {
+		query = query.Where("data_key_id IS NULL")
+	}
+	return query.Updates(map[string]interface{}{"data": data, "data_key_id": dataKeyID}).Error
+}
@@ -7,14 +7,22 @@ import (
 )
 
 // Document represents a medical document record belonging to a user.
+// The file itself is downloaded separately, see GET /auth/documents/{id}/file.
 type Document struct {
-	ID       uint      `gorm:"primaryKey" json:"id"`                // Unique document ID (hidden from JSON)
-	UserID   uint      `json:"user_id"`                             // ID of the user the document belongs to (hidden from JSON)
-	Name     string    `json:"name"`                                // Name/title of the document
-	Type     string    `json:"type"`                                // Type/category of document (e.g. prescription, report)
-	Date     time.Time `json:"date" example:"2025-07-12T23:45:00Z"` // Date the document was created or issued
-	Doctor   string    `json:"doctor"`                              // Name of the doctor associated with the document
-	FileData []byte    `json:"file_data"`                           // File contents (binary), base64-encoded when serialized to JSON
+	ID          uint      `gorm:"primaryKey" json:"id"`                      // Unique document ID (hidden from JSON)
+	UserID      uint      `json:"user_id"`                                   // ID of the user the document belongs to (hidden from JSON)
+	Name        string    `json:"name"`                                      // Name/title of the document
+	Type        string    `json:"type"`                                      // Type/category of document (e.g. prescription, report)
+	Date        time.Time `json:"date" example:"2025-07-12T23:45:00Z"`       // Date the document was created or issued
+	Doctor      string    `json:"doctor"`                                    // Name of the doctor associated with the document
+	FileName    string    `json:"file_name" example:"blood_test.pdf"`        // Name of the uploaded file
+	ContentType string    `json:"content_type" example:"application/pdf"`    // MIME type detected from the contents
+	Size        int64     `json:"size" example:"48213"`                      // File size in bytes
+	CreatedAt   time.Time `json:"created_at" example:"2025-07-12T23:45:00Z"` // When the document was uploaded
+	SHA256      string    `gorm:"column:sha256" json:"sha256"`               // Hash of the uploaded file
+	BlobKey     string    `json:"-"`                                         // Key of the encrypted file in the blob store
+	DataKeyID   *uint     `json:"-"`                                         // Data key the file is encrypted with, nil for plaintext files
+	FileData    []byte    `json:"-"`                                         // Contents of files not yet moved to the blob store
 }
 
 // DocumentGorm wraps a GORM DB instance to perform CRUD operations on Document models.
@@ -46,11 +54,21 @@ func (dg *DocumentGorm) GetDocumentById(id int) (*Document, error) {
 	return &doc, nil
 }
 
+// GetUserDocument retrieves a document with its file by its ID if it belongs to the given user.
+// Returns gorm.ErrRecordNotFound for documents of other users.
+func (dg *DocumentGorm) GetUserDocument(userID uint, id int) (*Document, error) {
+	var doc Document
+	if err := dg.DB.Table("documents").Scopes(OwnedBy(userID)).Where("id = ?", id).First(&doc).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
 // GetDocumentsByUserId fetches all documents belonging to a specific user by their user ID.
-// Returns a slice of Document objects or an error.
+// Returns a slice of Document objects without file contents or an error.
 func (dg *DocumentGorm) GetDocumentsByUserId(userId uint) ([]Document, error) {
-	var docs []Document
-	err := dg.DB.Table("documents").Where("user_id = ?", userId).Find(&docs).Error
+	docs := []Document{}
+	err := dg.DB.Table("documents").Scopes(OwnedBy(userId)).Omit("file_data").Order("id asc").Find(&docs).Error
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +98,7 @@ func (dg *DocumentGorm) UpdateDocument(id int, args map[string]interface{}) (*Do
 	}
 	if val, ok := args["FileData"].([]byte); ok {
 		doc.FileData = val
+		doc.Size = int64(len(val))
 	}
 
 	// Save updated record
@@ -89,9 +108,98 @@ func (dg *DocumentGorm) UpdateDocument(id int, args map[string]interface{}) (*Do
 	return doc, nil
 }
 
+// DeleteDocumentById deletes a document by its ID.
 func (dg *DocumentGorm) DeleteDocumentById(id int) error {
 	if err := dg.DB.Table("documents").Where("id = ?", id).Delete(&Document{}).Error; err != nil {
 		return err
 	}
 	return nil
 }
+
+// WithBlobLock runs fn in a transaction holding a lock on the blob key. Adding and removing
+// documents under the lock keeps a blob from being deleted while a new document refers to it.
+func (dg *DocumentGorm) WithBlobLock(key string, fn func(tx *DocumentGorm) error) error {
+	return dg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "blob:"+key).Error; err != nil {
+			return err
+		}
+		return fn(NewDocumentGorm(tx))
+	})
+}
+
+// BlobReferenced reports whether any document refers to the blob key.
+func (dg *DocumentGorm) BlobReferenced(key string) (bool, error) {
+	var count int64
+	err := dg.DB.Table("documents").Where("blob_key = ?", key).Count(&count).Error
+	return count > 0, err
+}
+
+// GetDatabaseFiles returns up to limit documents whose files are still kept in the database.
+func (dg *DocumentGorm) GetDatabaseFiles(limit int) ([]Document, error) {
+	docs := []Document{}
+	err := dg.DB.Table("documents").Where("blob_key = '' AND file_data IS NOT NULL").Order("id asc").Limit(limit).Find(&docs).Error
+	return docs, err
+}
+
+// CountDatabaseFiles counts the documents whose files are still kept in the database.
+func (dg *DocumentGorm) CountDatabaseFiles() (int64, error) {
+	var count int64
+	err := dg.DB.Table("documents").Where("blob_key = '' AND file_data IS NOT NULL").Count(&count).Error
+	return count, err
+}
+
+// MoveFileToBlob replaces the file of a document with a reference to its blob.
+// The hash is of the file itself, the key of the blob differs from it for encrypted files.
+func (dg *DocumentGorm) MoveFileToBlob(id uint, hash, key string, dataKeyID *uint) error {
+	return dg.DB.Table("documents").Where("id = ?", id).Updates(map[string]interface{}{
+		"sha256":      hash,
+		"blob_key":    key,
+		"data_key_id": dataKeyID,
+		"file_data":   nil,
+	}).Error
+}
+
+// GetBlobFiles returns up to limit documents kept in the blob store with IDs above afterID.
+func (dg *DocumentGorm) GetBlobFiles(afterID uint, limit int) ([]Document, error) {
+	docs := []Document{}
+	err := dg.DB.Table("documents").Omit("file_data").Where("blob_key <> '' AND id > ?", afterID).Order("id asc").Limit(limit).Find(&docs).Error
+	return docs, err
+}
+
+// GetStaleBlobFiles returns up to limit documents with IDs above afterID whose blobs are
+// not encrypted or are encrypted with one of the retired keys.
+func (dg *DocumentGorm) GetStaleBlobFiles(retiredKeys []uint, afterID uint, limit int) ([]Document, error) {
+	docs := []Document{}
+	query := dg.DB.Table("documents").Omit("file_data").Where("blob_key <> '' AND id > ?", afterID)
+	if len(retiredKeys) > 0 {
+		query = query.Where("data_key_id IS NULL OR data_key_id IN ?", retiredKeys)
+	} else {
+		query = query.Where("data_key_id IS NULL")
+	}
+	err := query.Order("id asc").Limit(limit).Find(&docs).Error
+	return docs, err
+}
+
+// ReplaceBlob points the document to a re-encrypted blob.
+// Returns false if the document was deleted or its blob replaced meanwhile.
+func (dg *DocumentGorm) ReplaceBlob(id uint, oldKey, newKey string, dataKeyID *uint) (bool, error) {
+	result := dg.DB.Table("documents").Where("id = ? AND blob_key = ?", id, oldKey).
+		Updates(map[string]interface{}{"blob_key": newKey, "data_key_id": dataKeyID})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MoveFileToDatabase stores the decrypted file in the document again and drops the blob reference.
+func (dg *DocumentGorm) MoveFileToDatabase(id uint, data []byte) error {
+	return dg.DB.Table("documents").Where("id = ?", id).Updates(map[string]interface{}{
+		"sha256":      "",
+		"blob_key":    "",
+		"data_key_id": nil,
+		"file_data":   data,
+	}).Error
+}
+
+// DeleteUserDocument deletes a document if it belongs to the given user.
+// Returns gorm.ErrRecordNotFound if the document does not exist or belongs to another user.
+func (dg *DocumentGorm) DeleteUserDocument(userID uint, id int) error {
+	return deleteOwned(dg.DB, "documents", &Document{}, userID, id)
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the drug history
+const (
+	DrugAdded    = "added"    // The drug was added to the cabinet
+	DrugUsed     = "used"     // A dose was taken
+	DrugReturned = "returned" // A taken dose was undone
+	DrugAdjusted = "adjusted" // The quantity was changed by hand
+	DrugRemoved  = "removed"  // The drug was removed from the cabinet
+)
+
+// DrugEvent records who added, used or removed a drug.
+// Events are kept after the drug is removed, so the name is stored with them.
+type DrugEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	DrugID    uint      `json:"drug_id"`
+	DrugName  string    `json:"drug_name"`              // Name of the drug at the time of the event
+	GroupID   *uint     `json:"group_id"`               // Group of a shared drug, null for personal drugs
+	UserID    *uint     `json:"user_id"`                // Who did it, null if the account was deleted
+	UserName  string    `gorm:"->" json:"user_name"`    // Name of the user, read from the users table
+	Action    string    `json:"action" example:"used"`  // added, used, returned, adjusted or removed
+	Delta     *float64  `json:"delta"`                  // Amount added, used, adjusted or removed, null if unknown
+	Unit      string    `json:"unit" example:"tablets"` // Unit of the amount
+	CreatedAt time.Time `json:"created_at"`             // When it happened
+}
+
+// recordDrugEvent adds an event to the drug's history.
+func recordDrugEvent(tx *gorm.DB, drug *Drug, userID uint, action string, delta *float64) error {
+	return tx.Table("drug_events").Omit("UserName").Create(&DrugEvent{
+		DrugID:    drug.ID,
+		DrugName:  drug.Name,
+		GroupID:   drug.GroupID,
+		UserID:    &userID,
+		Action:    action,
+		Delta:     delta,
+		Unit:      drug.Unit,
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+// drugEvents selects events together with the names of the users.
+func (dg *DrugGorm) drugEvents() *gorm.DB {
+	return dg.DB.Table("drug_events").
+		Select("drug_events.*, COALESCE(users.name, '') AS user_name").
+		Joins("LEFT JOIN users ON users.id = drug_events.user_id").
+		Order("drug_events.created_at desc, drug_events.id desc")
+}
+
+// GetDrugEvents lists the history of a drug, newest first.
+func (dg *DrugGorm) GetDrugEvents(drugID uint) ([]DrugEvent, error) {
+	var events []DrugEvent
+	err := dg.drugEvents().Where("drug_events.drug_id = ?", drugID).Scan(&events).Error
+	return events, err
+}
+
+// GetCabinetEvents lists the latest events of the user's personal drugs, of their groups'
+// drugs (of one group if groupID is set) or both, newest first, including removed drugs.
+func (dg *DrugGorm) GetCabinetEvents(userID uint, scope string, groupID *uint, limit int) ([]DrugEvent, error) {
+	query := dg.drugEvents()
+	groups := "drug_events.group_id IN (SELECT group_id FROM user_groups WHERE user_id = ?)"
+	personal := "drug_events.group_id IS NULL AND drug_events.user_id = ?"
+	switch scope {
+	case ScopeGroup:
+		query = query.Where(groups, userID)
+		if groupID != nil {
+			query = query.Where("drug_events.group_id = ?", *groupID)
+		}
+	case ScopeAll:
+		query = query.Where("("+personal+") OR "+groups, userID, userID)
+	default:
+		query = query.Where(personal, userID)
+	}
+
+	var events []DrugEvent
+	err := query.Limit(limit).Scan(&events).Error
+	return events, err
+}
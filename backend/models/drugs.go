@@ -4,13 +4,16 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Drug represents a medication record stored by a user.
 // It includes various fields describing the medication and its metadata.
 type Drug struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`                  // Unique identifier for the drug (hidden from JSON)
-	UserId       uint      `json:"user_id"`                               // ID of the user who owns the drug (hidden from JSON)
+	UserId       *uint     `json:"user_id"`                               // Owner of a personal drug, null for group drugs
+	GroupID      *uint     `json:"group_id"`                              // Group sharing the drug, null for personal drugs
+	AddedBy      *uint     `json:"added_by"`                              // User who added the drug
 	Name         string    `json:"name"`                                  // Name of the drug
 	Type         string    `json:"type"`                                  // Type or category of the drug
 	Description  string    `json:"description"`                           // Description or purpose of the drug
@@ -18,7 +21,31 @@ type Drug struct {
 	Location     string    `json:"location"`                              // Storage location of the drug
 	Manufacturer string    `json:"manufacturer"`                          // Manufacturer of the drug
 	Dose         string    `json:"dose"`                                  // Dosage information
-	Amount       string    `json:"amount"`                                // Quantity of the drug available
+	Amount       string    `json:"amount"`                                // Quantity of the drug available as free text
+	Quantity     *float64  `json:"quantity" example:"30"`                 // Structured quantity, null if unknown
+	Unit         string    `json:"unit" example:"tablets"`                // Unit of the quantity, see Units
+	LowStock     float64   `json:"low_stock" example:"5"`                 // Restock notification threshold, 0 disables it
+	KitID        *uint     `json:"kit_id"`                                // Kit the drug is kept in, null if none
+}
+
+// Drug list scopes
+const (
+	ScopePersonal = "personal" // The user's own drugs
+	ScopeGroup    = "group"    // Drugs shared by the user's groups
+	ScopeAll      = "all"      // Both
+)
+
+// CabinetScope is a GORM scope limiting a drugs query to the user's personal drugs,
+// drugs of their groups (of one group if groupID is set) or both, see the Scope constants.
+func CabinetScope(userID uint, scope string, groupID *uint) func(db *gorm.DB) *gorm.DB {
+	switch scope {
+	case ScopeGroup:
+		return SharedWith(userID, groupID)
+	case ScopeAll:
+		return OwnedOrShared(userID)
+	default:
+		return OwnedBy(userID)
+	}
 }
 
 // DrugGorm wraps a GORM DB instance for performing database operations on the Drug model.
@@ -33,8 +60,18 @@ func NewDrugGorm(db *gorm.DB) *DrugGorm {
 
 // CreateDrug inserts a new drug record into the database.
 // Takes a pointer to a Drug struct and returns the created record or an error.
+// If AddedBy is set, the addition is recorded in the drug's history.
 func (dg *DrugGorm) CreateDrug(drug *Drug) (*Drug, error) {
-	if err := dg.DB.Table("drugs").Create(drug).Error; err != nil {
+	err := dg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("drugs").Create(drug).Error; err != nil {
+			return err
+		}
+		if drug.AddedBy == nil {
+			return nil
+		}
+		return recordDrugEvent(tx, drug, *drug.AddedBy, DrugAdded, drug.Quantity)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return drug, nil
@@ -50,46 +87,181 @@ func (dg *DrugGorm) GetDrugById(id int) (*Drug, error) {
 	return &drug, nil
 }
 
-// UpdateDrug updates an existing drug record with the provided fields.
-// Accepts a map of fields to update and returns the updated drug or an error.
-func (dg *DrugGorm) UpdateDrug(id int, args map[string]interface{}) (*Drug, error) {
-	drug, err := dg.GetDrugById(id)
-	if err != nil {
+// GetUserDrug retrieves a drug record by its ID if it belongs to the given user or one of their groups.
+// Returns gorm.ErrRecordNotFound for drugs of other users.
+func (dg *DrugGorm) GetUserDrug(userID uint, id int) (*Drug, error) {
+	var drug Drug
+	if err := dg.DB.Table("drugs").Scopes(OwnedOrShared(userID)).Where("id = ?", id).First(&drug).Error; err != nil {
 		return nil, err
 	}
+	return &drug, nil
+}
 
-	// Update fields if provided
-	if val, ok := args["Type"].(string); ok {
-		drug.Type = val
-	}
-	if val, ok := args["Description"].(string); ok {
-		drug.Description = val
-	}
-	if val, ok := args["Expiry"].(time.Time); ok {
-		drug.Expiry = val
-	}
-	if val, ok := args["Location"].(string); ok {
-		drug.Location = val
+// GetUserDrugs retrieves the drugs with the given IDs that belong to the user or one of their groups.
+// Drugs of other users are left out.
+func (dg *DrugGorm) GetUserDrugs(userID uint, ids []uint) ([]Drug, error) {
+	drugs := []Drug{}
+	err := dg.DB.Table("drugs").Scopes(OwnedOrShared(userID)).Where("id IN ?", ids).Order("id asc").Find(&drugs).Error
+	return drugs, err
+}
+
+// UpdateDrug updates the provided fields of a drug under a row lock, so concurrent stock
+// changes are not overwritten. A changed quantity is recorded in the drug's history as
+// adjusted by userID. Accepts a map of fields to update and returns the updated drug or an error.
+func (dg *DrugGorm) UpdateDrug(id int, userID uint, args map[string]interface{}) (*Drug, error) {
+	columns := map[string]string{
+		"Name":         "name",
+		"Type":         "type",
+		"Description":  "description",
+		"Expiry":       "expiry",
+		"Location":     "location",
+		"Manufacturer": "manufacturer",
+		"Dose":         "dose",
+		"Amount":       "amount",
+		"Quantity":     "quantity",
+		"Unit":         "unit",
+		"LowStock":     "low_stock",
+		"KitID":        "kit_id",
 	}
 
-	// Save the updated record
-	if err := dg.DB.Table("drugs").Save(drug).Error; err != nil {
+	var drug Drug
+	err := dg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("drugs").Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&drug).Error; err != nil {
+			return err
+		}
+		before := drug.Quantity
+
+		updates := map[string]interface{}{}
+		for field, column := range columns {
+			if val, ok := args[field]; ok {
+				updates[column] = val
+			}
+		}
+
+		// A structured quantity always has a unit and is mirrored into the free-text amount
+		quantity, unit := drug.Quantity, drug.Unit
+		if val, ok := args["Quantity"].(float64); ok {
+			quantity = &val
+		}
+		if val, ok := args["Unit"].(string); ok {
+			unit = val
+		}
+		if quantity != nil && unit == "" {
+			unit = UnitPieces
+			updates["unit"] = unit
+		}
+		if _, ok := args["Amount"]; !ok && quantity != nil {
+			if _, ok := args["Quantity"]; ok {
+				updates["amount"] = FormatQuantity(*quantity, unit)
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Table("drugs").Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("drugs").Where("id = ?", id).First(&drug).Error; err != nil {
+			return err
+		}
+		if quantity == nil || (before != nil && *before == *quantity) {
+			return nil
+		}
+		delta := *quantity
+		if before != nil {
+			delta -= *before
+		}
+		return recordDrugEvent(tx, &drug, userID, DrugAdjusted, &delta)
+	})
+	if err != nil {
 		return nil, err
 	}
-	return drug, nil
+	return &drug, nil
+}
+
+// AdjustStock changes the quantity of a drug by delta, never going below zero, and keeps
+// the free-text amount in sync. Drugs without a known quantity are returned unchanged.
+// The change is recorded in the drug's history as used by userID, or returned for positive deltas.
+// Returns the quantity before and the drug after the change.
+func (dg *DrugGorm) AdjustStock(id, userID uint, delta float64) (float64, *Drug, error) {
+	var before float64
+	var drug Drug
+	err := dg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("drugs").Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&drug).Error; err != nil {
+			return err
+		}
+
+		action := DrugUsed
+		if delta > 0 {
+			action = DrugReturned
+		}
+		if err := recordDrugEvent(tx, &drug, userID, action, &delta); err != nil {
+			return err
+		}
+		if drug.Quantity == nil {
+			return nil
+		}
+
+		before = *drug.Quantity
+		after := before + delta
+		if after < 0 {
+			after = 0
+		}
+		drug.Quantity = &after
+		drug.Amount = FormatQuantity(after, drug.Unit)
+
+		return tx.Table("drugs").Where("id = ?", id).Updates(map[string]interface{}{
+			"quantity": after,
+			"amount":   drug.Amount,
+		}).Error
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return before, &drug, nil
 }
 
 // GetDrugsByUserId retrieves all drug records associated with a specific user ID.
 // Returns a slice of drugs or an error.
 func (dg *DrugGorm) GetDrugsByUserId(id uint) ([]Drug, error) {
 	var drugs []Drug
-	err := dg.DB.Table("drugs").Where("user_id = ?", id).Find(&drugs).Error
+	err := dg.DB.Table("drugs").Scopes(OwnedBy(id)).Order("id asc").Find(&drugs).Error
 	if err != nil {
 		return nil, err
 	}
 	return drugs, nil
 }
 
+// GetCabinetDrugs retrieves the drugs of a cabinet, see CabinetScope.
+func (dg *DrugGorm) GetCabinetDrugs(cabinet func(db *gorm.DB) *gorm.DB) ([]Drug, error) {
+	var drugs []Drug
+	err := dg.DB.Table("drugs").Scopes(cabinet).Order("id asc").Find(&drugs).Error
+	return drugs, err
+}
+
+// GetDrugsByKit retrieves the drugs of a cabinet kept in the given kit, or drugs without a kit if kitID is nil.
+func (dg *DrugGorm) GetDrugsByKit(cabinet func(db *gorm.DB) *gorm.DB, kitID *uint) ([]Drug, error) {
+	query := dg.DB.Table("drugs").Scopes(cabinet)
+	if kitID == nil {
+		query = query.Where("kit_id IS NULL")
+	} else {
+		query = query.Where("kit_id = ?", *kitID)
+	}
+
+	var drugs []Drug
+	err := query.Order("id asc").Find(&drugs).Error
+	return drugs, err
+}
+
+// GetDrugsExpiringBefore retrieves drugs of all users that expire before the given time,
+// including already expired ones.
+func (dg *DrugGorm) GetDrugsExpiringBefore(t time.Time) ([]Drug, error) {
+	var drugs []Drug
+	err := dg.DB.Table("drugs").Where("expiry < ?", t).Order("id asc").Find(&drugs).Error
+	return drugs, err
+}
+
 // DeleteDrugById deletes a drug record from the database by its ID.
 // Returns an error if the operation fails.
 func (dg *DrugGorm) DeleteDrugById(id int) error {
@@ -98,3 +270,32 @@ func (dg *DrugGorm) DeleteDrugById(id int) error {
 	}
 	return nil
 }
+
+// DeleteUserDrug deletes a drug record if it belongs to the given user or one of their groups,
+// and records the removal in the drug's history.
+// Returns gorm.ErrRecordNotFound if the drug does not exist or belongs to another user.
+func (dg *DrugGorm) DeleteUserDrug(userID uint, id int) error {
+	return dg.DB.Transaction(func(tx *gorm.DB) error {
+		var drug Drug
+		if err := tx.Table("drugs").Scopes(OwnedOrShared(userID)).Where("id = ?", id).First(&drug).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("drugs").Where("id = ?", drug.ID).Delete(&Drug{}).Error; err != nil {
+			return err
+		}
+		return recordDrugEvent(tx, &drug, userID, DrugRemoved, drug.Quantity)
+	})
+}
+
+// Recipients returns the users notified about the drug: its owner, or all members of its group.
+func (dg *DrugGorm) Recipients(drug *Drug) ([]uint, error) {
+	if drug.UserId != nil {
+		return []uint{*drug.UserId}, nil
+	}
+	var users []uint
+	if drug.GroupID == nil {
+		return users, nil
+	}
+	err := dg.DB.Table("user_groups").Where("group_id = ?", *drug.GroupID).Order("user_id asc").Pluck("user_id", &users).Error
+	return users, err
+}
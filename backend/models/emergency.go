@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmergencyContact is a person to contact when the user is in an emergency.
+// SOS alerts go to every address the contact has.
+type EmergencyContact struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `json:"-"`
+	Name       string    `json:"name" example:"Anna Ivanova"`
+	Phone      string    `json:"phone" example:"+79001234567"`
+	Email      string    `json:"email" example:"anna@example.com"`
+	WebhookURL string    `json:"webhook_url" example:"https://example.com/hooks/sos"`
+	Relation   string    `json:"relation" example:"daughter"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PublicContact is the part of an emergency contact shown on the emergency ID.
+type PublicContact struct {
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	Relation string `json:"relation"`
+}
+
+// EmergencyProfile holds the settings of a user's public emergency ID:
+// the hash of the public URL token and which fields are published.
+type EmergencyProfile struct {
+	UserID                uint      `gorm:"primaryKey" json:"-"`
+	TokenHash             string    `json:"-"` // SHA-256 of the unguessable part of the public URL
+	ShowName              bool      `json:"show_name"`
+	ShowBloodType         bool      `json:"show_blood_type"`
+	ShowAllergies         bool      `json:"show_allergies"`
+	ShowChronicConditions bool      `json:"show_chronic_conditions"`
+	ShowContacts          bool      `json:"show_contacts"`
+	CreatedAt             time.Time `json:"created_at"`
+	RotatedAt             time.Time `json:"rotated_at"` // When the current token was issued
+}
+
+// EmergencyCard is the public part of a profile, fields that are not published are left empty.
+type EmergencyCard struct {
+	Name              string          `json:"name,omitempty"`
+	BloodType         string          `json:"blood_type,omitempty"`
+	Allergies         []MedicalEntry  `json:"allergies,omitempty"`
+	ChronicConditions []MedicalEntry  `json:"chronic_conditions,omitempty"`
+	Contacts          []PublicContact `json:"contacts,omitempty"`
+}
+
+// EmergencyGorm wraps a GORM DB instance for emergency IDs and contacts.
+type EmergencyGorm struct {
+	DB *gorm.DB
+}
+
+// NewEmergencyGorm creates a new instance of EmergencyGorm.
+func NewEmergencyGorm(db *gorm.DB) *EmergencyGorm {
+	return &EmergencyGorm{DB: db}
+}
+
+// CreateContact inserts a new emergency contact.
+func (eg *EmergencyGorm) CreateContact(contact *EmergencyContact) (*EmergencyContact, error) {
+	if err := eg.DB.Table("emergency_contacts").Create(contact).Error; err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// GetUserContact retrieves an emergency contact of the user.
+func (eg *EmergencyGorm) GetUserContact(userID uint, id int) (*EmergencyContact, error) {
+	var contact EmergencyContact
+	if err := eg.DB.Table("emergency_contacts").Scopes(OwnedBy(userID)).Where("id = ?", id).First(&contact).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// GetUserContacts lists the emergency contacts of the user in the order they were added.
+func (eg *EmergencyGorm) GetUserContacts(userID uint) ([]EmergencyContact, error) {
+	var contacts []EmergencyContact
+	err := eg.DB.Table("emergency_contacts").Scopes(OwnedBy(userID)).Order("id asc").Find(&contacts).Error
+	return contacts, err
+}
+
+// UpdateContact saves the name, addresses and relation of a contact.
+func (eg *EmergencyGorm) UpdateContact(contact *EmergencyContact) error {
+	return eg.DB.Table("emergency_contacts").Where("id = ?", contact.ID).Updates(map[string]interface{}{
+		"name":        contact.Name,
+		"phone":       contact.Phone,
+		"email":       contact.Email,
+		"webhook_url": contact.WebhookURL,
+		"relation":    contact.Relation,
+	}).Error
+}
+
+// DeleteUserContact deletes an emergency contact of the user.
+func (eg *EmergencyGorm) DeleteUserContact(userID uint, id int) error {
+	return deleteOwned(eg.DB, "emergency_contacts", &EmergencyContact{}, userID, id)
+}
+
+// GetProfile retrieves the emergency ID settings of the user.
+// Returns gorm.ErrRecordNotFound if the user has not enabled it.
+func (eg *EmergencyGorm) GetProfile(userID uint) (*EmergencyProfile, error) {
+	var profile EmergencyProfile
+	if err := eg.DB.Table("emergency_profiles").Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SaveProfile creates or updates the emergency ID settings of the user.
+func (eg *EmergencyGorm) SaveProfile(profile *EmergencyProfile) error {
+	return eg.DB.Table("emergency_profiles").Save(profile).Error
+}
+
+// RotateToken replaces the token hash of the user's emergency ID, the old URL stops working.
+func (eg *EmergencyGorm) RotateToken(userID uint, tokenHash string, now time.Time) error {
+	result := eg.DB.Table("emergency_profiles").Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"token_hash": tokenHash,
+		"rotated_at": now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteProfile disables the user's emergency ID.
+func (eg *EmergencyGorm) DeleteProfile(userID uint) error {
+	result := eg.DB.Table("emergency_profiles").Where("user_id = ?", userID).Delete(&EmergencyProfile{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetEmergencyCard collects the published fields of the emergency ID with the given token hash.
+// Returns gorm.ErrRecordNotFound for unknown, rotated and revoked tokens.
+func (eg *EmergencyGorm) GetEmergencyCard(tokenHash string) (*EmergencyCard, error) {
+	var profile EmergencyProfile
+	if err := eg.DB.Table("emergency_profiles").Where("token_hash = ?", tokenHash).First(&profile).Error; err != nil {
+		return nil, err
+	}
+
+	card := &EmergencyCard{}
+	if profile.ShowName {
+		var user User
+		if err := eg.DB.Table("users").Select("name").Where("id = ?", profile.UserID).Take(&user).Error; err != nil {
+			return nil, err
+		}
+		card.Name = user.Name
+	}
+	if profile.ShowBloodType {
+		var medCard MedicalCard
+		err := eg.DB.Table("medical_cards").Where("user_id = ?", profile.UserID).First(&medCard).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		card.BloodType = medCard.BloodType
+	}
+	entries := NewMedCardGorm(eg.DB)
+	var err error
+	if profile.ShowAllergies {
+		if card.Allergies, err = entries.GetEntries(profile.UserID, EntryAllergy); err != nil {
+			return nil, err
+		}
+	}
+	if profile.ShowChronicConditions {
+		if card.ChronicConditions, err = entries.GetEntries(profile.UserID, EntryCondition); err != nil {
+			return nil, err
+		}
+	}
+	if profile.ShowContacts {
+		err := eg.DB.Table("emergency_contacts").Select("name, phone, relation").
+			Scopes(OwnedBy(profile.UserID)).Order("id asc").Scan(&card.Contacts).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return card, nil
+}
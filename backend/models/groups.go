@@ -1,14 +1,97 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles of group members
+const (
+	RoleOwner     = "owner"     // Created the group or received ownership, exactly one per group
+	RoleCaregiver = "caregiver" // Helps managing the household
+	RoleMember    = "member"    // Regular member
+)
+
+// Actions that depend on the member's role
+const (
+	PermManageGroup      = "manage_group"      // Rename or delete the group, change roles, remove members
+	PermInvite           = "invite"            // Invite new members
+	PermManageCabinet    = "manage_cabinet"    // Create, rename and delete shared kits
+	PermManageDependents = "manage_dependents" // Manage dependent profiles of the group
+)
+
+// rolePermissions lists the actions allowed for each role.
+var rolePermissions = map[string][]string{
+	RoleOwner:     {PermManageGroup, PermInvite, PermManageCabinet, PermManageDependents},
+	RoleCaregiver: {PermInvite, PermManageCabinet, PermManageDependents},
+	RoleMember:    {},
+}
+
+// IsRole reports whether role is one of the known roles.
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleCan reports whether members with the role may perform the action.
+func RoleCan(role, action string) bool {
+	for _, allowed := range rolePermissions[role] {
+		if allowed == action {
+			return true
+		}
+	}
+	return false
+}
+
+// rolesAllowedTo lists the roles that may perform the action.
+func rolesAllowedTo(action string) []string {
+	roles := []string{}
+	for role := range rolePermissions {
+		if RoleCan(role, action) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
 
 // Group represents a group of users.
 // It includes a many-to-many relationship with the User model via the join table 'user_groups'.
 type Group struct {
-	ID          uint   `gorm:"primaryKey"` // Unique identifier for the group
-	Name        string // Name of the group
-	Description string // Optional description of the group's purpose
-	Members     []User `gorm:"many2many:user_groups;"` // Many-to-many relationship with users
+	ID          uint      `gorm:"primaryKey" json:"id"`            // Unique identifier for the group
+	Name        string    `json:"name"`                            // Name of the group
+	Description string    `json:"description"`                     // Optional description of the group's purpose
+	CreatedAt   time.Time `json:"created_at"`                      // When the group was created
+	Members     []User    `gorm:"many2many:user_groups;" json:"-"` // Many-to-many relationship with users
+}
+
+// GroupMember is a row of the user_groups join table.
+type GroupMember struct {
+	UserID   uint      `gorm:"primaryKey" json:"user_id"`
+	GroupID  uint      `gorm:"primaryKey" json:"group_id"`
+	Role     string    `json:"role"`      // owner, caregiver or member
+	JoinedAt time.Time `json:"joined_at"` // When the user joined the group
+}
+
+// Can reports whether the member may perform the action.
+func (m *GroupMember) Can(action string) bool {
+	return RoleCan(m.Role, action)
+}
+
+// GroupMemberInfo is a member with their name and email.
+type GroupMemberInfo struct {
+	GroupMember
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// UserGroup is a group with the role of the user in it.
+type UserGroup struct {
+	Group
+	Role        string `json:"role"`
+	MemberCount int64  `json:"member_count"`
 }
 
 // GroupGorm wraps the GORM DB instance for performing database operations related to Group.
@@ -16,6 +99,11 @@ type GroupGorm struct {
 	DB *gorm.DB
 }
 
+// NewGroupGorm creates a new instance of GroupGorm.
+func NewGroupGorm(db *gorm.DB) *GroupGorm {
+	return &GroupGorm{DB: db}
+}
+
 // CreateGroup creates a new group record in the database.
 // It accepts a map of arguments to populate the group's fields.
 // The map can include keys: "Name" (string), "Description" (string), and "Members" ([]User).
@@ -41,6 +129,25 @@ func (gg *GroupGorm) CreateGroup(args map[string]interface{}) (*Group, error) {
 	return group, nil
 }
 
+// CreateOwnedGroup creates a group with the given user as its owner.
+func (gg *GroupGorm) CreateOwnedGroup(group *Group, ownerID uint) (*Group, error) {
+	err := gg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("groups").Omit("Members").Create(group).Error; err != nil {
+			return err
+		}
+		return tx.Table("user_groups").Create(&GroupMember{
+			UserID:   ownerID,
+			GroupID:  group.ID,
+			Role:     RoleOwner,
+			JoinedAt: group.CreatedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
 // GetGroupByID retrieves a group by its ID.
 // Returns the group if found, or an error otherwise.
 func (gg *GroupGorm) GetGroupByID(id uint) (*Group, error) {
@@ -50,3 +157,113 @@ func (gg *GroupGorm) GetGroupByID(id uint) (*Group, error) {
 	}
 	return &group, nil
 }
+
+// UpdateGroup saves the name and description of a group.
+func (gg *GroupGorm) UpdateGroup(group *Group) error {
+	return gg.DB.Table("groups").Where("id = ?", group.ID).Updates(map[string]interface{}{
+		"name":        group.Name,
+		"description": group.Description,
+	}).Error
+}
+
+// DeleteGroup deletes a group, its memberships, invitations and shared kits are removed by cascade.
+// Dependents of the group stay with the caregivers who created them, profiles whose creator deleted
+// their account are handed over to the heir, so that no profile is left without a caregiver.
+func (gg *GroupGorm) DeleteGroup(id, heirID uint) error {
+	return gg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("dependents").
+			Where("group_id = ? AND created_by IS NULL", id).
+			Update("created_by", heirID).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM groups WHERE id = ?", id).Error
+	})
+}
+
+// GetMembership returns the membership of the user in the group.
+// Returns gorm.ErrRecordNotFound if the user is not a member, so foreign groups behave as missing.
+func (gg *GroupGorm) GetMembership(userID, groupID uint) (*GroupMember, error) {
+	var member GroupMember
+	err := gg.DB.Table("user_groups").Where("user_id = ? AND group_id = ?", userID, groupID).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetUserGroups lists the groups of the user with their role and the number of members.
+func (gg *GroupGorm) GetUserGroups(userID uint) ([]UserGroup, error) {
+	var groups []UserGroup
+	err := gg.DB.Table("groups").
+		Select("groups.*, ug.role, (SELECT COUNT(*) FROM user_groups WHERE group_id = groups.id) AS member_count").
+		Joins("JOIN user_groups ug ON ug.group_id = groups.id AND ug.user_id = ?", userID).
+		Order("groups.id asc").
+		Scan(&groups).Error
+	return groups, err
+}
+
+// GetMembers lists the members of a group, the owner first.
+func (gg *GroupGorm) GetMembers(groupID uint) ([]GroupMemberInfo, error) {
+	var members []GroupMemberInfo
+	err := gg.DB.Table("user_groups").
+		Select("user_groups.*, users.name, users.email").
+		Joins("JOIN users ON users.id = user_groups.user_id").
+		Where("user_groups.group_id = ?", groupID).
+		Order("user_groups.role = 'owner' DESC, user_groups.joined_at ASC, user_groups.user_id ASC").
+		Scan(&members).Error
+	return members, err
+}
+
+// AddMember adds the user to the group with the given role.
+func (gg *GroupGorm) AddMember(member *GroupMember) error {
+	return gg.DB.Table("user_groups").Create(member).Error
+}
+
+// SetRole changes the role of a member.
+// Returns gorm.ErrRecordNotFound if the user is not a member.
+func (gg *GroupGorm) SetRole(groupID, userID uint, role string) error {
+	result := gg.DB.Table("user_groups").Where("group_id = ? AND user_id = ?", groupID, userID).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RemoveMember removes the user from the group.
+// Returns gorm.ErrRecordNotFound if the user is not a member.
+func (gg *GroupGorm) RemoveMember(groupID, userID uint) error {
+	result := gg.DB.Exec("DELETE FROM user_groups WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TransferOwnership makes the new owner the only owner, the previous owner becomes a caregiver.
+// Returns gorm.ErrRecordNotFound if the new owner is not a member.
+func (gg *GroupGorm) TransferOwnership(groupID, fromUserID, toUserID uint) error {
+	return gg.DB.Transaction(func(tx *gorm.DB) error {
+		// Demote first, a group can only have one owner at a time
+		if err := tx.Table("user_groups").
+			Where("group_id = ? AND user_id = ?", groupID, fromUserID).
+			Update("role", RoleCaregiver).Error; err != nil {
+			return err
+		}
+		result := tx.Table("user_groups").
+			Where("group_id = ? AND user_id = ?", groupID, toUserID).
+			Update("role", RoleOwner)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
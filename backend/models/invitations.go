@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GroupInvitation invites a user, identified by email, to join a group.
+// The invitation token is only stored as a hash.
+type GroupInvitation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"` // Unique identifier of the invitation
+	GroupID    uint       `json:"group_id"`             // Group the user is invited to
+	Email      string     `json:"email"`                // Email of the invited user
+	Role       string     `json:"role"`                 // Role the user gets on accepting
+	TokenHash  string     `json:"-"`                    // SHA-256 hash of the invitation token
+	InvitedBy  *uint      `json:"invited_by"`           // Member who sent the invitation
+	CreatedAt  time.Time  `json:"created_at"`           // When the invitation was sent
+	ExpiresAt  time.Time  `json:"expires_at"`           // The invitation can't be accepted after this time
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	DeclinedAt *time.Time `json:"declined_at,omitempty"`
+}
+
+// Pending reports whether the invitation can still be accepted or declined.
+func (i *GroupInvitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.DeclinedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// InvitationInfo is a pending invitation with the name of the group.
+type InvitationInfo struct {
+	GroupInvitation
+	GroupName string `json:"group_name"`
+}
+
+// CreateInvitation inserts a new invitation.
+func (gg *GroupGorm) CreateInvitation(invitation *GroupInvitation) (*GroupInvitation, error) {
+	if err := gg.DB.Table("group_invitations").Create(invitation).Error; err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// GetInvitationByHash retrieves an invitation by the hash of its token.
+func (gg *GroupGorm) GetInvitationByHash(hash string) (*GroupInvitation, error) {
+	var invitation GroupInvitation
+	if err := gg.DB.Table("group_invitations").Where("token_hash = ?", hash).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// GetPendingInvitations lists the invitations sent to the email that can still be accepted.
+func (gg *GroupGorm) GetPendingInvitations(email string, now time.Time) ([]InvitationInfo, error) {
+	var invitations []InvitationInfo
+	err := gg.DB.Table("group_invitations").
+		Select("group_invitations.*, groups.name AS group_name").
+		Joins("JOIN groups ON groups.id = group_invitations.group_id").
+		Where("LOWER(group_invitations.email) = LOWER(?)", email).
+		Where("accepted_at IS NULL AND declined_at IS NULL AND expires_at > ?", now).
+		Order("group_invitations.id asc").
+		Scan(&invitations).Error
+	return invitations, err
+}
+
+// AcceptInvitation adds the user to the group of the invitation and marks it as accepted.
+func (gg *GroupGorm) AcceptInvitation(invitation *GroupInvitation, userID uint, now time.Time) error {
+	return gg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("group_invitations").Where("id = ?", invitation.ID).Update("accepted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Table("user_groups").Create(&GroupMember{
+			UserID:   userID,
+			GroupID:  invitation.GroupID,
+			Role:     invitation.Role,
+			JoinedAt: now,
+		}).Error
+	})
+}
+
+// DeclineInvitation marks the invitation as declined.
+func (gg *GroupGorm) DeclineInvitation(invitation *GroupInvitation, now time.Time) error {
+	return gg.DB.Table("group_invitations").Where("id = ?", invitation.ID).Update("declined_at", now).Error
+}
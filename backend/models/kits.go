@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kit is a named first-aid kit, e.g. "Car kit" or "Home cabinet", owned by a user or a group.
+type Kit struct {
+	ID          uint      `gorm:"primaryKey" json:"id"` // Unique identifier of the kit
+	UserID      *uint     `json:"user_id"`              // Owner of a personal kit, null for group kits
+	GroupID     *uint     `json:"group_id"`             // Owner of a group kit, null for personal kits
+	Name        string    `json:"name"`                 // Name of the kit
+	Description string    `json:"description"`          // Optional description
+	CreatedAt   time.Time `json:"created_at"`           // When the kit was created
+}
+
+// ErrOtherCabinet is returned for drugs put into a kit of another cabinet, e.g. a personal drug into a group kit.
+var ErrOtherCabinet = errors.New("kit belongs to another cabinet")
+
+// Holds reports whether the drug may be kept in the kit: personal drugs go into personal kits
+// of their owner, group drugs into kits of their group.
+func (k *Kit) Holds(drug *Drug) bool {
+	if k.GroupID != nil {
+		return drug.GroupID != nil && *drug.GroupID == *k.GroupID
+	}
+	return drug.GroupID == nil && k.UserID != nil && drug.UserId != nil && *k.UserID == *drug.UserId
+}
+
+// KitSummary describes the contents of a kit. KitID is null for drugs without a kit.
+type KitSummary struct {
+	KitID         *uint      `json:"kit_id"`
+	ItemCount     int64      `json:"item_count"`     // Number of drugs in the kit
+	ExpiredCount  int64      `json:"expired_count"`  // Number of expired drugs
+	NearestExpiry *time.Time `json:"nearest_expiry"` // Earliest expiry date, null for kits without dated drugs
+}
+
+// KitGorm wraps a GORM DB instance for operations on kits.
+type KitGorm struct {
+	DB *gorm.DB
+}
+
+// NewKitGorm creates a new instance of KitGorm.
+func NewKitGorm(db *gorm.DB) *KitGorm {
+	return &KitGorm{DB: db}
+}
+
+// AccessibleKits is a GORM scope limiting a query to the user's personal kits
+// and kits of the groups the user is a member of.
+func AccessibleKits(userID uint) func(db *gorm.DB) *gorm.DB {
+	return OwnedOrShared(userID)
+}
+
+// CreateKit inserts a new kit.
+func (kg *KitGorm) CreateKit(kit *Kit) (*Kit, error) {
+	if err := kg.DB.Table("kits").Create(kit).Error; err != nil {
+		return nil, err
+	}
+	return kit, nil
+}
+
+// GetUserKit retrieves a kit accessible by the user.
+// Returns gorm.ErrRecordNotFound for kits of other users and groups.
+func (kg *KitGorm) GetUserKit(userID uint, id int) (*Kit, error) {
+	var kit Kit
+	if err := kg.DB.Table("kits").Scopes(AccessibleKits(userID)).Where("id = ?", id).First(&kit).Error; err != nil {
+		return nil, err
+	}
+	return &kit, nil
+}
+
+// GetUserKits lists the kits accessible by the user, ordered by ID.
+func (kg *KitGorm) GetUserKits(userID uint) ([]Kit, error) {
+	var kits []Kit
+	err := kg.DB.Table("kits").Scopes(AccessibleKits(userID)).Order("id asc").Find(&kits).Error
+	return kits, err
+}
+
+// GetCabinetKits lists the kits of a cabinet, ordered by ID. The cabinet is a scope on drugs,
+// see CabinetScope, kits have the same owner columns.
+func (kg *KitGorm) GetCabinetKits(cabinet func(db *gorm.DB) *gorm.DB) ([]Kit, error) {
+	var kits []Kit
+	err := kg.DB.Table("kits").Scopes(cabinet).Order("id asc").Find(&kits).Error
+	return kits, err
+}
+
+// UpdateKit saves the name and description of a kit and renames the location of its drugs.
+func (kg *KitGorm) UpdateKit(kit *Kit) error {
+	return kg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("kits").Where("id = ?", kit.ID).Updates(map[string]interface{}{
+			"name":        kit.Name,
+			"description": kit.Description,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Table("drugs").Where("kit_id = ?", kit.ID).Update("location", kit.Name).Error
+	})
+}
+
+// DeleteKit deletes a kit, its drugs stay in the cabinet without a kit and without a location.
+func (kg *KitGorm) DeleteKit(id uint) error {
+	return kg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("drugs").Where("kit_id = ?", id).Updates(map[string]interface{}{"kit_id": nil, "location": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Table("kits").Where("id = ?", id).Delete(&Kit{}).Error
+	})
+}
+
+// GetKitSummaries counts the drugs of a cabinet per kit, including drugs without a kit.
+// The cabinet is a scope on drugs, see CabinetScope. Drugs without an expiry date have
+// the zero time, they neither count as expired nor as the nearest expiry.
+func (kg *KitGorm) GetKitSummaries(cabinet func(db *gorm.DB) *gorm.DB, now time.Time) ([]KitSummary, error) {
+	var summaries []KitSummary
+	err := kg.DB.Table("drugs").
+		Select("kit_id, COUNT(*) AS item_count, COUNT(*) FILTER (WHERE expiry < ? AND expiry > ?) AS expired_count, "+
+			"MIN(expiry) FILTER (WHERE expiry > ?) AS nearest_expiry", now, time.Time{}, time.Time{}).
+		Scopes(cabinet).
+		Group("kit_id").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// MoveDrugs puts drugs of the user or their groups into a kit, or takes them out of any kit if kit is nil.
+// The location of the drugs is set to the kit name, taking drugs out of their kits clears it
+// (drugs without a kit keep their location). Returns gorm.ErrRecordNotFound if
+// any of the drugs is not accessible by the user and ErrOtherCabinet if the kit cannot
+// hold one of them, in both cases nothing is moved.
+func (kg *KitGorm) MoveDrugs(userID uint, drugIDs []uint, kit *Kit) error {
+	updates := map[string]interface{}{
+		"kit_id":   nil,
+		"location": gorm.Expr("CASE WHEN kit_id IS NULL THEN location ELSE '' END"),
+	}
+	if kit != nil {
+		updates = map[string]interface{}{"kit_id": kit.ID, "location": kit.Name}
+	}
+
+	return kg.DB.Transaction(func(tx *gorm.DB) error {
+		var drugs []Drug
+		err := tx.Table("drugs").Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(OwnedOrShared(userID)).
+			Where("id IN ?", drugIDs).Find(&drugs).Error
+		if err != nil {
+			return err
+		}
+		if len(drugs) != len(drugIDs) {
+			return gorm.ErrRecordNotFound
+		}
+		for i := range drugs {
+			if kit != nil && !kit.Holds(&drugs[i]) {
+				return ErrOtherCabinet
+			}
+		}
+		return tx.Table("drugs").Where("id IN ?", drugIDs).Updates(updates).Error
+	})
+}
@@ -3,12 +3,11 @@ package models
 import "gorm.io/gorm"
 
 // MedicalCard represents a user's health record with basic medical information.
+// Allergies and chronic conditions are kept as separate entries, see MedicalEntry.
 type MedicalCard struct {
-	ID          uint   `gorm:"primaryKey"` // Unique identifier for the medical card
-	UserID      uint   // Foreign key to associate the card with a specific user
-	Allergies   string // Any known allergies the user has
-	ChronicCond string // Description of chronic conditions (e.g., asthma, diabetes)
-	BloodType   string // Blood type (1+, 1-, 2+, 2-, ...)
+	ID        uint   `gorm:"primaryKey"` // Unique identifier for the medical card
+	UserID    uint   // Foreign key to associate the card with a specific user
+	BloodType string `gorm:"serializer:encrypted"` // Blood type (1+, 1-, 2+, 2-, ...), encrypted at rest
 }
 
 // MedicalCardGorm provides methods to interact with the medical_cards table.
@@ -22,12 +21,10 @@ func NewMedCardGorm(db *gorm.DB) *MedicalCardGorm {
 }
 
 // CreateCard creates and saves a new MedicalCard record in the database.
-func (mg *MedicalCardGorm) CreateCard(allergies, chronicCond, bloodType string, userID uint) (*MedicalCard, error) {
+func (mg *MedicalCardGorm) CreateCard(bloodType string, userID uint) (*MedicalCard, error) {
 	card := &MedicalCard{
-		UserID:      userID,
-		Allergies:   allergies,
-		ChronicCond: chronicCond,
-		BloodType:   bloodType,
+		UserID:    userID,
+		BloodType: bloodType,
 	}
 
 	// Insert the new medical card record into the medical_cards table.
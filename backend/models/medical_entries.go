@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of medical entries
+const (
+	EntryAllergy   = "allergy"   // Allergy or intolerance
+	EntryCondition = "condition" // Chronic condition
+)
+
+// Severities of medical entries, from the least to the most dangerous
+const (
+	SeverityMild            = "mild"
+	SeverityModerate        = "moderate"
+	SeveritySevere          = "severe"
+	SeverityLifeThreatening = "life_threatening"
+)
+
+// IsSeverity reports whether s is a known severity.
+func IsSeverity(s string) bool {
+	switch s {
+	case SeverityMild, SeverityModerate, SeveritySevere, SeverityLifeThreatening:
+		return true
+	}
+	return false
+}
+
+// MedicalEntry is an allergy or chronic condition of the user. Entries migrated from the old
+// free-text fields only have Text set.
+type MedicalEntry struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `json:"-"`
+	Kind      string     `json:"kind" example:"allergy"`                                        // allergy or condition
+	ICD10Code string     `gorm:"column:icd10_code" json:"icd10_code" example:"Z88.0"`           // Optional ICD-10 code
+	Text      string     `gorm:"serializer:encrypted" json:"text" example:"Penicillin allergy"` // Name or free-text description
+	Substance string     `gorm:"serializer:encrypted" json:"substance" example:"amoxicillin"`   // Allergen, for allergies
+	Reaction  string     `gorm:"serializer:encrypted" json:"reaction" example:"anaphylaxis"`    // Reaction to the allergen
+	Severity  string     `json:"severity" example:"life_threatening"`                           // mild, moderate, severe or life_threatening
+	OnsetDate *time.Time `json:"onset_date"`                                                    // When it started, if known
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Describe renders the entry as one line of text, e.g. for the AI assistant.
+func (e *MedicalEntry) Describe() string {
+	text := e.Text
+	if e.Substance != "" && !strings.EqualFold(e.Substance, e.Text) {
+		if text != "" {
+			text += ", "
+		}
+		text += e.Substance
+	}
+	if e.ICD10Code != "" {
+		if text == "" {
+			text = e.ICD10Code
+		} else {
+			text += " (ICD-10 " + e.ICD10Code + ")"
+		}
+	}
+	if e.Reaction != "" {
+		text += ", reaction: " + e.Reaction
+	}
+	if e.Severity != "" {
+		text += ", severity: " + strings.ReplaceAll(e.Severity, "_", "-")
+	}
+	if e.OnsetDate != nil {
+		text += fmt.Sprintf(", since %s", e.OnsetDate.Format("2006-01-02"))
+	}
+	return text
+}
+
+// GetEntries lists the user's entries of a kind in the order they were added.
+func (mg *MedicalCardGorm) GetEntries(userID uint, kind string) ([]MedicalEntry, error) {
+	entries := []MedicalEntry{}
+	err := mg.DB.Table("medical_entries").Scopes(OwnedBy(userID)).Where("kind = ?", kind).Order("id asc").Find(&entries).Error
+	return entries, err
+}
+
+// GetUserEntry retrieves an entry of the user.
+func (mg *MedicalCardGorm) GetUserEntry(userID uint, kind string, id int) (*MedicalEntry, error) {
+	var entry MedicalEntry
+	err := mg.DB.Table("medical_entries").Scopes(OwnedBy(userID)).Where("id = ? AND kind = ?", id, kind).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CreateEntry inserts a new entry.
+func (mg *MedicalCardGorm) CreateEntry(entry *MedicalEntry) (*MedicalEntry, error) {
+	if err := mg.DB.Table("medical_entries").Create(entry).Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// UpdateEntry saves every field of an entry but its kind.
+// The entry itself is passed to GORM, so its encrypted fields go through the serializer.
+func (mg *MedicalCardGorm) UpdateEntry(entry *MedicalEntry) error {
+	return mg.DB.Table("medical_entries").Model(entry).
+		Select("icd10_code", "text", "substance", "reaction", "severity", "onset_date").Updates(entry).Error
+}
+
+// DeleteUserEntry deletes an entry of the user.
+func (mg *MedicalCardGorm) DeleteUserEntry(userID uint, kind string, id int) error {
+	result := mg.DB.Table("medical_entries").Scopes(OwnedBy(userID)).Where("id = ? AND kind = ?", id, kind).Delete(&MedicalEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReplaceFreeText replaces the user's free-text entries of a kind, those without a code,
+// substance or other details, with a single entry. It supports clients still sending the
+// allergies and chronic conditions as one string.
+func (mg *MedicalCardGorm) ReplaceFreeText(userID uint, kind, text string) error {
+	return mg.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Table("medical_entries").Scopes(OwnedBy(userID)).
+			Where("kind = ? AND icd10_code = '' AND substance = '' AND reaction = '' AND severity = '' AND onset_date IS NULL", kind).
+			Delete(&MedicalEntry{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Table("medical_entries").Create(&MedicalEntry{UserID: userID, Kind: kind, Text: text, CreatedAt: time.Now()}).Error
+	})
+}
@@ -37,7 +37,7 @@ func (mg *MessageGorm) GetMessages(chatID uint) ([]Message, error) {
 	var messages []Message
 	err := mg.DB.
 		Where("chat_id = ?", chatID).
-		Order("timestamp asc").
+		Order("timestamp asc, id asc").
 		Find(&messages).Error
 	return messages, err
 }
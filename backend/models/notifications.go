@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notification kinds
+const (
+	NotificationDrugExpiry = "drug_expiry" // A drug expires soon or has expired
+	NotificationLowStock   = "low_stock"   // A drug fell to its restock threshold or ran out
+)
+
+// Notification represents a message for the user produced by background jobs.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"` // Unique identifier of the notification
+	UserID    uint       `json:"-"`                    // Recipient of the notification
+	Kind      string     `json:"kind"`                 // What the notification is about, e.g. drug_expiry
+	DrugID    *uint      `json:"drug_id,omitempty"`    // Related drug, if any
+	Title     string     `json:"title"`                // Short summary
+	Body      string     `json:"body"`                 // Details
+	DedupKey  string     `json:"-"`                    // Same key for the same user is stored only once
+	CreatedAt time.Time  `json:"created_at"`           // When the notification was produced
+	ReadAt    *time.Time `json:"read_at"`              // When the user read it, null while unread
+}
+
+// NotificationGorm wraps a GORM DB instance for operations on notifications.
+type NotificationGorm struct {
+	DB *gorm.DB
+}
+
+// NewNotificationGorm creates a new instance of NotificationGorm.
+func NewNotificationGorm(db *gorm.DB) *NotificationGorm {
+	return &NotificationGorm{DB: db}
+}
+
+// CreateNotification stores a notification unless one with the same dedup key already exists for the user.
+// Returns true if a new notification was stored.
+func (ng *NotificationGorm) CreateNotification(notification *Notification) (bool, error) {
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+
+	result := ng.DB.Table("notifications").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(notification)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetUserNotifications lists the user's notifications, newest first.
+// If unreadOnly is set, read notifications are skipped.
+func (ng *NotificationGorm) GetUserNotifications(userID uint, unreadOnly bool) ([]Notification, error) {
+	query := ng.DB.Table("notifications").Scopes(OwnedBy(userID))
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []Notification
+	err := query.Order("created_at desc, id desc").Find(&notifications).Error
+	return notifications, err
+}
+
+// CountUnread returns the number of unread notifications of the user.
+func (ng *NotificationGorm) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := ng.DB.Table("notifications").Scopes(OwnedBy(userID)).Where("read_at IS NULL").Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications as read.
+// Returns gorm.ErrRecordNotFound if it does not exist or belongs to another user.
+func (ng *NotificationGorm) MarkRead(userID uint, id int) error {
+	result := ng.DB.Table("notifications").
+		Scopes(OwnedBy(userID)).
+		Where("id = ?", id).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all unread notifications of the user as read.
+func (ng *NotificationGorm) MarkAllRead(userID uint) error {
+	return ng.DB.Table("notifications").
+		Scopes(OwnedBy(userID)).
+		Where("read_at IS NULL").
+		Update("read_at", time.Now()).Error
+}
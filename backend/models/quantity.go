@@ -0,0 +1,74 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Units of drug quantity
+const (
+	UnitTablets  = "tablets"
+	UnitCapsules = "capsules"
+	UnitMl       = "ml"
+	UnitGrams    = "g"
+	UnitDoses    = "doses"
+	UnitPieces   = "pieces"
+)
+
+// Units lists the supported quantity units.
+var Units = []string{UnitTablets, UnitCapsules, UnitMl, UnitGrams, UnitDoses, UnitPieces}
+
+// unitAliases maps lowercase words found in free-text amounts to units.
+// Matching is done by prefix, so "tablets", "tabs" and "таблеток" are all tablets.
+var unitAliases = []struct {
+	prefix string
+	unit   string
+}{
+	{"tab", UnitTablets}, {"pill", UnitTablets}, {"таб", UnitTablets},
+	{"cap", UnitCapsules}, {"капс", UnitCapsules},
+	{"ml", UnitMl}, {"мл", UnitMl},
+	{"g", UnitGrams}, {"г", UnitGrams},
+	{"dose", UnitDoses}, {"доз", UnitDoses},
+	{"pc", UnitPieces}, {"piece", UnitPieces}, {"шт", UnitPieces},
+}
+
+// amountPattern matches a number optionally followed by a word, e.g. "30 tablets" or "100,5мл".
+var amountPattern = regexp.MustCompile(`^\s*(\d+(?:[.,]\d+)?)\s*(\S*)`)
+
+// IsUnit reports whether unit is one of the supported units.
+func IsUnit(unit string) bool {
+	for _, u := range Units {
+		if u == unit {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAmount extracts a quantity from a free-text amount such as "30 tablets" or "100 ml".
+// A number without a recognised unit is counted in pieces. Returns false if there is no number.
+func ParseAmount(amount string) (float64, string, bool) {
+	match := amountPattern.FindStringSubmatch(amount)
+	if match == nil {
+		return 0, "", false
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	word := strings.ToLower(match[2])
+	for _, alias := range unitAliases {
+		if strings.HasPrefix(word, alias.prefix) {
+			return value, alias.unit, true
+		}
+	}
+	return value, UnitPieces, true
+}
+
+// FormatQuantity renders a quantity as a free-text amount, e.g. "18 tablets".
+func FormatQuantity(value float64, unit string) string {
+	return strconv.FormatFloat(value, 'f', -1, 64) + " " + unit
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences protects against rules expanding into an endless number of occurrences.
+const maxOccurrences = 100000
+
+// Recurrence is a subset of the iCalendar RRULE (RFC 5545) describing when a dose is due,
+// e.g. "FREQ=HOURLY;INTERVAL=8;COUNT=15" is every 8 hours for 5 days.
+// Supported parts: FREQ (HOURLY, DAILY, WEEKLY), INTERVAL, COUNT, UNTIL, BYHOUR and BYDAY.
+type Recurrence struct {
+	Freq     string         // HOURLY, DAILY or WEEKLY
+	Interval int            // Step between periods, 1 by default
+	Count    int            // Total number of occurrences, 0 means unlimited
+	Until    time.Time      // Last possible occurrence, zero means unlimited
+	ByHour   []int          // Hours of the day for DAILY and WEEKLY rules
+	ByDay    []time.Weekday // Days of the week for WEEKLY rules
+}
+
+// weekdays maps RRULE day codes to weekdays.
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// ParseRRule parses a rule such as "FREQ=DAILY;BYHOUR=8,20;COUNT=10".
+// A leading "RRULE:" prefix is accepted.
+func ParseRRule(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	rec := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rec.Freq = strings.ToUpper(value)
+			if rec.Freq != "HOURLY" && rec.Freq != "DAILY" && rec.Freq != "WEEKLY" {
+				return nil, fmt.Errorf("unsupported frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid interval %q", value)
+			}
+			rec.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count %q", value)
+			}
+			rec.Count = n
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid until %q", value)
+			}
+			rec.Until = until
+		case "BYHOUR":
+			for _, field := range strings.Split(value, ",") {
+				hour, err := strconv.Atoi(field)
+				if err != nil || hour < 0 || hour > 23 {
+					return nil, fmt.Errorf("invalid hour %q", field)
+				}
+				rec.ByHour = append(rec.ByHour, hour)
+			}
+			sort.Ints(rec.ByHour)
+		case "BYDAY":
+			for _, field := range strings.Split(value, ",") {
+				day, ok := weekdays[strings.ToUpper(field)]
+				if !ok {
+					return nil, fmt.Errorf("invalid day %q", field)
+				}
+				rec.ByDay = append(rec.ByDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if rec.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rec.Freq == "HOURLY" && len(rec.ByHour) > 0 {
+		return nil, errors.New("BYHOUR is not supported with FREQ=HOURLY")
+	}
+	if rec.Freq != "WEEKLY" && len(rec.ByDay) > 0 {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return rec, nil
+}
+
+// parseRRuleTime accepts both the RRULE format (20250712T234500Z) and RFC 3339.
+func parseRRuleTime(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Between returns occurrences of a rule starting at start that fall into [from, to).
+func (rec *Recurrence) Between(start, from, to time.Time) []time.Time {
+	var result []time.Time
+	rec.each(start, to, func(t time.Time) {
+		if !t.Before(from) {
+			result = append(result, t)
+		}
+	})
+	return result
+}
+
+// Includes reports whether t is an occurrence of a rule starting at start.
+func (rec *Recurrence) Includes(start, t time.Time) bool {
+	for _, occurrence := range rec.Between(start, t, t.Add(time.Second)) {
+		if occurrence.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// each calls fn for every occurrence before the given time, in chronological order.
+func (rec *Recurrence) each(start, before time.Time, fn func(time.Time)) {
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !t.Before(before) || (!rec.Until.IsZero() && t.After(rec.Until)) {
+			return false
+		}
+		if rec.Count > 0 && emitted >= rec.Count {
+			return false
+		}
+		fn(t)
+		emitted++
+		return emitted < maxOccurrences
+	}
+
+	for period := 0; period < maxOccurrences; period++ {
+		for _, t := range rec.periodOccurrences(start, period) {
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// periodOccurrences lists the candidate occurrences of the n-th period in chronological order.
+func (rec *Recurrence) periodOccurrences(start time.Time, n int) []time.Time {
+	step := n * rec.Interval
+
+	switch rec.Freq {
+	case "HOURLY":
+		return []time.Time{start.Add(time.Duration(step) * time.Hour)}
+	case "DAILY":
+		return rec.atHours(start, start.AddDate(0, 0, step))
+	default:
+		if len(rec.ByDay) == 0 {
+			return rec.atHours(start, start.AddDate(0, 0, 7*step))
+		}
+
+		// Occurrences on the selected days of the week the period starts with (weeks start on Monday)
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := start.AddDate(0, 0, 7*step-offset)
+
+		var days []time.Time
+		for _, weekday := range rec.ByDay {
+			days = append(days, rec.atHours(start, monday.AddDate(0, 0, (int(weekday)+6)%7))...)
+		}
+		sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+		return days
+	}
+}
+
+// atHours returns the occurrences on the given day: at BYHOUR hours if set, otherwise at the start's time.
+func (rec *Recurrence) atHours(start, day time.Time) []time.Time {
+	if len(rec.ByHour) == 0 {
+		return []time.Time{day}
+	}
+
+	y, m, d := day.Date()
+	times := make([]time.Time, 0, len(rec.ByHour))
+	for _, hour := range rec.ByHour {
+		times = append(times, time.Date(y, m, d, hour, start.Minute(), 0, 0, start.Location()))
+	}
+	return times
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dose statuses
+const (
+	DoseTaken   = "taken"   // The dose was taken
+	DoseSkipped = "skipped" // The user decided not to take the dose
+	DoseSnoozed = "snoozed" // The user will take the dose later
+	DosePending = "pending" // Nothing logged yet, only used in responses
+)
+
+// IntakeSchedule describes when and how much of a drug the user has to take.
+type IntakeSchedule struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`                            // Unique identifier of the schedule
+	UserID    uint      `json:"-"`                                               // Owner of the schedule
+	DrugID    uint      `json:"drug_id"`                                         // Drug to take
+	RRule     string    `json:"rrule" example:"FREQ=HOURLY;INTERVAL=8;COUNT=15"` // Recurrence rule, see Recurrence
+	StartsAt  time.Time `json:"starts_at" example:"2025-07-12T08:00:00Z"`        // First dose
+	Quantity  float64   `json:"quantity" example:"1"`                            // Units taken per dose, e.g. 1 tablet
+	Note      string    `json:"note"`                                            // Instructions, e.g. "after meals"
+	Active    bool      `json:"active"`                                          // Inactive schedules produce no doses
+	CreatedAt time.Time `json:"created_at"`                                      // When the schedule was created
+}
+
+// Doses returns the times of the schedule's doses in [from, to).
+// Times are in UTC, BYHOUR hours of the rule are UTC hours too.
+func (s *IntakeSchedule) Doses(from, to time.Time) ([]time.Time, error) {
+	rec, err := ParseRRule(s.RRule)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Between(s.StartsAt.UTC(), from, to), nil
+}
+
+// HasDose reports whether a dose of the schedule is due exactly at t.
+func (s *IntakeSchedule) HasDose(t time.Time) bool {
+	rec, err := ParseRRule(s.RRule)
+	if err != nil {
+		return false
+	}
+	return rec.Includes(s.StartsAt.UTC(), t.UTC())
+}
+
+// DoseLog records what happened with a single scheduled dose.
+type DoseLog struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`    // Unique identifier of the log entry
+	UserID       uint       `json:"-"`                       // Owner of the log entry
+	ScheduleID   uint       `json:"schedule_id"`             // Schedule the dose belongs to
+	DrugID       uint       `json:"drug_id"`                 // Drug of the schedule
+	ScheduledAt  time.Time  `json:"scheduled_at"`            // Occurrence of the schedule
+	Status       string     `json:"status"`                  // taken, skipped or snoozed
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"` // When a snoozed dose is due again
+	ActedAt      time.Time  `json:"acted_at"`                // When the user logged the status
+}
+
+// ScheduleGorm wraps a GORM DB instance for operations on intake schedules and dose logs.
+type ScheduleGorm struct {
+	DB *gorm.DB
+}
+
+// NewScheduleGorm creates a new instance of ScheduleGorm.
+func NewScheduleGorm(db *gorm.DB) *ScheduleGorm {
+	return &ScheduleGorm{DB: db}
+}
+
+// CreateSchedule inserts a new intake schedule.
+func (sg *ScheduleGorm) CreateSchedule(schedule *IntakeSchedule) (*IntakeSchedule, error) {
+	if err := sg.DB.Table("intake_schedules").Create(schedule).Error; err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// GetUserSchedule retrieves a schedule if it belongs to the given user.
+func (sg *ScheduleGorm) GetUserSchedule(userID uint, id uint) (*IntakeSchedule, error) {
+	var schedule IntakeSchedule
+	if err := sg.DB.Table("intake_schedules").Scopes(OwnedBy(userID)).Where("id = ?", id).First(&schedule).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetUserSchedules lists the user's schedules. If activeOnly is set, inactive ones are skipped.
+func (sg *ScheduleGorm) GetUserSchedules(userID uint, activeOnly bool) ([]IntakeSchedule, error) {
+	query := sg.DB.Table("intake_schedules").Scopes(OwnedBy(userID))
+	if activeOnly {
+		query = query.Where("active")
+	}
+
+	var schedules []IntakeSchedule
+	err := query.Order("id asc").Find(&schedules).Error
+	return schedules, err
+}
+
+// GetDrugSchedules lists all schedules of one of the user's drugs.
+func (sg *ScheduleGorm) GetDrugSchedules(userID, drugID uint) ([]IntakeSchedule, error) {
+	var schedules []IntakeSchedule
+	err := sg.DB.Table("intake_schedules").Scopes(OwnedBy(userID)).Where("drug_id = ?", drugID).Order("id asc").Find(&schedules).Error
+	return schedules, err
+}
+
+// DeleteUserSchedule deletes a schedule together with its dose logs if it belongs to the given user.
+func (sg *ScheduleGorm) DeleteUserSchedule(userID uint, id int) error {
+	return deleteOwned(sg.DB, "intake_schedules", &IntakeSchedule{}, userID, id)
+}
+
+// LogDose stores the status of a dose, replacing an earlier status of the same dose.
+// The schedule is locked while the dose is logged and fn is called in the same transaction with
+// the previous status of the dose, empty for new doses. Concurrent logs of a dose are serialized,
+// so each of them sees the status set by the one before.
+func (sg *ScheduleGorm) LogDose(log *DoseLog, fn func(tx *gorm.DB, previous string) error) (*DoseLog, error) {
+	err := sg.DB.Transaction(func(tx *gorm.DB) error {
+		var schedule IntakeSchedule
+		if err := tx.Table("intake_schedules").Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", log.ScheduleID).First(&schedule).Error; err != nil {
+			return err
+		}
+
+		var previous DoseLog
+		err := tx.Table("dose_logs").Where("schedule_id = ? AND scheduled_at = ?", log.ScheduleID, log.ScheduledAt).First(&previous).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Table("dose_logs").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "schedule_id"}, {Name: "scheduled_at"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "snoozed_until", "acted_at"}),
+		}).Create(log).Error; err != nil {
+			return err
+		}
+		return fn(tx, previous.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// GetUserDoseLogs returns the user's dose logs for doses scheduled in [from, to).
+func (sg *ScheduleGorm) GetUserDoseLogs(userID uint, from, to time.Time) ([]DoseLog, error) {
+	var logs []DoseLog
+	err := sg.DB.Table("dose_logs").
+		Scopes(OwnedBy(userID)).
+		Where("scheduled_at >= ? AND scheduled_at < ?", from, to).
+		Find(&logs).Error
+	return logs, err
+}
+
+// GetDrugDoseLogs returns the adherence history of one of the user's drugs, newest first.
+func (sg *ScheduleGorm) GetDrugDoseLogs(userID, drugID uint) ([]DoseLog, error) {
+	var logs []DoseLog
+	err := sg.DB.Table("dose_logs").
+		Scopes(OwnedBy(userID)).
+		Where("drug_id = ?", drugID).
+		Order("scheduled_at desc").
+		Find(&logs).Error
+	return logs, err
+}
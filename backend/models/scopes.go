@@ -0,0 +1,44 @@
+package models
+
+import "gorm.io/gorm"
+
+// OwnedBy is a GORM scope limiting a query to rows of the given user.
+// Every query on user-owned tables should use it, so that IDs of other users behave as missing rows.
+func OwnedBy(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userID)
+	}
+}
+
+// OwnedOrShared is a GORM scope for tables owned either by a user or by a group, such as kits and drugs.
+// It limits a query to the user's rows and rows of the groups the user is a member of.
+func OwnedOrShared(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? OR group_id IN (SELECT group_id FROM user_groups WHERE user_id = ?)", userID, userID)
+	}
+}
+
+// SharedWith is a GORM scope limiting a query to rows of the user's groups,
+// or of a single group if groupID is set.
+func SharedWith(userID uint, groupID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("group_id IN (SELECT group_id FROM user_groups WHERE user_id = ?)", userID)
+		if groupID != nil {
+			db = db.Where("group_id = ?", *groupID)
+		}
+		return db
+	}
+}
+
+// deleteOwned deletes the row with the given ID from the table if it belongs to the user.
+// Returns gorm.ErrRecordNotFound if nothing was deleted.
+func deleteOwned(db *gorm.DB, table string, model interface{}, userID uint, id int) error {
+	result := db.Table(table).Scopes(OwnedBy(userID)).Where("id = ?", id).Delete(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session represents a logged in device of a user.
+// Access tokens carry the session ID, so revoking the session invalidates them immediately.
+type Session struct {
+	ID           uint       `gorm:"primaryKey" json:"id"` // Unique identifier of the session
+	UserID       uint       `gorm:"index" json:"-"`       // Owner of the session
+	RefreshHash  string     `gorm:"uniqueIndex" json:"-"` // SHA-256 hash of the current refresh token
+	PreviousHash string     `gorm:"index" json:"-"`       // Hash of the rotated out refresh token, used to detect reuse
+	UserAgent    string     `json:"user_agent"`           // Client that created the session
+	IP           string     `json:"ip"`                   // Address the session was created from
+	CreatedAt    time.Time  `json:"created_at"`           // Login time
+	LastUsedAt   time.Time  `json:"last_used_at"`         // Last time the refresh token was used
+	ExpiresAt    time.Time  `json:"expires_at"`           // Refresh token expiry
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"` // Set when the user logs out or revokes the device
+}
+
+// Active reports whether the session can still be used.
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// SessionGorm wraps a GORM DB instance for operations on sessions.
+type SessionGorm struct {
+	DB *gorm.DB
+}
+
+// NewSessionGorm creates a new instance of SessionGorm.
+func NewSessionGorm(db *gorm.DB) *SessionGorm {
+	return &SessionGorm{DB: db}
+}
+
+// CreateSession inserts a new session record.
+func (sg *SessionGorm) CreateSession(session *Session) (*Session, error) {
+	if err := sg.DB.Table("sessions").Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSessionByID retrieves a session by its ID.
+func (sg *SessionGorm) GetSessionByID(id uint) (*Session, error) {
+	var session Session
+	if err := sg.DB.Table("sessions").Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionByRefreshHash retrieves the session whose current refresh token has the given hash.
+func (sg *SessionGorm) GetSessionByRefreshHash(hash string) (*Session, error) {
+	var session Session
+	if err := sg.DB.Table("sessions").Where("refresh_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionByPreviousHash retrieves the session whose already rotated refresh token has the given hash.
+func (sg *SessionGorm) GetSessionByPreviousHash(hash string) (*Session, error) {
+	var session Session
+	if err := sg.DB.Table("sessions").Where("previous_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveUserSessions lists sessions of the user that are neither revoked nor expired.
+func (sg *SessionGorm) GetActiveUserSessions(userID uint) ([]Session, error) {
+	var sessions []Session
+	err := sg.DB.Table("sessions").
+		Scopes(OwnedBy(userID)).
+		Where("revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Order("last_used_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RotateRefreshToken replaces the session's refresh token hash and extends its expiry.
+// The update only succeeds if the session still has the expected hash, so concurrent rotations cannot both win.
+func (sg *SessionGorm) RotateRefreshToken(session *Session, newHash string, expiresAt time.Time) error {
+	now := time.Now()
+	result := sg.DB.Table("sessions").
+		Where("id = ? AND refresh_hash = ? AND revoked_at IS NULL", session.ID, session.RefreshHash).
+		Updates(map[string]interface{}{
+			"previous_hash": session.RefreshHash,
+			"refresh_hash":  newHash,
+			"last_used_at":  now,
+			"expires_at":    expiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	session.PreviousHash = session.RefreshHash
+	session.RefreshHash = newHash
+	session.LastUsedAt = now
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+// RevokeSession marks the user's session as revoked.
+// Returns gorm.ErrRecordNotFound if the session does not exist, belongs to another user or is already revoked.
+func (sg *SessionGorm) RevokeSession(userID, id uint) error {
+	result := sg.DB.Table("sessions").
+		Scopes(OwnedBy(userID)).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Delivery statuses of an SOS alert
+const (
+	DeliveryPending = "pending" // Not attempted yet
+	DeliverySent    = "sent"    // Accepted by the mail server, SMS gateway or webhook
+	DeliveryFailed  = "failed"  // Sending failed, see the error
+	DeliverySkipped = "skipped" // The channel is not configured on the server
+)
+
+// SOSAlert is an emergency alert sent to the user's contacts.
+type SOSAlert struct {
+	ID         uint          `gorm:"primaryKey" json:"id"`
+	UserID     uint          `json:"-"`
+	Message    string        `json:"message"`
+	Latitude   *float64      `json:"latitude"`
+	Longitude  *float64      `json:"longitude"`
+	Accuracy   *float64      `json:"accuracy"` // Radius of the location in meters
+	CreatedAt  time.Time     `json:"created_at"`
+	Deliveries []SOSDelivery `gorm:"-" json:"deliveries"`
+}
+
+// SOSDelivery is the delivery of an alert to one contact over one channel.
+type SOSDelivery struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	AlertID     uint       `json:"alert_id"`
+	ContactID   *uint      `json:"contact_id"`   // Null if the contact was removed since
+	ContactName string     `json:"contact_name"` // Name of the contact when the alert was sent
+	Channel     string     `json:"channel" example:"sms"`
+	Address     string     `json:"address" example:"+79001234567"`
+	Status      string     `json:"status" example:"sent"`
+	Error       string     `json:"error,omitempty"`
+	AttemptedAt *time.Time `json:"attempted_at"`
+}
+
+// CreateAlert inserts an alert with its pending deliveries.
+func (eg *EmergencyGorm) CreateAlert(alert *SOSAlert) (*SOSAlert, error) {
+	err := eg.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("sos_alerts").Create(alert).Error; err != nil {
+			return err
+		}
+		for i := range alert.Deliveries {
+			alert.Deliveries[i].AlertID = alert.ID
+		}
+		if len(alert.Deliveries) == 0 {
+			return nil
+		}
+		return tx.Table("sos_deliveries").Create(&alert.Deliveries).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+// UpdateDelivery saves the status of a delivery attempt.
+func (eg *EmergencyGorm) UpdateDelivery(delivery *SOSDelivery) error {
+	return eg.DB.Table("sos_deliveries").Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":       delivery.Status,
+		"error":        delivery.Error,
+		"attempted_at": delivery.AttemptedAt,
+	}).Error
+}
+
+// GetUserAlert retrieves an alert of the user with its deliveries.
+func (eg *EmergencyGorm) GetUserAlert(userID uint, id int) (*SOSAlert, error) {
+	var alert SOSAlert
+	if err := eg.DB.Table("sos_alerts").Scopes(OwnedBy(userID)).Where("id = ?", id).First(&alert).Error; err != nil {
+		return nil, err
+	}
+	err := eg.DB.Table("sos_deliveries").Where("alert_id = ?", alert.ID).Order("id asc").Find(&alert.Deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// GetUserAlerts lists the latest alerts of the user with their deliveries, newest first.
+func (eg *EmergencyGorm) GetUserAlerts(userID uint, limit int) ([]SOSAlert, error) {
+	var alerts []SOSAlert
+	err := eg.DB.Table("sos_alerts").Scopes(OwnedBy(userID)).Order("created_at desc, id desc").Limit(limit).Find(&alerts).Error
+	if err != nil || len(alerts) == 0 {
+		return alerts, err
+	}
+
+	ids := make([]uint, len(alerts))
+	index := map[uint]int{}
+	for i, alert := range alerts {
+		ids[i] = alert.ID
+		index[alert.ID] = i
+	}
+	var deliveries []SOSDelivery
+	if err := eg.DB.Table("sos_deliveries").Where("alert_id IN ?", ids).Order("id asc").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	for _, delivery := range deliveries {
+		i := index[delivery.AlertID]
+		alerts[i].Deliveries = append(alerts[i].Deliveries, delivery)
+	}
+	return alerts, nil
+}
@@ -10,8 +10,8 @@ type User struct {
 	Name         string      // Full name of the user
 	Email        string      // Email address (should be unique)
 	PasswordHash string      // Hashed password for secure authentication
-	SNILS        string      // Russian personal insurance number
-	Passport     string      // Passport number
+	SNILS        string      `gorm:"serializer:encrypted"` // Russian personal insurance number, encrypted at rest
+	Passport     string      `gorm:"serializer:encrypted"` // Passport number, encrypted at rest
 	Address      string      // User's address
 	Groups       []Group     `gorm:"many2many:user_groups;"`                         // Many-to-many relation with groups
 	MedicalCard  MedicalCard `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"` // One-to-one relation with MedicalCard
@@ -64,3 +64,167 @@ func (ug *UserGorm) GetUserByEmail(email string) (*User, error) {
 func (ug *UserGorm) UpdateUser(user *User) error {
 	return ug.DB.Table("users").Save(user).Error
 }
+
+// DeletionSummary reports how many records were removed together with a user.
+type DeletionSummary struct {
+	Drugs             int64 `json:"drugs"`
+	Documents         int64 `json:"documents"`
+	Chats             int64 `json:"chats"`
+	Messages          int64 `json:"messages"`
+	MedicalCards      int64 `json:"medical_cards"`
+	MedicalEntries    int64 `json:"medical_entries"` // Allergies and chronic conditions
+	GroupMemberships  int64 `json:"group_memberships"`
+	Sessions          int64 `json:"sessions"`
+	Notifications     int64 `json:"notifications"`
+	IntakeSchedules   int64 `json:"intake_schedules"`
+	DoseLogs          int64 `json:"dose_logs"`
+	Kits              int64 `json:"kits"`
+	Groups            int64 `json:"groups"` // Groups deleted because the user was their last member
+	DrugEvents        int64 `json:"drug_events"`
+	EmergencyContacts int64 `json:"emergency_contacts"`
+	EmergencyProfiles int64 `json:"emergency_profiles"`
+	SOSAlerts         int64 `json:"sos_alerts"`
+	ProfileAudit      int64 `json:"profile_audit"` // Requests made on behalf of the profile
+	DataKeys          int64 `json:"data_keys"`     // Keys of the encrypted data, nothing deleted can be decrypted anymore
+	Dependents        int64 `json:"dependents"`    // Profiles no one else could manage, their data is counted above
+
+	BlobKeys []string `json:"-"` // Files of the deleted documents, to be removed from the blob store unless shared
+}
+
+// DeleteUserCascade deletes the user together with all of their data in a single transaction.
+// Profiles created by the user that no one else can manage afterwards are deleted as well.
+// Either everything is removed or nothing is.
+func (ug *UserGorm) DeleteUserCascade(userID uint) (*DeletionSummary, error) {
+	summary := &DeletionSummary{}
+
+	err := ug.DB.Transaction(func(tx *gorm.DB) error {
+		var created []uint
+		if err := tx.Table("dependents").Where("created_by = ?", userID).Pluck("user_id", &created).Error; err != nil {
+			return err
+		}
+		if err := deleteUserData(tx, userID, summary); err != nil {
+			return err
+		}
+		if len(created) == 0 {
+			return nil
+		}
+
+		// Profiles outside of any group, including groups deleted with the user,
+		// would be left without caregivers
+		var orphans []uint
+		if err := tx.Table("dependents").Where("user_id IN ? AND group_id IS NULL", created).Pluck("user_id", &orphans).Error; err != nil {
+			return err
+		}
+		for _, id := range orphans {
+			if err := deleteUserData(tx, id, summary); err != nil {
+				return err
+			}
+		}
+		summary.Dependents = int64(len(orphans))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// deleteUserData deletes the user and their data within the transaction, adding the deleted rows to the summary.
+func deleteUserData(tx *gorm.DB, userID uint, summary *DeletionSummary) error {
+	// Messages first, they reference chats
+	chats := tx.Table("chats").Select("id").Where("user_id = ?", userID)
+	result := tx.Exec("DELETE FROM messages WHERE chat_id IN (?)", chats)
+	if result.Error != nil {
+		return result.Error
+	}
+	summary.Messages += result.RowsAffected
+
+	// Owned groups go to the longest standing caregiver, or member if there is none.
+	// The user is demoted first, a group can only have one owner at a time.
+	var ownedGroups []uint
+	if err := tx.Table("user_groups").Where("user_id = ? AND role = ?", userID, RoleOwner).Pluck("group_id", &ownedGroups).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("UPDATE user_groups SET role = ? WHERE user_id = ?", RoleMember, userID).Error; err != nil {
+		return err
+	}
+	result = tx.Exec(`UPDATE user_groups SET role = 'owner'
+			WHERE (group_id, user_id) IN (
+				SELECT DISTINCT ON (next.group_id) next.group_id, next.user_id
+				FROM user_groups next
+				WHERE next.group_id IN (?) AND next.user_id <> ?
+				ORDER BY next.group_id, next.role = 'caregiver' DESC, next.joined_at, next.user_id
+			)`, ownedGroups, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	// Groups without other members are deleted with their shared kits
+	result = tx.Exec(`DELETE FROM groups WHERE id IN (
+				SELECT group_id FROM user_groups WHERE user_id = ?
+			) AND NOT EXISTS (
+				SELECT 1 FROM user_groups other WHERE other.group_id = groups.id AND other.user_id <> ?
+			)`, userID, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	summary.Groups += result.RowsAffected
+
+	// History of personal drugs, the user stays anonymous in the history of shared drugs
+	result = tx.Exec("DELETE FROM drug_events WHERE group_id IS NULL AND user_id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	summary.DrugEvents += result.RowsAffected
+
+	// Files are not in the database, the caller deletes them after the transaction commits
+	var blobKeys []string
+	if err := tx.Table("documents").Where("user_id = ? AND blob_key <> ''", userID).Distinct().Pluck("blob_key", &blobKeys).Error; err != nil {
+		return err
+	}
+	summary.BlobKeys = append(summary.BlobKeys, blobKeys...)
+
+	// Tables with a user_id column, counted into the summary
+	tables := []struct {
+		table string
+		count *int64
+	}{
+		{"chats", &summary.Chats},
+		{"dose_logs", &summary.DoseLogs},
+		{"intake_schedules", &summary.IntakeSchedules},
+		{"drugs", &summary.Drugs},
+		{"kits", &summary.Kits},
+		{"documents", &summary.Documents},
+		{"medical_cards", &summary.MedicalCards},
+		{"medical_entries", &summary.MedicalEntries},
+		{"user_groups", &summary.GroupMemberships},
+		{"sessions", &summary.Sessions},
+		{"notifications", &summary.Notifications},
+		{"sos_alerts", &summary.SOSAlerts},
+		{"emergency_contacts", &summary.EmergencyContacts},
+		{"emergency_profiles", &summary.EmergencyProfiles},
+		// Last, documents and their thumbnails reference the keys
+		{"data_keys", &summary.DataKeys},
+	}
+	for _, o := range tables {
+		result := tx.Exec("DELETE FROM "+o.table+" WHERE user_id = ?", userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		*o.count += result.RowsAffected
+	}
+
+	result = tx.Exec("DELETE FROM profile_audit WHERE profile_id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	summary.ProfileAudit += result.RowsAffected
+
+	result = tx.Exec("DELETE FROM users WHERE id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
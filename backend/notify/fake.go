@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Fake is a Notifier that keeps alerts in memory instead of sending them.
+// It is meant for tests and local development. Addresses in the reserved
+// .invalid domain fail, so that failed deliveries can be tested too.
+type Fake struct {
+	mu   sync.Mutex
+	sent []FakeMessage
+}
+
+// FakeMessage is an alert delivered to the fake.
+type FakeMessage struct {
+	To    Recipient
+	Alert Alert
+}
+
+// NewFake creates a fake notifier.
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// Send records the alert.
+func (f *Fake) Send(ctx context.Context, to Recipient, alert Alert) error {
+	if strings.Contains(to.Address, ".invalid") {
+		return errors.New("unreachable address")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, FakeMessage{To: to, Alert: alert})
+	log.Printf("Fake notifier: alert %d to %s <%s>", alert.ID, to.Name, to.Address)
+	return nil
+}
+
+// Sent returns the alerts recorded so far.
+func (f *Fake) Sent() []FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeMessage(nil), f.sent...)
+}
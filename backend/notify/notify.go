@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Channel identifies how an alert reaches a contact.
+type Channel string
+
+const (
+	ChannelEmail   Channel = "email"   // Email sent through an SMTP server
+	ChannelSMS     Channel = "sms"     // Text message sent through an HTTP SMS gateway
+	ChannelWebhook Channel = "webhook" // JSON posted to the contact's URL
+)
+
+// ErrNotConfigured is returned for channels the server has no notifier for.
+var ErrNotConfigured = errors.New("channel is not configured")
+
+// Location is where the user was when the alert was raised.
+type Location struct {
+	Latitude  float64  `json:"latitude" example:"55.7558"`
+	Longitude float64  `json:"longitude" example:"37.6173"`
+	Accuracy  *float64 `json:"accuracy,omitempty" example:"25"` // Radius in meters
+}
+
+// Alert is an SOS message sent to every emergency contact of a user.
+type Alert struct {
+	ID       uint      `json:"id"`
+	UserName string    `json:"user_name"`          // Who needs help
+	Message  string    `json:"message,omitempty"`  // Optional text from the user
+	Location *Location `json:"location,omitempty"` // Optional location
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// Recipient is the address of a contact on one channel.
+type Recipient struct {
+	Name    string // Name of the contact
+	Address string // Email, phone number or webhook URL depending on the channel
+}
+
+// Notifier delivers alerts over a single channel.
+type Notifier interface {
+	// Send delivers the alert to the recipient. A nil error means the message
+	// was accepted by the server or gateway, not that it was read.
+	Send(ctx context.Context, to Recipient, alert Alert) error
+}
+
+// Config describes which channels are available.
+type Config struct {
+	Fake bool // Deliver every channel to an in-memory fake, for tests and local development
+
+	SMTPHost     string // SMTP server host, email is disabled if empty
+	SMTPPort     int    // SMTP server port, 587 by default
+	SMTPUsername string // Optional SMTP login
+	SMTPPassword string
+	SMTPFrom     string // Sender address
+
+	SMSGatewayURL   string // Endpoint of the SMS gateway, SMS is disabled if empty
+	SMSGatewayToken string // Optional bearer token of the gateway
+
+	WebhookSecret string // Optional key signing webhook bodies
+}
+
+// Dispatcher holds the notifiers of the configured channels.
+type Dispatcher struct {
+	notifiers map[Channel]Notifier
+}
+
+// New creates a dispatcher with a notifier for every configured channel.
+func New(cfg Config) *Dispatcher {
+	d := &Dispatcher{notifiers: map[Channel]Notifier{}}
+	if cfg.Fake {
+		fake := NewFake()
+		for _, channel := range []Channel{ChannelEmail, ChannelSMS, ChannelWebhook} {
+			d.notifiers[channel] = fake
+		}
+		return d
+	}
+
+	if cfg.SMTPHost != "" {
+		d.notifiers[ChannelEmail] = NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+	if cfg.SMSGatewayURL != "" {
+		d.notifiers[ChannelSMS] = NewSMSGateway(cfg.SMSGatewayURL, cfg.SMSGatewayToken)
+	}
+	d.notifiers[ChannelWebhook] = NewWebhook(cfg.WebhookSecret)
+	return d
+}
+
+// Register replaces the notifier of a channel.
+func (d *Dispatcher) Register(channel Channel, notifier Notifier) {
+	d.notifiers[channel] = notifier
+}
+
+// Channels lists the configured channels.
+func (d *Dispatcher) Channels() []Channel {
+	var channels []Channel
+	for _, channel := range []Channel{ChannelEmail, ChannelSMS, ChannelWebhook} {
+		if _, ok := d.notifiers[channel]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// Send delivers the alert over the channel.
+// Returns ErrNotConfigured if the server has no notifier for it.
+func (d *Dispatcher) Send(ctx context.Context, channel Channel, to Recipient, alert Alert) error {
+	notifier, ok := d.notifiers[channel]
+	if !ok {
+		return ErrNotConfigured
+	}
+	return notifier.Send(ctx, to, alert)
+}
+
+// Text renders the alert as plain text for email and SMS.
+func Text(alert Alert) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "SOS: %s needs help.", alert.UserName)
+	if alert.Message != "" {
+		fmt.Fprintf(&text, " %s", alert.Message)
+	}
+	if loc := alert.Location; loc != nil {
+		fmt.Fprintf(&text, " Location: https://www.openstreetmap.org/?mlat=%.6f&mlon=%.6f#map=17/%.6f/%.6f",
+			loc.Latitude, loc.Longitude, loc.Latitude, loc.Longitude)
+		if loc.Accuracy != nil {
+			fmt.Fprintf(&text, " (within %.0f m)", *loc.Accuracy)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// smsTimeout limits a gateway request including reading the response.
+const smsTimeout = 10 * time.Second
+
+// SMSGateway sends alerts as text messages through an HTTP gateway.
+// The gateway receives a POST with {"to": "<phone>", "text": "<message>"}.
+type SMSGateway struct {
+	url    string       // Endpoint of the gateway
+	token  string       // Optional bearer token
+	client *http.Client // HTTP client used for requests
+}
+
+// smsRequest is the body sent to the gateway.
+type smsRequest struct {
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// NewSMSGateway creates an SMS notifier for the gateway endpoint.
+func NewSMSGateway(url, token string) *SMSGateway {
+	return &SMSGateway{url: url, token: token, client: &http.Client{Timeout: smsTimeout}}
+}
+
+// Send asks the gateway to text the alert to the recipient's phone.
+func (s *SMSGateway) Send(ctx context.Context, to Recipient, alert Alert) error {
+	body, err := json.Marshal(smsRequest{To: to.Address, Text: Text(alert)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	return doPost(s.client, req)
+}
+
+// doPost sends the request and turns non-2xx responses into errors. Errors end up in the
+// delivery status shown to the user, so they carry the status code but never the body.
+func doPost(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSMTPPort is the submission port used when none is configured.
+const defaultSMTPPort = 587
+
+// SMTP sends alerts by email.
+type SMTP struct {
+	addr string    // host:port of the server
+	auth smtp.Auth // Nil when the server accepts mail without login
+	from string    // Sender address
+}
+
+// NewSMTP creates an email notifier. Login is skipped if username is empty.
+func NewSMTP(host string, port int, username, password, from string) *SMTP {
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTP{addr: net.JoinHostPort(host, strconv.Itoa(port)), auth: auth, from: from}
+}
+
+// Send emails the alert to the recipient.
+func (s *SMTP) Send(ctx context.Context, to Recipient, alert Alert) error {
+	// Header injection through the address or name is not possible with line breaks removed
+	address := strings.NewReplacer("\r", "", "\n", "").Replace(to.Address)
+	body, err := s.message(address, alert, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, the send is abandoned when the context ends
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.from, []string{address}, body)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// message formats the email. The subject is encoded as RFC 2047 words, so names
+// in any language survive, and Date and Message-ID keep spam filters from rejecting it.
+func (s *SMTP) message(address string, alert Alert, now time.Time) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if from, err := mail.ParseAddress(s.from); err == nil {
+		if _, host, ok := strings.Cut(from.Address, "@"); ok {
+			domain = host
+		}
+	}
+
+	subject := "SOS from " + strings.NewReplacer("\r", "", "\n", "").Replace(alert.UserName)
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.from)
+	fmt.Fprintf(&body, "To: %s\r\n", address)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	fmt.Fprintf(&body, "\r\n%s\r\n", Text(alert))
+	return []byte(body.String()), nil
+}
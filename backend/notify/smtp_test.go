@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMTPMessage(t *testing.T) {
+	s := NewSMTP("smtp.example.com", 0, "", "", "First Aid <alerts@example.com>")
+	now := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+	data, err := s.message("anna@example.com", Alert{UserName: "Иван\r\nBcc: victim@example.com", Message: "Fell down"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Bcc"); got != "" {
+		t.Errorf("injected Bcc header %q", got)
+	}
+
+	raw := msg.Header.Get("Subject")
+	if !strings.HasPrefix(raw, "=?UTF-8?q?") {
+		t.Errorf("Subject %q is not encoded", raw)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(raw)
+	if err != nil || subject != "SOS from ИванBcc: victim@example.com" {
+		t.Errorf("Subject = %q, err = %v", subject, err)
+	}
+
+	if date, err := msg.Header.Date(); err != nil || !date.Equal(now) {
+		t.Errorf("Date = %v, err = %v", date, err)
+	}
+	if id := msg.Header.Get("Message-ID"); !regexp.MustCompile(`^<[0-9a-f]{32}@example\.com>$`).MatchString(id) {
+		t.Errorf("Message-ID = %q", id)
+	}
+	if msg.Header.Get("MIME-Version") != "1.0" || msg.Header.Get("To") != "anna@example.com" {
+		t.Errorf("header = %v", msg.Header)
+	}
+
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.Contains(string(body), "Fell down") {
+		t.Errorf("body = %q", body)
+	}
+
+	// Every message gets its own ID
+	again, err := s.message("anna@example.com", Alert{UserName: "Anna"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := mail.ReadMessage(bytes.NewReader(again))
+	if other.Header.Get("Message-ID") == msg.Header.Get("Message-ID") {
+		t.Error("Message-ID is reused")
+	}
+	// ASCII subjects are left readable
+	if got := other.Header.Get("Subject"); got != "SOS from Anna" {
+		t.Errorf("Subject = %q", got)
+	}
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body when a secret is configured.
+const SignatureHeader = "X-Signature-SHA256"
+
+// webhookTimeout limits a webhook request including reading the response.
+const webhookTimeout = 10 * time.Second
+
+// ErrForbiddenAddress is returned for webhook URLs pointing into the server's own network.
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// Webhook posts alerts as JSON to the contact's URL, e.g. a home automation or messenger bot.
+type Webhook struct {
+	secret []byte       // Optional key signing the body
+	client *http.Client // HTTP client used for requests
+}
+
+// webhookPayload is the body posted to the URL.
+type webhookPayload struct {
+	Alert
+	Contact string `json:"contact"` // Name of the contact the URL belongs to
+	Text    string `json:"text"`    // The alert as plain text
+}
+
+// NewWebhook creates a webhook notifier. Bodies are signed if secret is not empty.
+// URLs are chosen by users, so requests only go to public addresses: the address is checked
+// after DNS resolution, when connecting, and redirects are not followed.
+func NewWebhook(secret string) *Webhook {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: checkPublicAddress}
+	return &Webhook{
+		secret: []byte(secret),
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			Transport: &http.Transport{
+				// A proxy would connect on the server's behalf and bypass the check
+				Proxy:               nil,
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: webhookTimeout,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// CheckWebhookURL reports whether the URL can receive webhooks: it must be http or https and
+// must not name a loopback or private address. Host names are checked again when connecting.
+func CheckWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("webhook URL must be an http or https URL")
+	}
+	if u.Hostname() == "localhost" {
+		return ErrForbiddenAddress
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !publicAddress(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// checkPublicAddress refuses connections to addresses that are not public. It runs with the
+// resolved IP, so host names resolving to internal addresses are refused as well.
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	if !publicAddress(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// publicAddress reports whether the IP is reachable on the internet rather than being
+// a loopback, private, link-local, multicast or unspecified address.
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, not routed on the internet.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Send posts the alert to the recipient's URL.
+func (wh *Webhook) Send(ctx context.Context, to Recipient, alert Alert) error {
+	body, err := json.Marshal(webhookPayload{Alert: alert, Contact: to.Name, Text: Text(alert)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, to.Address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(wh.secret) > 0 {
+		mac := hmac.New(sha256.New, wh.secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	return doPost(wh.client, req)
+}
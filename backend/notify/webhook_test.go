@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckWebhookURL(t *testing.T) {
+	for raw, want := range map[string]error{
+		"https://example.com/hooks/sos": nil,
+		"http://93.184.216.34/hook":     nil,
+		"http://localhost:8080/":        ErrForbiddenAddress,
+		"http://127.0.0.1/":             ErrForbiddenAddress,
+		"http://10.0.0.5/":              ErrForbiddenAddress,
+		"http://192.168.1.1/":           ErrForbiddenAddress,
+		"http://169.254.169.254/latest": ErrForbiddenAddress,
+		"http://100.64.0.1/":            ErrForbiddenAddress,
+		"http://0.0.0.0/":               ErrForbiddenAddress,
+		"http://[::1]/":                 ErrForbiddenAddress,
+		"http://[::ffff:127.0.0.1]/":    ErrForbiddenAddress,
+		"http://[fe80::1]/":             ErrForbiddenAddress,
+	} {
+		if err := CheckWebhookURL(raw); !errors.Is(err, want) {
+			t.Errorf("CheckWebhookURL(%q) = %v, want %v", raw, err, want)
+		}
+	}
+	for _, raw := range []string{"ftp://example.com/", "example.com", "http://"} {
+		if err := CheckWebhookURL(raw); err == nil || errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("CheckWebhookURL(%q) = %v, want an invalid URL error", raw, err)
+		}
+	}
+}
+
+func TestWebhookRefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, like services next to the backend
+	err := NewWebhook("").Send(context.Background(), Recipient{Name: "Home", Address: server.URL}, Alert{ID: 1})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Send to %s = %v, want ErrForbiddenAddress", server.URL, err)
+	}
+	if called {
+		t.Fatal("the request reached the server")
+	}
+}
+
+func TestWebhookErrorsHideResponse(t *testing.T) {
+	internal := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	})
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+		internal = true
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "secret internal response", http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Allow loopback to reach the test server, the rest of the client stays as configured
+	wh := NewWebhook("")
+	wh.client.Transport = http.DefaultTransport
+
+	err := wh.Send(context.Background(), Recipient{Address: server.URL + "/redirect"}, Alert{ID: 1})
+	if err == nil || !strings.Contains(err.Error(), "302") {
+		t.Errorf("Send with a redirect = %v, want a 302 error", err)
+	}
+	if internal {
+		t.Error("the redirect was followed")
+	}
+
+	err = wh.Send(context.Background(), Recipient{Address: server.URL + "/error"}, Alert{ID: 1})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("Send to a failing URL = %v, want a 500 error", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q contains the response body", err)
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"first_aid_companion/encryption"
+	"first_aid_companion/models"
+	"first_aid_companion/storage"
+)
+
+// blobBatchSize is the number of documents loaded at once when moving files.
+const blobBatchSize = 20
+
+// MoveFilesToBlobs encrypts the files still kept in the documents table, copies them to the
+// blob store and replaces them with references. It can be interrupted and run again.
+// Returns the number of moved files.
+func MoveFilesToBlobs(ctx context.Context, docs *models.DocumentGorm, blobs storage.BlobStore, keyring *encryption.Keyring) (int, error) {
+	moved := 0
+	for {
+		batch, err := docs.GetDatabaseFiles(blobBatchSize)
+		if err != nil || len(batch) == 0 {
+			return moved, err
+		}
+		for _, doc := range batch {
+			data, dataKeyID, err := keyring.EncryptFile(doc.UserID, doc.FileData)
+			if err != nil {
+				return moved, err
+			}
+			key := storage.Key(data)
+			err = docs.WithBlobLock(key, func(tx *models.DocumentGorm) error {
+				if err := blobs.Put(ctx, key, data); err != nil {
+					return err
+				}
+				return tx.MoveFileToBlob(doc.ID, storage.Key(doc.FileData), key, keyIDRef(dataKeyID))
+			})
+			if err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+}
+
+// MoveFilesToDatabase decrypts the files of every document and copies them back into the
+// documents table, e.g. before reverting the blob store migration. The blobs themselves are kept.
+// Returns the number of restored files.
+func MoveFilesToDatabase(ctx context.Context, docs *models.DocumentGorm, blobs storage.BlobStore, keyring *encryption.Keyring) (int, error) {
+	restored := 0
+	var lastID uint
+	for {
+		batch, err := docs.GetBlobFiles(lastID, blobBatchSize)
+		if err != nil || len(batch) == 0 {
+			return restored, err
+		}
+		for _, doc := range batch {
+			data, err := blobs.Get(ctx, doc.BlobKey)
+			if err == nil {
+				data, err = keyring.DecryptFile(data)
+			}
+			if err != nil {
+				return restored, err
+			}
+			if err := docs.MoveFileToDatabase(doc.ID, data); err != nil {
+				return restored, err
+			}
+			lastID = doc.ID
+			restored++
+		}
+	}
+}
+
+// keyIDRef returns a reference to the data key ID for the documents table, nil for plaintext files.
+func keyIDRef(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
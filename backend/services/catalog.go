@@ -0,0 +1,194 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"first_aid_companion/models"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CatalogRecord is an entry of a registry file. CSV files use the JSON names as column headers,
+// other columns are ignored, so registry exports can be imported without editing.
+type CatalogRecord struct {
+	GTIN            string   `json:"gtin"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Description     string   `json:"description"`
+	Manufacturer    string   `json:"manufacturer"`
+	Dose            string   `json:"dose"`
+	Amount          string   `json:"amount"`
+	Quantity        *float64 `json:"quantity"`
+	Unit            string   `json:"unit"`
+	ShelfLifeMonths int      `json:"shelf_life_months"`
+}
+
+// ImportCatalog reads a CSV or JSON registry file, chosen by extension, into the catalog.
+// Records without a name or with an invalid barcode are skipped.
+// Returns the number of imported and skipped records.
+func ImportCatalog(catalog *models.CatalogGorm, path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var records []CatalogRecord
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		records, err = ParseCatalogCSV(f)
+	case ".json":
+		err = json.NewDecoder(f).Decode(&records)
+	default:
+		return 0, 0, fmt.Errorf("unsupported catalog format %q, use .csv or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	drugs := make([]models.CatalogDrug, 0, len(records))
+	seen := map[string]bool{}
+	skipped := 0
+	for i, record := range records {
+		drug, err := record.toCatalogDrug()
+		if err != nil {
+			log.Printf("Skipping catalog record %d: %v", i+1, err)
+			skipped++
+			continue
+		}
+
+		// The same entry twice in one batch would make the upsert fail. Entries without
+		// a barcode are identified by name, manufacturer, dose and amount.
+		key := catalogKey(drug)
+		if seen[key] {
+			log.Printf("Skipping catalog record %d: duplicate entry %s", i+1, key)
+			skipped++
+			continue
+		}
+		seen[key] = true
+		drugs = append(drugs, drug)
+	}
+
+	if err := catalog.UpsertCatalogDrugs(drugs); err != nil {
+		return 0, skipped, err
+	}
+	return len(drugs), skipped, nil
+}
+
+// catalogKey identifies an entry: by its barcode or, without one, by name, manufacturer, dose and amount.
+func catalogKey(drug models.CatalogDrug) string {
+	if drug.GTIN != nil {
+		return "with barcode " + *drug.GTIN
+	}
+	return fmt.Sprintf("%q by %q, %q, %q", drug.Name, drug.Manufacturer, drug.Dose, drug.Amount)
+}
+
+// ParseCatalogCSV reads CSV records, the first row must be the header.
+func ParseCatalogCSV(r io.Reader) ([]CatalogRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("missing \"name\" column")
+	}
+
+	var records []CatalogRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		record := CatalogRecord{
+			GTIN:         field("gtin"),
+			Name:         field("name"),
+			Type:         field("type"),
+			Description:  field("description"),
+			Manufacturer: field("manufacturer"),
+			Dose:         field("dose"),
+			Amount:       field("amount"),
+			Unit:         field("unit"),
+		}
+		if value := field("quantity"); value != "" {
+			quantity, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quantity %q", line, value)
+			}
+			record.Quantity = &quantity
+		}
+		if value := field("shelf_life_months"); value != "" {
+			months, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid shelf life %q", line, value)
+			}
+			record.ShelfLifeMonths = months
+		}
+		records = append(records, record)
+	}
+}
+
+// toCatalogDrug validates the record and fills the structured quantity from the amount if needed.
+func (record CatalogRecord) toCatalogDrug() (models.CatalogDrug, error) {
+	drug := models.CatalogDrug{
+		Name:            strings.TrimSpace(record.Name),
+		Type:            record.Type,
+		Description:     record.Description,
+		Manufacturer:    record.Manufacturer,
+		Dose:            record.Dose,
+		Amount:          record.Amount,
+		Quantity:        record.Quantity,
+		Unit:            record.Unit,
+		ShelfLifeMonths: record.ShelfLifeMonths,
+	}
+	if drug.Name == "" {
+		return drug, fmt.Errorf("empty name")
+	}
+	if drug.Unit != "" && !models.IsUnit(drug.Unit) {
+		return drug, fmt.Errorf("unknown unit %q", drug.Unit)
+	}
+
+	if record.GTIN != "" {
+		gtin, err := models.NormalizeGTIN(record.GTIN)
+		if err != nil {
+			return drug, fmt.Errorf("%v: %q", err, record.GTIN)
+		}
+		drug.GTIN = &gtin
+	}
+
+	if drug.Quantity == nil {
+		if value, unit, ok := models.ParseAmount(drug.Amount); ok {
+			drug.Quantity = &value
+			if drug.Unit == "" {
+				drug.Unit = unit
+			}
+		}
+	}
+	if drug.Quantity != nil && drug.Unit == "" {
+		drug.Unit = models.UnitPieces
+	}
+	return drug, nil
+}
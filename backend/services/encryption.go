@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"first_aid_companion/encryption"
+	"first_aid_companion/models"
+	"first_aid_companion/storage"
+	"log"
+	"time"
+)
+
+// encryptedTables lists the model fields encrypted with the "encrypted" serializer.
+var encryptedTables = []struct {
+	table   string
+	columns []string
+	model   func() interface{}
+}{
+	{"users", []string{"snils", "passport"}, func() interface{} { return &models.User{} }},
+	{"medical_cards", []string{"blood_type"}, func() interface{} { return &models.MedicalCard{} }},
+	{"medical_entries", []string{"text", "substance", "reaction"}, func() interface{} { return &models.MedicalEntry{} }},
+}
+
+// ReencryptResult counts what a re-encryption pass changed.
+type ReencryptResult struct {
+	Rows        int // Rows with re-encrypted fields
+	Files       int // Re-encrypted document files
+	Thumbnails  int // Re-encrypted document thumbnails
+	Failed      int // Rows and files that could not be re-encrypted, they are retried on the next pass
+	DeletedKeys int // Retired keys no longer in use
+}
+
+// Reencryptor periodically re-encrypts data written before encryption was enabled or with
+// keys retired by "keys rotate", then deletes the retired keys.
+type Reencryptor struct {
+	Keyring  *encryption.Keyring
+	Keys     *models.DataKeyGorm
+	Docs     *models.DocumentGorm
+	Blobs    storage.BlobStore
+	Interval time.Duration // Time between passes
+}
+
+// NewReencryptor creates a re-encryptor, a zero interval falls back to an hour.
+func NewReencryptor(keyring *encryption.Keyring, keys *models.DataKeyGorm, docs *models.DocumentGorm, blobs storage.BlobStore, interval time.Duration) *Reencryptor {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &Reencryptor{
+		Keyring:  keyring,
+		Keys:     keys,
+		Docs:     docs,
+		Blobs:    blobs,
+		Interval: interval,
+	}
+}
+
+// Run re-encrypts immediately and then once per interval until the context is cancelled.
+func (r *Reencryptor) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := r.Pass(ctx)
+		if err != nil {
+			log.Printf("Re-encryption failed: %v", err)
+		} else if result.Rows > 0 || result.Files > 0 || result.Thumbnails > 0 || result.Failed > 0 || result.DeletedKeys > 0 {
+			log.Printf("Re-encryption completed, %d row(s), %d file(s) and %d thumbnail(s) re-encrypted, %d failed, %d retired key(s) deleted",
+				result.Rows, result.Files, result.Thumbnails, result.Failed, result.DeletedKeys)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pass re-encrypts every stale value, file and thumbnail once. Retired keys are deleted if nothing failed.
+func (r *Reencryptor) Pass(ctx context.Context) (*ReencryptResult, error) {
+	result := &ReencryptResult{}
+	if !r.Keyring.Enabled() {
+		return result, nil
+	}
+
+	// Keys retired by another process may have been cached for a while, look them up again
+	r.Keyring.Forget()
+	retired, err := r.Keys.GetRetiredKeys()
+	if err != nil {
+		return result, err
+	}
+	retiredIDs := make([]uint, len(retired))
+	for i, key := range retired {
+		retiredIDs[i] = key.ID
+	}
+
+	// Keys retired longer ago than servers cache them can no longer be used for new data,
+	// so they are unused once this pass re-encrypts everything
+	var unused []uint
+	cutoff := time.Now().Add(-encryption.ActiveKeyTTL)
+	for _, key := range retired {
+		if key.RetiredAt != nil && key.RetiredAt.Before(cutoff) {
+			unused = append(unused, key.ID)
+		}
+	}
+
+	for _, t := range encryptedTables {
+		var lastID uint
+		for {
+			ids, err := r.Keys.GetStaleRows(t.table, t.columns, encryption.Prefix, retiredIDs, lastID, blobBatchSize)
+			if err != nil {
+				return result, err
+			}
+			if len(ids) == 0 {
+				break
+			}
+			for _, id := range ids {
+				if err := r.Keys.ResaveRow(t.table, t.columns, id, t.model()); err != nil {
+					log.Printf("Error re-encrypting %s %d: %v", t.table, id, err)
+					result.Failed++
+				} else {
+					result.Rows++
+				}
+				lastID = id
+			}
+		}
+	}
+
+	var lastID uint
+	for {
+		docs, err := r.Docs.GetStaleBlobFiles(retiredIDs, lastID, blobBatchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(docs) == 0 {
+			break
+		}
+		for _, doc := range docs {
+			if err := r.reencryptFile(ctx, &doc); err != nil {
+				log.Printf("Error re-encrypting file of document %d: %v", doc.ID, err)
+				result.Failed++
+			} else {
+				result.Files++
+			}
+			lastID = doc.ID
+		}
+	}
+
+	lastID = 0
+	for {
+		thumbs, err := r.Docs.GetStaleThumbnails(retiredIDs, lastID, blobBatchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(thumbs) == 0 {
+			break
+		}
+		for _, thumb := range thumbs {
+			if err := r.reencryptThumbnail(&thumb); err != nil {
+				log.Printf("Error re-encrypting thumbnail of document %d: %v", thumb.DocumentID, err)
+				result.Failed++
+			} else {
+				result.Thumbnails++
+			}
+			lastID = thumb.DocumentID
+		}
+	}
+
+	if result.Failed == 0 && len(unused) > 0 {
+		if err := r.Keys.DeleteKeys(unused); err != nil {
+			return result, err
+		}
+		result.DeletedKeys = len(unused)
+	}
+	return result, nil
+}
+
+// reencryptFile encrypts the file of the document with the owner's active key
+// and deletes the previous blob unless another document shares it.
+func (r *Reencryptor) reencryptFile(ctx context.Context, doc *models.Document) error {
+	data, err := r.Blobs.Get(ctx, doc.BlobKey)
+	if err != nil {
+		return err
+	}
+	if data, err = r.Keyring.DecryptFile(data); err != nil {
+		return err
+	}
+	data, dataKeyID, err := r.Keyring.EncryptFile(doc.UserID, data)
+	if err != nil {
+		return err
+	}
+
+	key := storage.Key(data)
+	replaced := false
+	err = r.Docs.WithBlobLock(key, func(tx *models.DocumentGorm) error {
+		if err := r.Blobs.Put(ctx, key, data); err != nil {
+			return err
+		}
+		replaced, err = tx.ReplaceBlob(doc.ID, doc.BlobKey, key, keyIDRef(dataKeyID))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The document was deleted or changed meanwhile, the new blob may be unused
+	if !replaced {
+		return releaseBlob(ctx, r.Docs, r.Blobs, key)
+	}
+	return releaseBlob(ctx, r.Docs, r.Blobs, doc.BlobKey)
+}
+
+// reencryptThumbnail encrypts the thumbnail with the active key of the document owner.
+func (r *Reencryptor) reencryptThumbnail(thumb *models.DocumentThumbnail) error {
+	data, err := r.Keyring.DecryptFile(thumb.Data)
+	if err != nil {
+		return err
+	}
+	data, dataKeyID, err := r.Keyring.EncryptFile(thumb.UserID, data)
+	if err != nil {
+		return err
+	}
+	return r.Docs.ReplaceThumbnailData(thumb, data, keyIDRef(dataKeyID))
+}
+
+// releaseBlob deletes the blob if no document refers to it anymore.
+func releaseBlob(ctx context.Context, docs *models.DocumentGorm, blobs storage.BlobStore, key string) error {
+	return docs.WithBlobLock(key, func(tx *models.DocumentGorm) error {
+		referenced, err := tx.BlobReferenced(key)
+		if err != nil || referenced {
+			return err
+		}
+		return blobs.Delete(ctx, key)
+	})
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"first_aid_companion/models"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// DefaultExpiryWindows are the days before expiry at which users are notified.
+var DefaultExpiryWindows = []int{30, 7, 0}
+
+// ExpiryResult counts what an expiry scan did.
+type ExpiryResult struct {
+	Created int // New notifications
+	Failed  int // Drugs and notifications that could not be processed, they are retried on the next scan
+}
+
+// ExpiryScheduler periodically scans all drugs and notifies owners about expiring ones.
+type ExpiryScheduler struct {
+	Drugs         *models.DrugGorm         // Drugs to scan
+	Notifications *models.NotificationGorm // Where notifications are stored
+	Windows       []int                    // Days before expiry to notify at, e.g. 30, 7 and 0
+	Interval      time.Duration            // Time between scans
+}
+
+// NewExpiryScheduler creates a scheduler, empty windows and zero interval fall back to defaults.
+func NewExpiryScheduler(drugs *models.DrugGorm, notifications *models.NotificationGorm, windows []int, interval time.Duration) *ExpiryScheduler {
+	if len(windows) == 0 {
+		windows = DefaultExpiryWindows
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	// Smallest window first, so a drug is matched with the tightest one it falls into
+	sorted := append([]int{}, windows...)
+	sort.Ints(sorted)
+
+	return &ExpiryScheduler{
+		Drugs:         drugs,
+		Notifications: notifications,
+		Windows:       sorted,
+		Interval:      interval,
+	}
+}
+
+// Run scans immediately and then once per interval until the context is cancelled.
+func (s *ExpiryScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.Scan(time.Now())
+		if err != nil {
+			log.Printf("Expiry scan failed: %v", err)
+		} else {
+			log.Printf("Expiry scan completed, %d new notification(s), %d failed", result.Created, result.Failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan creates notifications for drugs that fall into one of the windows at the given time.
+// Each drug is notified once per window, so repeated scans do not duplicate notifications.
+// A drug whose notifications cannot be stored does not stop the scan, it is retried on the next one.
+func (s *ExpiryScheduler) Scan(now time.Time) (*ExpiryResult, error) {
+	result := &ExpiryResult{}
+	today := truncateDay(now)
+	largest := s.Windows[len(s.Windows)-1]
+
+	drugs, err := s.Drugs.GetDrugsExpiringBefore(today.AddDate(0, 0, largest+1))
+	if err != nil {
+		return result, err
+	}
+
+	for _, drug := range drugs {
+		if drug.Expiry.IsZero() {
+			continue
+		}
+
+		daysLeft := int(truncateDay(drug.Expiry).Sub(today).Hours() / 24)
+		window, ok := s.windowFor(daysLeft)
+		if !ok {
+			continue
+		}
+
+		// Shared drugs notify every member of the group
+		recipients, err := s.Drugs.Recipients(&drug)
+		if err != nil {
+			log.Printf("Error fetching recipients of drug %d: %v", drug.ID, err)
+			result.Failed++
+			continue
+		}
+		for _, recipient := range recipients {
+			isNew, err := s.Notifications.CreateNotification(expiryNotification(&drug, recipient, window, daysLeft, now))
+			switch {
+			case err != nil:
+				log.Printf("Error creating expiry notification of drug %d for user %d: %v", drug.ID, recipient, err)
+				result.Failed++
+			case isNew:
+				result.Created++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// expiryNotification builds the notification of a drug falling into a window.
+func expiryNotification(drug *models.Drug, recipient uint, window, daysLeft int, now time.Time) *models.Notification {
+	drugID := drug.ID
+	return &models.Notification{
+		UserID: recipient,
+		Kind:   models.NotificationDrugExpiry,
+		DrugID: &drugID,
+		Title:  expiryTitle(drug.Name, daysLeft),
+		Body:   fmt.Sprintf("%s expires on %s.", drug.Name, drug.Expiry.Format("2006-01-02")),
+		// Changing the expiry date produces new notifications
+		DedupKey:  fmt.Sprintf("drug_expiry:%d:%d:%s", drug.ID, window, drug.Expiry.Format("2006-01-02")),
+		CreatedAt: now,
+	}
+}
+
+// windowFor returns the smallest window the remaining days fall into.
+func (s *ExpiryScheduler) windowFor(daysLeft int) (int, bool) {
+	for _, window := range s.Windows {
+		if daysLeft <= window {
+			return window, true
+		}
+	}
+	return 0, false
+}
+
+// expiryTitle describes how soon a drug expires.
+func expiryTitle(name string, daysLeft int) string {
+	switch {
+	case daysLeft < 0:
+		return name + " has expired"
+	case daysLeft == 0:
+		return name + " expires today"
+	case daysLeft == 1:
+		return name + " expires tomorrow"
+	default:
+		return fmt.Sprintf("%s expires in %d days", name, daysLeft)
+	}
+}
+
+// truncateDay returns midnight of the given time's day in UTC.
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"first_aid_companion/models"
+	"testing"
+	"time"
+)
+
+func TestNewExpirySchedulerDefaults(t *testing.T) {
+	s := NewExpiryScheduler(nil, nil, nil, 0)
+	if len(s.Windows) != 3 || s.Windows[0] != 0 || s.Windows[2] != 30 || s.Interval != 24*time.Hour {
+		t.Errorf("defaults = %v, %v, want [0 7 30] and 24h", s.Windows, s.Interval)
+	}
+	// The configured windows are sorted, the defaults are left alone
+	s = NewExpiryScheduler(nil, nil, []int{14, 1, 60}, time.Hour)
+	if s.Windows[0] != 1 || s.Windows[1] != 14 || s.Windows[2] != 60 {
+		t.Errorf("windows = %v, want [1 14 60]", s.Windows)
+	}
+	if DefaultExpiryWindows[0] != 30 {
+		t.Errorf("DefaultExpiryWindows = %v was changed", DefaultExpiryWindows)
+	}
+}
+
+func TestWindowFor(t *testing.T) {
+	s := NewExpiryScheduler(nil, nil, []int{30, 7, 0}, 0)
+	for daysLeft, want := range map[int]int{
+		-3: 0, // Expired drugs stay in the tightest window
+		0:  0,
+		1:  7,
+		7:  7,
+		8:  30,
+		30: 30,
+		31: -1,
+	} {
+		window, ok := s.windowFor(daysLeft)
+		if want < 0 {
+			if ok {
+				t.Errorf("windowFor(%d) = %d, want none", daysLeft, window)
+			}
+			continue
+		}
+		if !ok || window != want {
+			t.Errorf("windowFor(%d) = %d, %v, want %d", daysLeft, window, ok, want)
+		}
+	}
+}
+
+func TestExpiryNotification(t *testing.T) {
+	now := time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC)
+	drug := &models.Drug{ID: 42, Name: "Ibuprofen", Expiry: time.Date(2025, 7, 8, 23, 45, 0, 0, time.UTC)}
+
+	notification := expiryNotification(drug, 5, 7, 7, now)
+	if notification.UserID != 5 || notification.Kind != models.NotificationDrugExpiry || *notification.DrugID != 42 {
+		t.Errorf("notification = %+v", notification)
+	}
+	if notification.Title != "Ibuprofen expires in 7 days" || notification.Body != "Ibuprofen expires on 2025-07-08." {
+		t.Errorf("notification text = %q, %q", notification.Title, notification.Body)
+	}
+	key := notification.DedupKey
+	if key != "drug_expiry:42:7:2025-07-08" {
+		t.Errorf("DedupKey = %q", key)
+	}
+
+	// The same window and date on a later scan is the same notification
+	if again := expiryNotification(drug, 5, 7, 3, now.AddDate(0, 0, 4)); again.DedupKey != key {
+		t.Errorf("DedupKey on a later scan = %q, want %q", again.DedupKey, key)
+	}
+	// The next window and a new expiry date are new ones
+	if next := expiryNotification(drug, 5, 0, 0, now.AddDate(0, 0, 7)); next.DedupKey == key {
+		t.Error("the next window has the same DedupKey")
+	}
+	moved := *drug
+	moved.Expiry = moved.Expiry.AddDate(0, 1, 0)
+	if changed := expiryNotification(&moved, 5, 7, 7, now); changed.DedupKey == key {
+		t.Error("a changed expiry date has the same DedupKey")
+	}
+}
+
+func TestExpiryTitle(t *testing.T) {
+	for daysLeft, want := range map[int]string{
+		-1: "Aspirin has expired",
+		0:  "Aspirin expires today",
+		1:  "Aspirin expires tomorrow",
+		30: "Aspirin expires in 30 days",
+	} {
+		if got := expiryTitle("Aspirin", daysLeft); got != want {
+			t.Errorf("expiryTitle(%d) = %q, want %q", daysLeft, got, want)
+		}
+	}
+}
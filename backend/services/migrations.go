@@ -0,0 +1,236 @@
+package services
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles contains numbered SQL migrations, e.g. 0002_add_sessions.up.sql
+// and 0002_add_sessions.down.sql. Versions must only ever be added, never changed.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationName matches "<version>_<name>.<up|down>.sql" file names.
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int    // Sequential number of the migration
+	Name    string // Short description taken from the file name
+	Up      string // SQL applying the change
+	Down    string // SQL reverting the change
+}
+
+// SchemaMigration is a row of the schema_migrations table recording an applied migration.
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"` // Version of the applied migration
+	Name      string    // Name of the applied migration
+	AppliedAt time.Time // When the migration was applied
+}
+
+// MigrationStatus describes whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	Applied   bool      // True if the migration is recorded in schema_migrations
+	AppliedAt time.Time // Zero if not applied
+}
+
+// LoadMigrations reads migrations from the given file system and sorts them by version.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d is missing its up or down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be sequential, expected %d got %d", i+1, migration.Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations, recording them in schema_migrations.
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+// NewMigrator creates a migrator for the migrations bundled with the binary.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// ensureTable creates the schema_migrations table if it does not exist yet.
+func (m *Migrator) ensureTable() error {
+	return m.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`).Error
+}
+
+// applied returns the applied migrations ordered by version.
+func (m *Migrator) applied() ([]SchemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	err := m.DB.Table("schema_migrations").Order("version asc").Find(&rows).Error
+	return rows, err
+}
+
+// Status lists every known migration together with whether it has been applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	rows, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := map[int]time.Time{}
+	for _, row := range rows {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	// A database migrated by a newer binary must not be used by an older one
+	if len(rows) > 0 && rows[len(rows)-1].Version > len(m.Migrations) {
+		return nil, fmt.Errorf("database is at version %d, newer than the latest known migration %d",
+			rows[len(rows)-1].Version, len(m.Migrations))
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		at, ok := appliedAt[migration.Version]
+		statuses = append(statuses, MigrationStatus{Migration: migration, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies up to steps pending migrations, all of them if steps is 0 or less.
+// Every migration runs in its own transaction. Returns the applied migrations.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Table("schema_migrations").Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the given number of most recently applied migrations.
+// Returns the reverted migrations.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	rows, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(rows) - 1; i >= 0 && len(done) < steps; i-- {
+		version := rows[i].Version
+		if version > len(m.Migrations) {
+			return done, fmt.Errorf("migration %d is unknown to this binary", version)
+		}
+		migration := m.Migrations[version-1]
+
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS drugs;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS chats;
+DROP TABLE IF EXISTS documents;
+DROP TABLE IF EXISTS medical_cards;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,73 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    name          TEXT,
+    email         TEXT,
+    password_hash TEXT,
+    snils         TEXT,
+    passport      TEXT,
+    address       TEXT
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT,
+    description TEXT
+);
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    user_id  BIGINT NOT NULL,
+    group_id BIGINT NOT NULL,
+    PRIMARY KEY (user_id, group_id),
+    CONSTRAINT fk_user_groups_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_user_groups_group FOREIGN KEY (group_id) REFERENCES groups (id)
+);
+
+CREATE TABLE IF NOT EXISTS medical_cards (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT,
+    allergies    TEXT,
+    chronic_cond TEXT,
+    blood_type   TEXT,
+    CONSTRAINT fk_users_medical_card FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS documents (
+    id        BIGSERIAL PRIMARY KEY,
+    user_id   BIGINT,
+    name      TEXT,
+    type      TEXT,
+    date      TIMESTAMPTZ,
+    doctor    TEXT,
+    file_data BYTEA,
+    CONSTRAINT fk_users_documents FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS chats (
+    id      BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    title   TEXT
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id        BIGSERIAL PRIMARY KEY,
+    chat_id   BIGINT,
+    sender    BIGINT,
+    text      TEXT,
+    timestamp BIGINT,
+    CONSTRAINT fk_chats_messages FOREIGN KEY (chat_id) REFERENCES chats (id)
+);
+CREATE INDEX IF NOT EXISTS idx_messages_chat_id ON messages (chat_id);
+CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages (sender);
+
+CREATE TABLE IF NOT EXISTS drugs (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT,
+    name         TEXT,
+    type         TEXT,
+    description  TEXT,
+    expiry       TIMESTAMPTZ,
+    location     TEXT,
+    manufacturer TEXT,
+    dose         TEXT,
+    amount       TEXT
+);
@@ -0,0 +1,15 @@
+CREATE TABLE sessions (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    refresh_hash  TEXT NOT NULL,
+    previous_hash TEXT,
+    user_agent    TEXT,
+    ip            TEXT,
+    created_at    TIMESTAMPTZ NOT NULL,
+    last_used_at  TIMESTAMPTZ NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    revoked_at    TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_sessions_refresh_hash ON sessions (refresh_hash);
+CREATE INDEX idx_sessions_previous_hash ON sessions (previous_hash);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE notifications (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL,
+    drug_id    BIGINT,
+    title      TEXT NOT NULL,
+    body       TEXT,
+    dedup_key  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    read_at    TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_notifications_dedup ON notifications (user_id, dedup_key);
+CREATE INDEX idx_notifications_user_created ON notifications (user_id, created_at);
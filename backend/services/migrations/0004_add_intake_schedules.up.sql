@@ -0,0 +1,26 @@
+CREATE TABLE intake_schedules (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    drug_id    BIGINT NOT NULL REFERENCES drugs (id) ON DELETE CASCADE,
+    r_rule     TEXT NOT NULL,
+    starts_at  TIMESTAMPTZ NOT NULL,
+    quantity   DOUBLE PRECISION NOT NULL DEFAULT 1,
+    note       TEXT,
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_intake_schedules_user_id ON intake_schedules (user_id);
+CREATE INDEX idx_intake_schedules_drug_id ON intake_schedules (drug_id);
+
+CREATE TABLE dose_logs (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    schedule_id   BIGINT NOT NULL REFERENCES intake_schedules (id) ON DELETE CASCADE,
+    drug_id       BIGINT NOT NULL REFERENCES drugs (id) ON DELETE CASCADE,
+    scheduled_at  TIMESTAMPTZ NOT NULL,
+    status        TEXT NOT NULL,
+    snoozed_until TIMESTAMPTZ,
+    acted_at      TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX idx_dose_logs_schedule_time ON dose_logs (schedule_id, scheduled_at);
+CREATE INDEX idx_dose_logs_drug_id ON dose_logs (drug_id);
@@ -0,0 +1,3 @@
+ALTER TABLE drugs DROP COLUMN IF EXISTS low_stock;
+ALTER TABLE drugs DROP COLUMN IF EXISTS unit;
+ALTER TABLE drugs DROP COLUMN IF EXISTS quantity;
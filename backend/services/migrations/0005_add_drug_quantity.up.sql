@@ -0,0 +1,22 @@
+ALTER TABLE drugs ADD COLUMN quantity DOUBLE PRECISION;
+ALTER TABLE drugs ADD COLUMN unit TEXT NOT NULL DEFAULT '';
+ALTER TABLE drugs ADD COLUMN low_stock DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Best-effort parse of free-text amounts like "30 tablets" or "100,5 мл",
+-- amounts without a leading number keep a null quantity.
+-- Mirrors models.ParseAmount.
+UPDATE drugs
+SET quantity = REPLACE(parsed.match[1], ',', '.')::DOUBLE PRECISION,
+    unit = CASE
+        WHEN lower(parsed.match[2]) ~ '^(tab|pill|таб)' THEN 'tablets'
+        WHEN lower(parsed.match[2]) ~ '^(cap|капс)' THEN 'capsules'
+        WHEN lower(parsed.match[2]) ~ '^(ml|мл)' THEN 'ml'
+        WHEN lower(parsed.match[2]) ~ '^(g|г)' THEN 'g'
+        WHEN lower(parsed.match[2]) ~ '^(dose|доз)' THEN 'doses'
+        ELSE 'pieces'
+    END
+FROM (
+    SELECT id, regexp_match(amount, '^\s*(\d+(?:[.,]\d+)?)\s*(\S*)') AS match
+    FROM drugs
+) AS parsed
+WHERE drugs.id = parsed.id AND parsed.match IS NOT NULL;
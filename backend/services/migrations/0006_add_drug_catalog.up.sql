@@ -0,0 +1,18 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE catalog_drugs (
+    id                BIGSERIAL PRIMARY KEY,
+    gtin              TEXT UNIQUE,
+    name              TEXT NOT NULL,
+    type              TEXT,
+    description       TEXT,
+    manufacturer      TEXT NOT NULL DEFAULT '',
+    dose              TEXT NOT NULL DEFAULT '',
+    amount            TEXT NOT NULL DEFAULT '',
+    quantity          DOUBLE PRECISION,
+    unit              TEXT NOT NULL DEFAULT '',
+    shelf_life_months INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_catalog_drugs_name_trgm ON catalog_drugs USING GIN (name gin_trgm_ops);
+-- Entries without a barcode are identified by name, manufacturer, dose and amount
+CREATE UNIQUE INDEX idx_catalog_drugs_identity ON catalog_drugs (name, manufacturer, dose, amount) WHERE gtin IS NULL;
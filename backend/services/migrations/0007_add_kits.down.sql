@@ -0,0 +1,2 @@
+ALTER TABLE drugs DROP COLUMN IF EXISTS kit_id;
+DROP TABLE IF EXISTS kits;
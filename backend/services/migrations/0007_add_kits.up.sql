@@ -0,0 +1,25 @@
+CREATE TABLE kits (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    group_id    BIGINT REFERENCES groups (id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    description TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_kits_owner CHECK ((user_id IS NULL) <> (group_id IS NULL))
+);
+CREATE INDEX idx_kits_user_id ON kits (user_id);
+CREATE INDEX idx_kits_group_id ON kits (group_id);
+
+ALTER TABLE drugs ADD COLUMN kit_id BIGINT REFERENCES kits (id) ON DELETE SET NULL;
+CREATE INDEX idx_drugs_kit_id ON drugs (kit_id);
+
+-- Every distinct free-text location becomes a personal kit
+INSERT INTO kits (user_id, name, created_at)
+SELECT DISTINCT user_id, TRIM(location), NOW()
+FROM drugs
+WHERE user_id IS NOT NULL AND TRIM(COALESCE(location, '')) <> '';
+
+UPDATE drugs
+SET kit_id = kits.id
+FROM kits
+WHERE kits.user_id = drugs.user_id AND kits.name = TRIM(drugs.location);
@@ -0,0 +1,11 @@
+ALTER TABLE user_groups DROP CONSTRAINT fk_user_groups_user;
+ALTER TABLE user_groups DROP CONSTRAINT fk_user_groups_group;
+ALTER TABLE user_groups ADD CONSTRAINT fk_user_groups_user FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE user_groups ADD CONSTRAINT fk_user_groups_group FOREIGN KEY (group_id) REFERENCES groups (id);
+
+DROP TABLE IF EXISTS group_invitations;
+DROP INDEX IF EXISTS idx_user_groups_owner;
+ALTER TABLE user_groups DROP CONSTRAINT IF EXISTS chk_user_groups_role;
+ALTER TABLE user_groups DROP COLUMN IF EXISTS joined_at;
+ALTER TABLE user_groups DROP COLUMN IF EXISTS role;
+ALTER TABLE groups DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,36 @@
+ALTER TABLE groups ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE user_groups ADD COLUMN role TEXT NOT NULL DEFAULT 'member';
+ALTER TABLE user_groups ADD COLUMN joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE user_groups ADD CONSTRAINT chk_user_groups_role CHECK (role IN ('owner', 'caregiver', 'member'));
+
+-- Groups created before roles existed are owned by their first member
+UPDATE user_groups
+SET role = 'owner'
+WHERE (group_id, user_id) IN (
+    SELECT group_id, MIN(user_id) FROM user_groups GROUP BY group_id
+);
+
+CREATE UNIQUE INDEX idx_user_groups_owner ON user_groups (group_id) WHERE role = 'owner';
+
+CREATE TABLE group_invitations (
+    id          BIGSERIAL PRIMARY KEY,
+    group_id    BIGINT NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    email       TEXT NOT NULL,
+    role        TEXT NOT NULL DEFAULT 'member',
+    token_hash  TEXT NOT NULL UNIQUE,
+    invited_by  BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    declined_at TIMESTAMPTZ,
+    CONSTRAINT chk_group_invitations_role CHECK (role IN ('caregiver', 'member'))
+);
+CREATE INDEX idx_group_invitations_email ON group_invitations (LOWER(email));
+CREATE INDEX idx_group_invitations_group_id ON group_invitations (group_id);
+
+-- Deleting a group or a user removes their memberships
+ALTER TABLE user_groups DROP CONSTRAINT fk_user_groups_user;
+ALTER TABLE user_groups DROP CONSTRAINT fk_user_groups_group;
+ALTER TABLE user_groups ADD CONSTRAINT fk_user_groups_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE user_groups ADD CONSTRAINT fk_user_groups_group FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE;
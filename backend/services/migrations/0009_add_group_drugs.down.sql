@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS drug_events;
+ALTER TABLE drugs DROP CONSTRAINT IF EXISTS chk_drugs_owner;
+ALTER TABLE drugs DROP COLUMN IF EXISTS added_by;
+ALTER TABLE drugs DROP COLUMN IF EXISTS group_id;
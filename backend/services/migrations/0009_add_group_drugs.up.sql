@@ -0,0 +1,23 @@
+ALTER TABLE drugs ADD COLUMN group_id BIGINT REFERENCES groups (id) ON DELETE CASCADE;
+ALTER TABLE drugs ADD COLUMN added_by BIGINT REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE drugs ADD CONSTRAINT chk_drugs_owner CHECK (user_id IS NULL OR group_id IS NULL);
+CREATE INDEX idx_drugs_group_id ON drugs (group_id);
+
+-- Existing drugs were added by their owners
+UPDATE drugs SET added_by = user_id WHERE user_id IN (SELECT id FROM users);
+
+-- Who added, used or removed a drug. Rows outlive the drug, so removals stay visible.
+CREATE TABLE drug_events (
+    id         BIGSERIAL PRIMARY KEY,
+    drug_id    BIGINT NOT NULL,
+    drug_name  TEXT NOT NULL,
+    group_id   BIGINT REFERENCES groups (id) ON DELETE CASCADE,
+    user_id    BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    action     TEXT NOT NULL,
+    delta      DOUBLE PRECISION,
+    unit       TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_drug_events_drug_id ON drug_events (drug_id, created_at);
+CREATE INDEX idx_drug_events_group_id ON drug_events (group_id, created_at);
+CREATE INDEX idx_drug_events_user_id ON drug_events (user_id, created_at);
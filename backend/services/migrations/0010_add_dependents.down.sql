@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS profile_audit;
+DROP TABLE IF EXISTS dependents;
@@ -0,0 +1,23 @@
+-- Dependent profiles are users without credentials, managed by caregivers.
+-- Every user-owned table works for them unchanged.
+CREATE TABLE dependents (
+    user_id    BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    created_by BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    group_id   BIGINT REFERENCES groups (id) ON DELETE SET NULL,
+    birth_date DATE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_dependents_created_by ON dependents (created_by);
+CREATE INDEX idx_dependents_group_id ON dependents (group_id);
+
+-- Requests made on behalf of a dependent and changes to the profile
+CREATE TABLE profile_audit (
+    id         BIGSERIAL PRIMARY KEY,
+    profile_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    actor_id   BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    method     TEXT NOT NULL,
+    path       TEXT NOT NULL,
+    status     INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_profile_audit_profile ON profile_audit (profile_id, created_at);
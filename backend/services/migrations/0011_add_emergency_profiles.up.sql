@@ -0,0 +1,24 @@
+-- People to contact in an emergency, published on the emergency ID
+CREATE TABLE emergency_contacts (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    phone      TEXT NOT NULL DEFAULT '',
+    relation   TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_emergency_contacts_user ON emergency_contacts (user_id);
+
+-- Opt-in public emergency ID. The token is the unguessable part of the public URL,
+-- only its SHA-256 hash is stored, like refresh tokens and share links.
+CREATE TABLE emergency_profiles (
+    user_id                 BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    token_hash              TEXT NOT NULL UNIQUE,
+    show_name               BOOLEAN NOT NULL DEFAULT TRUE,
+    show_blood_type         BOOLEAN NOT NULL DEFAULT TRUE,
+    show_allergies          BOOLEAN NOT NULL DEFAULT TRUE,
+    show_chronic_conditions BOOLEAN NOT NULL DEFAULT TRUE,
+    show_contacts           BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rotated_at              TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS sos_deliveries;
+DROP TABLE IF EXISTS sos_alerts;
+ALTER TABLE emergency_contacts DROP COLUMN IF EXISTS webhook_url;
+ALTER TABLE emergency_contacts DROP COLUMN IF EXISTS email;
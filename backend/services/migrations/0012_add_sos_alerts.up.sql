@@ -0,0 +1,29 @@
+-- Contacts can be reached by email and webhook besides the phone
+ALTER TABLE emergency_contacts ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE emergency_contacts ADD COLUMN webhook_url TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE sos_alerts (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    message    TEXT NOT NULL DEFAULT '',
+    latitude   DOUBLE PRECISION,
+    longitude  DOUBLE PRECISION,
+    accuracy   DOUBLE PRECISION,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_sos_alerts_user ON sos_alerts (user_id, created_at);
+
+-- One row per contact and channel. Contact details are copied so the history
+-- survives changes to the contact.
+CREATE TABLE sos_deliveries (
+    id           BIGSERIAL PRIMARY KEY,
+    alert_id     BIGINT NOT NULL REFERENCES sos_alerts (id) ON DELETE CASCADE,
+    contact_id   BIGINT REFERENCES emergency_contacts (id) ON DELETE SET NULL,
+    contact_name TEXT NOT NULL,
+    channel      TEXT NOT NULL,
+    address      TEXT NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+    error        TEXT NOT NULL DEFAULT '',
+    attempted_at TIMESTAMPTZ
+);
+CREATE INDEX idx_sos_deliveries_alert ON sos_deliveries (alert_id);
@@ -0,0 +1,25 @@
+ALTER TABLE medical_cards ADD COLUMN allergies TEXT;
+ALTER TABLE medical_cards ADD COLUMN chronic_cond TEXT;
+
+-- Entries are joined back into the free-text columns
+UPDATE medical_cards
+SET allergies = entries.text
+FROM (
+    SELECT user_id, STRING_AGG(COALESCE(NULLIF(text, ''), NULLIF(substance, ''), icd10_code), ', ' ORDER BY id) AS text
+    FROM medical_entries
+    WHERE kind = 'allergy'
+    GROUP BY user_id
+) entries
+WHERE entries.user_id = medical_cards.user_id;
+
+UPDATE medical_cards
+SET chronic_cond = entries.text
+FROM (
+    SELECT user_id, STRING_AGG(COALESCE(NULLIF(text, ''), icd10_code), ', ' ORDER BY id) AS text
+    FROM medical_entries
+    WHERE kind = 'condition'
+    GROUP BY user_id
+) entries
+WHERE entries.user_id = medical_cards.user_id;
+
+DROP TABLE IF EXISTS medical_entries;
@@ -0,0 +1,28 @@
+-- Allergies and chronic conditions as coded entries instead of two free-text columns
+CREATE TABLE medical_entries (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL CHECK (kind IN ('allergy', 'condition')),
+    icd10_code TEXT NOT NULL DEFAULT '',
+    text       TEXT NOT NULL DEFAULT '',
+    substance  TEXT NOT NULL DEFAULT '',
+    reaction   TEXT NOT NULL DEFAULT '',
+    severity   TEXT NOT NULL DEFAULT '' CHECK (severity IN ('', 'mild', 'moderate', 'severe', 'life_threatening')),
+    onset_date DATE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_medical_entries_user ON medical_entries (user_id, kind);
+
+-- Legacy strings become one free-text entry each
+INSERT INTO medical_entries (user_id, kind, text)
+SELECT user_id, 'allergy', TRIM(allergies)
+FROM medical_cards
+WHERE user_id IS NOT NULL AND TRIM(COALESCE(allergies, '')) <> '';
+
+INSERT INTO medical_entries (user_id, kind, text)
+SELECT user_id, 'condition', TRIM(chronic_cond)
+FROM medical_cards
+WHERE user_id IS NOT NULL AND TRIM(COALESCE(chronic_cond, '')) <> '';
+
+ALTER TABLE medical_cards DROP COLUMN allergies;
+ALTER TABLE medical_cards DROP COLUMN chronic_cond;
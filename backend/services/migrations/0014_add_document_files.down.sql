@@ -0,0 +1,4 @@
+ALTER TABLE documents DROP COLUMN IF EXISTS created_at;
+ALTER TABLE documents DROP COLUMN IF EXISTS size;
+ALTER TABLE documents DROP COLUMN IF EXISTS content_type;
+ALTER TABLE documents DROP COLUMN IF EXISTS file_name;
@@ -0,0 +1,16 @@
+-- File metadata, so documents can be listed without their contents
+ALTER TABLE documents ADD COLUMN file_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN content_type TEXT NOT NULL DEFAULT 'application/octet-stream';
+ALTER TABLE documents ADD COLUMN size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Existing files are recognised by their signature
+UPDATE documents
+SET size = OCTET_LENGTH(file_data),
+    content_type = CASE
+        WHEN SUBSTRING(file_data FROM 1 FOR 5) = '\x255044462d'::BYTEA THEN 'application/pdf'
+        WHEN SUBSTRING(file_data FROM 1 FOR 3) = '\xffd8ff'::BYTEA THEN 'image/jpeg'
+        WHEN SUBSTRING(file_data FROM 1 FOR 8) = '\x89504e470d0a1a0a'::BYTEA THEN 'image/png'
+        ELSE 'application/octet-stream'
+    END
+WHERE file_data IS NOT NULL;
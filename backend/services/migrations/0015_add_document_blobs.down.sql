@@ -0,0 +1,11 @@
+-- Files only kept in the blob store would be lost
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM documents WHERE blob_key <> '' AND file_data IS NULL) THEN
+        RAISE EXCEPTION 'documents are kept in the blob store, run "main blobs restore" first';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_documents_blob_key;
+ALTER TABLE documents DROP COLUMN IF EXISTS blob_key;
+ALTER TABLE documents DROP COLUMN IF EXISTS sha256;
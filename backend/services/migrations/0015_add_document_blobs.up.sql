@@ -0,0 +1,7 @@
+-- Files move to the blob store. sha256 is the hash of the uploaded file, blob_key the key of the
+-- stored blob, which differs from the hash once files are encrypted.
+-- file_data stays: existing files are kept in it until "main blobs move" copies them out and
+-- clears it, and "main blobs restore" copies them back before this migration is reverted.
+ALTER TABLE documents ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN blob_key TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_documents_blob_key ON documents (blob_key) WHERE blob_key <> '';
@@ -0,0 +1,11 @@
+-- Encrypted values cannot be read without the data keys
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM data_keys) THEN
+        RAISE EXCEPTION 'data is encrypted with data keys, it cannot be reverted';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_documents_data_key;
+ALTER TABLE documents DROP COLUMN IF EXISTS data_key_id;
+DROP TABLE IF EXISTS data_keys;
@@ -0,0 +1,14 @@
+-- Per-user data keys for encryption at rest, wrapped with a master key from the configuration
+CREATE TABLE data_keys (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    master_key_id TEXT NOT NULL,
+    wrapped_key   BYTEA NOT NULL,
+    active        BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    retired_at    TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_data_keys_active ON data_keys (user_id) WHERE active;
+
+ALTER TABLE documents ADD COLUMN data_key_id BIGINT REFERENCES data_keys (id);
+CREATE INDEX idx_documents_data_key ON documents (data_key_id) WHERE data_key_id IS NOT NULL;
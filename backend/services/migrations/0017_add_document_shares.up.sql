@@ -0,0 +1,17 @@
+-- Time-limited read-only links to documents. The random token of a link is stored hashed,
+-- like invitation tokens.
+CREATE TABLE document_shares (
+    id                  BIGSERIAL PRIMARY KEY,
+    document_id         BIGINT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    user_id             BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash          TEXT NOT NULL UNIQUE,
+    pin_hash            TEXT NOT NULL DEFAULT '',
+    expires_at          TIMESTAMPTZ NOT NULL,
+    revoked_at          TIMESTAMPTZ,
+    access_count        INTEGER NOT NULL DEFAULT 0,
+    first_accessed_at   TIMESTAMPTZ,
+    last_accessed_at    TIMESTAMPTZ,
+    failed_pin_attempts INTEGER NOT NULL DEFAULT 0,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_document_shares_document ON document_shares (document_id);
@@ -0,0 +1,15 @@
+-- Preview images of documents, generated in the background after upload. They are small and
+-- derived from the file, so they are kept in the database and go away with their document.
+CREATE TABLE document_thumbnails (
+    document_id BIGINT PRIMARY KEY REFERENCES documents (id) ON DELETE CASCADE,
+    status      TEXT NOT NULL,
+    data        BYTEA,
+    data_key_id BIGINT REFERENCES data_keys (id),
+    sha256      TEXT NOT NULL DEFAULT '',
+    width       INTEGER NOT NULL DEFAULT 0,
+    height      INTEGER NOT NULL DEFAULT 0,
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    error       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_document_thumbnails_data_key ON document_thumbnails (data_key_id) WHERE data_key_id IS NOT NULL;
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// migrationFS returns a file system with the given migration files in migrations/.
+func migrationFS(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		fsys["migrations/"+name] = &fstest.MapFile{Data: []byte("-- " + name)}
+	}
+	return fsys
+}
+
+func TestLoadMigrationsSortsByVersion(t *testing.T) {
+	fsys := migrationFS(
+		"0010_add_kits.up.sql", "0010_add_kits.down.sql",
+		"0002_add_sessions.down.sql", "0002_add_sessions.up.sql",
+		"0001_init.up.sql", "0001_init.down.sql",
+	)
+	for version := 3; version <= 9; version++ {
+		name := fmt.Sprintf("%04d_step", version)
+		fsys["migrations/"+name+".up.sql"] = &fstest.MapFile{Data: []byte("up")}
+		fsys["migrations/"+name+".down.sql"] = &fstest.MapFile{Data: []byte("down")}
+	}
+
+	migrations, err := LoadMigrations(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 10 {
+		t.Fatalf("loaded %d migrations, want 10", len(migrations))
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d", i, migration.Version)
+		}
+	}
+	if migrations[1].Name != "add_sessions" || migrations[1].Up != "-- 0002_add_sessions.up.sql" || migrations[1].Down != "-- 0002_add_sessions.down.sql" {
+		t.Errorf("migration 2 = %+v", migrations[1])
+	}
+	if migrations[9].Name != "add_kits" {
+		t.Errorf("migration 10 = %+v", migrations[9])
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		files []string
+		err   string
+	}{
+		"missing down": {
+			[]string{"0001_init.up.sql", "0001_init.down.sql", "0002_add_sessions.up.sql"},
+			"migration 2 is missing its up or down file",
+		},
+		"missing up": {
+			[]string{"0001_init.down.sql"},
+			"migration 1 is missing its up or down file",
+		},
+		"duplicate version": {
+			[]string{"0001_init.up.sql", "0001_init.down.sql", "0001_add_sessions.up.sql", "0001_add_sessions.down.sql"},
+			"migration 1 has conflicting names",
+		},
+		"gap": {
+			[]string{"0001_init.up.sql", "0001_init.down.sql", "0003_add_kits.up.sql", "0003_add_kits.down.sql"},
+			"migration versions must be sequential, expected 2 got 3",
+		},
+		"not starting at 1": {
+			[]string{"0002_add_sessions.up.sql", "0002_add_sessions.down.sql"},
+			"migration versions must be sequential, expected 1 got 2",
+		},
+		"invalid name": {
+			[]string{"0001_init.up.sql", "0001_init.down.sql", "0002_Add-Kits.sql"},
+			`invalid migration file name "0002_Add-Kits.sql"`,
+		},
+	} {
+		_, err := LoadMigrations(migrationFS(test.files...), "migrations")
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: err = %v, want %q", name, err, test.err)
+		}
+	}
+
+	if _, err := LoadMigrations(fstest.MapFS{}, "migrations"); err == nil {
+		t.Error("missing directory: expected an error")
+	}
+}
+
+// TestBundledMigrations checks the migrations embedded in the binary.
+func TestBundledMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations are bundled")
+	}
+}
+
+// fakeSchema is an in-memory database for the Migrator: it keeps the schema_migrations rows
+// and the migration statements run. Statements containing FAIL return an error.
+type fakeSchema struct {
+	applied map[int64]string // Names of applied migrations by version
+	ran     []string         // Migration statements of committed transactions
+}
+
+// fakeConn is a connection to a fakeSchema. Changes made in a transaction
+// only reach the schema on commit.
+type fakeConn struct {
+	schema  *fakeSchema
+	pending []func()
+}
+
+type fakeConnector struct{ schema *fakeSchema }
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{schema: c.schema}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver { return nil }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.pending = []func(){}
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	for _, change := range c.pending {
+		change()
+	}
+	c.pending = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+// change applies the change at once, or on commit inside a transaction.
+func (c *fakeConn) change(change func()) {
+	if c.pending == nil {
+		change()
+		return
+	}
+	c.pending = append(c.pending, change)
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+	case strings.HasPrefix(query, `INSERT INTO "schema_migrations"`):
+		version, name := args[0].Value.(int64), args[1].Value.(string)
+		c.change(func() { c.schema.applied[version] = name })
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		version := args[0].Value.(int64)
+		c.change(func() { delete(c.schema.applied, version) })
+	case strings.Contains(query, "FAIL"):
+		return nil, errors.New("syntax error")
+	default:
+		c.change(func() { c.schema.ran = append(c.schema.ran, query) })
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.HasPrefix(query, `SELECT * FROM "schema_migrations"`) {
+		return nil, errors.New("unexpected query " + query)
+	}
+	rows := &fakeRows{}
+	for version, name := range c.schema.applied {
+		rows.values = append(rows.values, []driver.Value{version, name, time.Now()})
+	}
+	sort.Slice(rows.values, func(i, j int) bool { return rows.values[i][0].(int64) < rows.values[j][0].(int64) })
+	return rows, nil
+}
+
+// fakeRows returns schema_migrations rows.
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return []string{"version", "name", "applied_at"} }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newFakeMigrator creates a migrator for migrations 1 to n on a fake database
+// with the given versions applied. The up statement of version fail fails.
+func newFakeMigrator(t *testing.T, n int, applied []int, fail int) (*Migrator, *fakeSchema) {
+	t.Helper()
+	schema := &fakeSchema{applied: map[int64]string{}}
+	for _, version := range applied {
+		schema.applied[int64(version)] = "step"
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(&fakeConnector{schema: schema})}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator := &Migrator{DB: db}
+	for version := 1; version <= n; version++ {
+		migration := Migration{Version: version, Name: "step", Up: fmt.Sprintf("up %d", version), Down: fmt.Sprintf("down %d", version)}
+		if version == fail {
+			migration.Up = "FAIL"
+		}
+		migrator.Migrations = append(migrator.Migrations, migration)
+	}
+	return migrator, schema
+}
+
+// versions returns the versions of the migrations.
+func versions(migrations []Migration) []int {
+	var result []int
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+func TestMigratorUp(t *testing.T) {
+	migrator, schema := newFakeMigrator(t, 4, []int{1}, 0)
+
+	done, err := migrator.Up(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("applied %v, want [2 3]", got)
+	}
+
+	done, err = migrator.Up(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); len(got) != 1 || got[0] != 4 {
+		t.Errorf("applied %v, want [4]", got)
+	}
+	if strings.Join(schema.ran, ",") != "up 2,up 3,up 4" || len(schema.applied) != 4 {
+		t.Errorf("ran %q, applied %v", schema.ran, schema.applied)
+	}
+
+	// Nothing is left to apply
+	if done, err := migrator.Up(0); err != nil || len(done) != 0 {
+		t.Errorf("applied %v, err = %v", versions(done), err)
+	}
+}
+
+func TestMigratorUpStopsAtFailure(t *testing.T) {
+	migrator, schema := newFakeMigrator(t, 4, nil, 3)
+
+	done, err := migrator.Up(0)
+	if err == nil || !strings.Contains(err.Error(), "migration 0003_step failed") {
+		t.Errorf("err = %v", err)
+	}
+	if got := versions(done); len(got) != 2 {
+		t.Errorf("applied %v, want [1 2]", got)
+	}
+	// The failed migration is not recorded and later ones are not run
+	if _, ok := schema.applied[3]; ok || len(schema.applied) != 2 || len(schema.ran) != 2 {
+		t.Errorf("ran %q, applied %v", schema.ran, schema.applied)
+	}
+}
+
+func TestMigratorDown(t *testing.T) {
+	migrator, schema := newFakeMigrator(t, 4, []int{1, 2, 3}, 0)
+
+	done, err := migrator.Down(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); len(got) != 2 || got[0] != 3 || got[1] != 2 {
+		t.Errorf("reverted %v, want [3 2]", got)
+	}
+	if strings.Join(schema.ran, ",") != "down 3,down 2" || len(schema.applied) != 1 {
+		t.Errorf("ran %q, applied %v", schema.ran, schema.applied)
+	}
+
+	// Reverting more than is applied stops at the first migration
+	done, err = migrator.Down(5)
+	if err != nil || len(done) != 1 || len(schema.applied) != 0 {
+		t.Errorf("reverted %v, err = %v, applied %v", versions(done), err, schema.applied)
+	}
+}
+
+func TestMigratorNewerDatabase(t *testing.T) {
+	migrator, schema := newFakeMigrator(t, 2, []int{1, 2, 3}, 0)
+
+	if _, err := migrator.Up(0); err == nil || !strings.Contains(err.Error(), "newer than the latest known migration 2") {
+		t.Errorf("Up: err = %v", err)
+	}
+	if _, err := migrator.Down(1); err == nil || !strings.Contains(err.Error(), "migration 3 is unknown") {
+		t.Errorf("Down: err = %v", err)
+	}
+	if len(schema.applied) != 3 || len(schema.ran) != 0 {
+		t.Errorf("ran %q, applied %v", schema.ran, schema.applied)
+	}
+}
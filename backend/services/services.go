@@ -1,7 +1,14 @@
 package services
 
 import (
+	"first_aid_companion/encryption"
+	"first_aid_companion/icd10"
+	"first_aid_companion/interactions"
+	"first_aid_companion/kittemplates"
+	"first_aid_companion/llm"
 	"first_aid_companion/models"
+	"first_aid_companion/notify"
+	"first_aid_companion/storage"
 	"fmt"
 	"log"
 
@@ -10,17 +17,36 @@ import (
 )
 
 type DBService struct {
-	DB        *gorm.DB
-	UserDB    *models.UserGorm
-	DrugDB    *models.DrugGorm
-	MedCardDB *models.MedicalCardGorm
-	ChatDB    *models.ChatGorm
-	DocsDB    *models.DocumentGorm
-	MessageDB *models.MessageGorm
-	ApiKey    string
+	DB          *gorm.DB
+	UserDB      *models.UserGorm
+	DrugDB      *models.DrugGorm
+	MedCardDB   *models.MedicalCardGorm
+	ChatDB      *models.ChatGorm
+	DocsDB      *models.DocumentGorm
+	MessageDB   *models.MessageGorm
+	SessionDB   *models.SessionGorm
+	NotifDB     *models.NotificationGorm
+	ScheduleDB  *models.ScheduleGorm
+	CatalogDB   *models.CatalogGorm
+	KitDB       *models.KitGorm
+	GroupDB     *models.GroupGorm
+	DependentDB *models.DependentGorm
+	EmergencyDB *models.EmergencyGorm
+	KeyDB       *models.DataKeyGorm
+	ChatModel   llm.ChatModel
+
+	Interactions    *interactions.Dataset // Drug interaction rules, set after loading
+	ICD10           *icd10.Dataset        // ICD-10 codes of allergies and conditions, set after loading
+	KitTemplates    *kittemplates.Set     // Kit templates, set after loading
+	PublicURL       string                // Base URL of public links such as the emergency ID
+	MaxDocumentSize int64                 // Largest accepted document file in bytes
+	Blobs           storage.BlobStore     // Document files, set after configuration
+	Keyring         *encryption.Keyring   // Data keys encrypting sensitive fields and files, set after configuration
+	Notifier        *notify.Dispatcher    // SOS alert channels, set after configuration
+	Thumbnails      *ThumbnailWorker      // Generates document thumbnails, set after configuration
 }
 
-func NewDBService(ApiKey, dsn string) (*DBService, error) {
+func NewDBService(chatModel llm.ChatModel, dsn string) (*DBService, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -29,49 +55,39 @@ func NewDBService(ApiKey, dsn string) (*DBService, error) {
 	log.Println("Successfully connected to database")
 
 	return &DBService{
-		DB:        db,
-		UserDB:    models.NewUserGorm(db),
-		DrugDB:    models.NewDrugGorm(db),
-		MedCardDB: models.NewMedCardGorm(db),
-		ChatDB:    models.NewChatGorm(db),
-		MessageDB: models.NewMessageGorm(db),
-		DocsDB:    models.NewDocumentGorm(db),
-		ApiKey:    ApiKey,
+		DB:          db,
+		UserDB:      models.NewUserGorm(db),
+		DrugDB:      models.NewDrugGorm(db),
+		MedCardDB:   models.NewMedCardGorm(db),
+		ChatDB:      models.NewChatGorm(db),
+		MessageDB:   models.NewMessageGorm(db),
+		DocsDB:      models.NewDocumentGorm(db),
+		SessionDB:   models.NewSessionGorm(db),
+		NotifDB:     models.NewNotificationGorm(db),
+		ScheduleDB:  models.NewScheduleGorm(db),
+		CatalogDB:   models.NewCatalogGorm(db),
+		KitDB:       models.NewKitGorm(db),
+		GroupDB:     models.NewGroupGorm(db),
+		DependentDB: models.NewDependentGorm(db),
+		EmergencyDB: models.NewEmergencyGorm(db),
+		KeyDB:       models.NewDataKeyGorm(db),
+		ChatModel:   chatModel,
 	}, nil
 }
 
-func (db *DBService) Automigrate() error {
-	err := db.DB.AutoMigrate(
-		&models.User{},
-		&models.Chat{},
-		&models.Message{},
-		&models.Document{},
-		&models.Group{},
-		&models.Drug{},
-		&models.MedicalCard{},
-	)
-
+// CheckSchema returns an error if the database has migrations that were not applied yet.
+func (db *DBService) CheckSchema() error {
+	migrator, err := NewMigrator(db.DB)
 	if err != nil {
-		fmt.Printf("Error during automigration: %v", err)
 		return err
 	}
 
-	return nil
-}
-
-func (db *DBService) ResetDB() error {
-	err := db.DB.Migrator().DropTable(
-		&models.User{},
-		&models.Chat{},
-		&models.Message{},
-		&models.Document{},
-		&models.Group{},
-		&models.Drug{},
-		&models.MedicalCard{},
-	)
+	pending, err := migrator.Pending()
 	if err != nil {
-		return fmt.Errorf("failed to drop tables: %w", err)
+		return err
 	}
-
-	return db.Automigrate()
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind by %d migration(s), run \"migrate up\" first", len(pending))
+	}
+	return nil
 }
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"first_aid_companion/encryption"
+	"first_aid_companion/models"
+	"first_aid_companion/storage"
+	"first_aid_companion/thumbnails"
+	"log"
+	"time"
+)
+
+// ThumbnailResult counts what a thumbnail pass did.
+type ThumbnailResult struct {
+	Generated   int // New thumbnails
+	Unavailable int // Files without a preview
+	Failed      int // Files that could not be read or rendered, they are retried on the next pass
+}
+
+// ThumbnailWorker generates the preview images of documents in the background. New uploads
+// wake it up, documents missed by an earlier run and failed attempts are picked up once
+// per interval.
+type ThumbnailWorker struct {
+	Docs     *models.DocumentGorm
+	Blobs    storage.BlobStore
+	Keyring  *encryption.Keyring
+	Interval time.Duration // Time between passes
+
+	wake chan struct{}
+}
+
+// NewThumbnailWorker creates a worker, a zero interval falls back to 10 minutes.
+func NewThumbnailWorker(docs *models.DocumentGorm, blobs storage.BlobStore, keyring *encryption.Keyring, interval time.Duration) *ThumbnailWorker {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &ThumbnailWorker{
+		Docs:     docs,
+		Blobs:    blobs,
+		Keyring:  keyring,
+		Interval: interval,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Wake starts a pass as soon as the current one is done. It never blocks.
+func (tw *ThumbnailWorker) Wake() {
+	select {
+	case tw.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run generates thumbnails immediately, then on every wake up and once per interval
+// until the context is cancelled.
+func (tw *ThumbnailWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(tw.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := tw.Pass(ctx)
+		if err != nil {
+			log.Printf("Thumbnail generation failed: %v", err)
+		} else if result.Generated > 0 || result.Unavailable > 0 || result.Failed > 0 {
+			log.Printf("Thumbnail generation completed, %d generated, %d without preview, %d failed",
+				result.Generated, result.Unavailable, result.Failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-tw.wake:
+		}
+	}
+}
+
+// Pass generates the thumbnails of all documents that have none yet.
+func (tw *ThumbnailWorker) Pass(ctx context.Context) (*ThumbnailResult, error) {
+	result := &ThumbnailResult{}
+	var lastID uint
+	for {
+		docs, err := tw.Docs.GetDocumentsWithoutThumbnails(lastID, blobBatchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(docs) == 0 {
+			return result, nil
+		}
+		for _, doc := range docs {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			status, err := tw.generate(ctx, &doc)
+			switch {
+			case err != nil:
+				log.Printf("Error generating thumbnail of document %d: %v", doc.ID, err)
+				if err := tw.Docs.RecordThumbnailFailure(doc.ID, err.Error(), time.Now()); err != nil {
+					log.Printf("Error recording thumbnail failure of document %d: %v", doc.ID, err)
+				}
+				result.Failed++
+			case status == models.ThumbnailUnavailable:
+				result.Unavailable++
+			default:
+				result.Generated++
+			}
+			lastID = doc.ID
+		}
+	}
+}
+
+// generate renders the thumbnail of the document and stores it encrypted with the owner's key.
+// Returns the state of the stored thumbnail.
+func (tw *ThumbnailWorker) generate(ctx context.Context, doc *models.Document) (string, error) {
+	data, err := tw.readFile(ctx, doc)
+	if err != nil {
+		return "", err
+	}
+
+	thumb := &models.DocumentThumbnail{DocumentID: doc.ID, CreatedAt: time.Now()}
+	preview, err := thumbnails.Generate(data, doc.ContentType)
+	if errors.Is(err, thumbnails.ErrUnsupported) {
+		thumb.Status = models.ThumbnailUnavailable
+		return thumb.Status, tw.Docs.SaveThumbnail(thumb)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, dataKeyID, err := tw.Keyring.EncryptFile(doc.UserID, preview.Data)
+	if err != nil {
+		return "", err
+	}
+	thumb.Status = models.ThumbnailReady
+	thumb.Data = encrypted
+	thumb.DataKeyID = keyIDRef(dataKeyID)
+	thumb.SHA256 = storage.Key(preview.Data)
+	thumb.Width = preview.Width
+	thumb.Height = preview.Height
+	return thumb.Status, tw.Docs.SaveThumbnail(thumb)
+}
+
+// readFile returns the decrypted file of the document.
+func (tw *ThumbnailWorker) readFile(ctx context.Context, doc *models.Document) ([]byte, error) {
+	// Files of documents added before the blob store are still in the database
+	if doc.BlobKey == "" {
+		stored, err := tw.Docs.GetDocumentById(int(doc.ID))
+		if err != nil {
+			return nil, err
+		}
+		return stored.FileData, nil
+	}
+	data, err := tw.Blobs.Get(ctx, doc.BlobKey)
+	if err != nil {
+		return nil, err
+	}
+	return tw.Keyring.DecryptFile(data)
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	data := []byte("%PDF-1.4 blood test")
+	key := Key(data)
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Put = %v, want ErrNotFound", err)
+	}
+	if err := store.Put(ctx, key, data); err != nil {
+		t.Fatalf("Put = %v", err)
+	}
+	// Putting the same contents again keeps the file
+	if err := store.Put(ctx, key, data); err != nil {
+		t.Fatalf("second Put = %v", err)
+	}
+	path := filepath.Join(dir, key[:2], key[2:4], key)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("blob file: %v", err)
+	}
+	// No temporary files are left behind
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory of the blob has %d entries, want 1", len(entries))
+	}
+
+	got, err := store.Get(ctx, key)
+	if err != nil || string(got) != string(data) {
+		t.Fatalf("Get = %q, %v, want %q", got, err, data)
+	}
+	file, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open = %v", err)
+	}
+	if _, err := file.Seek(9, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got, err = io.ReadAll(file)
+	file.Close()
+	if err != nil || string(got) != "blood test" {
+		t.Fatalf("read from 9 = %q, %v", got, err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete = %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("second Delete = %v", err)
+	}
+}
+
+func TestLocalStoreInvalidKeys(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Keys are used in paths, so anything Key cannot make is refused
+	for _, key := range []string{"", "../../etc/passwd", "ab", Key(nil)[:63] + "G"} {
+		if err := store.Put(ctx, key, []byte("x")); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) = %v, want ErrNotFound", key, err)
+		}
+		if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q) = %v, want ErrNotFound", key, err)
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			t.Errorf("Delete(%q) = %v", key, err)
+		}
+	}
+
+	if _, err := NewLocal(""); err == nil {
+		t.Error("NewLocal without a directory succeeded")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if store, err := New(Config{Dir: t.TempDir()}); err != nil {
+		t.Errorf("New(local) = %v", err)
+	} else if _, ok := store.(*LocalStore); !ok {
+		t.Errorf("New(local) = %T, want *LocalStore", store)
+	}
+	if _, err := New(Config{Backend: "ftp"}); err == nil {
+		t.Error("New(ftp) succeeded")
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Backends of the blob store
+const (
+	BackendLocal = "local" // Files in a local directory
+	BackendS3    = "s3"    // Objects in an S3-compatible bucket, e.g. MinIO
+)
+
+// ErrNotFound is returned for blobs that are not in the store.
+var ErrNotFound = errors.New("blob not found")
+
+// keyPattern matches keys made by Key.
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// BlobStore keeps immutable blobs addressed by the SHA-256 hash of their contents.
+// Storing the same contents twice keeps a single copy.
+type BlobStore interface {
+	// Put stores the data under the key, which must be Key(data).
+	// Putting a key that is already stored does nothing.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data stored under the key or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Open returns a reader of the data stored under the key or ErrNotFound.
+	// The data is read as it is needed, so large blobs are not held in memory.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the key, deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config describes the blob store backend.
+type Config struct {
+	Backend string // local or s3, local by default
+
+	Dir string // Directory of the local backend
+
+	S3Endpoint  string // URL of the S3 server, e.g. http://minio:9000
+	S3Region    string // Region used for signing, us-east-1 by default
+	S3Bucket    string // Bucket holding the blobs, it must exist
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// New creates the configured blob store.
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", BackendLocal:
+		return NewLocal(cfg.Dir)
+	case BackendS3:
+		return NewS3(cfg)
+	}
+	return nil, fmt.Errorf("unknown blob store backend %q", cfg.Backend)
+}
+
+// Key returns the key of the data: the hex-encoded SHA-256 hash of it.
+func Key(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// validKey reports whether the key could have been made by Key, so it is safe to use in paths.
+func validKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
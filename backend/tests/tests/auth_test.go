@@ -3,6 +3,7 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -52,6 +53,13 @@ func (suite *AuthTestSuite) Test3_ProtectedEndpoint() {
 	defer resp.Body.Close()
 
 	requireOK(suite.T(), resp)
+
+	// Missing and invalid tokens are unauthorized
+	for _, token := range []string{"", "not-a-jwt", suite.token + "x"} {
+		resp := doRequest(suite.T(), "GET", "/auth/me", token, nil)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode, token)
+	}
 }
 
 func (suite *AuthTestSuite) Test4_UpdateUserInfo() {
@@ -93,6 +101,87 @@ func (suite *AuthTestSuite) Test4_UpdateUserInfo() {
 	assert.Equal(suite.T(), updatePayload["address"], userData["address"])
 }
 
+func (suite *AuthTestSuite) Test5_RefreshToken() {
+	t := suite.T()
+	tokens := login(t)
+	require.NotEmpty(t, tokens.RefreshToken)
+
+	// Exchange the refresh token for a new pair
+	resp := doRequest(t, "POST", "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var refreshed authTokens
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&refreshed))
+	assert.NotEmpty(t, refreshed.Data)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+	meResp := doRequest(t, "GET", "/auth/me", refreshed.Data, nil)
+	meResp.Body.Close()
+	assert.Equal(t, http.StatusOK, meResp.StatusCode)
+
+	// The rotated out refresh token must not work again and ends the session
+	reuse := doRequest(t, "POST", "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	reuse.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, reuse.StatusCode)
+
+	revoked := doRequest(t, "GET", "/auth/me", refreshed.Data, nil)
+	revoked.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, revoked.StatusCode)
+}
+
+func (suite *AuthTestSuite) Test6_Logout() {
+	t := suite.T()
+	tokens := login(t)
+
+	resp := doRequest(t, "POST", "/auth/logout", tokens.Data, nil)
+	resp.Body.Close()
+	requireOK(t, resp)
+
+	// Neither token works after logout
+	meResp := doRequest(t, "GET", "/auth/me", tokens.Data, nil)
+	meResp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, meResp.StatusCode)
+
+	refresh := doRequest(t, "POST", "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	refresh.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, refresh.StatusCode)
+}
+
+func (suite *AuthTestSuite) Test7_RevokeSession() {
+	t := suite.T()
+	other := login(t)
+
+	// The suite's own session lists the other device
+	resp := doRequest(t, "GET", "/auth/sessions", suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.GreaterOrEqual(t, len(result.Data), 2)
+
+	// Revoke the newest non-current session, which is the one just created
+	var otherID float64
+	for _, session := range result.Data {
+		if session["current"] != true {
+			otherID = session["id"].(float64)
+			break
+		}
+	}
+	require.NotZero(t, otherID)
+
+	revoke := doRequest(t, "POST", fmt.Sprintf("/auth/sessions/revoke/%d", int(otherID)), suite.token, nil)
+	revoke.Body.Close()
+	requireOK(t, revoke)
+
+	meResp := doRequest(t, "GET", "/auth/me", other.Data, nil)
+	meResp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, meResp.StatusCode)
+}
+
 func TestAuthSuite(t *testing.T) {
 	suite.Run(t, new(AuthTestSuite))
 }
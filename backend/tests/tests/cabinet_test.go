@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type CabinetTestSuite struct {
+	suite.Suite
+	ownerToken  string
+	memberToken string
+	groupID     int
+	drugID      int
+}
+
+func (suite *CabinetTestSuite) SetupSuite() {
+	t := suite.T()
+	stamp := time.Now().UnixNano()
+	suite.ownerToken = signUpUser(t, "Cabinet Owner", fmt.Sprintf("cabinet_owner_%d@example.com", stamp), "secure123")
+	memberEmail := fmt.Sprintf("cabinet_member_%d@example.com", stamp)
+	suite.memberToken = signUpUser(t, "Cabinet Member", memberEmail, "secure123")
+
+	var group map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/groups", suite.ownerToken, map[string]interface{}{"name": "Cabinet"}), &group)
+	suite.groupID = int(group["id"].(float64))
+
+	var invitation map[string]interface{}
+	decodeData(t, doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/invite", suite.groupID), suite.ownerToken,
+		map[string]interface{}{"email": memberEmail}), &invitation)
+	resp := doRequest(t, "POST", "/auth/invitations/accept", suite.memberToken, map[string]interface{}{"token": invitation["token"]})
+	requireOK(t, resp)
+	resp.Body.Close()
+}
+
+// drugs returns the drugs list of the user for the given query.
+func (suite *CabinetTestSuite) drugs(token, query string) []map[string]interface{} {
+	var drugs []map[string]interface{}
+	decodeData(suite.T(), doRequest(suite.T(), "GET", "/auth/drugs"+query, token, nil), &drugs)
+	return drugs
+}
+
+func (suite *CabinetTestSuite) Test1_AddSharedDrug() {
+	t := suite.T()
+
+	var created struct {
+		Drug map[string]interface{} `json:"drug"`
+	}
+	decodeData(t, doRequest(t, "POST", "/auth/drugs/add", suite.ownerToken, map[string]interface{}{
+		"name":     "Ibuprofen",
+		"expiry":   time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+		"quantity": 20,
+		"unit":     "tablets",
+		"group_id": suite.groupID,
+	}), &created)
+	suite.drugID = int(created.Drug["id"].(float64))
+	assert.Nil(t, created.Drug["user_id"])
+	assert.Equal(t, float64(suite.groupID), created.Drug["group_id"])
+
+	// Only members can add to the cabinet
+	outsider := getAuthToken(t)
+	resp := doRequest(t, "POST", "/auth/drugs/add", outsider, map[string]interface{}{
+		"name":     "Aspirin",
+		"expiry":   time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+		"group_id": suite.groupID,
+	})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *CabinetTestSuite) Test2_MembersSeeSharedDrugs() {
+	t := suite.T()
+
+	shared := suite.drugs(suite.memberToken, "?scope=group")
+	require.Len(t, shared, 1)
+	assert.Equal(t, "Ibuprofen", shared[0]["name"])
+
+	one := suite.drugs(suite.memberToken, fmt.Sprintf("?scope=group&group_id=%d", suite.groupID))
+	assert.Len(t, one, 1)
+
+	// Shared drugs are not part of the personal list
+	assert.Empty(t, suite.drugs(suite.memberToken, ""))
+
+	invalid := doRequest(t, "GET", "/auth/drugs?scope=everything", suite.memberToken, nil)
+	invalid.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, invalid.StatusCode)
+}
+
+func (suite *CabinetTestSuite) Test3_MembersEditSharedDrugs() {
+	t := suite.T()
+
+	var drug map[string]interface{}
+	decodeData(t, doRequest(t, "PUT", fmt.Sprintf("/auth/drugs/%d", suite.drugID), suite.memberToken,
+		map[string]interface{}{"location": "Kitchen shelf", "quantity": 24}), &drug)
+	assert.Equal(t, "Kitchen shelf", drug["location"])
+	assert.Equal(t, 24.0, drug["quantity"])
+
+	var schedule map[string]interface{}
+	startsAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	decodeData(t, doRequest(t, "POST", "/auth/schedules", suite.memberToken, map[string]interface{}{
+		"drug_id":   suite.drugID,
+		"rrule":     "FREQ=DAILY;COUNT=3",
+		"starts_at": startsAt.Format(time.RFC3339),
+		"quantity":  2,
+	}), &schedule)
+	resp := doRequest(t, "POST", "/auth/doses/log", suite.memberToken, map[string]interface{}{
+		"schedule_id":  schedule["id"],
+		"scheduled_at": startsAt.Format(time.RFC3339),
+		"status":       "taken",
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	shared := suite.drugs(suite.ownerToken, "?scope=group")
+	require.Len(t, shared, 1)
+	assert.Equal(t, 22.0, shared[0]["quantity"])
+}
+
+func (suite *CabinetTestSuite) Test4_History() {
+	t := suite.T()
+
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/drugs/remove/%d", suite.drugID), suite.memberToken, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+	assert.Empty(t, suite.drugs(suite.ownerToken, "?scope=group"))
+
+	// Removed drugs stay in the history, newest first
+	var events []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/drugs/history?scope=group", suite.ownerToken, nil), &events)
+	require.Len(t, events, 4)
+	assert.Equal(t, "removed", events[0]["action"])
+	assert.Equal(t, "Cabinet Member", events[0]["user_name"])
+	assert.Equal(t, "used", events[1]["action"])
+	assert.Equal(t, -2.0, events[1]["delta"])
+	assert.Equal(t, "adjusted", events[2]["action"])
+	assert.Equal(t, 4.0, events[2]["delta"])
+	assert.Equal(t, "Cabinet Member", events[2]["user_name"])
+	assert.Equal(t, "added", events[3]["action"])
+	assert.Equal(t, "Cabinet Owner", events[3]["user_name"])
+	assert.Equal(t, "Ibuprofen", events[3]["drug_name"])
+
+	// Personal history is separate
+	var personal []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/drugs/history", suite.ownerToken, nil), &personal)
+	assert.Empty(t, personal)
+}
+
+func (suite *CabinetTestSuite) Test5_MoveBetweenCabinets() {
+	t := suite.T()
+
+	var kit map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/kits", suite.ownerToken, map[string]interface{}{
+		"name":     "Shared kit",
+		"group_id": suite.groupID,
+	}), &kit)
+	kitID := int(kit["id"].(float64))
+
+	var personal, shared struct {
+		Drug map[string]interface{} `json:"drug"`
+	}
+	decodeData(t, doRequest(t, "POST", "/auth/drugs/add", suite.ownerToken, map[string]interface{}{
+		"name":   "Personal plaster",
+		"expiry": time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+	}), &personal)
+	decodeData(t, doRequest(t, "POST", "/auth/drugs/add", suite.ownerToken, map[string]interface{}{
+		"name":     "Shared plaster",
+		"expiry":   time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+		"group_id": suite.groupID,
+	}), &shared)
+	personalID := int(personal.Drug["id"].(float64))
+	sharedID := int(shared.Drug["id"].(float64))
+
+	// Personal drugs stay out of the shared kits
+	resp := doRequest(t, "POST", "/auth/drugs/move", suite.ownerToken, map[string]interface{}{
+		"drug_ids": []int{personalID},
+		"kit_id":   kitID,
+	})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp = doRequest(t, "PUT", fmt.Sprintf("/auth/drugs/%d", personalID), suite.ownerToken,
+		map[string]interface{}{"kit_id": kitID})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Regular members may not rearrange the cabinet
+	resp = doRequest(t, "POST", "/auth/drugs/move", suite.memberToken, map[string]interface{}{
+		"drug_ids": []int{sharedID},
+		"kit_id":   kitID,
+	})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, "POST", "/auth/drugs/move", suite.ownerToken, map[string]interface{}{
+		"drug_ids": []int{sharedID},
+		"kit_id":   kitID,
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	// The grouped shared list only has the shared kit and the drugs without a kit
+	grouped := suite.drugs(suite.ownerToken, fmt.Sprintf("?scope=group&group_id=%d&group_by=kit", suite.groupID))
+	require.Len(t, grouped, 2)
+	assert.Len(t, grouped[0]["drugs"], 1)
+}
+
+func TestCabinetSuite(t *testing.T) {
+	suite.Run(t, new(CabinetTestSuite))
+}
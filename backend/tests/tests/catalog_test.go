@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// CatalogTestSuite relies on data/catalog_sample.csv being imported.
+type CatalogTestSuite struct {
+	suite.Suite
+	token string
+}
+
+func (suite *CatalogTestSuite) SetupSuite() {
+	suite.token = getAuthToken(suite.T())
+}
+
+func (suite *CatalogTestSuite) Test1_Search() {
+	t := suite.T()
+
+	// A typo still finds the drug
+	resp := doRequest(t, "GET", "/auth/catalog/search?q=ibuprofn", suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.NotEmpty(t, result.Data)
+	assert.Equal(t, "Ibuprofen", result.Data[0]["name"])
+
+	empty := doRequest(t, "GET", "/auth/catalog/search?q=", suite.token, nil)
+	empty.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, empty.StatusCode)
+}
+
+func (suite *CatalogTestSuite) Test2_Barcode() {
+	t := suite.T()
+
+	resp := doRequest(t, "GET", "/auth/catalog/barcode/2000000000015", suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "Ibuprofen", result.Data["name"])
+	assert.Equal(t, 20.0, result.Data["quantity"])
+	assert.Equal(t, "tablets", result.Data["unit"])
+
+	// The prefilled drug can be saved as is
+	add := doRequest(t, "POST", "/auth/drugs/add", suite.token, result.Data)
+	add.Body.Close()
+	require.Equal(t, http.StatusOK, add.StatusCode)
+
+	drugID := lastID(t, doRequest(t, "GET", "/auth/drugs", suite.token, nil))
+	remove := doRequest(t, "POST", fmt.Sprintf("/auth/drugs/remove/%d", drugID), suite.token, nil)
+	remove.Body.Close()
+}
+
+func (suite *CatalogTestSuite) Test3_BarcodeErrors() {
+	t := suite.T()
+
+	invalid := doRequest(t, "GET", "/auth/catalog/barcode/2000000000016", suite.token, nil)
+	invalid.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, invalid.StatusCode)
+
+	unknown := doRequest(t, "GET", "/auth/catalog/barcode/12345670", suite.token, nil)
+	unknown.Body.Close()
+	assert.Equal(t, http.StatusNotFound, unknown.StatusCode)
+}
+
+func (suite *CatalogTestSuite) Test4_EntriesWithoutBarcode() {
+	t := suite.T()
+
+	// The sample is imported on every start, entries without a barcode are not duplicated
+	var drugs []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/catalog/search?q=elastic+bandage", suite.token, nil), &drugs)
+	count := 0
+	for _, drug := range drugs {
+		if drug["name"] == "Elastic bandage" {
+			count++
+			assert.Nil(t, drug["gtin"])
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestCatalogSuite(t *testing.T) {
+	suite.Run(t, new(CatalogTestSuite))
+}
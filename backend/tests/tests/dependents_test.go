@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DependentsTestSuite struct {
+	suite.Suite
+	caregiverToken string
+	outsiderToken  string
+	profileID      int
+}
+
+func (suite *DependentsTestSuite) SetupSuite() {
+	stamp := time.Now().UnixNano()
+	suite.caregiverToken = signUpUser(suite.T(), "Caregiver", fmt.Sprintf("caregiver_%d@example.com", stamp), "secure123")
+	suite.outsiderToken = signUpUser(suite.T(), "Outsider", fmt.Sprintf("outsider_%d@example.com", stamp), "secure123")
+}
+
+// onBehalf sends a request for the dependent profile.
+func (suite *DependentsTestSuite) onBehalf(method, url, token string, payload interface{}) *http.Response {
+	return onBehalfOf(suite.T(), suite.profileID, method, url, token, payload)
+}
+
+// onBehalfOf sends a request for a dependent profile.
+func onBehalfOf(t *testing.T, profileID int, method, url, token string, payload interface{}) *http.Response {
+	var body bytes.Buffer
+	if payload != nil {
+		require.NoError(t, json.NewEncoder(&body).Encode(payload))
+	}
+
+	req, err := http.NewRequest(method, config.BaseURL+url, &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-On-Behalf-Of", strconv.Itoa(profileID))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func (suite *DependentsTestSuite) Test1_CreateProfile() {
+	t := suite.T()
+
+	var profile map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/dependents", suite.caregiverToken, map[string]interface{}{
+		"name":       "Grandma",
+		"birth_date": "1950-03-01T00:00:00Z",
+	}), &profile)
+	suite.profileID = int(profile["id"].(float64))
+	assert.Equal(t, "Grandma", profile["name"])
+
+	var profiles []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/dependents", suite.caregiverToken, nil), &profiles)
+	require.Len(t, profiles, 1)
+
+	resp := doRequest(t, "POST", "/auth/dependents", suite.caregiverToken, map[string]interface{}{"name": " "})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *DependentsTestSuite) Test2_ActOnBehalf() {
+	t := suite.T()
+
+	resp := suite.onBehalf("POST", "/auth/drugs/add", suite.caregiverToken, map[string]interface{}{
+		"name":   "Warfarin",
+		"expiry": time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	var drugs []map[string]interface{}
+	decodeData(t, suite.onBehalf("GET", "/auth/drugs", suite.caregiverToken, nil), &drugs)
+	require.Len(t, drugs, 1)
+	assert.Equal(t, "Warfarin", drugs[0]["name"])
+
+	// The caregiver's own cabinet is separate
+	var own []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/drugs", suite.caregiverToken, nil), &own)
+	assert.Empty(t, own)
+
+	// Only caregivers of the profile can act for it
+	resp = suite.onBehalf("GET", "/auth/drugs", suite.outsiderToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *DependentsTestSuite) Test3_AccountEndpointsBlocked() {
+	t := suite.T()
+
+	for _, path := range []string{"/auth/sessions", "/auth/groups", "/auth/dependents"} {
+		resp := suite.onBehalf("GET", path, suite.caregiverToken, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, path)
+	}
+	resp := suite.onBehalf("DELETE", "/auth/me", suite.caregiverToken, map[string]string{"password": "secure123"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *DependentsTestSuite) Test4_AuditTrail() {
+	t := suite.T()
+
+	var entries []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", fmt.Sprintf("/auth/dependents/%d/audit", suite.profileID), suite.caregiverToken, nil), &entries)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "GET", entries[0]["method"])
+	assert.Equal(t, "/auth/drugs", entries[0]["path"])
+	assert.Equal(t, "Caregiver", entries[0]["actor_name"])
+	assert.Equal(t, "/auth/drugs/add", entries[1]["path"])
+	assert.Equal(t, "/auth/dependents", entries[2]["path"])
+
+	resp := doRequest(t, "GET", fmt.Sprintf("/auth/dependents/%d/audit", suite.profileID), suite.outsiderToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *DependentsTestSuite) Test5_RemoveProfile() {
+	t := suite.T()
+
+	var summary map[string]interface{}
+	decodeData(t, doRequest(t, "POST", fmt.Sprintf("/auth/dependents/remove/%d", suite.profileID), suite.caregiverToken, nil), &summary)
+	assert.Equal(t, 1.0, summary["drugs"])
+
+	var profiles []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/dependents", suite.caregiverToken, nil), &profiles)
+	assert.Empty(t, profiles)
+}
+
+func (suite *DependentsTestSuite) Test6_DeleteCaregiver() {
+	t := suite.T()
+	token := signUpUser(t, "Leaving Caregiver", fmt.Sprintf("leaving_%d@example.com", time.Now().UnixNano()), "secure123")
+
+	// A group the caregiver is the only member of is deleted with the account
+	var group map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/groups", token, map[string]interface{}{"name": "Solo household"}), &group)
+
+	var personal, grouped map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/dependents", token, map[string]interface{}{"name": "Son"}), &personal)
+	decodeData(t, doRequest(t, "POST", "/auth/dependents", token, map[string]interface{}{
+		"name":     "Daughter",
+		"group_id": group["id"],
+	}), &grouped)
+
+	resp := onBehalfOf(t, int(grouped["id"].(float64)), "POST", "/auth/documents/add", token, map[string]interface{}{
+		"name":      "Vaccination record",
+		"file_data": base64.StdEncoding.EncodeToString(pdfFile),
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	// Both profiles are left without caregivers, so they are deleted with their data
+	var summary map[string]float64
+	decodeData(t, doRequest(t, "DELETE", "/auth/me", token, map[string]string{"password": "secure123"}), &summary)
+	assert.Equal(t, 2.0, summary["dependents"])
+	assert.Equal(t, 1.0, summary["documents"])
+	assert.Equal(t, 1.0, summary["groups"])
+	assert.Equal(t, 3.0, summary["medical_cards"])
+}
+
+// orphanedGroupProfile creates a group of the owner with a caregiver, who adds a profile
+// to the group and deletes their account. Returns the IDs of the group and the profile.
+func orphanedGroupProfile(t *testing.T, ownerToken string) (int, int) {
+	stamp := time.Now().UnixNano()
+	var group map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/groups", ownerToken, map[string]interface{}{"name": "Household"}), &group)
+	groupID := int(group["id"].(float64))
+
+	email := fmt.Sprintf("departed_%d@example.com", stamp)
+	token := signUpUser(t, "Departed Caregiver", email, "secure123")
+	var invitation map[string]interface{}
+	decodeData(t, doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/invite", groupID), ownerToken,
+		map[string]interface{}{"email": email, "role": "caregiver"}), &invitation)
+	resp := doRequest(t, "POST", "/auth/invitations/accept", token, map[string]interface{}{"token": invitation["token"]})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	var profile map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/dependents", token, map[string]interface{}{
+		"name":     "Grandpa",
+		"group_id": groupID,
+	}), &profile)
+
+	// The profile stays with the group
+	var summary map[string]float64
+	decodeData(t, doRequest(t, "DELETE", "/auth/me", token, map[string]string{"password": "secure123"}), &summary)
+	assert.Equal(t, 0.0, summary["dependents"])
+	return groupID, int(profile["id"].(float64))
+}
+
+// managedProfiles returns the IDs of the profiles the user manages.
+func managedProfiles(t *testing.T, token string) []int {
+	var profiles []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/dependents", token, nil), &profiles)
+	ids := []int{}
+	for _, profile := range profiles {
+		ids = append(ids, int(profile["id"].(float64)))
+	}
+	return ids
+}
+
+func (suite *DependentsTestSuite) Test7_RemoveGroupKeepsProfiles() {
+	t := suite.T()
+	token := signUpUser(t, "Group Owner", fmt.Sprintf("group_owner_%d@example.com", time.Now().UnixNano()), "secure123")
+	groupID, profileID := orphanedGroupProfile(t, token)
+	assert.Equal(t, []int{profileID}, managedProfiles(t, token))
+
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/groups/remove/%d", groupID), token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	// The owner takes over the profile instead of it becoming unreachable
+	assert.Equal(t, []int{profileID}, managedProfiles(t, token))
+	resp = onBehalfOf(t, profileID, "GET", "/auth/drugs", token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+}
+
+func (suite *DependentsTestSuite) Test8_LastMemberLeavesKeepsProfiles() {
+	t := suite.T()
+	token := signUpUser(t, "Last Member", fmt.Sprintf("last_member_%d@example.com", time.Now().UnixNano()), "secure123")
+	groupID, profileID := orphanedGroupProfile(t, token)
+
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/leave", groupID), token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	assert.Equal(t, []int{profileID}, managedProfiles(t, token))
+	resp = onBehalfOf(t, profileID, "GET", "/auth/drugs", token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+}
+
+func TestDependentsSuite(t *testing.T) {
+	suite.Run(t, new(DependentsTestSuite))
+}
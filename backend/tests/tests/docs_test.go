@@ -6,6 +6,13 @@ import (
 	"encoding/json"
 	"first_aid_companion/controllers"
 	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"testing"
 	"time"
@@ -27,8 +34,92 @@ type Document struct {
 
 type DocumentTestSuite struct {
 	suite.Suite
-	token  string
-	sample map[string]interface{}
+	token    string
+	sample   map[string]interface{}
+	uploadID int
+}
+
+// pdfFile is the start of a PDF, enough for the type to be detected.
+var pdfFile = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+
+// upload sends a file as multipart form data.
+func (suite *DocumentTestSuite) upload(fields map[string]string, fileName string, content []byte) *http.Response {
+	t := suite.T()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		require.NoError(t, form.WriteField(name, value))
+	}
+	part, err := form.CreateFormFile("file", fileName)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req, err := http.NewRequest("POST", config.BaseURL+"/auth/documents/upload", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+suite.token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// download fetches the file of a document with optional extra headers.
+func (suite *DocumentTestSuite) download(path string, header map[string]string) *http.Response {
+	req, err := http.NewRequest("GET", config.BaseURL+path, nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("Authorization", "Bearer "+suite.token)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(suite.T(), err)
+	return resp
+}
+
+// imagePDF makes a PDF with a page showing the JPEG image, like a scanned document.
+func imagePDF(img []byte, width, height int) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im1 4 0 R >> >> /Contents 5 0 R >>", width, height),
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream", width, height, len(img), img),
+	}
+	content := fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im1 Do Q", width, height)
+	objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
+
+// waitThumbnail requests the thumbnail until it is no longer being generated.
+func (suite *DocumentTestSuite) waitThumbnail(id int, header map[string]string) *http.Response {
+	path := fmt.Sprintf("/auth/documents/%d/thumbnail", id)
+	for i := 0; ; i++ {
+		resp := suite.download(path, header)
+		if resp.StatusCode != http.StatusAccepted || i == 40 {
+			return resp
+		}
+		assert.NotEmpty(suite.T(), resp.Header.Get("Retry-After"))
+		resp.Body.Close()
+		time.Sleep(250 * time.Millisecond)
+	}
 }
 
 func (suite *DocumentTestSuite) SetupSuite() {
@@ -97,6 +188,191 @@ func (suite *DocumentTestSuite) Test2_GetDocuments() {
 		}
 	}
 	assert.True(suite.T(), found, fmt.Sprintf("Document %q not found in response", suite.sample["name"]))
+
+	// The list only has metadata
+	for _, doc := range docs {
+		assert.Empty(suite.T(), doc.FileData)
+	}
+}
+
+func (suite *DocumentTestSuite) Test3_UploadMultipart() {
+	t := suite.T()
+
+	resp := suite.upload(nil, "virus.exe", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp = suite.upload(map[string]string{"name": "Empty"}, "empty.pdf", nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var doc map[string]interface{}
+	decodeData(t, suite.upload(map[string]string{
+		"type":   "report",
+		"date":   "2025-07-12",
+		"doctor": "Dr. Test",
+	}, "Анализ крови.pdf", pdfFile), &doc)
+	suite.uploadID = int(doc["id"].(float64))
+	assert.Equal(t, "Анализ крови", doc["name"], "name defaults to the file name")
+	assert.Equal(t, "application/pdf", doc["content_type"])
+	assert.Equal(t, float64(len(pdfFile)), doc["size"])
+	assert.Nil(t, doc["file_data"])
+}
+
+func (suite *DocumentTestSuite) Test4_DownloadFile() {
+	t := suite.T()
+	path := fmt.Sprintf("/auth/documents/%d/file", suite.uploadID)
+
+	resp := suite.download(path, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "inline")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, pdfFile, body)
+
+	attachment := suite.download(path+"?download=true", nil)
+	attachment.Body.Close()
+	_, params, err := mime.ParseMediaType(attachment.Header.Get("Content-Disposition"))
+	require.NoError(t, err)
+	assert.Equal(t, "Анализ крови.pdf", params["filename"])
+
+	partial := suite.download(path, map[string]string{"Range": "bytes=0-7"})
+	defer partial.Body.Close()
+	assert.Equal(t, http.StatusPartialContent, partial.StatusCode)
+	assert.Equal(t, fmt.Sprintf("bytes 0-7/%d", len(pdfFile)), partial.Header.Get("Content-Range"))
+	body, err = io.ReadAll(partial.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(body))
+
+	// Files of other users are not found
+	other := doRequest(t, "GET", path, signUpUser(t, "Other", fmt.Sprintf("docs_%d@example.com", time.Now().UnixNano()), "secure123"), nil)
+	other.Body.Close()
+	assert.Equal(t, http.StatusNotFound, other.StatusCode)
+}
+
+func (suite *DocumentTestSuite) Test5_SharedFiles() {
+	t := suite.T()
+
+	// The same contents are stored once and keep their hash
+	var first, second map[string]interface{}
+	decodeData(t, suite.upload(map[string]string{"name": "Copy"}, "copy.pdf", pdfFile), &second)
+	var docs []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/documents", suite.token, nil), &docs)
+	for _, doc := range docs {
+		if int(doc["id"].(float64)) == suite.uploadID {
+			first = doc
+		}
+	}
+	require.NotNil(t, first)
+	assert.Len(t, first["sha256"], 64)
+	assert.Equal(t, first["sha256"], second["sha256"])
+
+	path := fmt.Sprintf("/auth/documents/%d/file", int(second["id"].(float64)))
+	resp := suite.download(path, nil)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, `"`+first["sha256"].(string)+`"`, etag)
+
+	notModified := suite.download(path, map[string]string{"If-None-Match": etag})
+	notModified.Body.Close()
+	assert.Equal(t, http.StatusNotModified, notModified.StatusCode)
+
+	// Removing one document keeps the file of the other
+	removed := doRequest(t, "POST", fmt.Sprintf("/auth/documents/remove/%d", suite.uploadID), suite.token, nil)
+	requireOK(t, removed)
+	removed.Body.Close()
+
+	resp = suite.download(path, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, pdfFile, body)
+
+	gone := suite.download(fmt.Sprintf("/auth/documents/%d/file", suite.uploadID), nil)
+	gone.Body.Close()
+	assert.Equal(t, http.StatusNotFound, gone.StatusCode)
+}
+
+func (suite *DocumentTestSuite) Test6_SameFileOfOtherUser() {
+	t := suite.T()
+
+	// Each user gets their own copy of the same file, removing one keeps the other
+	var mine, theirs map[string]interface{}
+	decodeData(t, suite.upload(map[string]string{"name": "Mine"}, "mine.pdf", pdfFile), &mine)
+	other := signUpUser(t, "Other", fmt.Sprintf("docs_%d@example.com", time.Now().UnixNano()), "secure123")
+	decodeData(t, doRequest(t, "POST", "/auth/documents/add", other, map[string]interface{}{
+		"name":      "Theirs",
+		"file_data": base64.StdEncoding.EncodeToString(pdfFile),
+	}), &theirs)
+	assert.Equal(t, mine["sha256"], theirs["sha256"])
+
+	removed := doRequest(t, "POST", fmt.Sprintf("/auth/documents/remove/%d", int(theirs["id"].(float64))), other, nil)
+	requireOK(t, removed)
+	removed.Body.Close()
+
+	resp := suite.download(fmt.Sprintf("/auth/documents/%d/file", int(mine["id"].(float64))), nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, pdfFile, body)
+}
+
+func (suite *DocumentTestSuite) Test7_Thumbnails() {
+	t := suite.T()
+
+	photo := image.NewRGBA(image.Rect(0, 0, 1200, 900))
+	for y := 0; y < 900; y++ {
+		for x := 0; x < 1200; x++ {
+			photo.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var pngFile, jpegFile bytes.Buffer
+	require.NoError(t, png.Encode(&pngFile, photo))
+	require.NoError(t, jpeg.Encode(&jpegFile, photo, nil))
+
+	// Images and scanned PDFs get a JPEG preview of at most 320 pixels
+	for name, content := range map[string][]byte{
+		"photo.png": pngFile.Bytes(),
+		"scan.pdf":  imagePDF(jpegFile.Bytes(), 1200, 900),
+	} {
+		var doc map[string]interface{}
+		decodeData(t, suite.upload(map[string]string{"name": name}, name, content), &doc)
+		id := int(doc["id"].(float64))
+
+		resp := suite.waitThumbnail(id, nil)
+		requireOK(t, resp)
+		assert.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"), name)
+		assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age=", name)
+		etag := resp.Header.Get("ETag")
+		assert.NotEmpty(t, etag, name)
+		preview, err := jpeg.Decode(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err, name)
+		assert.Equal(t, 320, preview.Bounds().Dx(), name)
+		assert.Equal(t, 240, preview.Bounds().Dy(), name)
+
+		notModified := suite.download(fmt.Sprintf("/auth/documents/%d/thumbnail", id), map[string]string{"If-None-Match": etag})
+		notModified.Body.Close()
+		assert.Equal(t, http.StatusNotModified, notModified.StatusCode, name)
+	}
+
+	// Text files have no preview
+	var text map[string]interface{}
+	decodeData(t, suite.upload(map[string]string{"name": "Notes"}, "notes.txt", []byte("Take with food")), &text)
+	resp := suite.waitThumbnail(int(text["id"].(float64)), nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Thumbnails of other users are not found
+	other := doRequest(t, "GET", fmt.Sprintf("/auth/documents/%d/thumbnail", int(text["id"].(float64))),
+		signUpUser(t, "Other", fmt.Sprintf("docs_%d@example.com", time.Now().UnixNano()), "secure123"), nil)
+	other.Body.Close()
+	assert.Equal(t, http.StatusNotFound, other.StatusCode)
 }
 
 func TestDocumentSuite(t *testing.T) {
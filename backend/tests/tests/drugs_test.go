@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -67,9 +68,73 @@ func (suite *DrugsTestSuite) Test2_ListDrugs() {
 	require.Greater(suite.T(), len(result.Data), 0)
 	lastDrug := result.Data[len(result.Data)-1]
 	suite.drugID = int(lastDrug["id"].(float64))
+
+	// The free-text amount is parsed into a structured quantity
+	assert.Equal(suite.T(), 30.0, lastDrug["quantity"])
+	assert.Equal(suite.T(), "tablets", lastDrug["unit"])
+}
+
+func (suite *DrugsTestSuite) Test3_UpdateDrug() {
+	payload := map[string]interface{}{
+		"name":   "Ibuprofen Forte",
+		"dose":   "400mg",
+		"amount": "20 tablets",
+	}
+	body, _ := json.Marshal(payload)
+
+	url := fmt.Sprintf("%s/auth/drugs/%d", config.BaseURL, suite.drugID)
+	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(body))
+	require.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+suite.token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	requireOK(suite.T(), resp)
+
+	var result struct {
+		Status int                    `json:"status"`
+		Data   map[string]interface{} `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "Ibuprofen Forte", result.Data["name"])
+	assert.Equal(suite.T(), "400mg", result.Data["dose"])
+	assert.Equal(suite.T(), "20 tablets", result.Data["amount"])
+	assert.Equal(suite.T(), 20.0, result.Data["quantity"])
+	// Fields missing from the body stay untouched
+	assert.Equal(suite.T(), "Pfizer", result.Data["manufacturer"])
 }
 
-func (suite *DrugsTestSuite) Test3_RemoveDrug() {
+func (suite *DrugsTestSuite) Test4_UpdateDrugValidation() {
+	payloads := []map[string]interface{}{
+		{"name": "  "},
+		{"expiry": "1990-01-01T00:00:00Z"},
+		{"quantity": -1},
+		{"unit": "barrels"},
+	}
+
+	for _, payload := range payloads {
+		body, _ := json.Marshal(payload)
+
+		url := fmt.Sprintf("%s/auth/drugs/%d", config.BaseURL, suite.drugID)
+		req, err := http.NewRequest("PUT", url, bytes.NewBuffer(body))
+		require.NoError(suite.T(), err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+suite.token)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+
+		assert.Equal(suite.T(), http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func (suite *DrugsTestSuite) Test5_RemoveDrug() {
 	url := fmt.Sprintf("%s/auth/drugs/remove/%d", config.BaseURL, suite.drugID)
 	req, err := http.NewRequest("POST", url, nil)
 	require.NoError(suite.T(), err)
@@ -82,7 +147,7 @@ func (suite *DrugsTestSuite) Test3_RemoveDrug() {
 	requireOK(suite.T(), resp)
 }
 
-func (suite *DrugsTestSuite) Test4_VerifyDrugRemoved() {
+func (suite *DrugsTestSuite) Test6_VerifyDrugRemoved() {
 	req, _ := http.NewRequest("GET", config.BaseURL+"/auth/drugs", nil)
 	req.Header.Set("Authorization", "Bearer "+suite.token)
 
@@ -106,6 +171,32 @@ func (suite *DrugsTestSuite) Test4_VerifyDrugRemoved() {
 	}
 }
 
+func (suite *DrugsTestSuite) Test7_AddDrugWithoutExpiry() {
+	t := suite.T()
+
+	// The expiry date is optional, but a name is required and old dates are mistakes
+	for _, payload := range []map[string]interface{}{
+		{"name": " ", "amount": "10 tablets"},
+		{"name": "Activated charcoal", "expiry": "1990-01-01T00:00:00Z"},
+	} {
+		resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, payload)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":   "Activated charcoal",
+		"amount": "10 tablets",
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	id := lastID(t, doRequest(t, "GET", "/auth/drugs", suite.token, nil))
+	removed := doRequest(t, "POST", fmt.Sprintf("/auth/drugs/remove/%d", id), suite.token, nil)
+	requireOK(t, removed)
+	removed.Body.Close()
+}
+
 func TestDrugsSuite(t *testing.T) {
 	suite.Run(t, new(DrugsTestSuite))
 }
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmergencyTestSuite struct {
+	suite.Suite
+	token string
+	url   string
+}
+
+func (suite *EmergencyTestSuite) SetupSuite() {
+	t := suite.T()
+	suite.token = signUpUser(t, "Emergency User", fmt.Sprintf("emergency_%d@example.com", time.Now().UnixNano()), "secure123")
+
+	resp := doRequest(t, "POST", "/auth/me", suite.token, map[string]string{
+		"blood_type": "2+",
+		"allergies":  "Penicillin",
+		"passport":   "4500 123456",
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+}
+
+// publicCard fetches the emergency ID without authentication.
+func (suite *EmergencyTestSuite) publicCard(url string) *http.Response {
+	path := url[strings.Index(url, "/emergency/"):]
+	resp, err := http.Get(config.BaseURL + path)
+	require.NoError(suite.T(), err)
+	return resp
+}
+
+func (suite *EmergencyTestSuite) Test1_EnableEmergencyID() {
+	t := suite.T()
+
+	resp := doRequest(t, "GET", "/auth/emergency", suite.token, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	var contact map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/emergency/contacts", suite.token, map[string]string{
+		"name":     "Anna",
+		"phone":    "+79001234567",
+		"relation": "daughter",
+	}), &contact)
+
+	var profile map[string]interface{}
+	decodeData(t, doRequest(t, "PUT", "/auth/emergency", suite.token, map[string]bool{"show_name": false}), &profile)
+	suite.url = profile["url"].(string)
+	assert.Equal(t, false, profile["show_name"])
+	assert.Equal(t, true, profile["show_allergies"])
+
+	// Only the hash of the token is stored, the url is not shown again
+	profile = nil
+	decodeData(t, doRequest(t, "GET", "/auth/emergency", suite.token, nil), &profile)
+	assert.Nil(t, profile["url"])
+	assert.Nil(t, profile["token"])
+	assert.Nil(t, profile["token_hash"])
+}
+
+func (suite *EmergencyTestSuite) Test2_PublicCard() {
+	t := suite.T()
+
+	var card map[string]interface{}
+	decodeData(t, suite.publicCard(suite.url), &card)
+	assert.Equal(t, "2+", card["blood_type"])
+	allergies := card["allergies"].([]interface{})
+	require.Len(t, allergies, 1)
+	assert.Equal(t, "Penicillin", allergies[0].(map[string]interface{})["text"])
+	assert.Nil(t, card["name"])
+	assert.Nil(t, card["passport"])
+	require.Len(t, card["contacts"], 1)
+}
+
+func (suite *EmergencyTestSuite) Test3_QRCode() {
+	t := suite.T()
+
+	token := suite.url[strings.LastIndex(suite.url, "/")+1:]
+	resp := doRequest(t, "GET", "/auth/emergency/qr?token="+token, suite.token, nil)
+	requireOK(t, resp)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(string(body), "\x89PNG"))
+
+	resp = doRequest(t, "GET", "/auth/emergency/qr?format=svg&token="+token, suite.token, nil)
+	requireOK(t, resp)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "image/svg+xml", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "<svg")
+
+	resp = doRequest(t, "GET", "/auth/emergency/qr?format=gif&token="+token, suite.token, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	for _, query := range []string{"", "?token=wrong"} {
+		resp = doRequest(t, "GET", "/auth/emergency/qr"+query, suite.token, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, query)
+	}
+}
+
+func (suite *EmergencyTestSuite) Test4_RotateAndRevoke() {
+	t := suite.T()
+
+	var profile map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/emergency/rotate", suite.token, nil), &profile)
+	rotated := profile["url"].(string)
+	assert.NotEqual(t, suite.url, rotated)
+
+	old := suite.publicCard(suite.url)
+	old.Body.Close()
+	assert.Equal(t, http.StatusNotFound, old.StatusCode)
+
+	resp := doRequest(t, "POST", "/auth/emergency/revoke", suite.token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	revoked := suite.publicCard(rotated)
+	revoked.Body.Close()
+	assert.Equal(t, http.StatusNotFound, revoked.StatusCode)
+}
+
+func TestEmergencySuite(t *testing.T) {
+	suite.Run(t, new(EmergencyTestSuite))
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type GroupsTestSuite struct {
+	suite.Suite
+	ownerToken  string
+	memberToken string
+	memberEmail string
+	memberID    int
+	groupID     int
+}
+
+func (suite *GroupsTestSuite) SetupSuite() {
+	stamp := time.Now().UnixNano()
+	suite.ownerToken = signUpUser(suite.T(), "Owner", fmt.Sprintf("owner_%d@example.com", stamp), "secure123")
+	suite.memberEmail = fmt.Sprintf("member_%d@example.com", stamp)
+	suite.memberToken = signUpUser(suite.T(), "Member", suite.memberEmail, "secure123")
+}
+
+// invite invites the member and returns the invitation token.
+func (suite *GroupsTestSuite) invite(role string) string {
+	var invitation map[string]interface{}
+	decodeData(suite.T(), doRequest(suite.T(), "POST", fmt.Sprintf("/auth/groups/%d/invite", suite.groupID), suite.ownerToken,
+		map[string]interface{}{"email": suite.memberEmail, "role": role}), &invitation)
+	return invitation["token"].(string)
+}
+
+// members returns the members of the group as seen by the owner.
+func (suite *GroupsTestSuite) members() []map[string]interface{} {
+	var group struct {
+		Members []map[string]interface{} `json:"members"`
+	}
+	decodeData(suite.T(), doRequest(suite.T(), "GET", fmt.Sprintf("/auth/groups/%d", suite.groupID), suite.ownerToken, nil), &group)
+	return group.Members
+}
+
+func (suite *GroupsTestSuite) Test1_CreateGroup() {
+	t := suite.T()
+
+	var group map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/groups", suite.ownerToken, map[string]interface{}{"name": "Household"}), &group)
+	suite.groupID = int(group["id"].(float64))
+
+	var groups []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/groups", suite.ownerToken, nil), &groups)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "owner", groups[0]["role"])
+
+	// Non-members do not see the group
+	resp := doRequest(t, "GET", fmt.Sprintf("/auth/groups/%d", suite.groupID), suite.memberToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *GroupsTestSuite) Test2_DeclineAndAcceptInvitation() {
+	t := suite.T()
+
+	token := suite.invite("member")
+	var pending []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/invitations", suite.memberToken, nil), &pending)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Household", pending[0]["group_name"])
+
+	// Only the invited user can use the token
+	foreign := doRequest(t, "POST", "/auth/invitations/accept", suite.ownerToken, map[string]interface{}{"token": token})
+	foreign.Body.Close()
+	assert.Equal(t, http.StatusNotFound, foreign.StatusCode)
+
+	resp := doRequest(t, "POST", "/auth/invitations/decline", suite.memberToken, map[string]interface{}{"token": token})
+	requireOK(t, resp)
+	resp.Body.Close()
+	again := doRequest(t, "POST", "/auth/invitations/accept", suite.memberToken, map[string]interface{}{"token": token})
+	again.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, again.StatusCode)
+
+	token = suite.invite("member")
+	resp = doRequest(t, "POST", "/auth/invitations/accept", suite.memberToken, map[string]interface{}{"token": token})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	members := suite.members()
+	require.Len(t, members, 2)
+	assert.Equal(t, "owner", members[0]["role"])
+	assert.Equal(t, "member", members[1]["role"])
+	suite.memberID = int(members[1]["user_id"].(float64))
+}
+
+func (suite *GroupsTestSuite) Test3_RolePermissions() {
+	t := suite.T()
+
+	// Members can neither invite nor manage shared kits
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/invite", suite.groupID), suite.memberToken,
+		map[string]interface{}{"email": "someone@example.com"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doRequest(t, "POST", "/auth/kits", suite.memberToken, map[string]interface{}{"name": "Shared", "group_id": suite.groupID})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Caregivers can
+	resp = doRequest(t, "PUT", fmt.Sprintf("/auth/groups/%d/members/%d", suite.groupID, suite.memberID), suite.ownerToken,
+		map[string]interface{}{"role": "caregiver"})
+	requireOK(t, resp)
+	resp.Body.Close()
+	resp = doRequest(t, "POST", "/auth/kits", suite.memberToken, map[string]interface{}{"name": "Shared", "group_id": suite.groupID})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	// But only the owner renames the group
+	resp = doRequest(t, "PUT", fmt.Sprintf("/auth/groups/%d", suite.groupID), suite.memberToken, map[string]interface{}{"name": "Mine"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func (suite *GroupsTestSuite) Test4_TransferAndLeave() {
+	t := suite.T()
+
+	// The owner can't leave a group with other members
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/leave", suite.groupID), suite.ownerToken, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/transfer", suite.groupID), suite.ownerToken,
+		map[string]interface{}{"user_id": suite.memberID})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	members := suite.members()
+	require.Len(t, members, 2)
+	assert.Equal(t, float64(suite.memberID), members[0]["user_id"])
+	assert.Equal(t, "owner", members[0]["role"])
+	assert.Equal(t, "caregiver", members[1]["role"])
+
+	resp = doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/leave", suite.groupID), suite.ownerToken, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	var groups []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/groups", suite.ownerToken, nil), &groups)
+	assert.Empty(t, groups)
+}
+
+func (suite *GroupsTestSuite) Test5_LastMemberLeaves() {
+	t := suite.T()
+
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/groups/%d/leave", suite.groupID), suite.memberToken, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	// The group is gone together with its shared kits
+	var kits []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/kits", suite.memberToken, nil), &kits)
+	assert.Empty(t, kits)
+}
+
+func TestGroupsSuite(t *testing.T) {
+	suite.Run(t, new(GroupsTestSuite))
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type InteractionsTestSuite struct {
+	suite.Suite
+	token string
+}
+
+type warning struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	DrugIDs  []int  `json:"drug_ids"`
+}
+
+func (suite *InteractionsTestSuite) SetupSuite() {
+	// A fresh user, so drugs of other suites do not interfere
+	email := fmt.Sprintf("interactions_%d@example.com", time.Now().UnixNano())
+	suite.token = signUpUser(suite.T(), "Interactions", email, "secure123")
+
+	resp := doRequest(suite.T(), "POST", "/auth/me", suite.token, map[string]interface{}{
+		"allergies": "Penicillin, pollen",
+	})
+	requireOK(suite.T(), resp)
+	resp.Body.Close()
+}
+
+// addDrug adds a drug and returns the warnings of the response.
+func (suite *InteractionsTestSuite) addDrug(name string) []warning {
+	t := suite.T()
+	resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":   name,
+		"expiry": time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+	})
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data struct {
+			Warnings []warning `json:"warnings"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result.Data.Warnings
+}
+
+func (suite *InteractionsTestSuite) Test1_NoWarnings() {
+	assert.Empty(suite.T(), suite.addDrug("Warfarin"))
+}
+
+func (suite *InteractionsTestSuite) Test2_DrugInteraction() {
+	warnings := suite.addDrug("Nurofen Forte")
+	require.Len(suite.T(), warnings, 1)
+	assert.Equal(suite.T(), "interaction", warnings[0].Kind)
+	assert.Equal(suite.T(), "major", warnings[0].Severity)
+	assert.Equal(suite.T(), "interaction:anticoagulants+nsaids", warnings[0].Rule)
+	assert.Len(suite.T(), warnings[0].DrugIDs, 2)
+}
+
+func (suite *InteractionsTestSuite) Test3_Allergy() {
+	warnings := suite.addDrug("Amoxicillin 500")
+	require.Len(suite.T(), warnings, 1)
+	assert.Equal(suite.T(), "allergy", warnings[0].Kind)
+	assert.Equal(suite.T(), "contraindicated", warnings[0].Severity)
+	assert.Equal(suite.T(), "allergy:penicillins", warnings[0].Rule)
+}
+
+func (suite *InteractionsTestSuite) Test4_CabinetCheck() {
+	t := suite.T()
+	resp := doRequest(t, "GET", "/auth/drugs/interactions", suite.token, nil)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data []warning `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	// Most severe first
+	require.Len(t, result.Data, 2)
+	assert.Equal(t, "contraindicated", result.Data[0].Severity)
+	assert.Equal(t, "major", result.Data[1].Severity)
+}
+
+// check returns the warnings of the cabinet check with the query.
+func (suite *InteractionsTestSuite) check(query string) []warning {
+	var warnings []warning
+	decodeData(suite.T(), doRequest(suite.T(), "GET", "/auth/drugs/interactions"+query, suite.token, nil), &warnings)
+	return warnings
+}
+
+func (suite *InteractionsTestSuite) Test5_SharedCabinet() {
+	t := suite.T()
+
+	var group map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/groups", suite.token, map[string]interface{}{"name": "Interactions household"}), &group)
+	resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":     "Metronidazole",
+		"expiry":   time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+		"group_id": group["id"],
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	// Shared drugs are checked together with the personal ones
+	warnings := suite.check("")
+	require.Len(t, warnings, 3)
+	rules := []string{}
+	for _, warning := range warnings {
+		rules = append(rules, warning.Rule)
+	}
+	assert.Contains(t, rules, "interaction:warfarin+metronidazole")
+	assert.Len(t, suite.check("?scope=personal"), 2)
+	assert.Empty(t, suite.check(fmt.Sprintf("?scope=group&group_id=%v", group["id"])))
+
+	resp = doRequest(t, "GET", "/auth/drugs/interactions?scope=everything", suite.token, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestInteractionsSuite(t *testing.T) {
+	suite.Run(t, new(InteractionsTestSuite))
+}
@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type KitsTestSuite struct {
+	suite.Suite
+	token     string
+	carKitID  int
+	homeKitID int
+	drugID    int
+}
+
+func (suite *KitsTestSuite) SetupSuite() {
+	// A fresh user, so drugs of other suites do not interfere
+	email := fmt.Sprintf("kits_%d@example.com", time.Now().UnixNano())
+	suite.token = signUpUser(suite.T(), "Kits", email, "secure123")
+}
+
+// createKit creates a kit and returns its ID.
+func (suite *KitsTestSuite) createKit(name string) int {
+	resp := doRequest(suite.T(), "POST", "/auth/kits", suite.token, map[string]interface{}{"name": name})
+	defer resp.Body.Close()
+	requireOK(suite.T(), resp)
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return int(result.Data["id"].(float64))
+}
+
+// drugs returns the drugs list for the given query.
+func (suite *KitsTestSuite) drugs(query string) []map[string]interface{} {
+	resp := doRequest(suite.T(), "GET", "/auth/drugs"+query, suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(suite.T(), resp)
+
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return result.Data
+}
+
+func (suite *KitsTestSuite) Test1_CreateKitsAndDrugs() {
+	t := suite.T()
+	suite.carKitID = suite.createKit("Car kit")
+	suite.homeKitID = suite.createKit("Home cabinet")
+
+	resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":   "Bandage",
+		"expiry": time.Now().AddDate(2, 0, 0).UTC().Format(time.RFC3339),
+		"kit_id": suite.carKitID,
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	resp = doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":   "Paracetamol",
+		"expiry": time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	empty := doRequest(t, "POST", "/auth/kits", suite.token, map[string]interface{}{"name": " "})
+	empty.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, empty.StatusCode)
+}
+
+func (suite *KitsTestSuite) Test2_FilterByKit() {
+	t := suite.T()
+
+	inCar := suite.drugs(fmt.Sprintf("?kit_id=%d", suite.carKitID))
+	require.Len(t, inCar, 1)
+	assert.Equal(t, "Bandage", inCar[0]["name"])
+	assert.Equal(t, "Car kit", inCar[0]["location"])
+	suite.drugID = int(inCar[0]["id"].(float64))
+
+	unassigned := suite.drugs("?kit_id=none")
+	require.Len(t, unassigned, 1)
+	assert.Equal(t, "Paracetamol", unassigned[0]["name"])
+
+	assert.Len(t, suite.drugs(""), 2)
+}
+
+func (suite *KitsTestSuite) Test3_MoveAndSummaries() {
+	t := suite.T()
+
+	resp := doRequest(t, "POST", "/auth/drugs/move", suite.token, map[string]interface{}{
+		"drug_ids": []int{suite.drugID},
+		"kit_id":   suite.homeKitID,
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	kitsResp := doRequest(t, "GET", "/auth/kits", suite.token, nil)
+	defer kitsResp.Body.Close()
+	requireOK(t, kitsResp)
+
+	var kits struct {
+		Data []struct {
+			ID      int    `json:"id"`
+			Name    string `json:"name"`
+			Summary struct {
+				ItemCount     int     `json:"item_count"`
+				NearestExpiry *string `json:"nearest_expiry"`
+			} `json:"summary"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(kitsResp.Body).Decode(&kits))
+	require.Len(t, kits.Data, 2)
+	assert.Equal(t, 0, kits.Data[0].Summary.ItemCount)
+	assert.Nil(t, kits.Data[0].Summary.NearestExpiry)
+	assert.Equal(t, 1, kits.Data[1].Summary.ItemCount)
+	assert.NotNil(t, kits.Data[1].Summary.NearestExpiry)
+
+	// Grouped list: both kits and the drugs without a kit
+	grouped := suite.drugs("?group_by=kit")
+	require.Len(t, grouped, 3)
+	assert.Nil(t, grouped[2]["kit"])
+	assert.Len(t, grouped[1]["drugs"], 1)
+}
+
+func (suite *KitsTestSuite) Test4_ForeignKit() {
+	t := suite.T()
+	other := getAuthToken(t)
+
+	resp := doRequest(t, "GET", fmt.Sprintf("/auth/drugs?kit_id=%d", suite.carKitID), other, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = doRequest(t, "POST", fmt.Sprintf("/auth/kits/remove/%d", suite.carKitID), other, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *KitsTestSuite) Test5_CheckAgainstTemplate() {
+	t := suite.T()
+
+	templates := doRequest(t, "GET", "/auth/kit_templates", suite.token, nil)
+	templates.Body.Close()
+	requireOK(t, templates)
+
+	// The car kit has 4 fresh masks and an expired tourniquet
+	for name, years := range map[string]int{"Medical masks": 1, "Tourniquet": -1} {
+		resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+			"name":   name,
+			"expiry": time.Now().AddDate(years, 0, 0).UTC().Format(time.RFC3339),
+			"amount": "4 pieces",
+			"kit_id": suite.carKitID,
+		})
+		requireOK(t, resp)
+		resp.Body.Close()
+	}
+
+	resp := doRequest(t, "GET", fmt.Sprintf("/auth/kits/%d/check?template=car_ru", suite.carKitID), suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var report struct {
+		Data struct {
+			Complete bool     `json:"complete"`
+			Missing  []string `json:"missing"`
+			Expired  []string `json:"expired"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.False(t, report.Data.Complete)
+	assert.Contains(t, report.Data.Missing, "scissors")
+	assert.NotContains(t, report.Data.Missing, "masks")
+	assert.Equal(t, []string{"tourniquet"}, report.Data.Expired)
+
+	unknown := doRequest(t, "GET", fmt.Sprintf("/auth/kits/%d/check?template=spaceship", suite.carKitID), suite.token, nil)
+	unknown.Body.Close()
+	assert.Equal(t, http.StatusNotFound, unknown.StatusCode)
+}
+
+func (suite *KitsTestSuite) Test6_RemoveKit() {
+	t := suite.T()
+
+	// Taking a drug out of its kit clears the location set from the kit name
+	resp := doRequest(t, "POST", "/auth/drugs/move", suite.token, map[string]interface{}{
+		"drug_ids": []int{suite.drugID},
+		"kit_id":   nil,
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+	for _, drug := range suite.drugs("?kit_id=none") {
+		assert.Equal(t, "", drug["location"], drug["name"])
+	}
+
+	resp = doRequest(t, "POST", "/auth/drugs/move", suite.token, map[string]interface{}{
+		"drug_ids": []int{suite.drugID},
+		"kit_id":   suite.homeKitID,
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	resp = doRequest(t, "POST", fmt.Sprintf("/auth/kits/remove/%d", suite.homeKitID), suite.token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	// Drugs of a removed kit stay in the cabinet without its location
+	unassigned := suite.drugs("?kit_id=none")
+	assert.Len(t, unassigned, 2)
+	for _, drug := range unassigned {
+		assert.Equal(t, "", drug["location"], drug["name"])
+	}
+}
+
+func TestKitsSuite(t *testing.T) {
+	suite.Run(t, new(KitsTestSuite))
+}
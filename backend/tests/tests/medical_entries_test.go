@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type MedicalEntriesTestSuite struct {
+	suite.Suite
+	token     string
+	allergyID int
+}
+
+func (suite *MedicalEntriesTestSuite) SetupSuite() {
+	suite.token = signUpUser(suite.T(), "Entries User", fmt.Sprintf("entries_%d@example.com", time.Now().UnixNano()), "secure123")
+}
+
+func (suite *MedicalEntriesTestSuite) Test1_ICD10Lookup() {
+	t := suite.T()
+
+	var codes []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/icd10?q=j45", suite.token, nil), &codes)
+	require.NotEmpty(t, codes)
+	for _, code := range codes {
+		assert.Regexp(t, `^J45`, code["code"])
+	}
+
+	decodeData(t, doRequest(t, "GET", "/auth/icd10?q=астма&limit=1", suite.token, nil), &codes)
+	assert.Len(t, codes, 1)
+
+	resp := doRequest(t, "GET", "/auth/icd10?q=", suite.token, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *MedicalEntriesTestSuite) Test2_AddAllergy() {
+	t := suite.T()
+
+	for _, invalid := range []map[string]string{
+		{},
+		{"icd10_code": "penicillin"},
+		{"text": "Penicillin", "severity": "deadly"},
+		{"text": "Penicillin", "onset_date": "01.06.2015"},
+	} {
+		resp := doRequest(t, "POST", "/auth/allergies", suite.token, invalid)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, invalid)
+	}
+
+	var entry map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/allergies", suite.token, map[string]string{
+		"icd10_code": "z88.0",
+		"substance":  "amoxicillin",
+		"reaction":   "anaphylaxis",
+		"severity":   "life_threatening",
+		"onset_date": "2015-06-01",
+	}), &entry)
+	suite.allergyID = int(entry["id"].(float64))
+	assert.Equal(t, "allergy", entry["kind"])
+	assert.Equal(t, "Z88.0", entry["icd10_code"])
+	assert.NotEmpty(t, entry["text"], "text defaults to the title of the code")
+
+	var condition map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/conditions", suite.token, map[string]string{"icd10_code": "J45.0"}), &condition)
+	assert.Equal(t, "condition", condition["kind"])
+}
+
+func (suite *MedicalEntriesTestSuite) Test3_ListAndUpdate() {
+	t := suite.T()
+
+	var entries []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/allergies", suite.token, nil), &entries)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "amoxicillin", entries[0]["substance"])
+
+	var entry map[string]interface{}
+	decodeData(t, doRequest(t, "PUT", fmt.Sprintf("/auth/allergies/%d", suite.allergyID), suite.token, map[string]string{
+		"substance": "amoxicillin",
+		"reaction":  "rash",
+		"severity":  "moderate",
+	}), &entry)
+	assert.Equal(t, "rash", entry["reaction"])
+	assert.Nil(t, entry["onset_date"])
+
+	// Entries are only found under their own kind
+	resp := doRequest(t, "PUT", fmt.Sprintf("/auth/conditions/%d", suite.allergyID), suite.token, map[string]string{"text": "x"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *MedicalEntriesTestSuite) Test4_InteractionWarning() {
+	t := suite.T()
+
+	var result struct {
+		Warnings []map[string]interface{} `json:"warnings"`
+	}
+	decodeData(t, doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":   "Amoxicillin",
+		"expiry": time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+	}), &result)
+	require.NotEmpty(t, result.Warnings)
+	assert.Equal(t, "allergy", result.Warnings[0]["kind"])
+	assert.Contains(t, result.Warnings[0]["message"], "reaction: rash")
+}
+
+func (suite *MedicalEntriesTestSuite) Test5_LegacyProfileFields() {
+	t := suite.T()
+
+	for _, text := range []string{"Pollen", "Pollen, dust"} {
+		resp := doRequest(t, "POST", "/auth/me", suite.token, map[string]string{"allergies": text})
+		requireOK(t, resp)
+		resp.Body.Close()
+	}
+
+	// The free-text entry is replaced, the coded one is kept
+	var me struct {
+		Allergies []map[string]interface{} `json:"allergies"`
+	}
+	resp := doRequest(t, "GET", "/auth/me", suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&me))
+	require.Len(t, me.Allergies, 2)
+	assert.Equal(t, "amoxicillin", me.Allergies[0]["substance"])
+	assert.Equal(t, "Pollen, dust", me.Allergies[1]["text"])
+}
+
+func (suite *MedicalEntriesTestSuite) Test6_RemoveEntry() {
+	t := suite.T()
+
+	other := getAuthToken(t)
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/allergies/remove/%d", suite.allergyID), other, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = doRequest(t, "POST", fmt.Sprintf("/auth/allergies/remove/%d", suite.allergyID), suite.token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	var entries []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/allergies", suite.token, nil), &entries)
+	assert.Len(t, entries, 1)
+}
+
+func TestMedicalEntriesSuite(t *testing.T) {
+	suite.Run(t, new(MedicalEntriesTestSuite))
+}
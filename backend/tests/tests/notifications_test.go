@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationsTestSuite struct {
+	suite.Suite
+	token         string
+	drugID        int
+	notifications []map[string]interface{}
+}
+
+func (suite *NotificationsTestSuite) SetupSuite() {
+	t := suite.T()
+	suite.token = signUpUser(t, "Notified User", fmt.Sprintf("notifications_%d@example.com", time.Now().UnixNano()), "secure123")
+
+	resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":      "Cetirizine",
+		"expiry":    time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+		"amount":    "2 tablets",
+		"low_stock": 1,
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+	suite.drugID = lastID(t, doRequest(t, "GET", "/auth/drugs", suite.token, nil))
+}
+
+// list returns the user's notifications, only unread ones if asked.
+func (suite *NotificationsTestSuite) list(unread bool) []map[string]interface{} {
+	var notifications []map[string]interface{}
+	decodeData(suite.T(), doRequest(suite.T(), "GET", fmt.Sprintf("/auth/notifications?unread=%v", unread), suite.token, nil), &notifications)
+	return notifications
+}
+
+func (suite *NotificationsTestSuite) Test1_List() {
+	t := suite.T()
+	assert.Empty(t, suite.list(false))
+
+	// Taking both tablets makes the drug run low and then run out
+	startsAt := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Minute)
+	var schedule map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/schedules", suite.token, map[string]interface{}{
+		"drug_id":   suite.drugID,
+		"rrule":     "FREQ=HOURLY;COUNT=2",
+		"starts_at": startsAt.Format(time.RFC3339),
+		"quantity":  1,
+	}), &schedule)
+	for _, at := range []time.Time{startsAt, startsAt.Add(time.Hour)} {
+		resp := doRequest(t, "POST", "/auth/doses/log", suite.token, map[string]interface{}{
+			"schedule_id":  schedule["id"],
+			"scheduled_at": at.Format(time.RFC3339),
+			"status":       "taken",
+		})
+		requireOK(t, resp)
+		resp.Body.Close()
+	}
+
+	suite.notifications = suite.list(false)
+	require.Len(t, suite.notifications, 2)
+	// Newest first
+	assert.Equal(t, "Cetirizine has run out", suite.notifications[0]["title"])
+	assert.Equal(t, "Cetirizine is running low", suite.notifications[1]["title"])
+	for _, notification := range suite.notifications {
+		assert.Equal(t, "low_stock", notification["kind"])
+		assert.Equal(t, float64(suite.drugID), notification["drug_id"])
+		assert.Nil(t, notification["read_at"])
+		assert.NotContains(t, notification, "user_id")
+	}
+	assert.Len(t, suite.list(true), 2)
+}
+
+func (suite *NotificationsTestSuite) Test2_MarkRead() {
+	t := suite.T()
+	require.Len(t, suite.notifications, 2)
+	id := int(suite.notifications[0]["id"].(float64))
+
+	// Notifications of other users are not found
+	other := getAuthToken(t)
+	foreign := doRequest(t, "POST", fmt.Sprintf("/auth/notifications/read/%d", id), other, nil)
+	foreign.Body.Close()
+	assert.Equal(t, http.StatusNotFound, foreign.StatusCode)
+	missing := doRequest(t, "POST", "/auth/notifications/read/999999999", suite.token, nil)
+	missing.Body.Close()
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+
+	for i := 0; i < 2; i++ {
+		resp := doRequest(t, "POST", fmt.Sprintf("/auth/notifications/read/%d", id), suite.token, nil)
+		requireOK(t, resp)
+		resp.Body.Close()
+	}
+
+	unread := suite.list(true)
+	require.Len(t, unread, 1)
+	assert.Equal(t, suite.notifications[1]["id"], unread[0]["id"])
+	all := suite.list(false)
+	require.Len(t, all, 2)
+	assert.NotNil(t, all[0]["read_at"])
+}
+
+func (suite *NotificationsTestSuite) Test3_MarkAllRead() {
+	t := suite.T()
+
+	resp := doRequest(t, "POST", "/auth/notifications/read_all", suite.token, nil)
+	requireOK(t, resp)
+	resp.Body.Close()
+
+	assert.Empty(t, suite.list(true))
+	for _, notification := range suite.list(false) {
+		assert.NotNil(t, notification["read_at"])
+	}
+}
+
+func TestNotificationsSuite(t *testing.T) {
+	suite.Run(t, new(NotificationsTestSuite))
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// OwnershipTestSuite checks that user B cannot access resources of user A.
+type OwnershipTestSuite struct {
+	suite.Suite
+	tokenA string
+	tokenB string
+	drugID int
+	docID  int
+	chatID int
+}
+
+func (suite *OwnershipTestSuite) SetupSuite() {
+	suite.tokenA = getAuthToken(suite.T())
+
+	email := fmt.Sprintf("intruder_%d@example.com", time.Now().UnixNano())
+	suite.tokenB = signUpUser(suite.T(), "Intruder", email, "secure123")
+}
+
+func (suite *OwnershipTestSuite) Test1_CreateResourcesOfA() {
+	t := suite.T()
+
+	resp := doRequest(t, "POST", "/auth/drugs/add", suite.tokenA, map[string]interface{}{
+		"name":   "Paracetamol",
+		"expiry": time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+	suite.drugID = lastID(t, doRequest(t, "GET", "/auth/drugs", suite.tokenA, nil))
+
+	resp = doRequest(t, "POST", "/auth/documents/add", suite.tokenA, map[string]interface{}{
+		"name":      "Private Report",
+		"type":      "report",
+		"date":      time.Now().UTC().Format(time.RFC3339),
+		"file_data": base64.StdEncoding.EncodeToString([]byte("secret")),
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+	suite.docID = lastID(t, doRequest(t, "GET", "/auth/documents", suite.tokenA, nil))
+
+	resp = doRequest(t, "POST", "/auth/new_chat", suite.tokenA, nil)
+	requireOK(t, resp)
+	var chat struct {
+		Data float64 `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&chat))
+	resp.Body.Close()
+	suite.chatID = int(chat.Data)
+}
+
+func (suite *OwnershipTestSuite) Test2_CannotReadForeignChat() {
+	resp := doRequest(suite.T(), "GET", fmt.Sprintf("/auth/chats/%d", suite.chatID), suite.tokenB, nil)
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *OwnershipTestSuite) Test3_CannotPostIntoForeignChat() {
+	resp := doRequest(suite.T(), "POST", "/auth/send_message", suite.tokenB, map[string]interface{}{
+		"chat_id": suite.chatID,
+		"text":    "Hello from B",
+	})
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *OwnershipTestSuite) Test4_CannotModifyForeignDrug() {
+	resp := doRequest(suite.T(), "PUT", fmt.Sprintf("/auth/drugs/%d", suite.drugID), suite.tokenB, map[string]interface{}{
+		"name": "Stolen",
+	})
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	resp = doRequest(suite.T(), "POST", fmt.Sprintf("/auth/drugs/remove/%d", suite.drugID), suite.tokenB, nil)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *OwnershipTestSuite) Test5_CannotDeleteForeignDocument() {
+	resp := doRequest(suite.T(), "POST", fmt.Sprintf("/auth/documents/remove/%d", suite.docID), suite.tokenB, nil)
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *OwnershipTestSuite) Test6_ResourcesOfAUntouched() {
+	t := suite.T()
+	assert.Equal(t, suite.drugID, lastID(t, doRequest(t, "GET", "/auth/drugs", suite.tokenA, nil)))
+	assert.Equal(t, suite.docID, lastID(t, doRequest(t, "GET", "/auth/documents", suite.tokenA, nil)))
+
+	// B does not see A's resources in its own lists
+	resp := doRequest(t, "GET", "/auth/drugs", suite.tokenB, nil)
+	defer resp.Body.Close()
+	var drugs struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&drugs))
+	assert.Empty(t, drugs.Data)
+}
+
+func (suite *OwnershipTestSuite) Test7_DeleteAccountOfB() {
+	t := suite.T()
+
+	resp := doRequest(t, "DELETE", "/auth/me", suite.tokenB, map[string]string{"password": "wrong-password"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, "DELETE", "/auth/me", suite.tokenB, map[string]string{"password": "secure123"})
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data map[string]float64 `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, float64(1), result.Data["medical_cards"])
+	assert.GreaterOrEqual(t, result.Data["sessions"], float64(1))
+
+	// The deleted account cannot be used anymore
+	me := doRequest(t, "GET", "/auth/me", suite.tokenB, nil)
+	me.Body.Close()
+	assert.NotEqual(t, http.StatusOK, me.StatusCode)
+}
+
+func (suite *OwnershipTestSuite) TearDownSuite() {
+	t := suite.T()
+	resp := doRequest(t, "POST", fmt.Sprintf("/auth/drugs/remove/%d", suite.drugID), suite.tokenA, nil)
+	resp.Body.Close()
+	resp = doRequest(t, "POST", fmt.Sprintf("/auth/documents/remove/%d", suite.docID), suite.tokenA, nil)
+	resp.Body.Close()
+}
+
+// lastID returns the ID of the last element of a list response and closes its body.
+func lastID(t *testing.T, resp *http.Response) int {
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.NotEmpty(t, result.Data)
+	return int(result.Data[len(result.Data)-1]["id"].(float64))
+}
+
+func TestOwnershipSuite(t *testing.T) {
+	suite.Run(t, new(OwnershipTestSuite))
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type SchedulesTestSuite struct {
+	suite.Suite
+	token      string
+	drugID     int
+	scheduleID int
+	startsAt   time.Time
+}
+
+func (suite *SchedulesTestSuite) SetupSuite() {
+	t := suite.T()
+	suite.token = getAuthToken(t)
+
+	resp := doRequest(t, "POST", "/auth/drugs/add", suite.token, map[string]interface{}{
+		"name":      "Amoxicillin",
+		"expiry":    time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+		"amount":    "2 capsules",
+		"low_stock": 1,
+	})
+	requireOK(t, resp)
+	resp.Body.Close()
+	suite.drugID = lastID(t, doRequest(t, "GET", "/auth/drugs", suite.token, nil))
+
+	// First dose an hour ago, so it is overdue now
+	suite.startsAt = time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+}
+
+func (suite *SchedulesTestSuite) Test1_CreateSchedule() {
+	t := suite.T()
+
+	invalid := doRequest(t, "POST", "/auth/schedules", suite.token, map[string]interface{}{
+		"drug_id":   suite.drugID,
+		"rrule":     "FREQ=MONTHLY",
+		"starts_at": suite.startsAt.Format(time.RFC3339),
+	})
+	invalid.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, invalid.StatusCode)
+
+	resp := doRequest(t, "POST", "/auth/schedules", suite.token, map[string]interface{}{
+		"drug_id":   suite.drugID,
+		"rrule":     "FREQ=HOURLY;INTERVAL=8;COUNT=15",
+		"starts_at": suite.startsAt.Format(time.RFC3339),
+		"quantity":  1,
+	})
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	suite.scheduleID = int(result.Data["id"].(float64))
+}
+
+func (suite *SchedulesTestSuite) Test2_UpcomingDoses() {
+	t := suite.T()
+	resp := doRequest(t, "GET", "/auth/doses/upcoming?hours=24", suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	// The overdue dose and the next two within 24 hours
+	count := 0
+	for _, dose := range result.Data {
+		if int(dose["schedule_id"].(float64)) == suite.scheduleID {
+			count++
+			assert.Equal(t, "pending", dose["status"])
+		}
+	}
+	assert.Equal(t, 3, count)
+}
+
+func (suite *SchedulesTestSuite) Test3_LogDose() {
+	t := suite.T()
+
+	wrongTime := doRequest(t, "POST", "/auth/doses/log", suite.token, map[string]interface{}{
+		"schedule_id":  suite.scheduleID,
+		"scheduled_at": suite.startsAt.Add(time.Hour).Format(time.RFC3339),
+		"status":       "taken",
+	})
+	wrongTime.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, wrongTime.StatusCode)
+
+	resp := doRequest(t, "POST", "/auth/doses/log", suite.token, map[string]interface{}{
+		"schedule_id":  suite.scheduleID,
+		"scheduled_at": suite.startsAt.Format(time.RFC3339),
+		"status":       "taken",
+	})
+	resp.Body.Close()
+	requireOK(t, resp)
+}
+
+func (suite *SchedulesTestSuite) Test4_Adherence() {
+	t := suite.T()
+	resp := doRequest(t, "GET", fmt.Sprintf("/auth/drugs/%d/adherence", suite.drugID), suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var result struct {
+		Data struct {
+			Taken   int                      `json:"taken"`
+			Missed  int                      `json:"missed"`
+			Rate    float64                  `json:"rate"`
+			History []map[string]interface{} `json:"history"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	assert.Equal(t, 1, result.Data.Taken)
+	assert.Equal(t, 0, result.Data.Missed)
+	assert.Equal(t, 1.0, result.Data.Rate)
+	assert.Len(t, result.Data.History, 1)
+}
+
+func (suite *SchedulesTestSuite) Test5_StockDecremented() {
+	t := suite.T()
+	resp := doRequest(t, "GET", "/auth/drugs", suite.token, nil)
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	var drugs struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&drugs))
+	for _, drug := range drugs.Data {
+		if int(drug["id"].(float64)) == suite.drugID {
+			assert.Equal(t, 1.0, drug["quantity"])
+			assert.Equal(t, "1 capsules", drug["amount"])
+		}
+	}
+
+	// Reaching the threshold raises a restock notification
+	notifResp := doRequest(t, "GET", "/auth/notifications", suite.token, nil)
+	defer notifResp.Body.Close()
+	requireOK(t, notifResp)
+
+	var notifications struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(notifResp.Body).Decode(&notifications))
+	found := false
+	for _, notification := range notifications.Data {
+		if notification["kind"] == "low_stock" && int(notification["drug_id"].(float64)) == suite.drugID {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func (suite *SchedulesTestSuite) Test6_ConcurrentUndo() {
+	t := suite.T()
+
+	// Undoing the taken dose from several devices at once restores the stock once
+	var wg sync.WaitGroup
+	statuses := make([]int, 5)
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp := doRequest(t, "POST", "/auth/doses/log", suite.token, map[string]interface{}{
+				"schedule_id":  suite.scheduleID,
+				"scheduled_at": suite.startsAt.Format(time.RFC3339),
+				"status":       "skipped",
+			})
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	for _, status := range statuses {
+		assert.Equal(t, http.StatusOK, status)
+	}
+
+	var drugs []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/drugs", suite.token, nil), &drugs)
+	for _, drug := range drugs {
+		if int(drug["id"].(float64)) == suite.drugID {
+			assert.Equal(t, 2.0, drug["quantity"])
+		}
+	}
+}
+
+func (suite *SchedulesTestSuite) TearDownSuite() {
+	resp := doRequest(suite.T(), "POST", fmt.Sprintf("/auth/drugs/remove/%d", suite.drugID), suite.token, nil)
+	resp.Body.Close()
+}
+
+func TestSchedulesSuite(t *testing.T) {
+	suite.Run(t, new(SchedulesTestSuite))
+}
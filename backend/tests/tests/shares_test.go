@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ShareTestSuite struct {
+	suite.Suite
+	token string
+	docID int
+}
+
+// shareLink is the part of a share link the tests look at.
+type shareLink struct {
+	ID           int        `json:"id"`
+	URL          string     `json:"url"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	AccessCount  int        `json:"access_count"`
+	LastAccessed *time.Time `json:"last_accessed_at"`
+	PINProtected bool       `json:"pin_protected"`
+	Active       bool       `json:"active"`
+}
+
+func (suite *ShareTestSuite) SetupSuite() {
+	t := suite.T()
+	suite.token = signUpUser(t, "Share User", fmt.Sprintf("share_%d@example.com", time.Now().UnixNano()), "secure123")
+
+	var doc map[string]interface{}
+	decodeData(t, doRequest(t, "POST", "/auth/documents/add", suite.token, map[string]interface{}{
+		"name":      "Prescription",
+		"doctor":    "Dr. House",
+		"file_data": base64.StdEncoding.EncodeToString(pdfFile),
+	}), &doc)
+	suite.docID = int(doc["id"].(float64))
+}
+
+// share creates a share link of the document.
+func (suite *ShareTestSuite) share(payload map[string]interface{}) shareLink {
+	var link shareLink
+	decodeData(suite.T(), doRequest(suite.T(), "POST", fmt.Sprintf("/auth/documents/%d/share", suite.docID), suite.token, payload), &link)
+	return link
+}
+
+// open fetches a path of the share link without authentication.
+func (suite *ShareTestSuite) open(url, suffix, pin string) *http.Response {
+	req, err := http.NewRequest("GET", config.BaseURL+url[strings.Index(url, "/shared/"):]+suffix, nil)
+	require.NoError(suite.T(), err)
+	if pin != "" {
+		req.Header.Set("X-Share-PIN", pin)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(suite.T(), err)
+	return resp
+}
+
+func (suite *ShareTestSuite) Test1_OpenLink() {
+	t := suite.T()
+
+	link := suite.share(map[string]interface{}{"expires_in_hours": 2})
+	assert.True(t, link.Active)
+	assert.False(t, link.PINProtected)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), link.ExpiresAt, time.Minute)
+
+	var shared map[string]interface{}
+	resp := suite.open(link.URL, "", "")
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	decodeData(t, resp, &shared)
+	assert.Equal(t, "Prescription", shared["name"])
+	assert.Equal(t, "Dr. House", shared["doctor"])
+	assert.NotContains(t, shared, "user_id")
+
+	file := suite.open(link.URL, "/file", "")
+	defer file.Body.Close()
+	requireOK(t, file)
+	body, err := io.ReadAll(file.Body)
+	require.NoError(t, err)
+	assert.Equal(t, pdfFile, body)
+
+	// Both accesses are counted
+	var links []shareLink
+	decodeData(t, doRequest(t, "GET", fmt.Sprintf("/auth/documents/%d/shares", suite.docID), suite.token, nil), &links)
+	require.NotEmpty(t, links)
+	assert.Equal(t, link.ID, links[0].ID)
+	assert.Equal(t, 2, links[0].AccessCount)
+	assert.NotNil(t, links[0].LastAccessed)
+	// Only the hash of the token is kept, the link is not shown again
+	assert.Empty(t, links[0].URL)
+}
+
+func (suite *ShareTestSuite) Test2_PIN() {
+	t := suite.T()
+
+	invalid := doRequest(t, "POST", fmt.Sprintf("/auth/documents/%d/share", suite.docID), suite.token, map[string]interface{}{"pin": "12"})
+	invalid.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, invalid.StatusCode)
+
+	link := suite.share(map[string]interface{}{"pin": "4821"})
+	assert.True(t, link.PINProtected)
+
+	missing := suite.open(link.URL, "", "")
+	missing.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, missing.StatusCode)
+
+	wrong := suite.open(link.URL, "", "0000")
+	wrong.Body.Close()
+	assert.Equal(t, http.StatusForbidden, wrong.StatusCode)
+
+	requireOK(t, suite.open(link.URL, "", "4821"))
+
+	// Guessing the PIN revokes the link
+	for i := 0; i < 4; i++ {
+		resp := suite.open(link.URL, "", "1111")
+		resp.Body.Close()
+	}
+	locked := suite.open(link.URL, "", "4821")
+	locked.Body.Close()
+	assert.Equal(t, http.StatusNotFound, locked.StatusCode)
+}
+
+func (suite *ShareTestSuite) Test3_Revoke() {
+	t := suite.T()
+	link := suite.share(nil)
+
+	var revoked shareLink
+	decodeData(t, doRequest(t, "POST", fmt.Sprintf("/auth/documents/shares/revoke/%d", link.ID), suite.token, nil), &revoked)
+	assert.False(t, revoked.Active)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	gone := suite.open(link.URL, "", "")
+	gone.Body.Close()
+	assert.Equal(t, http.StatusNotFound, gone.StatusCode)
+
+	// Links of other users cannot be revoked, tampered tokens are not found
+	other := signUpUser(t, "Other", fmt.Sprintf("share_other_%d@example.com", time.Now().UnixNano()), "secure123")
+	foreign := doRequest(t, "POST", fmt.Sprintf("/auth/documents/shares/revoke/%d", suite.share(nil).ID), other, nil)
+	foreign.Body.Close()
+	assert.Equal(t, http.StatusNotFound, foreign.StatusCode)
+
+	url := []byte(suite.share(nil).URL)
+	if url[len(url)-10] == 'A' {
+		url[len(url)-10] = 'B'
+	} else {
+		url[len(url)-10] = 'A'
+	}
+	tampered := suite.open(string(url), "", "")
+	tampered.Body.Close()
+	assert.Equal(t, http.StatusNotFound, tampered.StatusCode)
+}
+
+func (suite *ShareTestSuite) Test4_ParallelGuesses() {
+	t := suite.T()
+	link := suite.share(map[string]interface{}{"pin": "4821"})
+
+	// Parallel requests cannot guess more PINs than sequential ones
+	statuses := make(chan int, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(statuses); i++ {
+		wg.Add(1)
+		go func(pin string) {
+			defer wg.Done()
+			resp := suite.open(link.URL, "", pin)
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}(fmt.Sprintf("%04d", i))
+	}
+	wg.Wait()
+	close(statuses)
+
+	checked := 0
+	for status := range statuses {
+		if status == http.StatusForbidden {
+			checked++
+		}
+	}
+	assert.LessOrEqual(t, checked, 5)
+
+	locked := suite.open(link.URL, "", "4821")
+	locked.Body.Close()
+	assert.Equal(t, http.StatusNotFound, locked.StatusCode)
+}
+
+func TestShareSuite(t *testing.T) {
+	suite.Run(t, new(ShareTestSuite))
+}
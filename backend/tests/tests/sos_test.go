@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// SOSTestSuite expects the server to run with NOTIFIER=fake.
+type SOSTestSuite struct {
+	suite.Suite
+	token   string
+	alertID int
+}
+
+func (suite *SOSTestSuite) SetupSuite() {
+	suite.token = signUpUser(suite.T(), "SOS User", fmt.Sprintf("sos_%d@example.com", time.Now().UnixNano()), "secure123")
+}
+
+func (suite *SOSTestSuite) Test1_RequiresContacts() {
+	t := suite.T()
+
+	resp := doRequest(t, "POST", "/auth/sos", suite.token, map[string]interface{}{})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Contacts need an address to send alerts to
+	resp = doRequest(t, "POST", "/auth/emergency/contacts", suite.token, map[string]string{"name": "Nobody"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *SOSTestSuite) Test2_SendAlert() {
+	t := suite.T()
+
+	for _, contact := range []map[string]string{
+		{"name": "Anna", "phone": "+79001234567", "email": "anna@example.invalid"},
+		{"name": "Home", "webhook_url": "https://example.com/hooks/sos"},
+	} {
+		resp := doRequest(t, "POST", "/auth/emergency/contacts", suite.token, contact)
+		requireOK(t, resp)
+		resp.Body.Close()
+	}
+
+	resp := doRequest(t, "POST", "/auth/sos", suite.token, map[string]interface{}{"latitude": 91, "longitude": 0})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var alert struct {
+		ID         int                      `json:"id"`
+		Latitude   float64                  `json:"latitude"`
+		Deliveries []map[string]interface{} `json:"deliveries"`
+	}
+	decodeData(t, doRequest(t, "POST", "/auth/sos", suite.token, map[string]interface{}{
+		"message":   "Fell down the stairs",
+		"latitude":  55.7558,
+		"longitude": 37.6173,
+	}), &alert)
+	suite.alertID = alert.ID
+	assert.Equal(t, 55.7558, alert.Latitude)
+
+	// The fake rejects addresses in the .invalid domain
+	require.Len(t, alert.Deliveries, 3)
+	assert.Equal(t, "sms", alert.Deliveries[0]["channel"])
+	assert.Equal(t, "sent", alert.Deliveries[0]["status"])
+	assert.Equal(t, "email", alert.Deliveries[1]["channel"])
+	assert.Equal(t, "failed", alert.Deliveries[1]["status"])
+	assert.NotEmpty(t, alert.Deliveries[1]["error"])
+	assert.Equal(t, "webhook", alert.Deliveries[2]["channel"])
+	assert.Equal(t, "sent", alert.Deliveries[2]["status"])
+}
+
+func (suite *SOSTestSuite) Test3_History() {
+	t := suite.T()
+
+	var alerts []map[string]interface{}
+	decodeData(t, doRequest(t, "GET", "/auth/sos", suite.token, nil), &alerts)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "Fell down the stairs", alerts[0]["message"])
+	assert.Len(t, alerts[0]["deliveries"], 3)
+
+	var alert map[string]interface{}
+	decodeData(t, doRequest(t, "GET", fmt.Sprintf("/auth/sos/%d", suite.alertID), suite.token, nil), &alert)
+	assert.Len(t, alert["deliveries"], 3)
+
+	other := getAuthToken(t)
+	resp := doRequest(t, "GET", fmt.Sprintf("/auth/sos/%d", suite.alertID), other, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *SOSTestSuite) Test4_DeleteAccount() {
+	t := suite.T()
+
+	var summary map[string]float64
+	decodeData(t, doRequest(t, "DELETE", "/auth/me", suite.token, map[string]string{"password": "secure123"}), &summary)
+	assert.Equal(t, 2.0, summary["emergency_contacts"])
+	assert.Equal(t, 1.0, summary["sos_alerts"])
+}
+
+func TestSOSSuite(t *testing.T) {
+	suite.Run(t, new(SOSTestSuite))
+}
@@ -38,6 +38,32 @@ func loadTestConfig() {
 	}
 }
 
+type authTokens struct {
+	Status       int    `json:"status"`
+	Data         string `json:"data"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func login(t *testing.T) authTokens {
+	loginPayload := map[string]string{
+		"email":    config.TestEmail,
+		"password": config.TestPassword,
+	}
+	body, _ := json.Marshal(loginPayload)
+
+	resp, err := http.Post(config.BaseURL+"/login", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result authTokens
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err)
+
+	return result
+}
+
 func getAuthToken(t *testing.T) string {
 	loginPayload := map[string]string{
 		"email":    config.TestEmail,
@@ -61,14 +87,53 @@ func getAuthToken(t *testing.T) string {
 	return result.Data
 }
 
-func cleanupTestUser(t *testing.T) {
-	token := getAuthToken(t)
-	req, _ := http.NewRequest("DELETE", config.BaseURL+"/auth/me", nil)
+func signUpUser(t *testing.T, name, email, password string) string {
+	payload := map[string]string{
+		"name":     name,
+		"email":    email,
+		"password": password,
+	}
+	body, _ := json.Marshal(payload)
+
+	resp, err := http.Post(config.BaseURL+"/signup", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	requireOK(t, resp)
+
+	var result struct {
+		Status int    `json:"status"`
+		Data   string `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err)
+
+	return result.Data
+}
+
+func doRequest(t *testing.T, method, url, token string, payload interface{}) *http.Response {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		require.NoError(t, err)
+		body = bytes.NewBuffer(data)
+	}
+
+	req, err := http.NewRequest(method, config.BaseURL+url, body)
+	require.NoError(t, err)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	require.NoError(t, err)
+	return resp
+}
+
+func cleanupTestUser(t *testing.T) {
+	token := getAuthToken(t)
+	resp := doRequest(t, "DELETE", "/auth/me", token, map[string]string{"password": config.TestPassword})
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -82,3 +147,14 @@ func requireOK(t *testing.T, resp *http.Response) {
 		t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, string(body))
 	}
 }
+
+// decodeData checks that the request succeeded and decodes the data field of the response into dst.
+func decodeData(t *testing.T, resp *http.Response, dst interface{}) {
+	defer resp.Body.Close()
+	requireOK(t, resp)
+
+	result := struct {
+		Data interface{} `json:"data"`
+	}{Data: dst}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+}
@@ -0,0 +1,262 @@
+package thumbnails
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// maxOperators limits the operators read from a content stream, so a crafted page
+// cannot keep the worker busy.
+const maxOperators = 500_000
+
+// operand is an operand of a content stream operator: float64, string, pdfName or []operand.
+// Dictionaries and the keywords true, false and null are kept as nil.
+type operand interface{}
+
+// pdfName is a name operand like /F1, without the slash.
+type pdfName string
+
+// parseContent reads a page content stream, see section 7.8.2 of the PDF 1.7 specification,
+// and calls handle for every operator with its operands. Inline images are skipped.
+func parseContent(data []byte, handle func(op string, args []operand)) {
+	l := &contentLexer{data: data}
+	var args []operand
+	for count := 0; count < maxOperators; {
+		value, keyword, ok := l.next()
+		if !ok {
+			return
+		}
+		switch {
+		case keyword == "":
+			args = append(args, value)
+		case keyword == "BI":
+			l.skipInlineImage()
+			args = args[:0]
+		case keyword == "true" || keyword == "false" || keyword == "null":
+			args = append(args, nil)
+		default:
+			handle(keyword, args)
+			args = args[:0]
+			count++
+		}
+	}
+}
+
+// contentLexer splits a content stream into operands and operator keywords.
+type contentLexer struct {
+	data []byte
+	pos  int
+}
+
+// isSpace reports whether c is PDF white-space.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isDelimiter reports whether c ends a name, number or keyword.
+func isDelimiter(c byte) bool {
+	return isSpace(c) || bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace moves past white-space and comments.
+func (l *contentLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// next returns the next operand, or the next operator as keyword. ok is false at the end of the stream.
+func (l *contentLexer) next() (value operand, keyword string, ok bool) {
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return nil, "", false
+		}
+		switch c := l.data[l.pos]; {
+		case c == '(':
+			return l.literalString(), "", true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			l.skipUntil(">>")
+			return nil, "", true
+		case c == '<':
+			return l.hexString(), "", true
+		case c == '[':
+			l.pos++
+			return l.array(), "", true
+		case c == '/':
+			l.pos++
+			return pdfName(l.word()), "", true
+		case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			number, _ := strconv.ParseFloat(l.word(), 64)
+			return number, "", true
+		case isDelimiter(c):
+			// Stray closing brackets of malformed streams
+			l.pos++
+		default:
+			return nil, l.word(), true
+		}
+	}
+}
+
+// word reads regular characters up to the next delimiter.
+func (l *contentLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// array reads operands up to the closing bracket, keywords inside arrays are dropped.
+func (l *contentLexer) array() []operand {
+	var values []operand
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return values
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return values
+		}
+		value, keyword, ok := l.next()
+		if !ok {
+			return values
+		}
+		if keyword == "" {
+			values = append(values, value)
+		}
+	}
+}
+
+// skipUntil moves past the next occurrence of end, nested dictionaries included.
+func (l *contentLexer) skipUntil(end string) {
+	for depth := 1; l.pos < len(l.data); {
+		switch {
+		case l.data[l.pos] == '(':
+			l.literalString()
+			continue
+		case bytes.HasPrefix(l.data[l.pos:], []byte("<<")):
+			depth++
+			l.pos += 2
+			continue
+		case bytes.HasPrefix(l.data[l.pos:], []byte(end)):
+			l.pos += len(end)
+			if depth--; depth == 0 {
+				return
+			}
+			continue
+		}
+		l.pos++
+	}
+}
+
+// literalString reads a string in parentheses, resolving escapes.
+func (l *contentLexer) literalString() string {
+	var buf []byte
+	l.pos++
+	for depth := 1; l.pos < len(l.data); l.pos++ {
+		c := l.data[l.pos]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				l.pos++
+				return string(buf)
+			}
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.data) {
+				return string(buf)
+			}
+			c = l.data[l.pos]
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// A backslash at the end of a line continues the string
+				if c == '\r' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					code := 0
+					for i := 0; i < 3 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						code = code*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					l.pos--
+					c = byte(code)
+				}
+			}
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
+
+// hexString reads a string in angle brackets, a missing last digit counts as 0.
+func (l *contentLexer) hexString() string {
+	var buf []byte
+	var digits []byte
+	for l.pos++; l.pos < len(l.data) && l.data[l.pos] != '>'; l.pos++ {
+		if c := l.data[l.pos]; !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	for i := 0; i < len(digits); i += 2 {
+		b, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		buf = append(buf, byte(b))
+	}
+	return string(buf)
+}
+
+// skipInlineImage moves past the data of an inline image: its dictionary up to ID,
+// the binary data and the EI operator.
+func (l *contentLexer) skipInlineImage() {
+	for {
+		_, keyword, ok := l.next()
+		if !ok {
+			return
+		}
+		if keyword == "ID" {
+			break
+		}
+	}
+	// The data ends at white-space, EI and a delimiter
+	for l.pos++; l.pos+2 < len(l.data); l.pos++ {
+		if isSpace(l.data[l.pos]) && l.data[l.pos+1] == 'E' && l.data[l.pos+2] == 'I' &&
+			(l.pos+3 == len(l.data) || isDelimiter(l.data[l.pos+3])) {
+			l.pos += 3
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
@@ -0,0 +1,191 @@
+package thumbnails
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/draw"
+)
+
+func init() {
+	// pdfcpu would otherwise create a configuration directory in the home of the user
+	api.DisableConfigDir()
+}
+
+// renderFirstPage renders the first page of a PDF at most size pixels on each side, see pageRenderer
+// for what is drawn. Pages whose content is only in form XObjects, as some scanners write them,
+// show the largest image of the page instead. Pages without any content return ErrUnsupported.
+func renderFirstPage(data []byte, size int) (page *image.RGBA, err error) {
+	// Malformed files can make the parser panic, they must not take the server down
+	defer func() {
+		if r := recover(); r != nil {
+			page, err = nil, fmt.Errorf("reading PDF: %v", r)
+		}
+	}()
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	conf.Cmd = model.EXTRACTIMAGES
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(data), conf)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.PageCount == 0 {
+		return nil, ErrUnsupported
+	}
+	_, _, attrs, err := ctx.PageDict(1, false)
+	if err != nil {
+		return nil, err
+	}
+	content, err := pdfcpu.ExtractPageContent(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	images, largest, err := pageImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	box := attrs.CropBox
+	if box == nil {
+		box = attrs.MediaBox
+	}
+	if box == nil || box.Width() < 1 || box.Height() < 1 {
+		if largest == nil {
+			return nil, ErrUnsupported
+		}
+		// A page of unknown size takes the proportions of its image
+		bounds := largest.Bounds()
+		box = types.RectForDim(float64(bounds.Dx()), float64(bounds.Dy()))
+	}
+
+	// Points are scaled up so fit keeps the proportions of small pages
+	width, height := fit(int(box.Width()*10), int(box.Height()*10), size)
+	renderer := newPageRenderer(width, height, box.LL.X, box.LL.Y, box.UR.X, box.UR.Y)
+	renderer.images = images
+	renderer.fonts = pageFonts(ctx, attrs.Resources)
+	renderer.render(stream)
+
+	if !renderer.drawn {
+		if largest == nil {
+			return nil, ErrUnsupported
+		}
+		drawCentered(renderer.page, largest)
+	}
+	return orient(renderer.page, rotation(attrs.Rotate)), nil
+}
+
+// pageImages decodes the images of the first page by resource name and returns the largest of them.
+func pageImages(ctx *model.Context) (map[string]image.Image, image.Image, error) {
+	extracted, err := pdfcpu.ExtractPageImages(ctx, 1, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images := map[string]image.Image{}
+	var largest image.Image
+	for _, img := range extracted {
+		// Masks only cut out parts of other images
+		if img.IsImgMask {
+			continue
+		}
+		raw, err := io.ReadAll(img)
+		if err != nil {
+			continue
+		}
+		decoded, err := decodeImage(raw)
+		if err != nil {
+			continue
+		}
+		images[img.Name] = decoded
+		if largest == nil || area(decoded.Bounds()) > area(largest.Bounds()) {
+			largest = decoded
+		}
+	}
+	return images, largest, nil
+}
+
+// pageFonts reads the code lengths and glyph widths of the fonts in the resources of a page.
+func pageFonts(ctx *model.Context, resources types.Dict) map[string]*fontMetrics {
+	fonts := map[string]*fontMetrics{}
+	obj, found := resources.Find("Font")
+	if !found {
+		return fonts
+	}
+	dict, err := ctx.DereferenceDict(obj)
+	if err != nil {
+		return fonts
+	}
+	for name, obj := range dict {
+		font, err := ctx.DereferenceDict(obj)
+		if err != nil || font == nil {
+			continue
+		}
+		metrics := &fontMetrics{}
+		fonts[name] = metrics
+		switch subtype := font.NameEntry("Subtype"); {
+		case subtype != nil && *subtype == "Type0":
+			// Composite fonts mostly use two byte codes, like the Identity-H encoding
+			metrics.twoByte = true
+			continue
+		case subtype != nil && *subtype == "Type3":
+			// Widths are in glyph space, which only the font matrix relates to text space
+			continue
+		}
+		if first, found := font.Find("FirstChar"); found {
+			if value, err := ctx.DereferenceNumber(first); err == nil {
+				metrics.firstChar = int(value)
+			}
+		}
+		if widths, found := font.Find("Widths"); found {
+			array, err := ctx.DereferenceArray(widths)
+			if err != nil {
+				continue
+			}
+			for _, width := range array {
+				value, _ := ctx.DereferenceNumber(width)
+				metrics.widths = append(metrics.widths, value)
+			}
+		}
+	}
+	return fonts
+}
+
+// drawCentered draws the image as large as it fits onto the center of the page.
+func drawCentered(page *image.RGBA, img image.Image) {
+	bounds := img.Bounds()
+	width, height := page.Bounds().Dx(), page.Bounds().Dy()
+	ratio := min(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()))
+	imgWidth, imgHeight := max(1, int(float64(bounds.Dx())*ratio)), max(1, int(float64(bounds.Dy())*ratio))
+	x, y := (width-imgWidth)/2, (height-imgHeight)/2
+	draw.CatmullRom.Scale(page, image.Rect(x, y, x+imgWidth, y+imgHeight), img, bounds, draw.Over, nil)
+}
+
+// rotation returns the EXIF orientation turning a page like its Rotate entry,
+// which is a multiple of 90 degrees clockwise.
+func rotation(degrees int) int {
+	switch (degrees%360 + 360) % 360 {
+	case 90:
+		return 6
+	case 180:
+		return 3
+	case 270:
+		return 8
+	}
+	return 1
+}
+
+// area returns the number of pixels in the rectangle.
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
@@ -0,0 +1,576 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/vector"
+)
+
+// Limits of the page renderer
+const (
+	maxStateDepth = 64  // Nested q operators
+	curveSegments = 8   // Lines a Bézier curve is drawn with
+	pixelBudget   = 200 // Times the page area that may be painted
+)
+
+// Shape of the lines drawn for text, relative to the font size. Glyphs are not drawn,
+// at thumbnail size text reads as grey lines anyway.
+const (
+	glyphWidth  = 0.5 // Advance of glyphs whose font has no widths
+	textBottom  = 0.1 // Lower edge of the line above the baseline
+	textTop     = 0.6 // Upper edge of the line above the baseline
+	textOpacity = 0.5 // Text is drawn lighter than its color, like small type looks
+)
+
+// matrix is a PDF transformation matrix [a b c d e f], mapping x, y to a*x + c*y + e, b*x + d*y + f.
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// translation returns the matrix moving points by x, y.
+func translation(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// mul returns the transformation m followed by n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// apply transforms a point.
+func (m matrix) apply(x, y float64) point {
+	return point{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}
+}
+
+// point is a position on the thumbnail in pixels.
+type point struct{ x, y float64 }
+
+// graphicsState is the part of the PDF graphics state the renderer follows.
+type graphicsState struct {
+	ctm           matrix // Current transformation matrix, user space to page space
+	fill          color.RGBA
+	stroke        color.RGBA
+	fillPattern   bool // Whether fills use a pattern, those are not painted
+	strokePattern bool
+	lineWidth     float64
+}
+
+// fontMetrics is what the renderer knows about a font: how long its character codes are
+// and how far its glyphs advance.
+type fontMetrics struct {
+	twoByte   bool      // Whether codes take two bytes, as in Type0 fonts
+	firstChar int       // Code of the first width
+	widths    []float64 // Advances in thousandths of the font size, zero if unknown
+}
+
+// advance returns the advance of the glyph of a one byte code in units of the font size.
+func (f *fontMetrics) advance(code int) float64 {
+	if f != nil && code >= f.firstChar && code-f.firstChar < len(f.widths) && f.widths[code-f.firstChar] > 0 {
+		return f.widths[code-f.firstChar] / 1000
+	}
+	return glyphWidth
+}
+
+// textState is the PDF text state, see section 9.3 of the PDF 1.7 specification.
+type textState struct {
+	tm, tlm   matrix  // Text matrix and text line matrix
+	size      float64 // Font size
+	leading   float64
+	charSpace float64
+	wordSpace float64
+	scale     float64 // Horizontal scaling, 1 is 100%
+	rise      float64
+	mode      int          // Rendering mode, 3 and 7 are invisible
+	font      *fontMetrics // Nil for unknown fonts
+}
+
+// pageRenderer draws a page content stream onto a thumbnail. It follows the graphics state,
+// fills and strokes paths, places image XObjects and draws text as lines in its color.
+// Shadings, clipping, patterns, transparency and form XObjects are ignored.
+type pageRenderer struct {
+	page   *image.RGBA
+	device matrix                  // Page space to thumbnail pixels
+	images map[string]image.Image  // Image XObjects of the page by resource name
+	fonts  map[string]*fontMetrics // Fonts of the page by resource name
+	state  graphicsState
+	stack  []graphicsState
+	text   textState
+	path   [][]point // Subpaths in pixels
+	budget int       // Pixels that may still be painted
+	drawn  bool      // Whether anything was painted
+}
+
+// newPageRenderer creates a renderer drawing the part of the page space between the lower left
+// and upper right corners, e.g. the crop box, onto a white thumbnail of the given size.
+func newPageRenderer(width, height int, llx, lly, urx, ury float64) *pageRenderer {
+	page := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(page, page.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	sx, sy := float64(width)/(urx-llx), float64(height)/(ury-lly)
+	return &pageRenderer{
+		page: page,
+		// PDF coordinates grow upwards, pixels downwards
+		device: matrix{sx, 0, 0, -sy, -llx * sx, ury * sy},
+		state: graphicsState{
+			ctm:       identity,
+			fill:      color.RGBA{A: 0xff},
+			stroke:    color.RGBA{A: 0xff},
+			lineWidth: 1,
+		},
+		text:   textState{tm: identity, tlm: identity, scale: 1},
+		budget: pixelBudget * width * height,
+	}
+}
+
+// render draws the content stream.
+func (pr *pageRenderer) render(content []byte) {
+	parseContent(content, pr.handle)
+}
+
+// handle executes one operator.
+func (pr *pageRenderer) handle(op string, args []operand) {
+	switch op {
+	// Graphics state
+	case "q":
+		if len(pr.stack) < maxStateDepth {
+			pr.stack = append(pr.stack, pr.state)
+		}
+	case "Q":
+		if len(pr.stack) > 0 {
+			pr.state = pr.stack[len(pr.stack)-1]
+			pr.stack = pr.stack[:len(pr.stack)-1]
+		}
+	case "cm":
+		if n, ok := numbers(args, 6); ok {
+			pr.state.ctm = matrix(n).mul(pr.state.ctm)
+		}
+	case "w":
+		if n, ok := numbers(args, 1); ok {
+			pr.state.lineWidth = n[0]
+		}
+
+	// Colors, the number of operands tells the color space. Setting a color space
+	// starts with black, or no pattern for the Pattern space.
+	case "cs":
+		pr.state.fill, pr.state.fillPattern = color.RGBA{A: 0xff}, isPatternSpace(args)
+	case "CS":
+		pr.state.stroke, pr.state.strokePattern = color.RGBA{A: 0xff}, isPatternSpace(args)
+	case "g", "rg", "k", "sc", "scn":
+		if c, ok := operandColor(args); ok {
+			pr.state.fill, pr.state.fillPattern = c, false
+		} else if isPattern(args) {
+			pr.state.fillPattern = true
+		}
+	case "G", "RG", "K", "SC", "SCN":
+		if c, ok := operandColor(args); ok {
+			pr.state.stroke, pr.state.strokePattern = c, false
+		} else if isPattern(args) {
+			pr.state.strokePattern = true
+		}
+
+	// Paths
+	case "m":
+		if n, ok := numbers(args, 2); ok {
+			pr.path = append(pr.path, []point{pr.toPixels(n[0], n[1])})
+		}
+	case "l":
+		if n, ok := numbers(args, 2); ok {
+			pr.lineTo(pr.toPixels(n[0], n[1]))
+		}
+	case "c", "v", "y":
+		pr.curveTo(op, args)
+	case "h":
+		pr.closePath()
+	case "re":
+		if n, ok := numbers(args, 4); ok {
+			x, y, w, h := n[0], n[1], n[2], n[3]
+			pr.path = append(pr.path, []point{
+				pr.toPixels(x, y), pr.toPixels(x+w, y), pr.toPixels(x+w, y+h), pr.toPixels(x, y+h), pr.toPixels(x, y),
+			})
+		}
+	case "f", "F", "f*":
+		pr.fillPath()
+		pr.path = nil
+	case "S":
+		pr.strokePath()
+		pr.path = nil
+	case "s":
+		pr.closePath()
+		pr.strokePath()
+		pr.path = nil
+	case "B", "B*":
+		pr.fillPath()
+		pr.strokePath()
+		pr.path = nil
+	case "b", "b*":
+		pr.closePath()
+		pr.fillPath()
+		pr.strokePath()
+		pr.path = nil
+	case "n":
+		pr.path = nil
+
+	// Text
+	case "BT":
+		pr.text.tm, pr.text.tlm = identity, identity
+	case "Tf":
+		if len(args) >= 2 {
+			name, _ := args[len(args)-2].(pdfName)
+			pr.text.font = pr.fonts[string(name)]
+			pr.text.size, _ = args[len(args)-1].(float64)
+		}
+	case "TL", "Tc", "Tw", "Tz", "Ts", "Tr":
+		n, ok := numbers(args, 1)
+		if !ok {
+			return
+		}
+		switch op {
+		case "TL":
+			pr.text.leading = n[0]
+		case "Tc":
+			pr.text.charSpace = n[0]
+		case "Tw":
+			pr.text.wordSpace = n[0]
+		case "Tz":
+			pr.text.scale = n[0] / 100
+		case "Ts":
+			pr.text.rise = n[0]
+		case "Tr":
+			pr.text.mode = int(n[0])
+		}
+	case "Td", "TD":
+		if n, ok := numbers(args, 2); ok {
+			if op == "TD" {
+				pr.text.leading = -n[1]
+			}
+			pr.nextLine(n[0], n[1])
+		}
+	case "Tm":
+		if n, ok := numbers(args, 6); ok {
+			pr.text.tm, pr.text.tlm = matrix(n), matrix(n)
+		}
+	case "T*":
+		pr.nextLine(0, -pr.text.leading)
+	case "Tj":
+		pr.showText(args)
+	case "'":
+		pr.nextLine(0, -pr.text.leading)
+		pr.showText(args)
+	case "\"":
+		if n, ok := numbers(args[:max(0, len(args)-1)], 2); ok {
+			pr.text.wordSpace, pr.text.charSpace = n[0], n[1]
+		}
+		pr.nextLine(0, -pr.text.leading)
+		pr.showText(args)
+	case "TJ":
+		if len(args) == 0 {
+			return
+		}
+		items, _ := args[len(args)-1].([]operand)
+		for _, item := range items {
+			switch item := item.(type) {
+			case string:
+				pr.showString(item)
+			case float64:
+				pr.text.tm = translation(-item/1000*pr.text.size*pr.text.scale, 0).mul(pr.text.tm)
+			}
+		}
+
+	// External objects
+	case "Do":
+		if len(args) > 0 {
+			name, _ := args[len(args)-1].(pdfName)
+			if img, ok := pr.images[string(name)]; ok {
+				pr.drawImage(img)
+			}
+		}
+	}
+}
+
+// numbers returns the last n operands if they are numbers.
+func numbers(args []operand, n int) ([]float64, bool) {
+	if len(args) < n {
+		return nil, false
+	}
+	values := make([]float64, n)
+	for i, arg := range args[len(args)-n:] {
+		value, ok := arg.(float64)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+	return values, true
+}
+
+// operandColor reads a gray, RGB or CMYK color from the operands of a color operator.
+// Pattern names and other color spaces are ignored.
+func operandColor(args []operand) (color.RGBA, bool) {
+	component := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v)) * 0xff))
+	}
+	if n, ok := numbers(args, len(args)); ok {
+		switch len(n) {
+		case 1:
+			return color.RGBA{component(n[0]), component(n[0]), component(n[0]), 0xff}, true
+		case 3:
+			return color.RGBA{component(n[0]), component(n[1]), component(n[2]), 0xff}, true
+		case 4:
+			k := 1 - n[3]
+			return color.RGBA{component((1 - n[0]) * k), component((1 - n[1]) * k), component((1 - n[2]) * k), 0xff}, true
+		}
+	}
+	return color.RGBA{}, false
+}
+
+// isPatternSpace reports whether the operands of cs or CS select the Pattern color space.
+func isPatternSpace(args []operand) bool {
+	return len(args) > 0 && args[len(args)-1] == pdfName("Pattern")
+}
+
+// isPattern reports whether the operands of scn or SCN select a pattern.
+func isPattern(args []operand) bool {
+	if len(args) == 0 {
+		return false
+	}
+	_, ok := args[len(args)-1].(pdfName)
+	return ok
+}
+
+// toPixels transforms a point of user space to the thumbnail.
+func (pr *pageRenderer) toPixels(x, y float64) point {
+	return pr.state.ctm.mul(pr.device).apply(x, y)
+}
+
+// lineTo adds a line to the current subpath.
+func (pr *pageRenderer) lineTo(p point) {
+	if len(pr.path) == 0 {
+		pr.path = append(pr.path, []point{p})
+		return
+	}
+	pr.path[len(pr.path)-1] = append(pr.path[len(pr.path)-1], p)
+}
+
+// curveTo adds a Bézier curve of the c, v or y operator to the current subpath, drawn as lines.
+func (pr *pageRenderer) curveTo(op string, args []operand) {
+	count := 6
+	if op != "c" {
+		count = 4
+	}
+	n, ok := numbers(args, count)
+	if !ok || len(pr.path) == 0 {
+		return
+	}
+	subpath := pr.path[len(pr.path)-1]
+	p0 := subpath[len(subpath)-1]
+	var p1, p2, p3 point
+	switch op {
+	case "c":
+		p1, p2, p3 = pr.toPixels(n[0], n[1]), pr.toPixels(n[2], n[3]), pr.toPixels(n[4], n[5])
+	case "v":
+		p1, p2, p3 = p0, pr.toPixels(n[0], n[1]), pr.toPixels(n[2], n[3])
+	case "y":
+		p1, p2, p3 = pr.toPixels(n[0], n[1]), pr.toPixels(n[2], n[3]), pr.toPixels(n[2], n[3])
+	}
+	for i := 1; i <= curveSegments; i++ {
+		t := float64(i) / curveSegments
+		u := 1 - t
+		pr.lineTo(point{
+			u*u*u*p0.x + 3*u*u*t*p1.x + 3*u*t*t*p2.x + t*t*t*p3.x,
+			u*u*u*p0.y + 3*u*u*t*p1.y + 3*u*t*t*p2.y + t*t*t*p3.y,
+		})
+	}
+}
+
+// closePath closes the current subpath with a line to its start.
+func (pr *pageRenderer) closePath() {
+	if len(pr.path) == 0 {
+		return
+	}
+	subpath := pr.path[len(pr.path)-1]
+	pr.lineTo(subpath[0])
+}
+
+// fillPath fills the current path with the fill color.
+func (pr *pageRenderer) fillPath() {
+	if !pr.state.fillPattern {
+		pr.fillPolygons(pr.path, pr.state.fill)
+	}
+}
+
+// strokePath draws the lines of the current path with the stroke color and line width.
+func (pr *pageRenderer) strokePath() {
+	if pr.state.strokePattern {
+		return
+	}
+	m := pr.state.ctm.mul(pr.device)
+	// Zero is the thinnest line the device can draw
+	half := math.Max(0.5, pr.state.lineWidth*math.Sqrt(math.Abs(m[0]*m[3]-m[1]*m[2]))/2)
+
+	var quads [][]point
+	for _, subpath := range pr.path {
+		for i := 1; i < len(subpath); i++ {
+			a, b := subpath[i-1], subpath[i]
+			dx, dy := b.x-a.x, b.y-a.y
+			length := math.Hypot(dx, dy)
+			if length == 0 {
+				continue
+			}
+			// Lines are extended by half their width, like projecting caps
+			nx, ny := -dy/length*half, dx/length*half
+			ex, ey := dx/length*half, dy/length*half
+			quads = append(quads, []point{
+				{a.x - ex + nx, a.y - ey + ny}, {b.x + ex + nx, b.y + ey + ny},
+				{b.x + ex - nx, b.y + ey - ny}, {a.x - ex - nx, a.y - ey - ny},
+			})
+		}
+	}
+	pr.fillPolygons(quads, pr.state.stroke)
+}
+
+// fillPolygons paints the polygons with an anti-aliased fill of the color. Overlapping
+// polygons are painted once.
+func (pr *pageRenderer) fillPolygons(polygons [][]point, c color.RGBA) {
+	bounds := image.Rectangle{}
+	for _, polygon := range polygons {
+		for _, p := range polygon {
+			r := image.Rect(int(math.Floor(clampCoord(p.x))), int(math.Floor(clampCoord(p.y))),
+				int(math.Ceil(clampCoord(p.x)))+1, int(math.Ceil(clampCoord(p.y)))+1)
+			bounds = bounds.Union(r)
+		}
+	}
+	bounds = bounds.Intersect(pr.page.Bounds())
+	if bounds.Empty() || !pr.spend(bounds) {
+		return
+	}
+
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	for _, polygon := range polygons {
+		if len(polygon) < 2 {
+			continue
+		}
+		for i, p := range polygon {
+			x, y := float32(clampCoord(p.x)-float64(bounds.Min.X)), float32(clampCoord(p.y)-float64(bounds.Min.Y))
+			if i == 0 {
+				z.MoveTo(x, y)
+			} else {
+				z.LineTo(x, y)
+			}
+		}
+		z.ClosePath()
+	}
+	z.Draw(pr.page, bounds, image.NewUniform(c), image.Point{})
+	pr.drawn = true
+}
+
+// clampCoord keeps coordinates of huge or invalid paths near the thumbnail, where they
+// cannot make the rasterizer loop for long.
+func clampCoord(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return math.Max(-4*MaxSize, math.Min(5*MaxSize, v))
+}
+
+// spend takes the area from the pixel budget, it reports false once the budget is used up.
+func (pr *pageRenderer) spend(r image.Rectangle) bool {
+	area := r.Dx() * r.Dy()
+	if area > pr.budget {
+		pr.budget = 0
+		return false
+	}
+	pr.budget -= area
+	return true
+}
+
+// nextLine moves to the start of the next line, offset from the start of the current one.
+func (pr *pageRenderer) nextLine(x, y float64) {
+	pr.text.tlm = translation(x, y).mul(pr.text.tlm)
+	pr.text.tm = pr.text.tlm
+}
+
+// showText shows the string operand of a text operator.
+func (pr *pageRenderer) showText(args []operand) {
+	if len(args) == 0 {
+		return
+	}
+	if s, ok := args[len(args)-1].(string); ok {
+		pr.showString(s)
+	}
+}
+
+// showString draws a line where the glyphs of the string go and moves the text matrix past them.
+func (pr *pageRenderer) showString(s string) {
+	t := &pr.text
+	var advance float64
+	if t.font != nil && t.font.twoByte {
+		advance = float64(len(s)/2) * (glyphWidth*t.size + t.charSpace)
+	} else {
+		for i := 0; i < len(s); i++ {
+			advance += t.font.advance(int(s[i]))*t.size + t.charSpace
+			// Word spacing applies to the single byte space only
+			if s[i] == ' ' {
+				advance += t.wordSpace
+			}
+		}
+	}
+	advance *= t.scale
+
+	if advance > 0 && t.mode != 3 && t.mode != 7 && !pr.state.fillPattern {
+		m := t.tm.mul(pr.state.ctm).mul(pr.device)
+		bottom, top := t.rise+textBottom*t.size, t.rise+textTop*t.size
+		// Colors are premultiplied, the opacity scales all components
+		fade := func(v uint8) uint8 { return uint8(float64(v) * textOpacity) }
+		c := pr.state.fill
+		pr.fillPolygons([][]point{{
+			m.apply(0, bottom), m.apply(advance, bottom), m.apply(advance, top), m.apply(0, top),
+		}}, color.RGBA{fade(c.R), fade(c.G), fade(c.B), fade(c.A)})
+	}
+	t.tm = translation(advance, 0).mul(t.tm)
+}
+
+// drawImage draws an image XObject into the unit square of the current transformation.
+func (pr *pageRenderer) drawImage(img image.Image) {
+	m := pr.state.ctm.mul(pr.device)
+	if math.Abs(m[0]*m[3]-m[1]*m[2]) < 1e-9 {
+		return
+	}
+	corners := []point{m.apply(0, 0), m.apply(1, 0), m.apply(0, 1), m.apply(1, 1)}
+	bounds := image.Rectangle{}
+	for _, p := range corners {
+		bounds = bounds.Union(image.Rect(int(math.Floor(clampCoord(p.x))), int(math.Floor(clampCoord(p.y))),
+			int(math.Ceil(clampCoord(p.x))), int(math.Ceil(clampCoord(p.y)))))
+	}
+	if bounds.Intersect(pr.page.Bounds()).Empty() || !pr.spend(bounds.Intersect(pr.page.Bounds())) {
+		return
+	}
+
+	// Scaling down first keeps the transformation cheap and smooth
+	width := int(math.Ceil(math.Hypot(m[0], m[1])))
+	height := int(math.Ceil(math.Hypot(m[2], m[3])))
+	src := img.Bounds()
+	if width < src.Dx() || height < src.Dy() {
+		small := image.NewRGBA(image.Rect(0, 0, max(1, min(width, src.Dx())), max(1, min(height, src.Dy()))))
+		draw.CatmullRom.Scale(small, small.Bounds(), img, src, draw.Src, nil)
+		img, src = small, small.Bounds()
+	}
+
+	// Image space has its origin at the top left, it maps to the unit square upside down
+	w, h := float64(src.Dx()), float64(src.Dy())
+	s2d := f64.Aff3{
+		m[0] / w, -m[2] / h, m[2] + m[4] - (m[0]/w)*float64(src.Min.X) + (m[2]/h)*float64(src.Min.Y),
+		m[1] / w, -m[3] / h, m[3] + m[5] - (m[1]/w)*float64(src.Min.X) + (m[3]/h)*float64(src.Min.Y),
+	}
+	draw.BiLinear.Transform(pr.page, s2d, img, src, draw.Over, nil)
+	pr.drawn = true
+}
@@ -0,0 +1,114 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime"
+
+	// Decoders of the accepted document images
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/draw"
+)
+
+// ContentType is the type of generated thumbnails.
+const ContentType = "image/jpeg"
+
+// MaxSize is the longest side of a thumbnail in pixels. Smaller images are not enlarged.
+const MaxSize = 320
+
+// MaxPixels limits the images decoded for a thumbnail, so a small file declaring a huge
+// image cannot exhaust the memory of the server.
+const MaxPixels = 50_000_000
+
+// quality of the JPEG encoding
+const quality = 80
+
+// ErrUnsupported is returned for files that have no preview, e.g. text files
+// and PDFs whose first page draws nothing.
+var ErrUnsupported = errors.New("no preview for this file")
+
+// Thumbnail is a generated preview image.
+type Thumbnail struct {
+	Data   []byte // JPEG encoded image
+	Width  int
+	Height int
+}
+
+// Generate creates the thumbnail of a document file of the given MIME type.
+// Images are scaled down, PDFs show their first page, see renderFirstPage.
+func Generate(data []byte, contentType string) (*Thumbnail, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var thumb *image.RGBA
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		img, err := decodeImage(data)
+		if err != nil {
+			return nil, err
+		}
+		// Turning the small thumbnail is cheaper than turning the photo
+		thumb = scale(img, MaxSize)
+		if mediaType == "image/jpeg" {
+			thumb = orient(thumb, jpegOrientation(data))
+		}
+	case "application/pdf":
+		var err error
+		if thumb, err = renderFirstPage(data, MaxSize); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return &Thumbnail{Data: buf.Bytes(), Width: thumb.Bounds().Dx(), Height: thumb.Bounds().Dy()}, nil
+}
+
+// decodeImage decodes an image after checking its dimensions against MaxPixels.
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("image is empty")
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// fit returns the size of a width x height rectangle scaled down to fit into a square of the given size.
+func fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// scale draws the image onto a white background of at most size pixels on each side,
+// transparent parts of PNGs and GIFs become white in the JPEG.
+func scale(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := fit(bounds.Dx(), bounds.Dy(), size)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,200 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// generate creates the thumbnail of a file in testdata and decodes it.
+func generate(t *testing.T, name, contentType string) image.Image {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := Generate(data, contentType)
+	if err != nil {
+		t.Fatalf("Generate(%s) = %v", name, err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb.Data))
+	if err != nil {
+		t.Fatalf("thumbnail of %s is no JPEG: %v", name, err)
+	}
+	if img.Bounds().Dx() != thumb.Width || img.Bounds().Dy() != thumb.Height {
+		t.Errorf("thumbnail of %s is %v, reported %dx%d", name, img.Bounds().Size(), thumb.Width, thumb.Height)
+	}
+	return img
+}
+
+// checkSize fails the test unless the image has the given size.
+func checkSize(t *testing.T, img image.Image, width, height int) {
+	t.Helper()
+	if size := img.Bounds().Size(); size != image.Pt(width, height) {
+		t.Fatalf("thumbnail is %v, want %dx%d", size, width, height)
+	}
+}
+
+// checkColor fails the test unless the pixel is close to the color, JPEG shifts colors a little.
+func checkColor(t *testing.T, img image.Image, x, y int, want color.RGBA) {
+	t.Helper()
+	r, g, b, _ := img.At(x, y).RGBA()
+	got := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}
+	near := func(a, b uint8) bool { return int(a)-int(b) < 40 && int(b)-int(a) < 40 }
+	if !near(got.R, want.R) || !near(got.G, want.G) || !near(got.B, want.B) {
+		t.Errorf("pixel %d,%d is %v, want %v", x, y, got, want)
+	}
+}
+
+var (
+	white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	red   = color.RGBA{0xff, 0, 0, 0xff}
+	green = color.RGBA{0, 0xff, 0, 0xff}
+	blue  = color.RGBA{0, 0, 0xff, 0xff}
+	grey  = color.RGBA{0x80, 0x80, 0x80, 0xff}
+)
+
+func TestGenerateImage(t *testing.T) {
+	// Small images are not enlarged, transparency turns white
+	img := generate(t, "transparent.png", "image/png")
+	checkSize(t, img, 10, 10)
+	checkColor(t, img, 0, 0, white)
+	checkColor(t, img, 5, 5, green)
+}
+
+func TestGenerateOrientedPhoto(t *testing.T) {
+	// The photo is stored lying on its side, left half red, right half blue
+	img := generate(t, "rotated.jpg", "image/jpeg")
+	checkSize(t, img, 20, 40)
+	checkColor(t, img, 10, 5, red)
+	checkColor(t, img, 10, 35, blue)
+}
+
+func TestGeneratePDF(t *testing.T) {
+	// A page of 200x100 points with two lines of text above a blue bar
+	img := generate(t, "text.pdf", "application/pdf")
+	checkSize(t, img, 320, 160)
+	checkColor(t, img, 300, 10, white)
+	checkColor(t, img, 64, 43, grey)
+	checkColor(t, img, 64, 65, grey)
+	checkColor(t, img, 160, 120, blue)
+
+	// Pages are turned by their Rotate entry
+	img = generate(t, "rotated.pdf", "application/pdf")
+	checkSize(t, img, 160, 320)
+	checkColor(t, img, 40, 160, blue)
+
+	// A scan of 100x100 points in the middle of the page
+	img = generate(t, "scan.pdf", "application/pdf")
+	checkSize(t, img, 320, 160)
+	checkColor(t, img, 160, 80, red)
+	checkColor(t, img, 20, 80, white)
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	blank, err := os.ReadFile(filepath.Join("testdata", "blank.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []struct {
+		data        []byte
+		contentType string
+	}{
+		{blank, "application/pdf"},
+		{[]byte("Hemoglobin 14.2 g/dl"), "text/plain"},
+	} {
+		if _, err := Generate(file.data, file.contentType); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Generate(%s) = %v, want ErrUnsupported", file.contentType, err)
+		}
+	}
+
+	// Broken files fail without taking the caller down
+	for _, contentType := range []string{"application/pdf", "image/png", "image/jpeg"} {
+		if _, err := Generate([]byte("%PDF-1.4\n1 0 obj\n<< /Type"), contentType); err == nil || errors.Is(err, ErrUnsupported) {
+			t.Errorf("Generate(broken %s) = %v, want an error", contentType, err)
+		}
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	photo, err := os.ReadFile(filepath.Join("testdata", "rotated.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jpegOrientation(photo); got != 6 {
+		t.Errorf("jpegOrientation(rotated.jpg) = %d, want 6", got)
+	}
+	// Files without EXIF or cut short are upright
+	for _, data := range [][]byte{nil, []byte("not a JPEG"), photo[:30]} {
+		if got := jpegOrientation(data); got != 1 {
+			t.Errorf("jpegOrientation(%q) = %d, want 1", data, got)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// Where the top left pixel of a 3x2 image ends up, and the size of the result
+	for orientation, want := range map[int]struct {
+		corner image.Point
+		size   image.Point
+	}{
+		1: {image.Pt(0, 0), image.Pt(3, 2)},
+		2: {image.Pt(2, 0), image.Pt(3, 2)},
+		3: {image.Pt(2, 1), image.Pt(3, 2)},
+		4: {image.Pt(0, 1), image.Pt(3, 2)},
+		5: {image.Pt(0, 0), image.Pt(2, 3)},
+		6: {image.Pt(1, 0), image.Pt(2, 3)},
+		7: {image.Pt(1, 2), image.Pt(2, 3)},
+		8: {image.Pt(0, 2), image.Pt(2, 3)},
+	} {
+		src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+		src.SetRGBA(0, 0, red)
+		dst := orient(src, orientation)
+		if size := dst.Bounds().Size(); size != want.size {
+			t.Errorf("orient(%d) size = %v, want %v", orientation, size, want.size)
+			continue
+		}
+		if got := dst.RGBAAt(want.corner.X, want.corner.Y); got != red {
+			t.Errorf("orient(%d) pixel %v = %v, want the corner", orientation, want.corner, got)
+		}
+	}
+}
+
+func TestParseContent(t *testing.T) {
+	content := []byte(`% comment
+q 1 0 0 -1 0 792 cm
+BT /F1 12 Tf (a \(nested (b)\) \101\n) Tj <48 65 6c6C 6> Tj [(x) -250 (y)] TJ ET
+BI /W 2 /H 1 /BPC 8 /CS /G ID ab EI
+/P << /MCID 0 /Nested << /A (>>) >> >> BDC EMC`)
+
+	type call struct {
+		op   string
+		args []operand
+	}
+	var calls []call
+	parseContent(content, func(op string, args []operand) {
+		calls = append(calls, call{op, append([]operand(nil), args...)})
+	})
+
+	want := []call{
+		{"q", nil},
+		{"cm", []operand{1.0, 0.0, 0.0, -1.0, 0.0, 792.0}},
+		{"BT", nil},
+		{"Tf", []operand{pdfName("F1"), 12.0}},
+		{"Tj", []operand{"a (nested (b)) A\n"}},
+		{"Tj", []operand{"Hell`"}},
+		{"TJ", []operand{[]operand{"x", -250.0, "y"}}},
+		{"ET", nil},
+		{"BDC", []operand{pdfName("P"), nil}},
+		{"EMC", nil},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("parseContent =\n%v\nwant\n%v", calls, want)
+	}
+}